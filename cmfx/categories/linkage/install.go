@@ -1,4 +1,4 @@
-// SPDX-FileCopyrightText: 2024-2025 caixw
+// SPDX-FileCopyrightText: 2024-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
@@ -33,6 +33,15 @@ func Install(mod *cmfx.Module, tableName string, linkage *Linkage) *Linkages {
 	return NewLinkages(mod, tableName)
 }
 
+// Uninstall 删除由 [Install] 创建的数据表以及对应的缓存
+func Uninstall(mod *cmfx.Module, tableName string) error {
+	db := buildDB(mod, tableName)
+	if err := db.Drop(&linkagePO{}); err != nil {
+		return err
+	}
+	return mod.Server().Cache().Delete(buildCacheID(db))
+}
+
 func install(tx *orm.Tx, linkage *Linkage, parent int64) error {
 	lastID, err := tx.LastInsertID(linkage.toPO(parent))
 	if err != nil {
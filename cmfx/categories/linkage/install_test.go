@@ -1,4 +1,4 @@
-// SPDX-FileCopyrightText: 2024 caixw
+// SPDX-FileCopyrightText: 2024-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
@@ -31,3 +31,18 @@ func TestInstall(t *testing.T) {
 
 	s.TableExists("mod_lk")
 }
+
+func TestUninstall(t *testing.T) {
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+	defer s.Close()
+
+	mod := s.NewModule("mod")
+	l := Install(mod, "lk", &Linkage{Title: "t1"})
+	s.TableExists("mod_lk")
+	a.True(mod.Server().Cache().Exists(l.cacheID))
+
+	a.NotError(Uninstall(mod, "lk"))
+	s.TableNotExists("mod_lk")
+	a.False(mod.Server().Cache().Exists(l.cacheID))
+}
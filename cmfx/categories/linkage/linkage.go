@@ -1,4 +1,4 @@
-// SPDX-FileCopyrightText: 2024-2025 caixw
+// SPDX-FileCopyrightText: 2024-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
@@ -30,6 +30,9 @@ func buildDB(mod *cmfx.Module, tableName string) *orm.DB {
 	return mod.DB().New(mod.DB().TablePrefix() + "_" + tableName)
 }
 
+// buildCacheID 缓存的键名由表名前缀决定，[Uninstall] 需要根据此值删除缓存。
+func buildCacheID(db *orm.DB) string { return db.TablePrefix() + "_linkages" }
+
 // NewLinkages 声明 [Linkages] 对象
 //
 // mod 所属的模块；tableName 表名的后缀部分；
@@ -49,7 +52,7 @@ func NewLinkages(mod *cmfx.Module, tableName string) *Linkages {
 	root := items[0]
 	buildItems(root, all)
 
-	cacheID := mod.Server().UniqueID()
+	cacheID := buildCacheID(db)
 	if err := mod.Server().Cache().Set(cacheID, root, cache.Forever); err != nil {
 		panic(web.SprintError(mod.Server().Locale().Printer(), true, err))
 	}
@@ -1,4 +1,4 @@
-// SPDX-FileCopyrightText: 2025 caixw
+// SPDX-FileCopyrightText: 2025-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
@@ -34,3 +34,12 @@ func Install(mod *cmfx.Module, tableName string, tag ...string) *Tags {
 
 	return NewTags(mod, tableName)
 }
+
+// Uninstall 删除由 [Install] 创建的数据表以及对应的缓存
+func Uninstall(mod *cmfx.Module, tableName string) error {
+	db := buildDB(mod, tableName)
+	if err := db.Drop(&TagPO{}); err != nil {
+		return err
+	}
+	return mod.Server().Cache().Delete(buildCacheID(db))
+}
@@ -1,4 +1,4 @@
-// SPDX-FileCopyrightText: 2025 caixw
+// SPDX-FileCopyrightText: 2025-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
@@ -24,3 +24,18 @@ func TestInstall(t *testing.T) {
 
 	s.TableExists("mod_lk")
 }
+
+func TestUninstall(t *testing.T) {
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+	defer s.Close()
+
+	mod := s.NewModule("mod")
+	tags := Install(mod, "lk", "t1", "t2")
+	s.TableExists("mod_lk")
+	a.True(mod.Server().Cache().Exists(tags.cacheID))
+
+	a.NotError(Uninstall(mod, "lk"))
+	s.TableNotExists("mod_lk")
+	a.False(mod.Server().Cache().Exists(tags.cacheID))
+}
@@ -1,4 +1,4 @@
-// SPDX-FileCopyrightText: 2025 caixw
+// SPDX-FileCopyrightText: 2025-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
@@ -32,7 +32,7 @@ func NewTags(mod *cmfx.Module, tableName string) *Tags {
 		panic(web.SprintError(mod.Server().Locale().Printer(), true, err))
 	}
 
-	cacheID := mod.Server().UniqueID()
+	cacheID := buildCacheID(db)
 	if err := mod.Server().Cache().Set(cacheID, all, cache.Forever); err != nil {
 		panic(web.SprintError(mod.Server().Locale().Printer(), true, err))
 	}
@@ -48,6 +48,9 @@ func buildDB(mod *cmfx.Module, tableName string) *orm.DB {
 	return mod.DB().New(mod.DB().TablePrefix() + "_" + tableName)
 }
 
+// buildCacheID 缓存的键名由表名前缀决定，[Uninstall] 需要根据此值删除缓存。
+func buildCacheID(db *orm.DB) string { return db.TablePrefix() + "_tags" }
+
 // Get 获得所有内容
 func (m *Tags) Get() ([]*TagPO, error) {
 	list := make([]*TagPO, 0, 10)
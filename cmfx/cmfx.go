@@ -1,4 +1,4 @@
-// SPDX-FileCopyrightText: 2022-2025 caixw
+// SPDX-FileCopyrightText: 2022-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
@@ -61,7 +61,8 @@ const (
 
 // 413
 const (
-	RequestEntityTooLarge = web.ProblemRequestEntityTooLarge
+	RequestEntityTooLarge              = web.ProblemRequestEntityTooLarge
+	RequestEntityTooLargeQuotaExceeded = "41301" // 超出可用的存储空间
 )
 
 func ErrNotFound() error { return locales.ErrNotFound() }
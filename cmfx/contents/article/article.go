@@ -1,4 +1,4 @@
-// SPDX-FileCopyrightText: 2025 caixw
+// SPDX-FileCopyrightText: 2025-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
@@ -6,11 +6,17 @@
 package article
 
 import (
+	"context"
+	"strconv"
+
+	"github.com/issue9/events"
 	"github.com/issue9/orm/v6"
 
 	"github.com/issue9/cmfx/cmfx"
 	"github.com/issue9/cmfx/cmfx/categories/linkage"
 	"github.com/issue9/cmfx/cmfx/categories/tag"
+	"github.com/issue9/cmfx/cmfx/modules/upload"
+	"github.com/issue9/cmfx/cmfx/outbox"
 	"github.com/issue9/cmfx/cmfx/relationship"
 )
 
@@ -19,6 +25,17 @@ const (
 	tagsTableName   = "tags"
 )
 
+// 发布至 [outbox.Outbox] 的事件名称，可通过 [Articles.Topic] 转换成主题。
+const TopicPublish = "publish" // 发布文章
+
+// Event 与文章相关的事件
+type Event struct {
+	ID      int64  `json:"id"`
+	Slug    string `json:"slug"`
+	Title   string `json:"title"`
+	Creator int64  `json:"creator"`
+}
+
 // Articles 提供文章内容管理模块
 type Articles struct {
 	db       *orm.DB
@@ -27,6 +44,10 @@ type Articles struct {
 	tags     *tag.Tags
 	topicRel *relationship.Relationships[int64, int64] // 主题与文章的关联，0 文章，1 主题
 	tagRel   *relationship.Relationships[int64, int64] // 标签与文章的关联，0 文章，1 标签
+	up       *upload.Module
+
+	publishEvent *events.Event[*Event]
+	outbox       *outbox.Outbox // 如果未声明 [outbox.Outbox]，则为空。
 }
 
 func buildDB(mod *cmfx.Module, tableName string) *orm.DB {
@@ -37,7 +58,8 @@ func buildDB(mod *cmfx.Module, tableName string) *orm.DB {
 //
 // mod 所属的模块；
 // tablePrefix 其它表都以此作为表名前缀；
-func NewArticles(mod *cmfx.Module, tablePrefix string) *Articles {
+// up 上传模块，文章的缩略图会在其中登记引用，可以为空；
+func NewArticles(mod *cmfx.Module, tablePrefix string, up *upload.Module) *Articles {
 	m := &Articles{
 		db:       buildDB(mod, tablePrefix),
 		mod:      mod,
@@ -45,13 +67,47 @@ func NewArticles(mod *cmfx.Module, tablePrefix string) *Articles {
 		tags:     tag.NewTags(mod, tablePrefix+"_"+tagsTableName),
 		topicRel: relationship.NewRelationships[int64, int64](mod, tablePrefix+"_article_topic"),
 		tagRel:   relationship.NewRelationships[int64, int64](mod, tablePrefix+"_article_tag"),
+		up:       up,
+
+		publishEvent: events.New[*Event](),
+		outbox:       outbox.Get(mod.Server()),
 	}
 
 	return m
 }
 
+// OnPublish 发布文章之后的事件
+func (m *Articles) OnPublish(f func(*Event)) context.CancelFunc { return m.publishEvent.Subscribe(f) }
+
+// Outbox 发布事件的 [outbox.Outbox] 对象
+//
+// 如果未声明 [outbox.Outbox]，则返回 nil。
+func (m *Articles) Outbox() *outbox.Outbox { return m.outbox }
+
+// Topic 将事件名称 name 转换成当前对象在 [outbox.Outbox] 中的主题
+//
+// name 可以是 [TopicPublish]。
+func (m *Articles) Topic(name string) string { return m.db.TablePrefix() + "_" + name }
+
+func (m *Articles) publish(tx *orm.Tx, name string, e *Event) error {
+	if m.outbox == nil {
+		return nil
+	}
+	return m.outbox.Publish(tx, m.Topic(name), e)
+}
+
 // Topics 用到的主题分类
 func (m *Articles) Topics() *linkage.Linkages { return m.topics }
 
 // Tags 用到的标签分类
 func (m *Articles) Tags() *tag.Tags { return m.tags }
+
+// 将文章 article 的缩略图登记到上传模块
+//
+// 仅登记最新快照的缩略图，images 为空表示取消所有引用。
+func (m *Articles) setImageRefs(tx *orm.Tx, article int64, images ...string) error {
+	if m.up == nil {
+		return nil
+	}
+	return m.up.SetRefs(tx, m.db.TablePrefix()+"_images_"+strconv.FormatInt(article, 10), images...)
+}
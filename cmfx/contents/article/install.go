@@ -1,4 +1,4 @@
-// SPDX-FileCopyrightText: 2025 caixw
+// SPDX-FileCopyrightText: 2025-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
@@ -10,13 +10,14 @@ import (
 	"github.com/issue9/cmfx/cmfx"
 	"github.com/issue9/cmfx/cmfx/categories/linkage"
 	"github.com/issue9/cmfx/cmfx/categories/tag"
+	"github.com/issue9/cmfx/cmfx/modules/upload"
 	"github.com/issue9/cmfx/cmfx/relationship"
 )
 
 // Install 安装数据
 //
-// mod 所属模块；tablePrefix 表名前缀；ts 关联的标签列表；
-func Install(mod *cmfx.Module, tablePrefix string, ts ...string) *Articles {
+// mod 所属模块；tablePrefix 表名前缀；up 上传模块，可以为空；ts 关联的标签列表；
+func Install(mod *cmfx.Module, tablePrefix string, up *upload.Module, ts ...string) *Articles {
 	linkage.Install(mod, tablePrefix+"_"+topicsTableName, &linkage.Linkage{Title: "topics"})
 	tag.Install(mod, tablePrefix+"_"+tagsTableName, ts...)
 	relationship.Install[int64, int64](mod, tablePrefix+"_article_topic")
@@ -27,5 +28,5 @@ func Install(mod *cmfx.Module, tablePrefix string, ts ...string) *Articles {
 		panic(web.SprintError(mod.Server().Locale().Printer(), true, err))
 	}
 
-	return NewArticles(mod, tablePrefix)
+	return NewArticles(mod, tablePrefix, up)
 }
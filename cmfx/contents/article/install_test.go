@@ -1,4 +1,4 @@
-// SPDX-FileCopyrightText: 2025 caixw
+// SPDX-FileCopyrightText: 2025-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
@@ -19,7 +19,7 @@ func TestInstall(t *testing.T) {
 	defer s.Close()
 
 	mod := s.NewModule("test")
-	Install(mod, "abc")
+	Install(mod, "abc", nil)
 	prefix := mod.ID() + "_" + "abc"
 
 	s.TableExists(prefix + "_article_snapshots").
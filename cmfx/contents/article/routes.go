@@ -269,6 +269,7 @@ func (m *Articles) HandlePostArticle(ctx *web.Context, creator int64) web.Respon
 		return resp
 	}
 
+	var event *Event
 	err := m.db.DoTransactionTx(ctx, nil, func(tx *orm.Tx) error {
 		article, err := tx.LastInsertID(&articlePO{ // 添加文章
 			Slug:     a.Slug,
@@ -298,6 +299,10 @@ func (m *Articles) HandlePostArticle(ctx *web.Context, creator int64) web.Respon
 			return err
 		}
 
+		if err := m.setImageRefs(tx, article, a.Images...); err != nil {
+			return err
+		}
+
 		// 插入标签关系表
 		for _, t := range a.Tags {
 			if err := m.tagRel.Add(tx, article, t); err != nil {
@@ -313,13 +318,18 @@ func (m *Articles) HandlePostArticle(ctx *web.Context, creator int64) web.Respon
 		}
 
 		// 更新 Article.Last
-		_, err = tx.Update(&articlePO{ID: article, Last: snapshot})
-		return err
+		if _, err = tx.Update(&articlePO{ID: article, Last: snapshot}); err != nil {
+			return err
+		}
+
+		event = &Event{ID: article, Slug: a.Slug, Title: a.Title, Creator: creator}
+		return m.publish(tx, TopicPublish, event)
 	})
 	if err != nil {
 		return ctx.Error(err, "")
 	}
 
+	m.publishEvent.Publish(true, event)
 	return web.Created(nil, "")
 }
 
@@ -376,6 +386,10 @@ func (m *Articles) HandlePatchArticle(ctx *web.Context, article, modifier int64)
 			return err
 		}
 
+		if err = m.setImageRefs(tx, article, a.Images...); err != nil {
+			return err
+		}
+
 		if err = m.tagRel.DeleteByV1(tx, article); err != nil { // 删除旧的关系
 			return err
 		}
@@ -427,6 +441,9 @@ func (m *Articles) HandleDeleteArticle(ctx *web.Context, article, deleter int64)
 		if err = m.topicRel.DeleteByV1(tx, article); err != nil { // 删除旧的关系
 			return err
 		}
+		if err = m.setImageRefs(tx, article); err != nil {
+			return err
+		}
 
 		_, err = tx.NewEngine(m.db.TablePrefix()).Update(&articlePO{
 			ID:      article,
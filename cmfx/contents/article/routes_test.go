@@ -1,4 +1,4 @@
-// SPDX-FileCopyrightText: 2025 caixw
+// SPDX-FileCopyrightText: 2025-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/issue9/assert/v4"
 	"github.com/issue9/mux/v9/header"
@@ -18,6 +19,7 @@ import (
 
 	"github.com/issue9/cmfx/cmfx"
 	"github.com/issue9/cmfx/cmfx/initial/test"
+	"github.com/issue9/cmfx/cmfx/modules/upload/uploadtest"
 	"github.com/issue9/cmfx/cmfx/query"
 )
 
@@ -26,7 +28,7 @@ func TestModule_Handle(t *testing.T) {
 	s := test.NewSuite(a)
 
 	mod := s.NewModule("article")
-	m := Install(mod, "a", "t1", "t2")
+	m := Install(mod, "a", uploadtest.NewModule(s, "upload"), "t1", "t2")
 
 	s.Router().
 		Post("/articles", func(ctx *web.Context) web.Responser { return m.HandlePostArticle(ctx, 1) }).
@@ -97,12 +99,18 @@ func TestModule_Handle(t *testing.T) {
 		Views:    10,
 		Order:    1,
 	}
+	var published *Event
+	m.OnPublish(func(e *Event) { published = e })
 	data, err := json.Marshal(article)
 	a.NotError(err)
 	s.Post("/articles", data).
 		Header(header.ContentType, header.JSON).Header(header.Accept, header.JSON).
 		Do(nil).
 		Status(http.StatusCreated)
+	a.Wait(time.Millisecond * 500) // OnPublish 是个异步方法
+	a.NotNil(published).
+		Equal(published.Slug, "slug").
+		Equal(published.Creator, 1)
 	spo := &snapshotPO{}
 	ssize, err := m.db.Where("true").Select(true, spo)
 	a.NotError(err).Equal(ssize, 1)
@@ -1,19 +1,37 @@
-// SPDX-FileCopyrightText: 2025 caixw
+// SPDX-FileCopyrightText: 2025-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
 package comment
 
 import (
+	"context"
+
+	"github.com/issue9/events"
 	"github.com/issue9/orm/v6"
 
 	"github.com/issue9/cmfx/cmfx"
+	"github.com/issue9/cmfx/cmfx/outbox"
 )
 
+// 发布至 [outbox.Outbox] 的事件名称，可通过 [Comments.Topic] 转换成主题。
+const TopicPost = "post" // 发表评论
+
+// Event 与评论相关的事件
+type Event struct {
+	ID      int64 `json:"id"`
+	Target  int64 `json:"target"`
+	Parent  int64 `json:"parent,omitempty"`
+	Creator int64 `json:"creator"`
+}
+
 // Comments 评论管理
 type Comments struct {
 	mod *cmfx.Module
 	db  *orm.DB
+
+	postEvent *events.Event[*Event]
+	outbox    *outbox.Outbox // 如果未声明 [outbox.Outbox]，则为空。
 }
 
 func buildDB(mod *cmfx.Module, tableName string) *orm.DB {
@@ -24,7 +42,30 @@ func NewComments(mod *cmfx.Module, tablePrefix string) *Comments {
 	m := &Comments{
 		mod: mod,
 		db:  buildDB(mod, tablePrefix),
+
+		postEvent: events.New[*Event](),
+		outbox:    outbox.Get(mod.Server()),
 	}
 
 	return m
 }
+
+// OnPost 发表评论之后的事件
+func (m *Comments) OnPost(f func(*Event)) context.CancelFunc { return m.postEvent.Subscribe(f) }
+
+// Outbox 发布事件的 [outbox.Outbox] 对象
+//
+// 如果未声明 [outbox.Outbox]，则返回 nil。
+func (m *Comments) Outbox() *outbox.Outbox { return m.outbox }
+
+// Topic 将事件名称 name 转换成当前对象在 [outbox.Outbox] 中的主题
+//
+// name 可以是 [TopicPost]。
+func (m *Comments) Topic(name string) string { return m.db.TablePrefix() + "_" + name }
+
+func (m *Comments) publish(tx *orm.Tx, name string, e *Event) error {
+	if m.outbox == nil {
+		return nil
+	}
+	return m.outbox.Publish(tx, m.Topic(name), e)
+}
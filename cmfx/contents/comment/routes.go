@@ -187,6 +187,7 @@ func (m *Comments) HandlePostComment(ctx *web.Context, creator, target int64) we
 		return resp
 	}
 
+	var event *Event
 	err := m.db.DoTransactionTx(ctx, nil, func(tx *orm.Tx) error {
 		comment, err := tx.LastInsertID(&commentPO{ // 添加评论
 			Author:   a.Author,
@@ -211,13 +212,18 @@ func (m *Comments) HandlePostComment(ctx *web.Context, creator, target int64) we
 		}
 
 		// 更新 Comment.Last
-		_, err = tx.Update(&commentPO{ID: comment, Last: snapshot})
-		return err
+		if _, err = tx.Update(&commentPO{ID: comment, Last: snapshot}); err != nil {
+			return err
+		}
+
+		event = &Event{ID: comment, Target: target, Parent: a.Parent, Creator: creator}
+		return m.publish(tx, TopicPost, event)
 	})
 	if err != nil {
 		return ctx.Error(err, "")
 	}
 
+	m.postEvent.Publish(true, event)
 	return web.Created(nil, "")
 }
 
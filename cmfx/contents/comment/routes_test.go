@@ -11,6 +11,7 @@ import (
 	"net/http"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/issue9/assert/v4"
 	"github.com/issue9/mux/v9/header"
@@ -83,12 +84,18 @@ func TestModule_Handle(t *testing.T) {
 		Content: "content<p>line</p>",
 		Rate:    5,
 	}
+	var posted *Event
+	m.OnPost(func(e *Event) { posted = e })
 	data, err := json.Marshal(article)
 	a.NotError(err)
 	s.Post("/comments", data).
 		Header(header.ContentType, header.JSON).Header(header.Accept, header.JSON).
 		Do(nil).
 		Status(http.StatusCreated).BodyFunc(func(a *assert.Assertion, body []byte) { fmt.Println(string(body)) })
+	a.Wait(time.Millisecond * 500) // OnPost 是个异步方法
+	a.NotNil(posted).
+		Equal(posted.Target, 1).
+		Equal(posted.Creator, 1)
 	spo := &snapshotPO{}
 	ssize, err := m.db.Where("true").Select(true, spo)
 	a.NotError(err).Equal(ssize, 1)
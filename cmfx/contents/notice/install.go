@@ -1,4 +1,4 @@
-// SPDX-FileCopyrightText: 2025 caixw
+// SPDX-FileCopyrightText: 2025-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
@@ -7,6 +7,7 @@ package notice
 import (
 	"github.com/issue9/web"
 
+	"github.com/issue9/cmfx/cmfx"
 	"github.com/issue9/cmfx/cmfx/categories/tag"
 	"github.com/issue9/cmfx/cmfx/user"
 )
@@ -22,3 +23,13 @@ func Install(u *user.Users) *Notices {
 
 	return NewNotices(u)
 }
+
+// Uninstall 删除由 [Install] 创建的数据表
+//
+// mod 为 [Install] 中用户系统所在的模块，即 [user.Users.Module]。
+func Uninstall(mod *cmfx.Module) error {
+	if err := mod.DB().Drop(&noticePO{}, &groupPO{}); err != nil {
+		return err
+	}
+	return tag.Uninstall(mod, typesKey)
+}
@@ -1,4 +1,4 @@
-// SPDX-FileCopyrightText: 2025 caixw
+// SPDX-FileCopyrightText: 2025-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
@@ -25,4 +25,9 @@ func TestInstall(t *testing.T) {
 	s.TableExists(u.Module().ID() + "_" + typesKey).
 		TableExists(u.Module().ID() + "_notice_groups").
 		TableExists(u.Module().ID() + "_notices")
+
+	a.NotError(Uninstall(u.Module()))
+	s.TableNotExists(u.Module().ID() + "_" + typesKey).
+		TableNotExists(u.Module().ID() + "_notice_groups").
+		TableNotExists(u.Module().ID() + "_notices")
 }
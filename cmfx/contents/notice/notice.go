@@ -1,4 +1,4 @@
-// SPDX-FileCopyrightText: 2025 caixw
+// SPDX-FileCopyrightText: 2025-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
@@ -7,7 +7,10 @@ package notice
 
 import (
 	"iter"
+	"slices"
+	"time"
 
+	"github.com/issue9/orm/v6"
 	"github.com/issue9/web"
 
 	"github.com/issue9/cmfx/cmfx/categories/tag"
@@ -58,3 +61,47 @@ func (m *Notices) RegisterFilter(name string, g Filter) error {
 	m.filters[name] = g
 	return nil
 }
+
+// Send 发送通知
+//
+// creator 为发送者的 ID，系统发送的通知可以为 0；
+// typ 为通知类型，即 [Notices.Types] 中的 ID；
+// uids 为接收通知的用户，如果为空，表示发送给所有用户；
+func (m *Notices) Send(creator, typ int64, author, title, content string, uids ...int64) error {
+	po := &noticePO{
+		NO:      m.user.Module().Server().UniqueID(),
+		Created: time.Now(),
+		Creator: creator,
+		All:     len(uids) == 0,
+		Type:    typ,
+		Author:  author,
+		Title:   title,
+		Content: content,
+	}
+
+	if po.All {
+		return m.insert(po, nil)
+	}
+	return m.insert(po, slices.Values(uids))
+}
+
+// 写入通知内容，uids 为空表示所有用户。
+func (m *Notices) insert(po *noticePO, uids iter.Seq[int64]) error {
+	if uids == nil {
+		_, err := m.user.Module().DB().Insert(po)
+		return err
+	}
+
+	return m.user.Module().DB().DoTransaction(func(tx *orm.Tx) error {
+		id, err := tx.LastInsertID(po)
+		if err != nil {
+			return err
+		}
+
+		gs := make([]orm.TableNamer, 0, 100)
+		for uid := range uids {
+			gs = append(gs, &groupPO{NID: id, UID: uid})
+		}
+		return tx.InsertMany(100, gs...)
+	})
+}
@@ -0,0 +1,41 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package notice
+
+import (
+	"testing"
+
+	"github.com/issue9/assert/v4"
+
+	"github.com/issue9/cmfx/cmfx/initial/test"
+	"github.com/issue9/cmfx/cmfx/user/usertest"
+)
+
+func TestNotices_Send(t *testing.T) {
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+	defer s.Close()
+
+	mod := Install(usertest.NewModule(s))
+	a.NotError(mod.Types().Add("t1"))
+	db := mod.user.Module().DB()
+
+	a.NotError(mod.Send(0, 1, "system", "title1", "content1"))
+	npo := &noticePO{}
+	size, err := db.Where("title=?", "title1").Select(true, npo)
+	a.NotError(err).Equal(size, 1).
+		True(npo.All).
+		Equal(npo.Creator, 0).
+		Equal(npo.Author, "system")
+	cnt, err := db.Where("nid=?", npo.ID).Count(&groupPO{})
+	a.NotError(err).Equal(cnt, 0)
+
+	a.NotError(mod.Send(1, 1, "admin", "title2", "content2", 1, 2))
+	npo = &noticePO{}
+	size, err = db.Where("title=?", "title2").Select(true, npo)
+	a.NotError(err).Equal(size, 1).False(npo.All)
+	cnt, err = db.Where("nid=?", npo.ID).Count(&groupPO{})
+	a.NotError(err).Equal(cnt, 2)
+}
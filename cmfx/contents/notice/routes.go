@@ -6,6 +6,7 @@ package notice
 
 import (
 	"database/sql"
+	"slices"
 	"time"
 
 	"github.com/issue9/conv"
@@ -85,33 +86,11 @@ func (m *Notices) HandlePostNotices(ctx *web.Context, creator int64) web.Respons
 	var err error
 	switch to.Kind {
 	case "group":
-		err = m.user.Module().DB().DoTransaction(func(tx *orm.Tx) error {
-			id, err := tx.LastInsertID(po)
-			if err != nil {
-				return err
-			}
-
-			gs := make([]orm.TableNamer, 0, 100)
-			for uid := range m.filters[to.FilterName].Users() {
-				gs = append(gs, &groupPO{NID: id, UID: uid})
-			}
-			return tx.InsertMany(100, gs...)
-		})
+		err = m.insert(po, m.filters[to.FilterName].Users())
 	case "users":
-		err = m.user.Module().DB().DoTransaction(func(tx *orm.Tx) error {
-			id, err := tx.LastInsertID(po)
-			if err != nil {
-				return err
-			}
-
-			gs := make([]orm.TableNamer, 0, 100)
-			for _, uid := range to.Users {
-				gs = append(gs, &groupPO{NID: id, UID: uid})
-			}
-			return tx.InsertMany(100, gs...)
-		})
+		err = m.insert(po, slices.Values(to.Users))
 	case "all":
-		_, err = m.user.Module().DB().Insert(po)
+		err = m.insert(po, nil)
 	}
 
 	if err != nil {
@@ -0,0 +1,511 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package cmfx
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/issue9/orm/v6"
+	"github.com/issue9/web"
+)
+
+// 导出目录中的清单文件
+const exportManifest = "manifest.json"
+
+// 列的数据类型，与具体的数据库无关。
+const (
+	kindInt    = "int"
+	kindFloat  = "float"
+	kindBool   = "bool"
+	kindTime   = "time"
+	kindBytes  = "bytes"
+	kindString = "string"
+)
+
+type exportManifestVO struct {
+	Version string                 `json:"version"` // 导出时的框架版本
+	Dialect string                 `json:"dialect"` // 导出时的数据库类型
+	Created time.Time              `json:"created"`
+	Tables  []*exportManifestTable `json:"tables"`
+}
+
+type exportManifestTable struct {
+	Module  string          `json:"module"` // 所属模块的 ID，为空表示不属于任何模块。
+	Table   string          `json:"table"`  // 不包含模块前缀的表名
+	File    string          `json:"file"`
+	Rows    int64           `json:"rows"`
+	Columns []*exportColumn `json:"columns"`
+}
+
+type exportColumn struct {
+	Name string `json:"name"`
+	Type string `json:"type"` // 数据库中的原始类型
+	Kind string `json:"kind"`
+}
+
+// Export 将所有已注册模块的数据表导出至目录 dir
+//
+// 每张表导出为一个 NDJSON 文件，每一行为一条记录。
+// 表与模块的对应关系以及各列的类型保存在 dir 下的 manifest.json 中。
+// 导出的内容与数据库类型无关，可以由 [Registry.Import] 导入至其它类型的数据库。
+func (r *Registry) Export(dir string) error {
+	db, mods, err := r.exportDB()
+	if err != nil {
+		return err
+	}
+
+	tables, err := listTables(db)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+
+	m := &exportManifestVO{
+		Version: Version(),
+		Dialect: db.Dialect().Name(),
+		Created: time.Now(),
+	}
+
+	// 按模块的依赖顺序导出
+	owners := make(map[string][]string, len(mods)+1)
+	for _, t := range tables {
+		owner := tableOwner(mods, t)
+		owners[owner] = append(owners[owner], t)
+	}
+	ids := make([]string, 0, len(mods)+1)
+	ids = append(ids, "")
+	for _, mod := range mods {
+		ids = append(ids, mod.ID())
+	}
+
+	for _, id := range ids {
+		for _, t := range owners[id] {
+			mt := &exportManifestTable{Module: id, Table: strings.TrimPrefix(t, id), File: t + ".ndjson"}
+			if err := exportTable(db, t, filepath.Join(dir, mt.File), mt); err != nil {
+				return err
+			}
+			m.Tables = append(m.Tables, mt)
+		}
+	}
+
+	data, err := json.MarshalIndent(m, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, exportManifest), data, 0o644)
+}
+
+// Import 从 [Registry.Export] 导出的目录 dir 中导入数据
+//
+// 会先对所有模块执行 [ActionInstall] 以创建数据表，之后清空这些表并导入导出的数据。
+// 导出数据中存在而当前表中不存在的列会被忽略。
+// 所有表的清空与导入在同一个事务中进行，任意一张表导入失败，其它表的数据也不会被修改。
+//
+// 数据库迁移的记录不会被导入，而是保留由 [ActionInstall] 写入的当前版本的记录。
+//
+// NOTE: 目标数据库必须是空的，[ActionInstall] 在数据表已经存在时会返回错误。
+func (r *Registry) Import(dir string) error {
+	data, err := os.ReadFile(filepath.Join(dir, exportManifest))
+	if err != nil {
+		return err
+	}
+	m := &exportManifestVO{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return err
+	}
+
+	db, mods, err := r.exportDB()
+	if err != nil {
+		return err
+	}
+	for _, t := range m.Tables {
+		if t.Module != "" && !slices.ContainsFunc(mods, func(mod *Module) bool { return mod.ID() == t.Module }) {
+			return web.NewLocaleError("module %s not found", t.Module)
+		}
+		if strings.ContainsAny(t.File, "/"+string(os.PathSeparator)) {
+			return web.NewLocaleError("invalid file %s in manifest", t.File)
+		}
+	}
+
+	// 数据表由当前版本的 [ActionInstall] 创建，迁移记录也应该以当前版本为准。
+	migrations := (&migrationPO{}).TableName()
+	m.Tables = slices.DeleteFunc(m.Tables, func(t *exportManifestTable) bool {
+		return t.Module == "" && t.Table == migrations
+	})
+
+	if err := r.Exec(ActionInstall); err != nil {
+		return err
+	}
+
+	return db.DoTransaction(func(tx *orm.Tx) error {
+		for _, t := range m.Tables {
+			if err := importTable(tx, t.Module+t.Table, filepath.Join(dir, t.File), t); err != nil {
+				return err
+			}
+		}
+
+		if db.Dialect().Name() == "postgres" { // 导入的数据指定了自增列的值，需要同步序列。
+			for _, t := range m.Tables {
+				if err := syncSequences(tx, t.Module+t.Table); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// 返回不带表名前缀的数据库以及按依赖顺序排列的模块
+func (r *Registry) exportDB() (*orm.DB, []*Module, error) {
+	mods, err := r.Modules()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(mods) == 0 {
+		return nil, nil, web.NewLocaleError("no module registered")
+	}
+	return mods[0].DB().New(""), mods, nil
+}
+
+// 表 t 所属的模块，即表名前缀最长的模块。
+//
+// 模块的表名均以 _ 开头，所以只有以 ID 加 _ 开头的表才属于该模块，
+// 以避免 ID 为 ab 的模块匹配到模块 abc 的表。
+func tableOwner(mods []*Module, t string) string {
+	var owner string
+	for _, mod := range mods {
+		if id := mod.ID(); len(id) > len(owner) && strings.HasPrefix(t, id+"_") {
+			owner = id
+		}
+	}
+	return owner
+}
+
+// 列出数据库中的所有表
+func listTables(db *orm.DB) ([]string, error) {
+	var query string
+	switch db.Dialect().Name() {
+	case "sqlite3":
+		query = "SELECT name FROM sqlite_master WHERE type='table' AND name NOT LIKE 'sqlite_%'"
+	case "mysql", "mariadb":
+		query = "SELECT table_name FROM information_schema.tables WHERE table_schema=DATABASE() AND table_type='BASE TABLE'"
+	case "postgres":
+		query = "SELECT table_name FROM information_schema.tables WHERE table_schema=current_schema() AND table_type='BASE TABLE'"
+	default:
+		return nil, web.NewLocaleError("unsupported dialect %s", db.Dialect().Name())
+	}
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tables := make([]string, 0, 20)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		tables = append(tables, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	slices.Sort(tables)
+	return tables, nil
+}
+
+// 将数据库类型转换为与数据库无关的类型
+func columnKind(typ string) string {
+	typ = strings.ToUpper(typ)
+	switch {
+	case strings.Contains(typ, "BOOL"):
+		return kindBool
+	case strings.Contains(typ, "INT") || strings.Contains(typ, "SERIAL"):
+		return kindInt
+	case strings.Contains(typ, "REAL") || strings.Contains(typ, "FLOAT") || strings.Contains(typ, "DOUBLE") ||
+		strings.Contains(typ, "DECIMAL") || strings.Contains(typ, "NUMERIC"):
+		return kindFloat
+	case strings.Contains(typ, "DATE") || strings.Contains(typ, "TIME"):
+		return kindTime
+	case strings.Contains(typ, "BLOB") || strings.Contains(typ, "BINARY") || strings.Contains(typ, "BYTEA"):
+		return kindBytes
+	default:
+		return kindString
+	}
+}
+
+func exportTable(db *orm.DB, table, path string, mt *exportManifestTable) (err error) {
+	rows, err := db.Query("SELECT * FROM {" + table + "}")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return err
+	}
+	for _, t := range types {
+		mt.Columns = append(mt.Columns, &exportColumn{Name: t.Name(), Type: t.DatabaseTypeName(), Kind: columnKind(t.DatabaseTypeName())})
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() { err = errors.Join(err, f.Close()) }()
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+
+	values := make([]any, len(types))
+	ptrs := make([]any, len(types))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return err
+		}
+
+		row := make(map[string]any, len(values))
+		for i, v := range values {
+			row[mt.Columns[i].Name] = exportValue(v, mt.Columns[i].Kind)
+		}
+		if err := enc.Encode(row); err != nil {
+			return err
+		}
+		mt.Rows++
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	return w.Flush()
+}
+
+func exportValue(v any, kind string) any {
+	switch vv := v.(type) {
+	case []byte:
+		if kind == kindBytes {
+			return base64.StdEncoding.EncodeToString(vv)
+		}
+		return string(vv)
+	case time.Time:
+		return vv.Format(time.RFC3339Nano)
+	default:
+		return v
+	}
+}
+
+func importTable(tx *orm.Tx, table, path string, mt *exportManifestTable) error {
+	// 当前表中的列
+	rows, err := tx.Query("SELECT * FROM {" + table + "} WHERE 1=0")
+	if err != nil {
+		return err
+	}
+	types, err := rows.ColumnTypes()
+	rows.Close()
+	if err != nil {
+		return err
+	}
+
+	src := make(map[string]string, len(mt.Columns))
+	for _, c := range mt.Columns {
+		src[c.Name] = c.Kind
+	}
+
+	cols := make([]string, 0, len(types))
+	kinds := make([]string, 0, len(types))
+	for _, t := range types {
+		if _, found := src[t.Name()]; found {
+			cols = append(cols, t.Name())
+			kinds = append(kinds, columnKind(t.DatabaseTypeName()))
+		}
+	}
+
+	query := &strings.Builder{}
+	query.WriteString("INSERT INTO {" + table + "} (")
+	for i, c := range cols {
+		if i > 0 {
+			query.WriteByte(',')
+		}
+		query.WriteString("{" + c + "}")
+	}
+	query.WriteString(") VALUES (" + strings.TrimSuffix(strings.Repeat("?,", len(cols)), ",") + ")")
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := tx.Exec("DELETE FROM {" + table + "}"); err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bufio.NewReader(f))
+	dec.UseNumber()
+	for {
+		row := map[string]any{}
+		if err := dec.Decode(&row); errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		args := make([]any, 0, len(cols))
+		for i, c := range cols {
+			v, err := importValue(row[c], src[c], kinds[i])
+			if err != nil {
+				return web.NewLocaleError("invalid value of column %s.%s: %s", table, c, err)
+			}
+			args = append(args, v)
+		}
+		if _, err := tx.Exec(query.String(), args...); err != nil {
+			return err
+		}
+	}
+}
+
+// 将导出的值 v 转换为类型为 dst 的列可以接受的值
+//
+// src 为导出时列的类型。
+func importValue(v any, src, dst string) (any, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	if s, ok := v.(string); ok && src == kindBytes {
+		data, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, err
+		}
+		if dst == kindBytes {
+			return data, nil
+		}
+		v = string(data)
+	}
+
+	switch dst {
+	case kindInt:
+		switch vv := v.(type) {
+		case json.Number:
+			if i, err := vv.Int64(); err == nil {
+				return i, nil
+			}
+			f, err := vv.Float64()
+			return int64(f), err
+		case string:
+			return strconv.ParseInt(strings.TrimSpace(vv), 10, 64)
+		case bool:
+			if vv {
+				return int64(1), nil
+			}
+			return int64(0), nil
+		}
+	case kindFloat:
+		switch vv := v.(type) {
+		case json.Number:
+			return vv.Float64()
+		case string:
+			return strconv.ParseFloat(strings.TrimSpace(vv), 64)
+		case bool:
+			if vv {
+				return float64(1), nil
+			}
+			return float64(0), nil
+		}
+	case kindBool:
+		switch vv := v.(type) {
+		case bool:
+			return vv, nil
+		case json.Number:
+			return vv.String() != "0", nil
+		case string:
+			return strconv.ParseBool(strings.TrimSpace(vv))
+		}
+	case kindTime:
+		if s, ok := v.(string); ok {
+			return parseTime(s)
+		}
+	case kindBytes:
+		if s, ok := v.(string); ok {
+			return []byte(s), nil
+		}
+	default:
+		switch vv := v.(type) {
+		case string:
+			return vv, nil
+		case json.Number:
+			return vv.String(), nil
+		case bool:
+			return strconv.FormatBool(vv), nil
+		}
+	}
+
+	return nil, web.NewLocaleError("can not convert %v to %s", v, dst)
+}
+
+// 可以解析的时间格式
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	time.DateOnly,
+}
+
+func parseTime(s string) (t time.Time, err error) {
+	for _, layout := range timeLayouts {
+		if t, err = time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return t, err
+}
+
+// 将表 table 中的自增序列设置为当前的最大值
+func syncSequences(tx *orm.Tx, table string) error {
+	rows, err := tx.Query("SELECT column_name FROM information_schema.columns WHERE table_schema=current_schema() AND table_name=? AND column_default LIKE 'nextval(%'", table)
+	if err != nil {
+		return err
+	}
+	cols := make([]string, 0, 1)
+	for rows.Next() {
+		var col string
+		if err := rows.Scan(&col); err != nil {
+			rows.Close()
+			return err
+		}
+		cols = append(cols, col)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, col := range cols {
+		q := "SELECT setval(pg_get_serial_sequence(?, ?), COALESCE(MAX({" + col + "}), 1), MAX({" + col + "}) IS NOT NULL) FROM {" + table + "}"
+		if _, err := tx.Exec(q, table, col); err != nil {
+			return err
+		}
+	}
+	return nil
+}
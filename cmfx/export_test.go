@@ -0,0 +1,207 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package cmfx
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/orm/v6"
+	"github.com/issue9/orm/v6/dialect"
+	"github.com/issue9/web"
+	"github.com/issue9/web/openapi"
+)
+
+type exportPO struct {
+	ID      int64     `orm:"name(id);ai"`
+	Name    string    `orm:"name(name);len(20)"`
+	Enable  bool      `orm:"name(enable)"`
+	Rate    float64   `orm:"name(rate)"`
+	Data    []byte    `orm:"name(data);nullable"`
+	Created time.Time `orm:"name(created)"`
+}
+
+func (*exportPO) TableName() string { return "_exports" }
+
+// 创建一个包含 m1 模块的注册表，m1 在安装时会创建 _exports 表并写入一条默认数据，
+// 同时将 versions 指定的迁移操作标记为已执行。
+func newExportRegistry(a *assert.Assertion, dbFile string, versions ...int64) (*Module, *orm.DB) {
+	srv := newServer(a)
+	db, err := orm.NewDB("", dbFile, dialect.Sqlite3("sqlite3"))
+	a.NotError(err).NotNil(db)
+
+	root := NewModule("", web.Phrase("root"), srv, db, srv.Routers().New("def", nil), openapi.New(srv, web.Phrase("test")))
+	m1 := root.New("m1", web.Phrase("m1"))
+	m1.Register(&Lifecycle{
+		Install: func(mod *Module) error {
+			if err := mod.DB().Create(&exportPO{}); err != nil {
+				return err
+			}
+			if _, err := mod.DB().Insert(&exportPO{Name: "default", Created: time.Now()}); err != nil {
+				return err
+			}
+
+			items := make([]*Migration, 0, len(versions))
+			for _, v := range versions {
+				items = append(items, &Migration{Version: v, Up: func(*Module) error { return nil }})
+			}
+			return mod.Migrations(items...).Install()
+		},
+	})
+
+	return m1, db
+}
+
+func TestRegistry_Export(t *testing.T) {
+	a := assert.New(t, false)
+	const srcFile, destFile, dir = "./export-src.db", "./export-dest.db", "./testdata/export"
+	defer func() {
+		a.NotError(os.Remove(srcFile))
+		a.NotError(os.Remove(destFile))
+		a.NotError(os.RemoveAll("./testdata"))
+	}()
+
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	src, srcDB := newExportRegistry(a, srcFile, 1)
+	a.NotError(src.Registry().Exec(ActionInstall))
+	_, err := src.DB().Insert(&exportPO{Name: "n2", Enable: true, Rate: 1.5, Data: []byte{0, 1, 0xff}, Created: created})
+	a.NotError(err)
+	a.NotError(src.Registry().Export(dir))
+	a.NotError(srcDB.Close())
+
+	data, err := os.ReadFile(dir + "/" + exportManifest)
+	a.NotError(err)
+	m := &exportManifestVO{}
+	a.NotError(json.Unmarshal(data, m)).
+		Equal(m.Dialect, "sqlite3").
+		Length(m.Tables, 2).
+		Equal(m.Tables[0].Module, "").
+		Equal(m.Tables[0].Table, "_migrations").
+		Equal(m.Tables[1].Module, "m1").
+		Equal(m.Tables[1].Table, "_exports").
+		Equal(m.Tables[1].Rows, 2)
+
+	dest, destDB := newExportRegistry(a, destFile, 1, 2)
+	defer destDB.Close()
+	a.NotError(dest.Registry().Import(dir))
+
+	// 保留当前版本安装时的迁移记录
+	states, err := dest.Migrations(&Migration{Version: 1, Up: func(*Module) error { return nil }}, &Migration{Version: 2, Up: func(*Module) error { return nil }}).States()
+	a.NotError(err).Length(states, 2).
+		False(states[0].Applied.IsZero()).
+		False(states[1].Applied.IsZero())
+
+	po := &exportPO{ID: 2}
+	found, err := dest.DB().Select(po)
+	a.NotError(err).True(found).
+		Equal(po.Name, "n2").
+		True(po.Enable).
+		Equal(po.Rate, 1.5).
+		Equal(po.Data, []byte{0, 1, 0xff}).
+		Equal(po.Created.UTC(), created)
+
+	// 安装时写入的默认数据被导出的数据替换
+	cnt, err := dest.DB().SQLBuilder().Select().Count("count(*) as cnt").From(orm.TableName(&exportPO{})).QueryInt("cnt")
+	a.NotError(err).Equal(cnt, 2)
+
+	// 自增列继续有效
+	_, err = dest.DB().Insert(&exportPO{Name: "n3", Created: created})
+	a.NotError(err)
+	po = &exportPO{ID: 3}
+	found, err = dest.DB().Select(po)
+	a.NotError(err).True(found).Equal(po.Name, "n3")
+}
+
+func TestRegistry_Import_rollback(t *testing.T) {
+	a := assert.New(t, false)
+	const srcFile, destFile, dir = "./import-src.db", "./import-dest.db", "./testdata/import"
+	defer func() {
+		a.NotError(os.Remove(srcFile))
+		a.NotError(os.Remove(destFile))
+		a.NotError(os.RemoveAll("./testdata"))
+	}()
+
+	src, srcDB := newExportRegistry(a, srcFile)
+	a.NotError(src.Registry().Exec(ActionInstall))
+	_, err := src.DB().Insert(&exportPO{Name: "n2", Created: time.Now()})
+	a.NotError(err)
+	a.NotError(src.Registry().Export(dir))
+	a.NotError(srcDB.Close())
+
+	// 追加一张导入失败的表
+	data, err := os.ReadFile(dir + "/" + exportManifest)
+	a.NotError(err)
+	m := &exportManifestVO{}
+	a.NotError(json.Unmarshal(data, m))
+	bad := *m.Tables[len(m.Tables)-1]
+	bad.File = "bad.ndjson"
+	m.Tables = append(m.Tables, &bad)
+	data, err = json.Marshal(m)
+	a.NotError(err)
+	a.NotError(os.WriteFile(dir+"/"+exportManifest, data, 0o644))
+	a.NotError(os.WriteFile(dir+"/bad.ndjson", []byte(`{"id":"abc"}`+"\n"), 0o644))
+
+	dest, destDB := newExportRegistry(a, destFile)
+	defer destDB.Close()
+	a.Error(dest.Registry().Import(dir))
+
+	// 第一张表的导入也被回滚，只有安装时写入的默认数据。
+	cnt, err := dest.DB().SQLBuilder().Select().Count("count(*) as cnt").From(orm.TableName(&exportPO{})).QueryInt("cnt")
+	a.NotError(err).Equal(cnt, 1)
+}
+
+func TestTableOwner(t *testing.T) {
+	a := assert.New(t, false)
+	const dbFile = "./owner.db"
+	defer func() { a.NotError(os.Remove(dbFile)) }()
+
+	m1, db := newExportRegistry(a, dbFile)
+	defer db.Close()
+	mods := []*Module{m1, m1.New("x", web.Phrase("m1x")), m1.New("_x", web.Phrase("m1_x"))}
+
+	a.Equal(tableOwner(mods, "m1_files"), "m1").
+		Equal(tableOwner(mods, "m1x_files"), "m1x").
+		Equal(tableOwner(mods, "m1_x_files"), "m1_x").
+		Equal(tableOwner(mods, "m1y_files"), "").
+		Equal(tableOwner(mods, "files"), "")
+}
+
+func TestImportValue(t *testing.T) {
+	a := assert.New(t, false)
+
+	v, err := importValue(json.Number("1"), kindInt, kindBool)
+	a.NotError(err).Equal(v, true)
+
+	v, err = importValue(true, kindBool, kindInt)
+	a.NotError(err).Equal(v, int64(1))
+
+	v, err = importValue("12", kindString, kindInt)
+	a.NotError(err).Equal(v, int64(12))
+
+	v, err = importValue(json.Number("1.5"), kindFloat, kindString)
+	a.NotError(err).Equal(v, "1.5")
+
+	v, err = importValue("2026-01-02 03:04:05", kindString, kindTime)
+	a.NotError(err).Equal(v, time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+
+	v, err = importValue("AAH/", kindBytes, kindBytes)
+	a.NotError(err).Equal(v, []byte{0, 1, 0xff})
+
+	v, err = importValue("YWJj", kindBytes, kindString)
+	a.NotError(err).Equal(v, "abc")
+
+	v, err = importValue(nil, kindInt, kindInt)
+	a.NotError(err).Nil(v)
+
+	_, err = importValue("abc", kindString, kindInt)
+	a.Error(err)
+
+	_, err = importValue(json.Number("1"), kindInt, kindTime)
+	a.Error(err)
+}
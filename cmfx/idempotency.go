@@ -0,0 +1,58 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package cmfx
+
+import (
+	"github.com/issue9/web"
+
+	"github.com/issue9/cmfx/cmfx/locales"
+)
+
+// IdempotencyKeyHeader 幂等键的报头名称
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// MaxIdempotencyKeyLength 幂等键的最大长度
+const MaxIdempotencyKeyLength = 100
+
+type idempotencyKeyType int
+
+const idempotencyKey idempotencyKeyType = 0
+
+// Idempotency 读取 Idempotency-Key 报头的中间件
+//
+// 报头的值可以通过 [IdempotencyKey] 获取，并传递给支持幂等键的操作，
+// 客户端在重试请求时使用相同的值，可以保证操作只被执行一次。
+//
+// required 表示是否必须提供该报头，缺少该报头或是值的长度超过 [MaxIdempotencyKeyLength]
+// 都会返回 [BadRequestInvalidHeader]。
+func Idempotency(required bool) web.Middleware {
+	return web.MiddlewareFunc(func(next web.HandlerFunc, _, _, _ string) web.HandlerFunc {
+		return func(ctx *web.Context) web.Responser {
+			key := ctx.Request().Header.Get(IdempotencyKeyHeader)
+			switch {
+			case key == "" && required:
+				return ctx.Problem(BadRequestInvalidHeader).
+					WithParam(IdempotencyKeyHeader, locales.Required.LocaleString(ctx.LocalePrinter()))
+			case len(key) > MaxIdempotencyKeyLength:
+				return ctx.Problem(BadRequestInvalidHeader).
+					WithParam(IdempotencyKeyHeader, locales.InvalidValue.LocaleString(ctx.LocalePrinter()))
+			case key != "":
+				ctx.SetVar(idempotencyKey, key)
+			}
+
+			return next(ctx)
+		}
+	})
+}
+
+// IdempotencyKey 获取由 [Idempotency] 保存的幂等键
+//
+// 如果未使用 [Idempotency] 中间件或是客户端未提供该报头，返回空字符串。
+func IdempotencyKey(ctx *web.Context) string {
+	if v, found := ctx.GetVar(idempotencyKey); found {
+		return v.(string)
+	}
+	return ""
+}
@@ -0,0 +1,49 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package cmfx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/web"
+)
+
+func TestIdempotency(t *testing.T) {
+	a := assert.New(t, false)
+	s := newServer(a)
+	problems(s)
+
+	var key string
+	h := func(ctx *web.Context) web.Responser {
+		key = IdempotencyKey(ctx)
+		return web.NoContent()
+	}
+
+	r := s.Routers().New("default", nil)
+	r.Get("/optional", h, Idempotency(false)).
+		Get("/required", h, Idempotency(true))
+
+	do := func(path, k string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if k != "" {
+			req.Header.Set(IdempotencyKeyHeader, k)
+		}
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	a.Equal(do("/optional", ""), http.StatusNoContent).Empty(key)
+	a.Equal(do("/optional", "k1"), http.StatusNoContent).Equal(key, "k1")
+	a.Equal(do("/optional", strings.Repeat("k", MaxIdempotencyKeyLength+1)), http.StatusBadRequest)
+
+	key = ""
+	a.Equal(do("/required", ""), http.StatusBadRequest).Empty(key)
+	a.Equal(do("/required", "k2"), http.StatusNoContent).Equal(key, "k2")
+}
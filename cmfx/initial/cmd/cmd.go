@@ -6,13 +6,17 @@
 package cmd
 
 import (
+	"bufio"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
+	"slices"
+	"strings"
 	"time"
 
-	xupload "github.com/issue9/upload/v5"
 	"github.com/issue9/web"
 	"github.com/issue9/web/mimetype/cbor"
 	"github.com/issue9/web/mimetype/json"
@@ -24,16 +28,41 @@ import (
 	"github.com/issue9/webuse/v7/middlewares/auth/token"
 	"github.com/issue9/webuse/v7/openapis"
 	"github.com/kardianos/service"
+	"golang.org/x/text/message"
 
 	"github.com/issue9/cmfx/cmfx"
+	"github.com/issue9/cmfx/cmfx/contents/article"
+	"github.com/issue9/cmfx/cmfx/contents/comment"
+	"github.com/issue9/cmfx/cmfx/contents/notice"
 	"github.com/issue9/cmfx/cmfx/modules/admin"
 	"github.com/issue9/cmfx/cmfx/modules/member"
 	"github.com/issue9/cmfx/cmfx/modules/system"
 	"github.com/issue9/cmfx/cmfx/modules/upload"
+	"github.com/issue9/cmfx/cmfx/modules/webhook"
+	"github.com/issue9/cmfx/cmfx/outbox"
+	"github.com/issue9/cmfx/cmfx/user"
+	"github.com/issue9/cmfx/cmfx/user/currency"
 	"github.com/issue9/cmfx/cmfx/user/passport/fido/passkey"
 	"github.com/issue9/cmfx/cmfx/user/passport/otp/totp"
 )
 
+// 带参数的指令，可以是 action 或是 action:arg 的形式。
+const (
+	// 从备份中恢复数据，参数为备份文件名，未指定则采用最近的一次备份。
+	actionRestore = "restore"
+
+	// 将所有模块的数据导出为与数据库无关的格式，参数为导出的目录，未指定则为 ./export。
+	actionExport = "export"
+
+	// 安装所有模块并导入由 export 导出的数据，参数为导出的目录，未指定则为 ./export。
+	actionImport = "import"
+)
+
+const defaultExportDir = "./export"
+
+// 记录提交内容的最大长度，超过此值的提交内容不会出现在审计日志中。
+const maxRecordedBodySize = 64 << 10
+
 // 运行服务
 //
 // config 相对于工作目录的配置文件；
@@ -44,13 +73,13 @@ func Exec(id, version string, config string) error {
 		NewServer:      initServer,
 		ConfigDir:      "./",
 		ConfigFilename: config,
-		ServeActions:   []string{"serve"},
+		ServeActions:   []string{cmfx.ActionServe},
 		ErrorHandling:  flag.ExitOnError,
 		Daemon: &service.Config{
 			Name:        id,
 			DisplayName: id,
 			Description: id,
-			Arguments:   []string{"-a=serve"},
+			Arguments:   []string{"-a=" + cmfx.ActionServe},
 		},
 	}).Exec()
 }
@@ -60,6 +89,7 @@ func initServer(id, ver string, o *server.Options, user *Config, action string)
 	if err != nil {
 		return nil, err
 	}
+	o.HTTPServer.Handler = cmfx.RecordRequestBody(o.HTTPServer.Handler, maxRecordedBodySize) // 审计日志需要提交的内容
 
 	c := web.NewCache(user.Ratelimit.Prefix, s.Cache())
 	limit := ratelimit.New(c, user.Ratelimit.Capacity, user.Ratelimit.Rate.Duration(), nil)
@@ -81,6 +111,7 @@ func initServer(id, ver string, o *server.Options, user *Config, action string)
 	router.Get("/openapi", doc.Handler())
 
 	root := cmfx.Init(s, limit, user.DB.DB(), router, doc)
+	outboxMod := root.New("outbox", web.Phrase("outbox module"))
 	uploadMod := root.New("upload", web.Phrase("upload module"))
 	adminMod := root.New("admin", web.Phrase("admin module"), "admin")
 	systemMod := root.New("system", web.Phrase("system module"))
@@ -92,43 +123,331 @@ func initServer(id, ver string, o *server.Options, user *Config, action string)
 	if err != nil {
 		return nil, err
 	}
-	upRoot, err := os.OpenRoot("./uploads")
-	if err != nil {
-		return nil, err
-	}
-	uploadSaver, err := xupload.NewLocalSaver(upRoot, url, func(dir fs.FS, filename, ext string) string {
+	uploadSaver, err := user.Upload.NewSaver(url, func(dir fs.FS, filename, ext string) string {
 		return s.UniqueID() + ext // filename 可能带非英文字符
 	})
 	if err != nil {
 		return nil, err
 	}
-	uploadL := upload.Load(uploadMod, uploadPrefix, uploadSaver)
+	uploadL := upload.Load(uploadMod, uploadPrefix, uploadSaver, user.Upload)
+
+	uploadMod.Register(&cmfx.Lifecycle{
+		Install: func(mod *cmfx.Module) error {
+			upload.Install(mod)
+			return nil
+		},
+		Load: func(mod *cmfx.Module) error {
+			if user.Upload.GC != "" {
+				uploadL.AddGCService(user.Upload.GC, user.Upload.Grace.Duration())
+			}
+			return nil
+		},
+		Uninstall:  upload.Uninstall,
+		Migrations: upload.Migrations,
+	})
 
-	switch action {
-	case "serve":
-		adminL := admin.Load(adminMod, user.Admin, uploadL)
-		totp.Init(adminL.UserModule(), "totp", web.Phrase("TOTP passport"))
-		passkey.Init(adminL.UserModule(), "webauthn", web.Phrase("webauthn passport"), time.Minute, "http://localhost:8080", "http://localhost:5173")
+	outboxMod.Register(&cmfx.Lifecycle{
+		Install: func(mod *cmfx.Module) error {
+			outbox.Install(mod)
+			outbox.New(mod, 10*time.Second, 10)
+			return nil
+		},
+		Load: func(mod *cmfx.Module) error {
+			outbox.New(mod, 10*time.Second, 10)
+			return nil
+		},
+		Uninstall: outbox.Uninstall,
+	})
 
-		member.Load(memberMod, user.Member, uploadL, adminL)
+	var adminL *admin.Module
+	var adminNotices *notice.Notices // 管理员的站内通知
+	adminMod.Register(&cmfx.Lifecycle{
+		Deps: []string{outboxMod.ID(), uploadMod.ID()},
+		Install: func(mod *cmfx.Module) error {
+			adminL = admin.Install(mod, user.Admin, uploadL)
+			adminNotices = notice.Install(adminL.UserModule())
+			totp.Install(adminL.UserModule().Module(), "totp")
+			passkey.Install(adminL.UserModule().Module(), "webauthn")
+			return nil
+		},
+		Load: func(mod *cmfx.Module) error {
+			adminL = admin.Load(mod, user.Admin, uploadL)
+			adminNotices = notice.NewNotices(adminL.UserModule())
+			totp.Init(adminL.UserModule(), "totp", web.Phrase("TOTP passport"))
+			passkey.Init(adminL.UserModule(), "webauthn", web.Phrase("webauthn passport"), time.Minute, "http://localhost:8080", "http://localhost:5173")
+			return nil
+		},
+		Uninstall: func(mod *cmfx.Module) error {
+			if err := totp.Uninstall(mod, "totp"); err != nil {
+				return err
+			}
+			if err := passkey.Uninstall(mod, "webauthn"); err != nil {
+				return err
+			}
+			if err := notice.Uninstall(mod); err != nil {
+				return err
+			}
+			return admin.Uninstall(mod)
+		},
+	})
 
-		system.Load(systemMod, user.System, adminL)
+	var memberL *member.Module
+	memberMod.Register(&cmfx.Lifecycle{
+		Deps: []string{adminMod.ID()},
+		Install: func(mod *cmfx.Module) error {
+			memberL = member.Install(mod, user.Member, uploadL, adminL, nil, nil)
+			return nil
+		},
+		Load: func(mod *cmfx.Module) error {
+			memberL = member.Load(mod, user.Member, uploadL, adminL)
+			return nil
+		},
+		Uninstall: func(mod *cmfx.Module) error { return member.Uninstall(mod, adminMod) },
+	})
 
+	systemMod.Register(&cmfx.Lifecycle{
+		Deps: []string{adminMod.ID()},
+		Install: func(mod *cmfx.Module) error {
+			system.Install(mod, user.System, adminL)
+			return nil
+		},
+		Migrations: system.Migrations,
+		Load: func(mod *cmfx.Module) error {
+			systemL := system.Load(mod, user.System, adminL)
+			if a := user.System.Alerts; a != nil && a.Notice > 0 {
+				if !adminNotices.Types().Valid(a.Notice) {
+					return web.NewLocaleError("notice type %d not found", a.Notice)
+				}
+				systemL.NotifyAlerts(adminNotices, a.Notice)
+			}
+			return nil
+		},
+		Uninstall: func(mod *cmfx.Module) error { return system.Uninstall(mod, adminMod) },
+	})
+
+	if user.Webhook != nil {
+		webhookMod := root.New("webhook", web.Phrase("webhook module"))
+		webhookMod.Register(&cmfx.Lifecycle{
+			Deps: []string{adminMod.ID(), memberMod.ID()},
+			Install: func(mod *cmfx.Module) error {
+				initWebhook(webhook.Install(mod, user.Webhook, adminL), memberL, nil, nil)
+				return nil
+			},
+			Load: func(mod *cmfx.Module) error {
+				initWebhook(webhook.Load(mod, user.Webhook, adminL), memberL, nil, nil)
+				return nil
+			},
+			Uninstall: func(mod *cmfx.Module) error { return webhook.Uninstall(mod, adminMod) },
+		})
+	}
+
+	if action == "status" {
+		p := s.Locale().Printer()
+		err = root.Registry().Visit(func(mod *cmfx.Module, l *cmfx.Lifecycle) error {
+			if l.Migrations == nil {
+				return nil
+			}
+
+			states, err := l.States(mod)
+			if err == nil {
+				printPending(p, mod, states)
+			}
+			return err
+		})
+		return s, err
+	}
+
+	if name, found := cutAction(action, actionRestore); found {
+		return s, restore(s, root, user.System, name)
+	}
+
+	if dir, found := cutAction(action, actionExport); found {
+		if dir == "" {
+			dir = defaultExportDir
+		}
+		if err := root.Registry().Export(dir); err != nil {
+			return nil, err
+		}
+		fmt.Println(web.Phrase("data has been exported to %s", dir).LocaleString(s.Locale().Printer()))
+		return s, nil
+	}
+
+	if dir, found := cutAction(action, actionImport); found {
+		if dir == "" {
+			dir = defaultExportDir
+		}
+		ok, err := confirm(s.Locale().Printer(), web.Phrase("import will overwrite all data with %s, continue? [y/N]", dir))
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, web.NewLocaleError("import canceled")
+		}
+		return s, root.Registry().Import(dir)
+	}
+
+	if action == cmfx.ActionUninstall {
+		if err := confirmUninstall(s, root, user.System); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := root.Registry().Exec(action); err != nil {
+		return nil, err
+	}
+
+	if action == cmfx.ActionServe {
 		// 在所有模块加载完成之后调用，需要等待其它模块里的私有错误代码加载完成。
 		doc.WithDescription(nil, web.Phrase(`problems response:
 
 %s
 `, openapi.MarkdownProblems(s, 4)))
-	case "install":
-		adminL := admin.Install(adminMod, user.Admin, uploadL)
-		totp.Install(adminL.UserModule().Module(), "totp")
-		passkey.Install(adminL.UserModule().Module(), "webauthn")
+	}
 
-		member.Install(memberMod, user.Member, uploadL, adminL, nil, nil)
+	return s, nil
+}
 
-		system.Install(systemMod, user.System, adminL)
-	default:
-		panic(fmt.Sprintf("invalid action %s", action))
+// 注册 Webhook 的事件
+//
+// articles、comments 和 currencies 为空时不注册对应的事件，
+// 当前程序并未加载这些内容模块，加载之后传入相应的对象即可。
+func initWebhook(l *webhook.Module, memberL *member.Module, articles *article.Articles, comments *comment.Comments, currencies ...*currency.Currency) {
+	l.RegisterEvent("member.register", web.Phrase("member register"))
+	l.RegisterEvent("member.login", web.Phrase("member login"))
+
+	users := memberL.UserModule()
+	if o := users.Outbox(); o != nil {
+		l.Forward(o, users.Topic(user.TopicAdd), "member.register")
+	} else {
+		users.OnAdd(func(u *user.User) { triggerWebhook(l, "member.register", &user.Event{ID: u.ID, NO: u.NO}) })
+	}
+	users.OnLogin(func(u *user.User) { triggerWebhook(l, "member.login", &user.Event{ID: u.ID, NO: u.NO}) })
+
+	if articles != nil {
+		l.RegisterEvent("article.publish", web.Phrase("article publish"))
+		if o := articles.Outbox(); o != nil {
+			l.Forward(o, articles.Topic(article.TopicPublish), "article.publish")
+		} else {
+			articles.OnPublish(func(e *article.Event) { triggerWebhook(l, "article.publish", e) })
+		}
+	}
+
+	if comments != nil {
+		l.RegisterEvent("comment.post", web.Phrase("comment post"))
+		if o := comments.Outbox(); o != nil {
+			l.Forward(o, comments.Topic(comment.TopicPost), "comment.post")
+		} else {
+			comments.OnPost(func(e *comment.Event) { triggerWebhook(l, "comment.post", e) })
+		}
+	}
+
+	// 余额变化的事件只发布至 outbox
+	if o := outbox.Get(l.Module().Server()); o != nil {
+		for _, c := range currencies {
+			event := "currency." + c.ID() + ".change"
+			l.RegisterEvent(event, web.Phrase("currency %s change", c.ID()))
+			l.Forward(o, c.Topic(currency.TopicChanged), event)
+		}
+	}
+}
+
+func triggerWebhook(l *webhook.Module, event string, data any) {
+	if err := l.Trigger(nil, event, data); err != nil {
+		l.Module().Server().Logs().ERROR().Error(err)
+	}
+}
+
+// 卸载之前需要用户确认，并对数据库进行备份。
+func confirmUninstall(s web.Server, root *cmfx.Module, conf *system.Config) error {
+	mods, err := root.Registry().Modules()
+	if err != nil {
+		return err
+	}
+	ids := make([]string, 0, len(mods))
+	for _, mod := range mods {
+		ids = append(ids, mod.ID())
+	}
+
+	p := s.Locale().Printer()
+	ok, err := confirm(p, web.Phrase("uninstall will drop all tables of modules %s, continue? [y/N]", strings.Join(ids, ",")))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return web.NewLocaleError("uninstall canceled")
+	}
+
+	path, err := system.BackupNow(root, conf)
+	if err != nil {
+		return err
+	}
+	fmt.Println(web.Phrase("database has been backed up to %s", path).LocaleString(p))
+	return nil
+}
+
+// 从 action 中分离出指令 name 的参数
+func cutAction(action, name string) (string, bool) {
+	arg, found := strings.CutPrefix(action, name)
+	if !found || (arg != "" && arg[0] != ':') {
+		return "", false
+	}
+	return strings.TrimPrefix(arg, ":"), true
+}
+
+// 经用户确认之后从备份文件 name 中恢复数据
+func restore(s web.Server, root *cmfx.Module, conf *system.Config, name string) error {
+	p := s.Locale().Printer()
+
+	if conf.Backup != nil && conf.Backup.Offline {
+		fmt.Println(web.Phrase("the server must be stopped before restoring").LocaleString(p))
+	}
+
+	var q web.LocaleStringer
+	if name == "" {
+		q = web.Phrase("restore will overwrite current data with the latest backup, continue? [y/N]")
+	} else {
+		q = web.Phrase("restore will overwrite current data with backup %s, continue? [y/N]", name)
+	}
+	ok, err := confirm(p, q)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return web.NewLocaleError("restore canceled")
+	}
+
+	path, err := system.RestoreNow(root, conf, name)
+	if path != "" {
+		fmt.Println(web.Phrase("database has been backed up to %s", path).LocaleString(p))
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Println(web.Phrase("database has been restored").LocaleString(p))
+	return nil
+}
+
+// 向用户提问 q 并等待确认
+func confirm(p *message.Printer, q web.LocaleStringer) (bool, error) {
+	fmt.Printf("%s ", q.LocaleString(p))
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, err
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
+
+// 输出 mod 中未执行的数据库迁移操作
+func printPending(p *message.Printer, mod *cmfx.Module, states []*cmfx.MigrationState) {
+	states = slices.DeleteFunc(states, func(s *cmfx.MigrationState) bool { return !s.Applied.IsZero() })
+	fmt.Println(web.Phrase("module %s has %d pending migrations", mod.ID(), len(states)).LocaleString(p))
+
+	for _, state := range states {
+		var desc string
+		if state.Desc != nil {
+			desc = state.Desc.LocaleString(p)
+		}
+		fmt.Printf("\t%d\t%s\n", state.Version, desc)
 	}
-	return s, nil
 }
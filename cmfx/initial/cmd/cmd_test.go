@@ -0,0 +1,27 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/issue9/assert/v4"
+)
+
+func TestCutAction(t *testing.T) {
+	a := assert.New(t, false)
+
+	arg, found := cutAction("export", actionExport)
+	a.True(found).Empty(arg)
+
+	arg, found = cutAction("export:./dir", actionExport)
+	a.True(found).Equal(arg, "./dir")
+
+	_, found = cutAction("exports", actionExport)
+	a.False(found)
+
+	_, found = cutAction("import:./dir", actionExport)
+	a.False(found)
+}
@@ -1,4 +1,4 @@
-// SPDX-FileCopyrightText: 2022-2024 caixw
+// SPDX-FileCopyrightText: 2022-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
@@ -16,6 +16,8 @@ import (
 	"github.com/issue9/cmfx/cmfx/modules/admin"
 	"github.com/issue9/cmfx/cmfx/modules/member"
 	"github.com/issue9/cmfx/cmfx/modules/system"
+	"github.com/issue9/cmfx/cmfx/modules/upload"
+	"github.com/issue9/cmfx/cmfx/modules/webhook"
 )
 
 // Config 配置文件的自定义部分内容
@@ -33,6 +35,16 @@ type Config struct {
 	System *system.Config `yaml:"system" xml:"system" json:"system"`
 
 	Member *member.Config `yaml:"member" xml:"member" json:"member"`
+
+	// Upload 上传文件的存储配置
+	//
+	// 为空表示保存在本地的 ./uploads 目录。
+	Upload *upload.StorageConfig `yaml:"upload,omitempty" xml:"upload,omitempty" json:"upload,omitempty"`
+
+	// Webhook Webhook 模块的相关配置
+	//
+	// 为空表示不启用该模块。
+	Webhook *webhook.Config `yaml:"webhook,omitempty" xml:"webhook,omitempty" json:"webhook,omitempty"`
 }
 
 func (c *Config) SanitizeConfig() *web.FieldError {
@@ -63,6 +75,9 @@ func (c *Config) SanitizeConfig() *web.FieldError {
 	if err := c.System.SanitizeConfig(); err != nil {
 		return err.AddFieldParent("system")
 	}
+	if b := c.System.Backup; b != nil && b.Restore == nil {
+		b.Restore, b.Offline = system.DBRestorer(c.DB.Type, c.DB.DSN)
+	}
 
 	if c.Member == nil {
 		return web.NewFieldError("member", locales.Required)
@@ -71,6 +86,22 @@ func (c *Config) SanitizeConfig() *web.FieldError {
 		return err.AddFieldParent("member")
 	}
 
+	if c.Upload == nil {
+		c.Upload = &upload.StorageConfig{}
+	}
+	if err := c.Upload.SanitizeConfig(); err != nil {
+		return err.AddFieldParent("upload")
+	}
+	if err := c.Upload.CheckSecret(c.Admin.Upload, c.Member.Upload); err != nil {
+		return err.AddFieldParent("upload")
+	}
+
+	if c.Webhook != nil {
+		if err := c.Webhook.SanitizeConfig(); err != nil {
+			return err.AddFieldParent("webhook")
+		}
+	}
+
 	return nil
 }
 
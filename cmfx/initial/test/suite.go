@@ -1,4 +1,4 @@
-// SPDX-FileCopyrightText: 2022-2025 caixw
+// SPDX-FileCopyrightText: 2022-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
@@ -23,6 +23,7 @@ import (
 	"github.com/issue9/web"
 	"github.com/issue9/web/mimetype/cbor"
 	"github.com/issue9/web/mimetype/json"
+	"github.com/issue9/web/mimetype/nop"
 	"github.com/issue9/web/mimetype/yaml"
 	"github.com/issue9/web/openapi"
 	"github.com/issue9/web/server"
@@ -121,6 +122,14 @@ func (s *Suite) TableExists(name string) *Suite {
 	return s
 }
 
+func (s *Suite) TableNotExists(name string) *Suite {
+	s.Assertion().TB().Helper()
+
+	exists, err := s.DB().SQLBuilder().TableExists().Table(name).Exists()
+	s.Assertion().NotError(err).False(exists)
+	return s
+}
+
 // newServer 创建 [web.Server] 实例
 func newServer(a *assert.Assertion) *cmfx.Module {
 	s := config.Serializer{}
@@ -128,17 +137,21 @@ func newServer(a *assert.Assertion) *cmfx.Module {
 		Add(xj.Marshal, xj.Unmarshal, ".json").
 		Add(xml.Marshal, xml.Unmarshal, ".xml")
 
+	hs := &http.Server{Addr: ":8080"}
 	srv, err := server.NewHTTP("test", "1.0.0", &server.Options{
 		Language: language.SimplifiedChinese,
 		Logs:     logs.New(logs.NewTermHandler(os.Stdout, nil), logs.WithLevels(logs.AllLevels()...), logs.WithCreated(logs.NanoLayout)),
 		Codec: web.NewCodec().
 			AddMimetype(json.Mimetype, json.Marshal, json.Unmarshal, json.ProblemMimetype, true, true).
 			AddMimetype(yaml.Mimetype, yaml.Marshal, yaml.Unmarshal, yaml.ProblemMimetype, true, true).
-			AddMimetype(cbor.Mimetype, cbor.Marshal, cbor.Unmarshal, cbor.ProblemMimetype, true, true),
-		HTTPServer: &http.Server{Addr: ":8080"},
+			AddMimetype(cbor.Mimetype, cbor.Marshal, cbor.Unmarshal, cbor.ProblemMimetype, true, true).
+			AddMimetype("multipart/form-data", nop.Marshal, nop.Unmarshal, "", false, false).
+			AddMimetype("application/offset+octet-stream", nop.Marshal, nop.Unmarshal, "", false, false),
+		HTTPServer: hs,
 		Config:     config.Dir(s, "./"),
 	})
 	a.NotError(err).NotNil(srv)
+	hs.Handler = cmfx.RecordRequestBody(hs.Handler, 64<<10)
 
 	rate := ratelimit.New(web.NewCache("_test_rate", srv.Cache()), 10, time.Second, nil)
 
@@ -160,5 +173,8 @@ func newServer(a *assert.Assertion) *cmfx.Module {
 		openapis.WithCDNViewer(srv, "scalar", ""),
 	)
 
-	return cmfx.Init(srv, rate, db, srv.Routers().New("amin", nil), doc)
+	return cmfx.Init(srv, rate, db, srv.Routers().New("amin", nil,
+		web.WithAnyInterceptor("any"),
+		web.WithDigitInterceptor("digit"),
+	), doc)
 }
@@ -0,0 +1,237 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package cmfx
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/issue9/orm/v6"
+	"github.com/issue9/web"
+)
+
+// Migration 数据库的版本迁移操作
+//
+// 对于已经部署的应用，数据表结构的所有变更(比如添加字段)都应该以 Migration 的形式提供。
+// 已经发布的迁移操作不能再修改，新的操作只能追加，且版本号必须大于已有的版本号。
+type Migration struct {
+	// Version 版本号
+	//
+	// 在同一模块中必须是唯一的，按从小到大的顺序执行。
+	Version int64
+
+	// Desc 对当前迁移操作的描述
+	Desc web.LocaleStringer
+
+	// Up 升级操作
+	Up func(*Module) error
+
+	// Down 降级操作
+	//
+	// 可以为空，表示当前版本不支持回退。
+	Down func(*Module) error
+}
+
+// MigrationState 迁移操作的状态
+type MigrationState struct {
+	Module  string             `json:"module" yaml:"module" cbor:"module"`
+	Version int64              `json:"version" yaml:"version" cbor:"version"`
+	Desc    web.LocaleStringer `json:"-" yaml:"-" cbor:"-"`
+	Applied time.Time          `json:"applied,omitzero" yaml:"applied,omitempty" cbor:"applied,omitzero"` // 执行时间，为零值表示未执行。
+}
+
+// Migrations 模块的数据库迁移列表
+type Migrations struct {
+	mod   *Module
+	db    *orm.DB
+	items []*Migration
+}
+
+type migrationPO struct {
+	Module  string    `orm:"name(module);len(100);unique(module_version)"`
+	Version int64     `orm:"name(version);unique(module_version)"`
+	Applied time.Time `orm:"name(applied)"`
+}
+
+func (*migrationPO) TableName() string { return "_migrations" }
+
+// Migrations 声明当前模块的数据库迁移列表
+//
+// 迁移记录保存在不带模块前缀的 _migrations 表中，以 [Module.ID] 区分不同的模块。
+// items 会按 [Migration.Version] 排序，如果存在相同的版本号会 panic。
+func (m *Module) Migrations(items ...*Migration) *Migrations {
+	items = slices.Clone(items)
+	slices.SortFunc(items, func(a, b *Migration) int { return cmp.Compare(a.Version, b.Version) })
+	for i := 1; i < len(items); i++ {
+		if items[i].Version == items[i-1].Version {
+			panic(fmt.Sprintf("模块 %s 存在相同版本号的迁移操作 %d", m.ID(), items[i].Version))
+		}
+	}
+
+	for _, item := range items {
+		if item.Up == nil {
+			panic(fmt.Sprintf("模块 %s 的迁移操作 %d 未指定 Up", m.ID(), item.Version))
+		}
+	}
+
+	return &Migrations{
+		mod:   m,
+		db:    m.DB().New(""),
+		items: items,
+	}
+}
+
+// 创建 _migrations 表，如果已经存在，则不作任何操作。
+func (ms *Migrations) init() error {
+	exists, err := ms.db.SQLBuilder().TableExists().Table(orm.TableName(&migrationPO{})).Exists()
+	if err != nil || exists {
+		return err
+	}
+	return ms.db.Create(&migrationPO{})
+}
+
+// 已经执行的版本号以及执行时间
+func (ms *Migrations) applied() (map[int64]time.Time, error) {
+	if err := ms.init(); err != nil {
+		return nil, err
+	}
+
+	pos := make([]*migrationPO, 0, len(ms.items))
+	if _, err := ms.db.Where("module=?", ms.mod.ID()).Select(true, &pos); err != nil {
+		return nil, err
+	}
+
+	versions := make(map[int64]time.Time, len(pos))
+	for _, po := range pos {
+		versions[po.Version] = po.Applied
+	}
+	return versions, nil
+}
+
+// Install 将所有的迁移操作标记为已执行
+//
+// 安装模块时，数据表已经是最新的结构，所以只需要记录版本，而不需要真正执行迁移操作。
+func (ms *Migrations) Install() error {
+	versions, err := ms.applied()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	return ms.db.DoTransaction(func(tx *orm.Tx) error {
+		for _, item := range ms.items {
+			if _, found := versions[item.Version]; found {
+				continue
+			}
+
+			if _, err := tx.Insert(&migrationPO{Module: ms.mod.ID(), Version: item.Version, Applied: now}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// States 所有迁移操作的状态
+func (ms *Migrations) States() ([]*MigrationState, error) {
+	versions, err := ms.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]*MigrationState, 0, len(ms.items))
+	for _, item := range ms.items {
+		states = append(states, &MigrationState{
+			Module:  ms.mod.ID(),
+			Version: item.Version,
+			Desc:    item.Desc,
+			Applied: versions[item.Version],
+		})
+	}
+	return states, nil
+}
+
+// Pending 未执行的迁移操作
+func (ms *Migrations) Pending() ([]*MigrationState, error) {
+	states, err := ms.States()
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(states, func(s *MigrationState) bool { return !s.Applied.IsZero() }), nil
+}
+
+// Upgrade 按版本号从小到大执行所有未执行的迁移操作
+//
+// 遇到错误时会中断执行，已经成功执行的迁移操作会被记录。
+func (ms *Migrations) Upgrade() error {
+	versions, err := ms.applied()
+	if err != nil {
+		return err
+	}
+
+	for _, item := range ms.items {
+		if _, found := versions[item.Version]; found {
+			continue
+		}
+
+		if err := item.Up(ms.mod); err != nil {
+			return fmt.Errorf("upgrade %s to %d: %w", ms.mod.ID(), item.Version, err)
+		}
+
+		if _, err := ms.db.Insert(&migrationPO{Module: ms.mod.ID(), Version: item.Version, Applied: time.Now()}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Downgrade 回退所有版本号大于 version 的迁移操作
+//
+// 按版本号从大到小执行 [Migration.Down]，如果某一版本未提供 [Migration.Down]，则中断执行并返回错误。
+func (ms *Migrations) Downgrade(version int64) error {
+	versions, err := ms.applied()
+	if err != nil {
+		return err
+	}
+
+	for _, item := range slices.Backward(ms.items) {
+		if item.Version <= version {
+			break
+		}
+
+		if _, found := versions[item.Version]; !found {
+			continue
+		}
+
+		if item.Down == nil {
+			return web.NewLocaleError("migration %d of %s can not be downgraded", item.Version, ms.mod.ID())
+		}
+
+		if err := item.Down(ms.mod); err != nil {
+			return fmt.Errorf("downgrade %s from %d: %w", ms.mod.ID(), item.Version, err)
+		}
+
+		if _, err := ms.db.Delete(&migrationPO{Module: ms.mod.ID(), Version: item.Version}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Uninstall 删除当前模块的所有迁移记录
+//
+// 一般在卸载模块时调用，之后可以重新调用 [Migrations.Install]。
+func (ms *Migrations) Uninstall() error {
+	if err := ms.init(); err != nil {
+		return err
+	}
+
+	_, err := ms.db.Where("module=?", ms.mod.ID()).Delete(&migrationPO{})
+	return err
+}
@@ -0,0 +1,133 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package cmfx
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/orm/v6"
+	"github.com/issue9/orm/v6/dialect"
+	"github.com/issue9/web"
+	"github.com/issue9/web/openapi"
+)
+
+func TestMigrations(t *testing.T) {
+	a := assert.New(t, false)
+	srv := newServer(a)
+
+	const dbFile = "./migrations.db"
+
+	db, err := orm.NewDB("", dbFile, dialect.Sqlite3("sqlite3"))
+	a.NotError(err).NotNil(db)
+	defer func() {
+		a.NotError(db.Close())
+		a.NotError(os.Remove(dbFile))
+	}()
+
+	mod := NewModule("m1", web.Phrase("m1"), srv, db, srv.Routers().New("def", nil), openapi.New(srv, web.Phrase("test")))
+
+	a.PanicString(func() {
+		mod.Migrations(&Migration{Version: 1, Up: func(*Module) error { return nil }}, &Migration{Version: 1, Up: func(*Module) error { return nil }})
+	}, "模块 m1 存在相同版本号的迁移操作 1")
+
+	a.PanicString(func() {
+		mod.Migrations(&Migration{Version: 1})
+	}, "模块 m1 的迁移操作 1 未指定 Up")
+
+	ups := make([]int64, 0, 3)
+	downs := make([]int64, 0, 3)
+	newMigration := func(v int64, down bool) *Migration {
+		m := &Migration{
+			Version: v,
+			Desc:    web.Phrase("m%d", v),
+			Up: func(*Module) error {
+				ups = append(ups, v)
+				return nil
+			},
+		}
+		if down {
+			m.Down = func(*Module) error {
+				downs = append(downs, v)
+				return nil
+			}
+		}
+		return m
+	}
+
+	// Install
+
+	ms := mod.Migrations(newMigration(2, true), newMigration(1, false))
+	a.NotError(ms.Install())
+	a.Empty(ups)
+	pending, err := ms.Pending()
+	a.NotError(err).Empty(pending)
+
+	// Upgrade
+
+	ms = mod.Migrations(newMigration(2, true), newMigration(1, false), newMigration(4, true), newMigration(3, true))
+	pending, err = ms.Pending()
+	a.NotError(err).Length(pending, 2).
+		Equal(pending[0].Version, 3).
+		Equal(pending[1].Version, 4).
+		Equal(pending[0].Module, "m1")
+
+	a.NotError(ms.Upgrade())
+	a.Equal(ups, []int64{3, 4})
+	pending, err = ms.Pending()
+	a.NotError(err).Empty(pending)
+
+	states, err := ms.States()
+	a.NotError(err).Length(states, 4)
+	for _, s := range states {
+		a.False(s.Applied.IsZero())
+	}
+
+	// Downgrade
+
+	a.NotError(ms.Downgrade(2))
+	a.Equal(downs, []int64{4, 3})
+	pending, err = ms.Pending()
+	a.NotError(err).Length(pending, 2)
+
+	a.Error(ms.Downgrade(0)) // 1 不支持 Down
+	a.Equal(downs, []int64{4, 3, 2})
+	pending, err = ms.Pending()
+	a.NotError(err).Length(pending, 3)
+
+	// Upgrade 出错
+
+	ups = ups[:0]
+	fail := errors.New("fail")
+	ms = mod.Migrations(newMigration(1, false), newMigration(2, true), &Migration{Version: 3, Up: func(*Module) error { return fail }}, newMigration(4, true))
+	a.ErrorIs(ms.Upgrade(), fail)
+	a.Equal(ups, []int64{2})
+	pending, err = ms.Pending()
+	a.NotError(err).Length(pending, 2).Equal(pending[0].Version, 3)
+
+	// Lifecycle
+
+	ups = ups[:0]
+	upgraded := false
+	l := &Lifecycle{
+		Migrations: func(m *Module) *Migrations {
+			return m.Migrations(newMigration(1, false), newMigration(2, true), newMigration(3, true), newMigration(4, true))
+		},
+		Upgrade: func(*Module) error {
+			a.Equal(ups, []int64{3, 4}) // 迁移操作先于 Upgrade 执行
+			upgraded = true
+			return nil
+		},
+	}
+	a.NotError(l.upgrade(mod))
+	a.True(upgraded)
+	states, err = l.States(mod)
+	a.NotError(err).Length(states, 4)
+
+	states, err = (&Lifecycle{}).States(mod)
+	a.NotError(err).Nil(states)
+}
@@ -1,4 +1,4 @@
-// SPDX-FileCopyrightText: 2022-2024 caixw
+// SPDX-FileCopyrightText: 2022-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
@@ -19,8 +19,9 @@ import (
 )
 
 const (
-	moduleKey serverVarsKey = 0
-	limitKey  serverVarsKey = 1
+	moduleKey   serverVarsKey = 0
+	limitKey    serverVarsKey = 1
+	registryKey serverVarsKey = 2
 )
 
 type serverVarsKey int
@@ -171,5 +172,7 @@ func problems(s web.Server) {
 		&web.LocaleProblem{ID: NotFoundInvalidPath, Title: web.StringPhrase("not found invalid path"), Detail: web.StringPhrase("not found invalid path detail")},
 	).Add(http.StatusPreconditionFailed,
 		&web.LocaleProblem{ID: PreconditionFailedNeedSSE, Title: web.StringPhrase("precondition failed need sse"), Detail: web.StringPhrase("precondition failed need sse detail")},
+	).Add(http.StatusRequestEntityTooLarge,
+		&web.LocaleProblem{ID: RequestEntityTooLargeQuotaExceeded, Title: web.StringPhrase("request entity too large quota exceeded"), Detail: web.StringPhrase("request entity too large quota exceeded detail")},
 	)
 }
@@ -22,8 +22,7 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 )
 
-func TestNewModule(t *testing.T) {
-	a := assert.New(t, false)
+func newServer(a *assert.Assertion) web.Server {
 	srv, err := server.NewHTTP("test", "1.0.0", &server.Options{
 		Language:   language.SimplifiedChinese,
 		Logs:       logs.New(logs.NewTermHandler(os.Stdout, nil), logs.WithLevels(logs.AllLevels()...), logs.WithCreated(logs.NanoLayout)),
@@ -31,6 +30,12 @@ func TestNewModule(t *testing.T) {
 		HTTPServer: &http.Server{Addr: ":8080"},
 	})
 	a.NotError(err).NotNil(srv)
+	return srv
+}
+
+func TestNewModule(t *testing.T) {
+	a := assert.New(t, false)
+	srv := newServer(a)
 
 	const dbFile = "./sqlite3.db"
 
@@ -90,3 +90,30 @@ func Install(mod *cmfx.Module, o *Config, up *upload.Module) *Module {
 
 	return l
 }
+
+// Uninstall 删除由 [Install] 创建的数据表
+//
+// 需要在所有依赖于管理模块的模块卸载之后再调用。
+func Uninstall(mod *cmfx.Module) error {
+	if err := mod.DB().Drop(&info{}); err != nil {
+		return err
+	}
+
+	if err := linkage.Uninstall(mod, departmentsTableName); err != nil {
+		return err
+	}
+
+	if err := rbac.Uninstall(mod); err != nil {
+		return err
+	}
+
+	return user.Uninstall(mod)
+}
+
+// DeleteResourceGroup 从所有角色中删除模块 mod 的资源
+//
+// adminMod 为管理模块，即 [Install] 的 mod 参数；
+// mod 为调用 [Module.NewResourceGroup] 时传入的模块。
+func DeleteResourceGroup(adminMod, mod *cmfx.Module) error {
+	return rbac.DeleteResourceGroup(adminMod, mod.ID())
+}
@@ -1,4 +1,4 @@
-// SPDX-FileCopyrightText: 2022-2024 caixw
+// SPDX-FileCopyrightText: 2022-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
@@ -24,3 +24,18 @@ func TestInstall(t *testing.T) {
 
 	suite.TableExists(mod.ID() + "_info")
 }
+
+func TestUninstall(t *testing.T) {
+	a := assert.New(t, false)
+	suite := test.NewSuite(a)
+	defer suite.Close()
+
+	mod := suite.NewModule("test")
+	Install(mod, defaultConfig(a), uploadtest.NewModule(suite, "admin_upload"))
+
+	a.NotError(Uninstall(mod))
+	suite.TableNotExists(mod.ID() + "_info").
+		TableNotExists(mod.ID() + "_" + departmentsTableName).
+		TableNotExists(mod.ID() + "_rbac_roles").
+		TableNotExists(mod.ID() + "_users")
+}
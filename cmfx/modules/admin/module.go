@@ -5,8 +5,10 @@
 package admin
 
 import (
+	"strconv"
 	"time"
 
+	"github.com/issue9/events"
 	"github.com/issue9/orm/v6"
 	"github.com/issue9/web"
 	"github.com/issue9/web/mimetype/sse"
@@ -28,6 +30,10 @@ type Module struct {
 	sse       *sse.Server[int64]
 	temp      *temporary.Temporary[*user.User]
 	deps      *linkage.Linkages
+	up        *upload.Module
+	upConf    *upload.Config
+
+	operations *events.Event[*Operation]
 }
 
 // Load 加载管理模块
@@ -36,9 +42,13 @@ type Module struct {
 // up 上传模块；
 func Load(mod *cmfx.Module, o *Config, up *upload.Module) *Module {
 	m := &Module{
-		user: user.NewUsers(mod, o.User),
-		sse:  sse.NewServer[int64](mod.Server(), o.SSE.Retry.Duration(), o.SSE.KeepAlive.Duration(), o.SSE.Cap, web.Phrase("admin sse server")),
-		temp: temporary.New[*user.User](mod.Server(), time.Minute, true, "token", cmfx.UnauthorizedInvalidToken, web.ProblemInternalServerError),
+		user:   user.NewUsers(mod, o.User),
+		sse:    sse.NewServer[int64](mod.Server(), o.SSE.Retry.Duration(), o.SSE.KeepAlive.Duration(), o.SSE.Cap, web.Phrase("admin sse server")),
+		temp:   temporary.New[*user.User](mod.Server(), time.Minute, true, "token", cmfx.UnauthorizedInvalidToken, web.ProblemInternalServerError),
+		up:     up,
+		upConf: o.Upload,
+
+		operations: events.New[*Operation](),
 	}
 
 	inst := rbac.New(mod, func(ctx *web.Context) (int64, web.Responser) {
@@ -66,6 +76,7 @@ func Load(mod *cmfx.Module, o *Config, up *upload.Module) *Module {
 	postDepartments := g.New("post-departments", web.StringPhrase("post departments"))
 	deleteDepartment := g.New("delete-department", web.StringPhrase("delete department"))
 	putDepartment := g.New("put-department", web.StringPhrase("edit department"))
+	getUploadUsages := g.New("get-upload-usages", web.StringPhrase("view upload usages"))
 
 	p := mod.Router().Prefix(m.URLPrefix(), m)
 
@@ -197,9 +208,15 @@ func Load(mod *cmfx.Module, o *Config, up *upload.Module) *Module {
 		Delete("/admins/{id:digit}", m.deleteAdmin, delAdmin, mod.API(func(o *openapi.Operation) {
 			o.Desc(web.Phrase("delete the admin api"), nil).
 				ResponseEmpty("204")
+		})).
+		Get("/upload-usages", m.getUploadUsages, getUploadUsages, mod.API(func(o *openapi.Operation) {
+			o.Tag("upload").
+				Desc(web.Phrase("get upload usages of admins api"), nil).
+				QueryObject(query.Limit{}, nil).
+				Response200(query.Page[upload.UsageVO]{})
 		}))
 
-	up.Handle(p, mod.API, o.Upload)
+	up.Handle(p, mod.API, o.Upload, func(ctx *web.Context) int64 { return m.CurrentUser(ctx).ID }, m.roleIDs)
 
 	return m
 }
@@ -207,25 +224,32 @@ func Load(mod *cmfx.Module, o *Config, up *upload.Module) *Module {
 func (m *Module) URLPrefix() string { return m.user.URLPrefix() }
 
 // Middleware 验证是否登录
+//
+// 同时会记录非 GET、HEAD 和 OPTIONS 请求的操作，可通过 [Module.OnOperation] 获取。
 func (m *Module) Middleware(next web.HandlerFunc, method, path, router string) web.HandlerFunc {
-	return m.user.Middleware(next, method, path, router)
+	return m.user.Middleware(m.recordOperation(next, method), method, path, router)
 }
 
 // CurrentUser 获取当前登录的用户信息
 func (m *Module) CurrentUser(ctx *web.Context) *user.User { return m.user.CurrentUser(ctx) }
 
 // NewResourceGroup 以模块为单位创建资源分组
-func (m *Module) NewResourceGroup(mod *cmfx.Module) *rbac.ResourceGroup {
-	return m.roleGroup.RBAC().NewResourceGroup(mod.ID(), mod.Desc())
+func (m *Module) NewResourceGroup(mod *cmfx.Module) *ResourceGroup {
+	return &ResourceGroup{ResourceGroup: m.roleGroup.RBAC().NewResourceGroup(mod.ID(), mod.Desc()), id: mod.ID()}
 }
 
 // GetResourceGroup 获取指定模块的资源分组
-func (m *Module) GetResourceGroup(mod *cmfx.Module) *rbac.ResourceGroup {
-	return m.roleGroup.RBAC().ResourceGroup(mod.ID())
+//
+// 如果不存在，返回 nil。
+func (m *Module) GetResourceGroup(mod *cmfx.Module) *ResourceGroup {
+	if g := m.roleGroup.RBAC().ResourceGroup(mod.ID()); g != nil {
+		return &ResourceGroup{ResourceGroup: g, id: mod.ID()}
+	}
+	return nil
 }
 
 // ResourceGroup 管理模块的资源分组
-func (m *Module) ResourceGroup() *rbac.ResourceGroup { return m.GetResourceGroup(m.user.Module()) }
+func (m *Module) ResourceGroup() *ResourceGroup { return m.GetResourceGroup(m.user.Module()) }
 
 // AddSecurityLog 记录一条安全日志
 func (m *Module) AddSecurityLog(tx *orm.Tx, uid int64, content, ip, ua string) error {
@@ -239,6 +263,11 @@ func (m *Module) AddSecurityLogWithContext(tx *orm.Tx, uid int64, ctx *web.Conte
 
 func (m *Module) UserModule() *user.Users { return m.user }
 
+// 头像在上传模块中的引用者
+func (m *Module) avatarOwner(uid int64) string {
+	return m.user.Module().ID() + "_avatar_" + strconv.FormatInt(uid, 10)
+}
+
 // 手动添加一个新的管理员
 func (m *Module) addAdmin(data *infoWithAccountTO, ip, ua, content string) error {
 	u, err := m.user.New(user.StateNormal, data.Username, data.Password, ip, ua, content)
@@ -253,7 +282,13 @@ func (m *Module) addAdmin(data *infoWithAccountTO, ip, ua, content string) error
 		Avatar:   data.Avatar,
 		Sex:      data.Sex,
 	}
-	if _, err = m.user.Module().DB().Insert(a); err != nil {
+	err = m.user.Module().DB().DoTransaction(func(tx *orm.Tx) error {
+		if _, err := m.user.Module().Engine(tx).Insert(a); err != nil {
+			return err
+		}
+		return m.up.SetRefs(tx, m.avatarOwner(u.ID), a.Avatar)
+	})
+	if err != nil {
 		return err
 	}
 
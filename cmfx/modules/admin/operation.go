@@ -0,0 +1,168 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/issue9/mux/v9/header"
+	"github.com/issue9/web"
+
+	"github.com/issue9/cmfx/cmfx"
+	"github.com/issue9/cmfx/cmfx/user/rbac"
+)
+
+type resourceKeyType int
+
+const resourceKey resourceKeyType = 0
+
+// 提交内容中需要脱敏的字段，只要字段名中包含以下内容（不区分大小写）即会被替换。
+var sensitiveFields = []string{"password", "secret", "token"}
+
+const sensitiveMask = "******"
+
+// ResourceGroup 资源分组
+//
+// 与 [rbac.ResourceGroup] 的区别在于，由 New 返回的中间件会将资源 ID
+// 记录在 [web.Context] 中，供 [Operation.Resource] 使用。
+type ResourceGroup struct {
+	*rbac.ResourceGroup
+	id string
+}
+
+// New 添加新的资源
+//
+// 返回的是用于判断是否拥有当前资源权限的中间件。
+func (g *ResourceGroup) New(id string, desc web.LocaleStringer) web.MiddlewareFunc {
+	mw := g.ResourceGroup.New(id, desc)
+	id = g.id + "_" + id // 与 rbac 中资源 ID 的拼接方式相同
+
+	return func(next web.HandlerFunc, method, path, router string) web.HandlerFunc {
+		next = mw(next, method, path, router)
+		return func(ctx *web.Context) web.Responser {
+			ctx.SetVar(resourceKey, id)
+			return next(ctx)
+		}
+	}
+}
+
+// Operation 管理员的操作记录
+//
+// 所有经由 [Module.Middleware] 的非 GET、HEAD 和 OPTIONS 请求都会生成一条记录。
+type Operation struct {
+	UID       int64             // 操作者
+	Resource  string            // 接口关联的 RBAC 资源 ID，未关联资源的接口为空。
+	Method    string            // 请求方法
+	Path      string            // 请求地址
+	Targets   map[string]string // 地址中的参数，一般为被操作对象的 ID。
+	Status    int               // 响应的状态码
+	IP        string
+	UserAgent string
+	Created   time.Time
+
+	// 已经脱敏的提交内容，仅记录 JSON 格式的内容。
+	//
+	// 只是请求中提交的数据，并不包含被操作对象在修改之前的状态，
+	// 所以无法据此得到修改前后的差异，需要时可以结合该对象之前的操作记录查看。
+	Body []byte
+}
+
+// OnOperation 注册管理员操作的事件
+//
+// f 在请求结束之后同步调用。提交内容需要由 [cmfx.RecordRequestBody] 记录，否则 [Operation.Body] 始终为空。
+func (m *Module) OnOperation(f func(*Operation)) context.CancelFunc {
+	return m.operations.Subscribe(f)
+}
+
+// 记录操作的中间件，需要在登录验证之后调用。
+func (m *Module) recordOperation(next web.HandlerFunc, method string) web.HandlerFunc {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return next
+	}
+
+	return func(ctx *web.Context) web.Responser {
+		ctx.OnExit(func(ctx *web.Context, status int) {
+			u := m.CurrentUser(ctx)
+			if u == nil {
+				return
+			}
+
+			op := &Operation{
+				UID:       u.ID,
+				Method:    ctx.Request().Method,
+				Path:      ctx.Request().URL.Path,
+				Status:    status,
+				IP:        ctx.ClientIP(),
+				UserAgent: ctx.Request().UserAgent(),
+				Created:   ctx.Begin(),
+			}
+			if v, found := ctx.GetVar(resourceKey); found {
+				op.Resource = v.(string)
+			}
+			if ps := ctx.Route().Params(); ps != nil && ps.Count() > 0 {
+				op.Targets = make(map[string]string, ps.Count())
+				ps.Range(func(k, v string) { op.Targets[k] = v })
+			}
+			if strings.HasPrefix(ctx.Request().Header.Get(header.ContentType), header.JSON) {
+				op.Body = sanitizeBody(cmfx.RequestBody(ctx))
+			}
+
+			m.operations.Publish(false, op)
+		})
+		return next(ctx)
+	}
+}
+
+// 将 JSON 格式的 body 中的敏感字段替换为 [sensitiveMask]
+//
+// 如果 body 不是合法的 JSON，返回 nil。
+func sanitizeBody(body []byte) []byte {
+	if len(body) == 0 {
+		return nil
+	}
+
+	var v any
+	if err := json.Unmarshal(body, &v); err != nil {
+		return nil
+	}
+	data, err := json.Marshal(sanitizeValue(v))
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+func sanitizeValue(v any) any {
+	switch vv := v.(type) {
+	case map[string]any:
+		for k, item := range vv {
+			if isSensitiveField(k) {
+				vv[k] = sensitiveMask
+			} else {
+				vv[k] = sanitizeValue(item)
+			}
+		}
+	case []any:
+		for i, item := range vv {
+			vv[i] = sanitizeValue(item)
+		}
+	}
+	return v
+}
+
+func isSensitiveField(name string) bool {
+	name = strings.ToLower(name)
+	for _, f := range sensitiveFields {
+		if strings.Contains(name, f) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,22 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package admin
+
+import (
+	"testing"
+
+	"github.com/issue9/assert/v4"
+)
+
+func TestSanitizeBody(t *testing.T) {
+	a := assert.New(t, false)
+
+	a.Nil(sanitizeBody(nil)).
+		Nil(sanitizeBody([]byte("not json")))
+
+	a.Equal(string(sanitizeBody([]byte(`{"username":"u1","password":"123"}`))), `{"password":"******","username":"u1"}`).
+		Equal(string(sanitizeBody([]byte(`[{"name":"n1","AccessToken":"t1","info":{"secretKey":"k"}}]`))), `[{"AccessToken":"******","info":{"secretKey":"******"},"name":"n1"}]`).
+		Equal(string(sanitizeBody([]byte(`["password"]`))), `["password"]`)
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/issue9/web/filter"
 
 	"github.com/issue9/cmfx/cmfx"
+	"github.com/issue9/cmfx/cmfx/modules/upload"
 	"github.com/issue9/cmfx/cmfx/query"
 	"github.com/issue9/cmfx/cmfx/types"
 	"github.com/issue9/cmfx/cmfx/user"
@@ -48,10 +49,7 @@ func (m *Module) getAdmin(ctx *web.Context) web.Responser {
 		return ctx.Error(err, "")
 	}
 
-	rs := make([]string, 0, 4)
-	for _, r := range m.roleGroup.UserRoles(id) {
-		rs = append(rs, r.ID)
-	}
+	rs := m.roleIDs(id)
 
 	ps := slices.Collect(m.user.Identities(id))
 	slices.SortFunc(ps, func(a, b *user.IdentityVO) int { return cmp.Compare(a.ID, b.ID) }) // 排序，尽量使输出的内容相同
@@ -120,11 +118,7 @@ func (m *Module) getAdmins(ctx *web.Context) web.Responser {
 	}
 
 	return query.PagingResponserWithConvert(ctx, &q.Limit, sql, func(i *modelInfo) *infoWithRoleStateVO {
-		roles := m.roleGroup.UserRoles(i.ID)
-		rs := make([]string, 0, len(roles))
-		for _, r := range roles {
-			rs = append(rs, r.ID)
-		}
+		rs := m.roleIDs(i.ID)
 
 		return &infoWithRoleStateVO{
 			info:    i.info,
@@ -151,7 +145,10 @@ func (m *Module) patchAdmin(ctx *web.Context) web.Responser {
 
 	err := m.UserModule().Module().DB().DoTransaction(func(tx *orm.Tx) error {
 		e := tx.NewEngine(m.UserModule().Module().DB().TablePrefix())
-		if _, err := e.Update(&data.info, "sex"); err != nil {
+		if _, err := e.Update(&data.info, "sex", "avatar"); err != nil {
+			return err
+		}
+		if err := m.up.SetRefs(tx, m.avatarOwner(u.ID), data.Avatar); err != nil {
 			return err
 		}
 
@@ -243,3 +240,29 @@ func (m *Module) getUserFromPath(ctx *web.Context) (*user.User, web.Responser) {
 
 	return u, nil
 }
+
+// 用户 uid 拥有的角色 ID
+func (m *Module) roleIDs(uid int64) []string {
+	roles := m.roleGroup.UserRoles(uid)
+	rs := make([]string, 0, len(roles))
+	for _, r := range roles {
+		rs = append(rs, r.ID)
+	}
+	return rs
+}
+
+func (m *Module) getUploadUsages(ctx *web.Context) web.Responser {
+	q := &query.Limit{}
+	if resp := ctx.QueryObject(true, q, cmfx.BadRequestInvalidQuery); resp != nil {
+		return resp
+	}
+
+	p, err := m.up.Usages(m.URLPrefix(), q, func(u *upload.UsageVO) { u.Quota = m.upConf.UserQuota(m.roleIDs(u.UID)) })
+	if err != nil {
+		return ctx.Error(err, "")
+	}
+	if p == nil {
+		return ctx.NotFound()
+	}
+	return web.OK(p)
+}
@@ -10,6 +10,7 @@ import (
 	"slices"
 	"time"
 
+	"github.com/issue9/orm/v6"
 	"github.com/issue9/web"
 
 	"github.com/issue9/cmfx/cmfx"
@@ -66,7 +67,12 @@ func (m *Module) patchInfo(ctx *web.Context) web.Responser {
 	a := m.CurrentUser(ctx)
 
 	data.ID = a.ID // 确保 ID 正确
-	_, err := m.UserModule().Module().DB().Update(data, "sex")
+	err := m.UserModule().Module().DB().DoTransaction(func(tx *orm.Tx) error {
+		if _, err := tx.NewEngine(m.UserModule().Module().DB().TablePrefix()).Update(data, "sex", "avatar"); err != nil {
+			return err
+		}
+		return m.up.SetRefs(tx, m.avatarOwner(a.ID), data.Avatar)
+	})
 	if err != nil {
 		return ctx.Error(err, "")
 	}
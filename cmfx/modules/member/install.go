@@ -1,4 +1,4 @@
-// SPDX-FileCopyrightText: 2024 caixw
+// SPDX-FileCopyrightText: 2024-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
@@ -46,3 +46,26 @@ func Install(mod *cmfx.Module, o *Config, up *upload.Module, adminL *admin.Modul
 
 	return m
 }
+
+// Uninstall 删除由 [Install] 创建的数据表
+//
+// adminMod 为管理模块，用于删除当前模块在角色中的资源；
+func Uninstall(mod, adminMod *cmfx.Module) error {
+	if err := admin.DeleteResourceGroup(adminMod, mod); err != nil {
+		return err
+	}
+
+	if err := mod.DB().Drop(&infoPO{}); err != nil {
+		return err
+	}
+
+	if err := tag.Uninstall(mod, typesTableName); err != nil {
+		return err
+	}
+
+	if err := tag.Uninstall(mod, levelsTableName); err != nil {
+		return err
+	}
+
+	return user.Uninstall(mod)
+}
@@ -1,4 +1,4 @@
-// SPDX-FileCopyrightText: 2024 caixw
+// SPDX-FileCopyrightText: 2024-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
@@ -27,3 +27,18 @@ func TestInstall(t *testing.T) {
 		TableExists(mod.ID() + "_" + typesTableName).
 		TableExists(mod.ID() + "_" + levelsTableName)
 }
+
+func TestUninstall(t *testing.T) {
+	a := assert.New(t, false)
+	suite := test.NewSuite(a)
+	defer suite.Close()
+
+	adminL := admintest.NewModule(suite)
+	mod := suite.NewModule("member")
+	Install(mod, defaultConfig(a), uploadtest.NewModule(suite, "mem_upload"), adminL, nil, nil)
+
+	a.NotError(Uninstall(mod, adminL.UserModule().Module()))
+	suite.TableNotExists(mod.ID() + "_info").
+		TableNotExists(mod.ID() + "_" + typesTableName).
+		TableNotExists(mod.ID() + "_" + levelsTableName)
+}
@@ -6,6 +6,7 @@ package member
 
 import (
 	"database/sql"
+	"strconv"
 	"time"
 
 	"github.com/issue9/orm/v6"
@@ -26,6 +27,7 @@ import (
 type Module struct {
 	user  *user.Users
 	admin *admin.Module
+	up    *upload.Module
 
 	levels *tag.Tags
 	types  *tag.Tags
@@ -36,6 +38,7 @@ func Load(mod *cmfx.Module, conf *Config, up *upload.Module, adminMod *admin.Mod
 	m := &Module{
 		user:  user.NewUsers(mod, conf.User),
 		admin: adminMod,
+		up:    up,
 
 		levels: tag.NewTags(mod, levelsTableName),
 		types:  tag.NewTags(mod, typesTableName),
@@ -129,7 +132,7 @@ func Load(mod *cmfx.Module, conf *Config, up *upload.Module, adminMod *admin.Mod
 
 	// 需要登录
 	p := mod.Router().Prefix(m.URLPrefix(), m)
-	up.Handle(p, mod.API, conf.Upload)
+	up.Handle(p, mod.API, conf.Upload, func(ctx *web.Context) int64 { return m.user.CurrentUser(ctx).ID }, nil)
 	p.
 		Get("/info", m.memberGetInfo, mod.API(func(o *openapi.Operation) {
 			o.Desc(web.Phrase("get login user info api"), nil).
@@ -185,6 +188,11 @@ func (m *Module) AddSecurityLogWithContext(tx *orm.Tx, uid int64, ctx *web.Conte
 
 func (m *Module) UserModule() *user.Users { return m.user }
 
+// 头像在上传模块中的引用者
+func (m *Module) avatarOwner(uid int64) string {
+	return m.user.Module().ID() + "_avatar_" + strconv.FormatInt(uid, 10)
+}
+
 type RegisterInfo struct {
 	Username string
 	Password string
@@ -215,7 +223,13 @@ func (m *Module) Add(state user.State, data *RegisterInfo, ip, ua, msg string) (
 		info.Birthday = sql.NullTime{Valid: true, Time: data.Birthday}
 	}
 
-	if _, err = m.UserModule().Module().DB().Insert(info); err != nil {
+	err = m.UserModule().Module().DB().DoTransaction(func(tx *orm.Tx) error {
+		if _, err := m.UserModule().Module().Engine(tx).Insert(info); err != nil {
+			return err
+		}
+		return m.up.SetRefs(tx, m.avatarOwner(u.ID), info.Avatar)
+	})
+	if err != nil {
 		return nil, err
 	}
 	return u, nil
@@ -10,6 +10,7 @@ import (
 	"slices"
 	"time"
 
+	"github.com/issue9/orm/v6"
 	"github.com/issue9/web"
 	"github.com/issue9/web/filter"
 	"github.com/issue9/webuse/v7/filters/validator"
@@ -97,7 +98,13 @@ func (m *Module) memberPatchInfo(ctx *web.Context) web.Responser {
 		Nickname: data.Nickname,
 		Avatar:   data.Avatar,
 	}
-	if _, _, err := m.user.Module().DB().Save(info, "birthday", "avatar"); err != nil {
+	err := m.user.Module().DB().DoTransaction(func(tx *orm.Tx) error {
+		if _, _, err := tx.NewEngine(m.user.Module().DB().TablePrefix()).Save(info, "birthday", "avatar"); err != nil {
+			return err
+		}
+		return m.up.SetRefs(tx, m.avatarOwner(u.ID), info.Avatar)
+	})
+	if err != nil {
 		return ctx.Error(err, "")
 	}
 
@@ -0,0 +1,386 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package system
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/issue9/events"
+	"github.com/issue9/web"
+	"github.com/issue9/webuse/v7/plugins/health"
+	"github.com/issue9/webuse/v7/services/systat"
+
+	"github.com/issue9/cmfx/cmfx/contents/notice"
+)
+
+// 告警规则的类型
+const (
+	alertKindCPU          = "cpu"
+	alertKindMem          = "mem"
+	alertKindProcessCPU   = "process-cpu"
+	alertKindProcessMem   = "process-mem"
+	alertKindRouteErrors  = "route-errors"
+	alertKindRouteLatency = "route-latency"
+	alertKindService      = "service"
+	alertKindBackup       = "backup"
+)
+
+// 告警的状态
+const (
+	AlertPending  = "pending"  // 满足条件，但是持续时间未达到 [AlertRule.For]。
+	AlertFiring   = "firing"   // 告警中
+	AlertResolved = "resolved" // 已恢复
+)
+
+const alertSSEEvent = "alert"
+
+// Alert 告警信息
+type Alert struct {
+	Rule      string    `json:"rule" yaml:"rule" cbor:"rule"`                                        // 规则名称
+	Kind      string    `json:"kind" yaml:"kind" cbor:"kind"`                                        // 规则类型
+	Target    string    `json:"target,omitempty" yaml:"target,omitempty" cbor:"target,omitempty"`    // 告警的对象，比如路由或是服务名称。
+	State     string    `json:"state" yaml:"state" cbor:"state"`                                     // 状态
+	Value     float64   `json:"value" yaml:"value" cbor:"value"`                                     // 最近一次检测到的值
+	Threshold float64   `json:"threshold" yaml:"threshold" cbor:"threshold"`                         // 规则的阈值
+	Message   string    `json:"message,omitempty" yaml:"message,omitempty" cbor:"message,omitempty"` // 错误信息
+	Since     time.Time `json:"since" yaml:"since" cbor:"since"`                                     // 开始满足条件的时间
+	Updated   time.Time `json:"updated" yaml:"updated" cbor:"updated"`                               // 状态的变更时间
+}
+
+// 某一次检测的结果
+type alertObservation struct {
+	target  string
+	value   float64
+	message string
+	unknown bool // 数据不足以作出判断，保持原有的状态。
+}
+
+type alertKey struct {
+	rule, target string
+}
+
+// 路由的累计数据，用于计算两次检测之间的差值。
+type routeSnapshot struct {
+	count        int
+	serverErrors int
+	spend        time.Duration
+}
+
+// 告警规则的检测
+type alerts struct {
+	m     *Module
+	rules []*AlertRule
+
+	mux       sync.Mutex
+	active    map[alertKey]*Alert
+	silences  map[string]time.Time // 规则的静默截止时间
+	routes    map[string]*routeSnapshot
+	stats     *systat.Stats
+	backupErr error
+
+	event *events.Event[*Alert]
+}
+
+func newAlerts(m *Module, conf *Alerts) *alerts {
+	a := &alerts{
+		m:        m,
+		rules:    conf.Rules,
+		active:   make(map[alertKey]*Alert, 10),
+		silences: make(map[string]time.Time, len(conf.Rules)),
+		event:    events.New[*Alert](),
+	}
+
+	if slices.ContainsFunc(a.rules, func(r *AlertRule) bool {
+		return r.Kind == alertKindCPU || r.Kind == alertKindMem || r.Kind == alertKindProcessCPU || r.Kind == alertKindProcessMem
+	}) {
+		m.stats.Subscribe(func(s *systat.Stats) {
+			a.mux.Lock()
+			a.stats = s
+			a.mux.Unlock()
+		})
+	}
+
+	return a
+}
+
+// 记录最近一次备份的结果
+func (a *alerts) setBackupError(err error) {
+	a.mux.Lock()
+	a.backupErr = err
+	a.mux.Unlock()
+}
+
+// 检测所有的规则，并在告警状态变化时发送通知。
+func (a *alerts) evaluate(now time.Time) error {
+	routes := a.m.health.States()
+	p := a.m.mod.Server().Locale().Printer()
+
+	failed := make([]*alertObservation, 0, 5)
+	a.m.mod.Server().Services().Visit(func(title web.LocaleStringer, s web.State, err error) {
+		if s == web.Failed {
+			failed = append(failed, newFailedObservation(title.LocaleString(p), err))
+		}
+	})
+	a.m.mod.Server().Services().VisitJobs(func(j *web.Job) {
+		if j.State() == web.Failed {
+			failed = append(failed, newFailedObservation(j.Title().LocaleString(p), j.Err()))
+		}
+	})
+
+	a.mux.Lock()
+	deltas := a.routeDeltas(routes)
+
+	changed := make([]*Alert, 0, 5)
+	observed := make(map[alertKey]struct{}, len(a.active))
+	for _, r := range a.rules {
+		var list []*alertObservation
+		switch r.Kind {
+		case alertKindRouteErrors, alertKindRouteLatency:
+			list = r.observeRoutes(deltas)
+		case alertKindService:
+			list = failed
+		case alertKindBackup:
+			if a.backupErr != nil {
+				list = []*alertObservation{{value: 1, message: a.backupErr.Error()}}
+			}
+		default:
+			list = r.observeStats(a.stats)
+		}
+
+		for _, o := range list {
+			key := alertKey{rule: r.Name, target: o.target}
+			alert, found := a.active[key]
+			if o.unknown {
+				if found {
+					observed[key] = struct{}{}
+				}
+				continue
+			}
+			observed[key] = struct{}{}
+
+			if !found {
+				alert = &Alert{
+					Rule:      r.Name,
+					Kind:      r.Kind,
+					Target:    o.target,
+					State:     AlertPending,
+					Threshold: r.Threshold,
+					Since:     now,
+					Updated:   now,
+				}
+				a.active[key] = alert
+			}
+			alert.Value = o.value
+			alert.Message = o.message
+
+			if alert.State == AlertPending && now.Sub(alert.Since) >= r.For.Duration() {
+				alert.State = AlertFiring
+				alert.Updated = now
+				changed = append(changed, alert)
+			}
+		}
+	}
+
+	for key, alert := range a.active {
+		if _, found := observed[key]; found {
+			continue
+		}
+
+		delete(a.active, key)
+		if alert.State == AlertFiring {
+			alert.State = AlertResolved
+			alert.Updated = now
+			changed = append(changed, alert)
+		}
+	}
+
+	notify := make([]*Alert, 0, len(changed))
+	for _, alert := range changed {
+		if until, found := a.silences[alert.Rule]; !found || !now.Before(until) {
+			v := *alert
+			notify = append(notify, &v)
+		}
+	}
+	a.mux.Unlock()
+
+	for _, alert := range notify {
+		a.publish(alert)
+	}
+	return nil
+}
+
+func newFailedObservation(title string, err error) *alertObservation {
+	o := &alertObservation{target: title, value: 1}
+	if err != nil {
+		o.message = err.Error()
+	}
+	return o
+}
+
+// 计算各路由与上一次检测之间的差值，并更新快照。
+//
+// 第一次检测时没有可比较的数据，返回值为空。
+// 两次检测之间没有请求的路由，返回的差值中 count 为 0。
+func (a *alerts) routeDeltas(states []*health.State) map[*health.State]*routeSnapshot {
+	first := a.routes == nil
+	if first {
+		a.routes = make(map[string]*routeSnapshot, len(states))
+	}
+
+	deltas := make(map[*health.State]*routeSnapshot, len(states))
+	for _, s := range states {
+		key := s.Router + " " + s.Method + " " + s.Pattern
+		curr := &routeSnapshot{count: s.Count, serverErrors: s.ServerErrors, spend: s.Spend}
+		prev, found := a.routes[key]
+		a.routes[key] = curr
+
+		if first || !found {
+			continue
+		}
+		if curr.count < prev.count { // 统计数据被重置
+			deltas[s] = curr
+			continue
+		}
+		deltas[s] = &routeSnapshot{
+			count:        curr.count - prev.count,
+			serverErrors: curr.serverErrors - prev.serverErrors,
+			spend:        curr.spend - prev.spend,
+		}
+	}
+	return deltas
+}
+
+func (r *AlertRule) observeRoutes(deltas map[*health.State]*routeSnapshot) []*alertObservation {
+	list := make([]*alertObservation, 0, 5)
+	for s, d := range deltas {
+		if r.Pattern != "" && r.Pattern != s.Pattern {
+			continue
+		}
+
+		target := s.Method + " " + s.Pattern
+		if d.count == 0 || d.count < r.MinRequests {
+			list = append(list, &alertObservation{target: target, unknown: true})
+			continue
+		}
+
+		var v float64
+		if r.Kind == alertKindRouteErrors {
+			v = float64(d.serverErrors) / float64(d.count)
+		} else {
+			v = float64(d.spend) / float64(time.Millisecond) / float64(d.count)
+		}
+		if v > r.Threshold {
+			list = append(list, &alertObservation{target: target, value: v})
+		}
+	}
+	slices.SortFunc(list, func(a, b *alertObservation) int { return cmp.Compare(a.target, b.target) })
+	return list
+}
+
+func (r *AlertRule) observeStats(s *systat.Stats) []*alertObservation {
+	if s == nil {
+		return nil
+	}
+
+	var v float64
+	switch r.Kind {
+	case alertKindCPU:
+		v = s.OS.CPU
+	case alertKindMem:
+		v = float64(s.OS.Mem) / 1024 / 1024
+	case alertKindProcessCPU:
+		v = s.Process.CPU
+	case alertKindProcessMem:
+		v = float64(s.Process.Mem) / 1024 / 1024
+	}
+
+	if v > r.Threshold {
+		return []*alertObservation{{value: v}}
+	}
+	return nil
+}
+
+// 向所有在线的管理员推送告警信息，并触发 [Module.OnAlert] 注册的事件。
+func (a *alerts) publish(alert *Alert) {
+	for _, s := range a.m.admin.SSE().Sources() {
+		if err := s.NewEvent(alertSSEEvent, json.Marshal).Sent(alert); err != nil {
+			a.m.mod.Server().Logs().ERROR().Error(err)
+		}
+	}
+	a.event.Publish(true, alert)
+}
+
+// 返回所有未恢复的告警
+func (a *alerts) list() []*Alert {
+	a.mux.Lock()
+	list := make([]*Alert, 0, len(a.active))
+	for _, alert := range a.active {
+		v := *alert
+		list = append(list, &v)
+	}
+	a.mux.Unlock()
+
+	slices.SortFunc(list, func(a, b *Alert) int {
+		if c := cmp.Compare(a.Rule, b.Rule); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Target, b.Target)
+	})
+	return list
+}
+
+// 将规则 name 静默至 until，为零值表示取消静默。
+//
+// 静默期间依然会检测规则，但是不会发送通知。
+func (a *alerts) silence(name string, until time.Time) bool {
+	if !slices.ContainsFunc(a.rules, func(r *AlertRule) bool { return r.Name == name }) {
+		return false
+	}
+
+	a.mux.Lock()
+	defer a.mux.Unlock()
+	if until.IsZero() {
+		delete(a.silences, name)
+	} else {
+		a.silences[name] = until
+	}
+	return true
+}
+
+// OnAlert 注册告警状态变化的事件
+//
+// 仅在告警触发和恢复时调用，静默期间的告警不会触发此事件。
+// 如果未启用告警，则不执行任何操作。
+func (m *Module) OnAlert(f func(*Alert)) context.CancelFunc {
+	if m.alerts == nil {
+		return func() {}
+	}
+	return m.alerts.event.Subscribe(f)
+}
+
+// NotifyAlerts 将告警的状态变化以通知的形式发送给 n 中的所有用户
+//
+// typ 为通知的类型，即 [notice.Notices.Types] 中的 ID。
+func (m *Module) NotifyAlerts(n *notice.Notices, typ int64) context.CancelFunc {
+	p := m.mod.Server().Locale().Printer()
+	return m.OnAlert(func(alert *Alert) {
+		var title web.LocaleStringer
+		if alert.State == AlertFiring {
+			title = web.Phrase("alert %s is firing", alert.Rule)
+		} else {
+			title = web.Phrase("alert %s is resolved", alert.Rule)
+		}
+		content := web.Phrase("alert content %s %s %v %v %s", alert.Kind, alert.Target, alert.Value, alert.Threshold, alert.Message)
+
+		if err := n.Send(0, typ, m.mod.ID(), title.LocaleString(p), content.LocaleString(p)); err != nil {
+			m.mod.Server().Logs().ERROR().Error(err)
+		}
+	})
+}
@@ -0,0 +1,259 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package system
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/mux/v9/header"
+	"github.com/issue9/web"
+	"github.com/issue9/web/server/config"
+	"github.com/issue9/web/server/servertest"
+	"github.com/issue9/webuse/v7/middlewares/auth"
+	"github.com/issue9/webuse/v7/plugins/health"
+	"github.com/issue9/webuse/v7/services/systat"
+
+	"github.com/issue9/cmfx/cmfx/initial/test"
+	"github.com/issue9/cmfx/cmfx/modules/admin/admintest"
+)
+
+func TestAlerts_SanitizeConfig(t *testing.T) {
+	a := assert.New(t, false)
+
+	conf := &Alerts{}
+	a.Equal(conf.SanitizeConfig().Field, "rules")
+
+	conf = &Alerts{Rules: []*AlertRule{{Name: "r1", Kind: alertKindCPU, Threshold: 80}, {Name: "r1", Kind: alertKindBackup}}}
+	a.Equal(conf.SanitizeConfig().Field, "rules[1].name")
+
+	conf = &Alerts{Rules: []*AlertRule{{Name: "r1", Kind: "disk"}}}
+	a.Equal(conf.SanitizeConfig().Field, "rules[0].kind")
+
+	conf = &Alerts{Rules: []*AlertRule{{Name: "r1", Kind: alertKindRouteErrors, Threshold: 2}}}
+	a.Equal(conf.SanitizeConfig().Field, "rules[0].threshold")
+
+	conf = &Alerts{Rules: []*AlertRule{{Name: "r1", Kind: alertKindMem}}}
+	a.Equal(conf.SanitizeConfig().Field, "rules[0].threshold")
+
+	conf = &Alerts{Rules: []*AlertRule{{Name: "r1", Kind: alertKindService, For: -1}}}
+	a.Equal(conf.SanitizeConfig().Field, "rules[0].for")
+
+	conf = &Alerts{Rules: []*AlertRule{{Name: "r1", Kind: alertKindRouteLatency, Threshold: 10, MinRequests: -1}}}
+	a.Equal(conf.SanitizeConfig().Field, "rules[0].minRequests")
+
+	conf = &Alerts{Notice: -1, Rules: []*AlertRule{{Name: "r1", Kind: alertKindService}}}
+	a.Equal(conf.SanitizeConfig().Field, "notice")
+
+	conf = &Alerts{Rules: []*AlertRule{{Name: "r1", Kind: alertKindService}, {Name: "r2", Kind: alertKindRouteErrors, Threshold: .5}}}
+	a.NotError(conf.SanitizeConfig()).
+		Equal(conf.Interval, config.Duration(time.Minute)).
+		Equal(conf.Rules[0].MinRequests, 0).
+		Equal(conf.Rules[1].MinRequests, 10)
+}
+
+func TestAlertRule_observe(t *testing.T) {
+	a := assert.New(t, false)
+
+	stats := &systat.Stats{
+		OS:      &systat.OS{CPU: 90, Mem: 2048 * 1024 * 1024},
+		Process: &systat.Process{CPU: 10, Mem: 100 * 1024 * 1024},
+	}
+	r := &AlertRule{Kind: alertKindCPU, Threshold: 80}
+	a.Length(r.observeStats(stats), 1).Nil(r.observeStats(nil))
+	r = &AlertRule{Kind: alertKindProcessCPU, Threshold: 80}
+	a.Empty(r.observeStats(stats))
+	r = &AlertRule{Kind: alertKindMem, Threshold: 1024}
+	a.Equal(r.observeStats(stats)[0].value, 2048.0)
+	r = &AlertRule{Kind: alertKindProcessMem, Threshold: 1024}
+	a.Empty(r.observeStats(stats))
+
+	al := &alerts{}
+	s1 := &health.State{Router: "def", Method: http.MethodGet, Pattern: "/p1", Count: 10, ServerErrors: 1, Spend: time.Second}
+	s2 := &health.State{Router: "def", Method: http.MethodGet, Pattern: "/p2", Count: 10, Spend: time.Second}
+	a.Empty(al.routeDeltas([]*health.State{s1, s2})) // 第一次没有可比较的数据
+
+	s1 = &health.State{Router: "def", Method: http.MethodGet, Pattern: "/p1", Count: 20, ServerErrors: 7, Spend: 3 * time.Second}
+	s2 = &health.State{Router: "def", Method: http.MethodGet, Pattern: "/p2", Count: 20, ServerErrors: 1, Spend: 2 * time.Second}
+	deltas := al.routeDeltas([]*health.State{s1, s2})
+	a.Length(deltas, 2)
+
+	r = &AlertRule{Kind: alertKindRouteErrors, Threshold: .5}
+	list := r.observeRoutes(deltas)
+	a.Length(list, 1).Equal(list[0].target, "GET /p1").Equal(list[0].value, .6)
+
+	r = &AlertRule{Kind: alertKindRouteLatency, Threshold: 100}
+	list = r.observeRoutes(deltas)
+	a.Length(list, 1).Equal(list[0].target, "GET /p1").Equal(list[0].value, 200.0)
+
+	r = &AlertRule{Kind: alertKindRouteLatency, Threshold: 50, Pattern: "/p2"}
+	list = r.observeRoutes(deltas)
+	a.Length(list, 1).Equal(list[0].target, "GET /p2")
+
+	// 请求数量不足
+	r = &AlertRule{Kind: alertKindRouteErrors, Threshold: .5, MinRequests: 11}
+	list = r.observeRoutes(deltas)
+	a.Length(list, 2).True(list[0].unknown).True(list[1].unknown)
+
+	// 两次检测之间没有请求
+	deltas = al.routeDeltas([]*health.State{s1, s2})
+	a.Length(deltas, 2).Equal(deltas[s1].count, 0)
+	r = &AlertRule{Kind: alertKindRouteErrors, Threshold: .5}
+	list = r.observeRoutes(deltas)
+	a.Length(list, 2).True(list[0].unknown).True(list[1].unknown)
+
+	// 统计数据被重置
+	s1 = &health.State{Router: "def", Method: http.MethodGet, Pattern: "/p1", Count: 5, ServerErrors: 5, Spend: time.Second}
+	deltas = al.routeDeltas([]*health.State{s1})
+	a.Length(deltas, 1).Equal(deltas[s1].count, 5).Equal(deltas[s1].serverErrors, 5)
+}
+
+func TestModule_routeAlerts(t *testing.T) {
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+
+	conf := &Config{Alerts: &Alerts{Rules: []*AlertRule{
+		{Name: "errors", Kind: alertKindRouteErrors, Threshold: .5, MinRequests: 2, Pattern: "/alert-errors"},
+	}}}
+	a.NotError(conf.SanitizeConfig())
+	l := Install(s.NewModule("test"), conf, admintest.NewModule(s))
+	s.Router().Get("/alert-errors", func(*web.Context) web.Responser { return web.Status(http.StatusInternalServerError) })
+
+	events := make(chan *Alert, 10)
+	l.OnAlert(func(alert *Alert) { events <- alert })
+	next := func() *Alert {
+		select {
+		case alert := <-events:
+			return alert
+		case <-time.After(500 * time.Millisecond):
+			return nil
+		}
+	}
+
+	defer servertest.Run(a, s.Module().Server())()
+	defer s.Close()
+
+	request := func() {
+		s.Get("/alert-errors").Do(nil).Status(http.StatusInternalServerError)
+	}
+
+	request()
+	now := time.Now()
+	a.NotError(l.alerts.evaluate(now)) // 第一次检测，没有可比较的数据。
+
+	// 单个请求的失败不触发告警
+	request()
+	a.NotError(l.alerts.evaluate(now.Add(time.Minute)))
+	a.Nil(next()).Empty(l.alerts.list())
+
+	request()
+	request()
+	a.NotError(l.alerts.evaluate(now.Add(2 * time.Minute)))
+	alert := next()
+	a.NotNil(alert).Equal(alert.State, AlertFiring).Equal(alert.Target, "GET /alert-errors")
+
+	// 没有新的请求，保持告警状态。
+	a.NotError(l.alerts.evaluate(now.Add(3 * time.Minute)))
+	a.Nil(next()).Length(l.alerts.list(), 1)
+}
+
+func TestModule_alerts(t *testing.T) {
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+
+	conf := &Config{Alerts: &Alerts{Rules: []*AlertRule{
+		{Name: "backup", Kind: alertKindBackup},
+		{Name: "backup-for", Kind: alertKindBackup, For: config.Duration(2 * time.Minute)},
+	}}}
+	a.NotError(conf.SanitizeConfig())
+	l := Install(s.NewModule("test"), conf, admintest.NewModule(s))
+
+	events := make(chan *Alert, 10)
+	l.OnAlert(func(alert *Alert) { events <- alert })
+	next := func() *Alert { // 事件是异步触发的
+		select {
+		case alert := <-events:
+			return alert
+		case <-time.After(time.Second):
+			return nil
+		}
+	}
+
+	now := time.Now()
+	a.NotError(l.alerts.evaluate(now))
+
+	l.alerts.setBackupError(errors.New("backup error"))
+	a.NotError(l.alerts.evaluate(now))
+	alert := next()
+	a.NotNil(alert).
+		Equal(alert.Rule, "backup").
+		Equal(alert.State, AlertFiring).
+		Equal(alert.Message, "backup error")
+	list := l.alerts.list()
+	a.Length(list, 2).
+		Equal(list[0].State, AlertFiring).
+		Equal(list[1].State, AlertPending)
+
+	// 状态未变化，不会重复发送。
+	a.NotError(l.alerts.evaluate(now.Add(time.Minute)))
+
+	a.NotError(l.alerts.evaluate(now.Add(2 * time.Minute)))
+	alert = next()
+	a.NotNil(alert).
+		Equal(alert.Rule, "backup-for").
+		Equal(alert.State, AlertFiring)
+
+	// 静默期间恢复，不发送通知。
+	a.True(l.alerts.silence("backup", now.Add(time.Hour))).
+		False(l.alerts.silence("not-exists", now.Add(time.Hour)))
+	l.alerts.setBackupError(nil)
+	a.NotError(l.alerts.evaluate(now.Add(3 * time.Minute)))
+	alert = next()
+	a.NotNil(alert).
+		Equal(alert.Rule, "backup-for").
+		Equal(alert.State, AlertResolved).
+		Empty(l.alerts.list()).
+		Nil(next())
+
+	defer servertest.Run(a, s.Module().Server())()
+	defer s.Close()
+
+	token := admintest.GetToken(s, l.admin)
+
+	s.Get("/admin/system/alerts").
+		Header(header.Accept, header.JSON).
+		Header(header.Authorization, auth.BuildToken(auth.Bearer, token)).
+		Do(nil).
+		Status(http.StatusOK).
+		BodyFunc(func(a *assert.Assertion, body []byte) {
+			list := make([]*Alert, 0, 2)
+			a.NotError(json.Unmarshal(body, &list)).Empty(list)
+		})
+
+	s.Post("/admin/system/alerts/backup-for/silence", []byte(`{"until":"`+now.Add(time.Hour).Format(time.RFC3339)+`"}`)).
+		Header(header.ContentType, header.JSON).
+		Header(header.Authorization, auth.BuildToken(auth.Bearer, token)).
+		Do(nil).
+		Status(http.StatusNoContent)
+	s.Post("/admin/system/alerts/backup-for/silence", []byte(`{}`)).
+		Header(header.ContentType, header.JSON).
+		Header(header.Authorization, auth.BuildToken(auth.Bearer, token)).
+		Do(nil).
+		Status(http.StatusBadRequest)
+	s.Post("/admin/system/alerts/not-exists/silence", []byte(`{"until":"`+now.Add(time.Hour).Format(time.RFC3339)+`"}`)).
+		Header(header.ContentType, header.JSON).
+		Header(header.Authorization, auth.BuildToken(auth.Bearer, token)).
+		Do(nil).
+		Status(http.StatusNotFound)
+
+	s.Delete("/admin/system/alerts/backup-for/silence").
+		Header(header.Authorization, auth.BuildToken(auth.Bearer, token)).
+		Do(nil).
+		Status(http.StatusNoContent)
+}
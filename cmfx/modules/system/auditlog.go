@@ -0,0 +1,214 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package system
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/issue9/mux/v9/header"
+	"github.com/issue9/orm/v6"
+	"github.com/issue9/orm/v6/sqlbuilder"
+	"github.com/issue9/web"
+
+	"github.com/issue9/cmfx/cmfx"
+	"github.com/issue9/cmfx/cmfx/filters"
+	"github.com/issue9/cmfx/cmfx/modules/admin"
+	"github.com/issue9/cmfx/cmfx/query"
+)
+
+// 将管理员的操作写入审计日志
+func (m *Module) addAuditLog(op *admin.Operation) {
+	po := &auditLogPO{
+		UID:       op.UID,
+		Resource:  op.Resource,
+		Method:    op.Method,
+		Path:      op.Path,
+		Body:      string(op.Body),
+		Status:    op.Status,
+		IP:        op.IP,
+		UserAgent: op.UserAgent,
+		Created:   op.Created,
+	}
+	if len(op.Targets) > 0 {
+		data, err := json.Marshal(op.Targets)
+		if err != nil {
+			m.mod.Server().Logs().ERROR().Error(err)
+			return
+		}
+		po.Targets = string(data)
+	}
+
+	if _, err := m.mod.DB().Insert(po); err != nil {
+		m.mod.Server().Logs().ERROR().Error(err)
+	}
+}
+
+// 删除超过保留时间的审计日志
+func (m *Module) pruneAuditLogs(now time.Time) error {
+	_, err := m.mod.DB().Where("created<?", now.Add(-m.auditLogs.Retention.Duration())).Delete(&auditLogPO{})
+	return err
+}
+
+type auditLogQuery struct {
+	query.Text
+	UID          int64     `query:"uid"`           // 操作者
+	Resource     string    `query:"resource"`      // RBAC 资源 ID
+	Method       string    `query:"method"`        // 请求方法
+	Status       int       `query:"status"`        // 响应的状态码
+	CreatedStart time.Time `query:"created.start"` // 起始时间
+	CreatedEnd   time.Time `query:"created.end"`   // 结束时间
+}
+
+type auditLogVO struct {
+	ID        int64             `json:"id" yaml:"id" cbor:"id"`
+	UID       int64             `json:"uid" yaml:"uid" cbor:"uid"`                                              // 操作者
+	Resource  string            `json:"resource,omitempty" yaml:"resource,omitempty" cbor:"resource,omitempty"` // RBAC 资源 ID
+	Method    string            `json:"method" yaml:"method" cbor:"method"`                                     // 请求方法
+	Path      string            `json:"path" yaml:"path" cbor:"path"`                                           // 请求地址
+	Targets   map[string]string `json:"targets,omitempty" yaml:"targets,omitempty" cbor:"targets,omitempty"`    // 地址中的参数
+	Body      string            `json:"body,omitempty" yaml:"body,omitempty" cbor:"body,omitempty"`             // 已脱敏的提交内容，不包含修改之前的数据。
+	Status    int               `json:"status" yaml:"status" cbor:"status"`                                     // 响应的状态码
+	IP        string            `json:"ip" yaml:"ip" cbor:"ip"`
+	UserAgent string            `json:"ua" yaml:"ua" cbor:"ua"`
+	Created   time.Time         `json:"created" yaml:"created" cbor:"created"`
+}
+
+func newAuditLogVO(po *auditLogPO) *auditLogVO {
+	vo := &auditLogVO{
+		ID:        po.ID,
+		UID:       po.UID,
+		Resource:  po.Resource,
+		Method:    po.Method,
+		Path:      po.Path,
+		Body:      po.Body,
+		Status:    po.Status,
+		IP:        po.IP,
+		UserAgent: po.UserAgent,
+		Created:   po.Created,
+	}
+	if po.Targets != "" {
+		_ = json.Unmarshal([]byte(po.Targets), &vo.Targets) // 由 addAuditLog 写入，不会出错。
+	}
+	return vo
+}
+
+func (m *Module) auditLogsSQL(q *auditLogQuery) *sqlbuilder.SelectStmt {
+	sql := m.mod.DB().SQLBuilder().Select().Columns("*").From(orm.TableName(&auditLogPO{})).Desc("created").Desc("id")
+	if q.Text.Text != "" {
+		txt := "%" + q.Text.Text + "%"
+		sql.AndGroup(func(ws *sqlbuilder.WhereStmt) {
+			ws.Or("{path} LIKE ?", txt).Or("{body} LIKE ?", txt).Or("{ip} LIKE ?", txt)
+		})
+	}
+	if q.UID > 0 {
+		sql.And("uid=?", q.UID)
+	}
+	if q.Resource != "" {
+		sql.And("resource=?", q.Resource)
+	}
+	if q.Method != "" {
+		sql.And("method=?", q.Method)
+	}
+	if q.Status > 0 {
+		sql.And("status=?", q.Status)
+	}
+	if !q.CreatedStart.IsZero() {
+		sql.And("created>=?", q.CreatedStart)
+	}
+	if !q.CreatedEnd.IsZero() {
+		sql.And("created<?", q.CreatedEnd)
+	}
+	return sql
+}
+
+func (m *Module) adminGetAuditLogs(ctx *web.Context) web.Responser {
+	q := &auditLogQuery{}
+	if resp := ctx.QueryObject(true, q, cmfx.BadRequestInvalidQuery); resp != nil {
+		return resp
+	}
+
+	return query.PagingResponserWithConvert(ctx, &q.Limit, m.auditLogsSQL(q), newAuditLogVO)
+}
+
+// 以 CSV 格式导出审计日志，查询参数中的分页参数会被忽略。
+func (m *Module) adminGetAuditLogsCSV(ctx *web.Context) web.Responser {
+	q := &auditLogQuery{}
+	if resp := ctx.QueryObject(true, q, cmfx.BadRequestInvalidQuery); resp != nil {
+		return resp
+	}
+
+	list := make([]*auditLogPO, 0, 100)
+	if _, err := m.auditLogsSQL(q).QueryObject(true, &list); err != nil {
+		return ctx.Error(err, "")
+	}
+
+	return web.ResponserFunc(func(ctx *web.Context) {
+		ctx.Header().Set(header.ContentType, "text/csv; charset=utf-8")
+		ctx.Header().Set(header.ContentDisposition, `attachment; filename="audit-logs.csv"`)
+		if err := writeAuditLogsCSV(ctx, list); err != nil {
+			ctx.Logs().ERROR().Error(err)
+		}
+	})
+}
+
+func writeAuditLogsCSV(w io.Writer, list []*auditLogPO) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"id", "created", "uid", "resource", "method", "path", "targets", "body", "status", "ip", "ua"}); err != nil {
+		return err
+	}
+	for _, l := range list {
+		err := cw.Write([]string{
+			strconv.FormatInt(l.ID, 10),
+			l.Created.Format(time.RFC3339),
+			strconv.FormatInt(l.UID, 10),
+			l.Resource,
+			l.Method,
+			l.Path,
+			l.Targets,
+			l.Body,
+			strconv.Itoa(l.Status),
+			l.IP,
+			l.UserAgent,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+type auditLogRetentionVO struct {
+	Retention string `json:"retention" yaml:"retention" cbor:"retention"` // 日志的保留时间
+}
+
+func (m *Module) adminGetAuditLogsRetention(*web.Context) web.Responser {
+	return web.OK(&auditLogRetentionVO{Retention: m.auditLogs.Retention.Duration().String()})
+}
+
+type pruneAuditLogsQuery struct {
+	Before time.Time `query:"before"` // 删除此时间之前的日志
+}
+
+func (q *pruneAuditLogsQuery) Filter(ctx *web.FilterContext) {
+	ctx.Add(filters.NotZero[time.Time]()("before", &q.Before))
+}
+
+// 手动删除指定时间之前的审计日志
+func (m *Module) adminDeleteAuditLogs(ctx *web.Context) web.Responser {
+	q := &pruneAuditLogsQuery{}
+	if resp := ctx.QueryObject(true, q, cmfx.BadRequestInvalidQuery); resp != nil {
+		return resp
+	}
+
+	if _, err := m.mod.DB().Where("created<?", q.Before).Delete(&auditLogPO{}); err != nil {
+		return ctx.Error(err, "")
+	}
+	return web.NoContent()
+}
@@ -0,0 +1,119 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package system
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/mux/v9/header"
+	"github.com/issue9/web/server/servertest"
+	"github.com/issue9/webuse/v7/middlewares/auth"
+
+	"github.com/issue9/cmfx/cmfx/initial/test"
+	"github.com/issue9/cmfx/cmfx/modules/admin/admintest"
+	"github.com/issue9/cmfx/cmfx/query"
+)
+
+func TestModule_auditLogs(t *testing.T) {
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+	l := newModule(s)
+
+	defer servertest.Run(a, s.Module().Server())()
+	defer s.Close()
+
+	token := admintest.GetToken(s, l.admin)
+
+	s.Put("/admin/system/settings/general", []byte(`{"name":"n","shortName":"s","description":"d","logo":"https://example.com/logo.png","password":"123"}`)).
+		Header(header.ContentType, header.JSON).
+		Header(header.Authorization, auth.BuildToken(auth.Bearer, token)).
+		Do(nil).
+		Status(http.StatusNoContent)
+	s.Post("/admin/admins/2/locked", nil).
+		Header(header.Authorization, auth.BuildToken(auth.Bearer, token)).
+		Do(nil).
+		Status(http.StatusCreated)
+
+	getLogs := func(q string) *query.Page[auditLogVO] {
+		p := &query.Page[auditLogVO]{}
+		s.Get("/admin/system/audit-logs"+q).
+			Header(header.Accept, header.JSON).
+			Header(header.Authorization, auth.BuildToken(auth.Bearer, token)).
+			Do(nil).
+			Status(http.StatusOK).
+			BodyFunc(func(a *assert.Assertion, body []byte) { a.NotError(json.Unmarshal(body, p)) })
+		return p
+	}
+
+	p := getLogs("")
+	a.Equal(p.Count, 2).Length(p.Current, 2)
+	lock := p.Current[0]
+	a.Equal(lock.Method, http.MethodPost).
+		Equal(lock.Path, "/admin/admins/2/locked").
+		Equal(lock.Resource, "admin_put-admin").
+		Equal(lock.Targets, map[string]string{"id": "2"}).
+		Equal(lock.Status, http.StatusCreated).
+		Equal(lock.UID, 1).
+		Empty(lock.Body)
+	settings := p.Current[1]
+	a.Equal(settings.Method, http.MethodPut).
+		Equal(settings.Resource, "test_setting-general").
+		Empty(settings.Targets).
+		Equal(settings.Status, http.StatusNoContent).
+		Equal(settings.Body, `{"description":"d","logo":"https://example.com/logo.png","name":"n","password":"******","shortName":"s"}`)
+
+	p = getLogs("?method=PUT")
+	a.Equal(p.Count, 1).Equal(p.Current[0].ID, settings.ID)
+	p = getLogs("?resource=admin_put-admin&status=201")
+	a.Equal(p.Count, 1).Equal(p.Current[0].ID, lock.ID)
+	p = getLogs("?text=shortName")
+	a.Equal(p.Count, 1).Equal(p.Current[0].ID, settings.ID)
+	p = getLogs("?text=admin&method=POST") // text 与其它条件同时生效
+	a.Equal(p.Count, 1).Equal(p.Current[0].ID, lock.ID)
+
+	s.Get("/admin/system/audit-logs/csv?method=PUT").
+		Header(header.Authorization, auth.BuildToken(auth.Bearer, token)).
+		Do(nil).
+		Status(http.StatusOK).
+		Header(header.ContentType, "text/csv; charset=utf-8").
+		BodyFunc(func(a *assert.Assertion, body []byte) {
+			lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+			a.Length(lines, 2).
+				True(strings.HasPrefix(lines[0], "id,created,uid,resource")).
+				True(strings.Contains(lines[1], "test_setting-general"))
+		})
+
+	s.Get("/admin/system/audit-logs/retention").
+		Header(header.Accept, header.JSON).
+		Header(header.Authorization, auth.BuildToken(auth.Bearer, token)).
+		Do(nil).
+		Status(http.StatusOK).
+		StringBody(`{"retention":"4320h0m0s"}`)
+
+	s.Delete("/admin/system/audit-logs").
+		Header(header.Authorization, auth.BuildToken(auth.Bearer, token)).
+		Do(nil).
+		Status(http.StatusBadRequest)
+	s.Delete("/admin/system/audit-logs?before="+url.QueryEscape(time.Now().Add(time.Hour).Format(time.RFC3339))).
+		Header(header.Authorization, auth.BuildToken(auth.Bearer, token)).
+		Do(nil).
+		Status(http.StatusNoContent)
+
+	// 删除操作本身也会被记录
+	p = getLogs("")
+	a.Equal(p.Count, 1).
+		Equal(p.Current[0].Method, http.MethodDelete).
+		Equal(p.Current[0].Status, http.StatusNoContent)
+
+	a.NotError(l.pruneAuditLogs(time.Now().Add(l.auditLogs.Retention.Duration() + time.Hour)))
+	cnt, err := l.mod.DB().Where("true").Count(&auditLogPO{})
+	a.NotError(err).Equal(cnt, 0)
+}
@@ -0,0 +1,468 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package system
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/issue9/orm/v6"
+	"github.com/issue9/web"
+	"github.com/klauspost/compress/zstd"
+)
+
+// 支持的压缩算法
+const (
+	compressGzip = "gzip"
+	compressZstd = "zstd"
+)
+
+// 备份文件的附属文件的扩展名
+const (
+	manifestExt = ".manifest.json"
+	uploadsExt  = ".uploads.tar"
+)
+
+// 恢复之前自动生成的备份文件的前缀
+//
+// 此类文件名无法按 [Backup.Format] 解析，所以不受 [Retention] 的影响。
+const safetyPrefix = "pre-restore-"
+
+var (
+	errChecksumMismatch    = web.NewLocaleError("backup checksum mismatch")
+	errRestoreNotSupported = web.NewLocaleError("restore is not supported")
+	errRestoreOffline      = web.NewLocaleError("restore must be run from the command line with the server stopped")
+)
+
+// 备份文件的清单，记录了每个文件的校验和。
+type manifest struct {
+	Created  time.Time       `json:"created"`
+	Compress string          `json:"compress,omitempty"`
+	Files    []*manifestFile `json:"files"`
+}
+
+type manifestFile struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+func (b *Backup) compressExt() string {
+	switch b.Compress {
+	case compressGzip:
+		return ".gz"
+	case compressZstd:
+		return ".zst"
+	default:
+		return ""
+	}
+}
+
+func (b *Backup) compress(w io.Writer) (io.WriteCloser, error) {
+	switch b.Compress {
+	case compressGzip:
+		return gzip.NewWriter(w), nil
+	case compressZstd:
+		return zstd.NewWriter(w)
+	default:
+		return nopWriteCloser{w}, nil
+	}
+}
+
+// 返回文件名中表示压缩算法的扩展名
+func compressExt(name string) string {
+	switch ext := filepath.Ext(name); ext {
+	case ".gz", ".zst":
+		return ext
+	default:
+		return ""
+	}
+}
+
+// 根据文件名的扩展名对 r 进行解压
+func decompress(name string, r io.Reader) (io.ReadCloser, error) {
+	switch compressExt(name) {
+	case ".gz":
+		return gzip.NewReader(r)
+	case ".zst":
+		d, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	default:
+		return io.NopCloser(r), nil
+	}
+}
+
+// 是否为数据库的备份文件，而不是附属文件或是临时文件。
+func isBackupFile(name string) bool {
+	return name != "" && name[0] != '.' &&
+		!strings.HasSuffix(name, manifestExt) &&
+		!strings.Contains(name, uploadsExt) &&
+		!strings.ContainsAny(name, "/"+string(os.PathSeparator))
+}
+
+// 备份数据库以及上传文件
+//
+// p 为不包含压缩扩展名的数据库备份文件路径，返回实际的数据库备份文件路径。
+func (b *Backup) backup(db *orm.DB, p string) (string, error) {
+	dir, name := filepath.Split(p)
+	tmp := filepath.Join(dir, "."+name+".tmp")
+	if err := db.Backup(tmp); err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp)
+
+	m := &manifest{Created: time.Now(), Compress: b.Compress}
+
+	dest := p + b.compressExt()
+	f, err := b.writeFile(dest, func(w io.Writer) error {
+		src, err := os.Open(tmp)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+
+		_, err = io.Copy(w, src)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	m.Files = append(m.Files, f)
+
+	if b.Uploads != "" {
+		f, err := b.writeFile(p+uploadsExt+b.compressExt(), func(w io.Writer) error {
+			tw := tar.NewWriter(w)
+			if err := tw.AddFS(os.DirFS(b.Uploads)); err != nil {
+				return err
+			}
+			return tw.Close()
+		})
+		if err != nil {
+			return "", err
+		}
+		m.Files = append(m.Files, f)
+	}
+
+	data, err := json.MarshalIndent(m, "", "\t")
+	if err != nil {
+		return "", err
+	}
+	return dest, os.WriteFile(p+manifestExt, data, 0o644)
+}
+
+// 创建文件 p 并将 write 写入的内容按配置压缩后保存
+func (b *Backup) writeFile(p string, write func(io.Writer) error) (mf *manifestFile, err error) {
+	f, err := os.Create(p)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err = errors.Join(err, f.Close()); err != nil {
+			mf = nil
+		}
+	}()
+
+	h := sha256.New()
+	w, err := b.compress(io.MultiWriter(f, h))
+	if err != nil {
+		return nil, err
+	}
+	if err = write(w); err != nil {
+		return nil, errors.Join(err, w.Close())
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return &manifestFile{Name: filepath.Base(p), Size: info.Size(), SHA256: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+// 根据清单验证备份文件 name 的完整性
+//
+// 没有清单的备份文件由旧版本生成，不作验证。
+func (b *Backup) verify(name string) error {
+	data, err := os.ReadFile(filepath.Join(b.Dir, strings.TrimSuffix(name, compressExt(name))+manifestExt))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	m := &manifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return err
+	}
+
+	for _, f := range m.Files {
+		file, err := os.Open(filepath.Join(b.Dir, f.Name))
+		if err != nil {
+			return err
+		}
+
+		h := sha256.New()
+		size, err := io.Copy(h, file)
+		file.Close()
+		if err != nil {
+			return err
+		}
+		if size != f.Size || hex.EncodeToString(h.Sum(nil)) != f.SHA256 {
+			return errChecksumMismatch
+		}
+	}
+	return nil
+}
+
+// 从备份文件 name 恢复数据库和上传文件
+//
+// 在恢复之前会先对当前的数据进行备份，返回该备份文件的路径。
+// 上传文件的恢复只会覆盖同名的文件，备份之后新增的文件会被保留。
+func (b *Backup) restore(db *orm.DB, name string, now time.Time) (string, error) {
+	if b.Restore == nil {
+		return "", errRestoreNotSupported
+	}
+
+	if !isBackupFile(name) {
+		return "", fs.ErrNotExist
+	}
+	p := filepath.Join(b.Dir, name)
+	if _, err := os.Stat(p); err != nil {
+		return "", err
+	}
+	if err := b.verify(name); err != nil {
+		return "", err
+	}
+
+	safety, err := b.backup(db, filepath.Join(b.Dir, safetyPrefix+now.Format(b.Format)))
+	if err != nil {
+		return "", err
+	}
+
+	tmp := filepath.Join(b.Dir, "."+name+".restore")
+	err = readFile(p, func(r io.Reader) error {
+		f, err := os.Create(tmp)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(f, r)
+		return errors.Join(err, f.Close())
+	})
+	defer os.Remove(tmp)
+	if err != nil {
+		return safety, err
+	}
+	if err := b.Restore(tmp); err != nil {
+		return safety, err
+	}
+
+	if b.Uploads == "" {
+		return safety, nil
+	}
+	uploads := filepath.Join(b.Dir, strings.TrimSuffix(name, compressExt(name))+uploadsExt+compressExt(name))
+	if _, err := os.Stat(uploads); errors.Is(err, fs.ErrNotExist) {
+		return safety, nil
+	}
+	return safety, readFile(uploads, func(r io.Reader) error { return extract(r, b.Uploads) })
+}
+
+// 打开文件 p 并将解压后的内容交由 read 处理
+func readFile(p string, read func(io.Reader) error) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r, err := decompress(p, f)
+	if err != nil {
+		return err
+	}
+	return errors.Join(read(r), r.Close())
+}
+
+// 将 tar 格式的 r 解压至 dir
+func extract(r io.Reader, dir string) error {
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := root.MkdirAll(hdr.Name, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if d := path.Dir(hdr.Name); d != "." {
+				if err := root.MkdirAll(d, 0o755); err != nil {
+					return err
+				}
+			}
+
+			f, err := root.OpenFile(hdr.Name, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, tr)
+			if err = errors.Join(err, f.Close()); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// 列出所有的备份文件
+func (b *Backup) list() ([]*backupFileVO, error) {
+	entries, err := os.ReadDir(b.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]struct{}, len(entries))
+	for _, e := range entries {
+		names[e.Name()] = struct{}{}
+	}
+
+	list := make([]*backupFileVO, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || !isBackupFile(e.Name()) {
+			continue
+		}
+
+		info, err := e.Info()
+		if errors.Is(err, fs.ErrNotExist) { // 可能已经被删除
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		vo := &backupFileVO{Path: e.Name(), Mod: info.ModTime(), Size: int(info.Size())}
+		base := strings.TrimSuffix(vo.Path, compressExt(vo.Path))
+		if _, found := names[base+manifestExt]; found {
+			vo.Manifest = base + manifestExt
+		}
+		if u := base + uploadsExt + compressExt(vo.Path); u != vo.Path {
+			if _, found := names[u]; found {
+				vo.Uploads = u
+			}
+		}
+		list = append(list, vo)
+	}
+
+	return list, nil
+}
+
+// 删除备份文件 name 及其附属文件
+func (b *Backup) remove(name string) error {
+	if !isBackupFile(name) {
+		return fs.ErrNotExist
+	}
+
+	if err := os.Remove(filepath.Join(b.Dir, name)); err != nil {
+		return err
+	}
+
+	base := strings.TrimSuffix(name, compressExt(name))
+	for _, f := range []string{base + manifestExt, base + uploadsExt + compressExt(name)} {
+		if err := os.Remove(filepath.Join(b.Dir, f)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// 按照 [Backup.Retention] 删除多余的备份文件
+func (b *Backup) prune() error {
+	r := b.Retention
+	if r == nil || (r.Daily == 0 && r.Weekly == 0 && r.Monthly == 0) {
+		return nil
+	}
+
+	list, err := b.list()
+	if err != nil {
+		return err
+	}
+
+	type item struct {
+		name string
+		t    time.Time
+	}
+	items := make([]item, 0, len(list))
+	for _, f := range list {
+		t, err := time.ParseInLocation(b.Format, strings.TrimSuffix(f.Path, compressExt(f.Path)), time.Local)
+		if err != nil { // 非自动生成的备份文件
+			continue
+		}
+		items = append(items, item{name: f.Path, t: t})
+	}
+	slices.SortFunc(items, func(a, b item) int { return b.t.Compare(a.t) })
+
+	keep := make(map[string]struct{}, len(items))
+	bucket := func(n int, key func(time.Time) string) {
+		seen := make(map[string]struct{}, n)
+		for _, i := range items {
+			if len(seen) >= n {
+				return
+			}
+			if k := key(i.t); !contains(seen, k) {
+				seen[k] = struct{}{}
+				keep[i.name] = struct{}{}
+			}
+		}
+	}
+	bucket(r.Daily, func(t time.Time) string { return t.Format(time.DateOnly) })
+	bucket(r.Weekly, func(t time.Time) string {
+		y, w := t.ISOWeek()
+		return fmt.Sprintf("%d-%d", y, w)
+	})
+	bucket(r.Monthly, func(t time.Time) string { return t.Format("2006-01") })
+
+	for _, i := range items {
+		if !contains(keep, i.name) {
+			if err := b.remove(i.name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func contains(m map[string]struct{}, k string) bool {
+	_, found := m[k]
+	return found
+}
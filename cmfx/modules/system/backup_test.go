@@ -0,0 +1,240 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package system
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/mux/v9/header"
+	"github.com/issue9/web/server/servertest"
+	"github.com/issue9/webuse/v7/middlewares/auth"
+
+	"github.com/issue9/cmfx/cmfx/initial/test"
+	"github.com/issue9/cmfx/cmfx/modules/admin/admintest"
+)
+
+// 生成用于测试的备份配置，restored 用于接收恢复的数据库内容。
+func newBackup(a *assert.Assertion, compress string, restored *[]byte) *Backup {
+	a.NotError(os.MkdirAll("./testdata/backup", os.ModePerm))
+	a.NotError(os.MkdirAll("./testdata/uploads/sub", os.ModePerm))
+	a.NotError(os.WriteFile("./testdata/uploads/1.txt", []byte("1"), os.ModePerm))
+	a.NotError(os.WriteFile("./testdata/uploads/sub/2.txt", []byte("2"), os.ModePerm))
+
+	b := &Backup{
+		Dir:      "./testdata/backup",
+		Format:   "20060102-150405.db",
+		Cron:     "@daily",
+		Compress: compress,
+		Uploads:  "./testdata/uploads",
+		Restore: func(src string) (err error) {
+			*restored, err = os.ReadFile(src)
+			return err
+		},
+	}
+	a.NotError(b.SanitizeConfig())
+	return b
+}
+
+func TestBackup_SanitizeConfig(t *testing.T) {
+	a := assert.New(t, false)
+
+	b := &Backup{Dir: "./", Cron: "@daily", Compress: "xz"}
+	a.Equal(b.SanitizeConfig().Field, "compress")
+
+	b = &Backup{Dir: "./", Cron: "@daily", Uploads: "./not-exists"}
+	a.Equal(b.SanitizeConfig().Field, "uploads")
+
+	b = &Backup{Dir: "./", Cron: "@daily", Retention: &Retention{Weekly: -1}}
+	a.Equal(b.SanitizeConfig().Field, "retention.weekly")
+
+	b = &Backup{Dir: "./", Cron: "@daily", Compress: compressZstd, Retention: &Retention{Daily: 7}}
+	a.NotError(b.SanitizeConfig())
+}
+
+func TestBackup_restore(t *testing.T) {
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+	defer s.Close()
+	defer os.RemoveAll("./testdata")
+
+	for _, compress := range []string{"", compressGzip, compressZstd} {
+		a.NotError(os.RemoveAll("./testdata"))
+
+		var restored []byte
+		b := newBackup(a, compress, &restored)
+		now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.Local)
+
+		p, err := b.backup(s.Module().DB(), b.buildFile(now))
+		a.NotError(err).Equal(filepath.Base(p), "20260102-030405.db"+b.compressExt())
+
+		list, err := b.list()
+		a.NotError(err).Length(list, 1)
+		name := list[0].Path
+		a.Equal(list[0].Manifest, "20260102-030405.db"+manifestExt).
+			Equal(list[0].Uploads, "20260102-030405.db"+uploadsExt+b.compressExt())
+		a.NotError(b.verify(name))
+
+		// 修改上传的文件，恢复之后应该还原。
+		a.NotError(os.WriteFile("./testdata/uploads/1.txt", []byte("changed"), os.ModePerm))
+		a.NotError(os.Remove("./testdata/uploads/sub/2.txt"))
+
+		safety, err := b.restore(s.Module().DB(), name, now.Add(time.Hour))
+		a.NotError(err).Equal(filepath.Base(safety), safetyPrefix+"20260102-040405.db"+b.compressExt())
+		db, err := os.ReadFile("test.db")
+		a.NotError(err).Equal(restored, db)
+		data, err := os.ReadFile("./testdata/uploads/1.txt")
+		a.NotError(err).Equal(data, []byte("1"))
+		data, err = os.ReadFile("./testdata/uploads/sub/2.txt")
+		a.NotError(err).Equal(data, []byte("2"))
+
+		list, err = b.list()
+		a.NotError(err).Length(list, 2)
+
+		// 文件被篡改
+		a.NotError(os.WriteFile(filepath.Join(b.Dir, list[0].Uploads), []byte("invalid"), os.ModePerm))
+		_, err = b.restore(s.Module().DB(), list[0].Path, now)
+		a.Equal(err, errChecksumMismatch)
+
+		_, err = b.restore(s.Module().DB(), list[0].Manifest, now)
+		a.True(errors.Is(err, fs.ErrNotExist))
+		_, err = b.restore(s.Module().DB(), "../test.db", now)
+		a.True(errors.Is(err, fs.ErrNotExist))
+
+		a.NotError(b.remove(name))
+		list, err = b.list()
+		a.NotError(err).Length(list, 1)
+		a.FileNotExists(filepath.Join(b.Dir, "20260102-030405.db"+manifestExt))
+	}
+
+	b := &Backup{Dir: "./", Cron: "@daily"}
+	a.NotError(b.SanitizeConfig())
+	_, err := b.restore(s.Module().DB(), "test.db", time.Now())
+	a.Equal(err, errRestoreNotSupported)
+}
+
+func TestBackup_prune(t *testing.T) {
+	a := assert.New(t, false)
+	defer os.RemoveAll("./testdata")
+
+	var restored []byte
+	b := newBackup(a, compressGzip, &restored)
+
+	start := time.Date(2026, 3, 31, 23, 0, 0, 0, time.Local)
+	for i := range 60 { // 每天两份备份
+		for _, h := range []time.Duration{0, 12 * time.Hour} {
+			name := start.AddDate(0, 0, -i).Add(-h).Format(b.Format) + b.compressExt()
+			a.NotError(os.WriteFile(filepath.Join(b.Dir, name), nil, os.ModePerm))
+		}
+	}
+	a.NotError(os.WriteFile(filepath.Join(b.Dir, safetyPrefix+"20200101-000000.db"), nil, os.ModePerm))
+
+	b.Retention = &Retention{Daily: 3, Weekly: 2, Monthly: 3}
+	a.NotError(b.prune())
+
+	list, err := b.list()
+	a.NotError(err)
+	names := make([]string, 0, len(list))
+	for _, f := range list {
+		names = append(names, f.Path)
+	}
+	a.Equal(names, []string{
+		"20260131-230000.db.gz", // 1 月
+		"20260228-230000.db.gz", // 2 月
+		"20260329-230000.db.gz", // 第 13 周
+		"20260330-230000.db.gz",
+		"20260331-230000.db.gz", // 3 月，第 14 周
+		safetyPrefix + "20200101-000000.db",
+	})
+}
+
+func TestModule_backup(t *testing.T) {
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+	defer os.RemoveAll("./testdata")
+
+	var restored []byte
+	b := newBackup(a, compressGzip, &restored)
+	conf := &Config{Backup: b}
+	a.NotError(conf.SanitizeConfig())
+	l := Install(s.NewModule("test"), conf, admintest.NewModule(s))
+
+	defer servertest.Run(a, s.Module().Server())()
+	defer s.Close()
+
+	token := admintest.GetToken(s, l.admin)
+
+	s.Post("/admin/system/backup", nil).
+		Header(header.Authorization, auth.BuildToken(auth.Bearer, token)).
+		Do(nil).
+		Status(http.StatusCreated)
+
+	list, err := b.list()
+	a.NotError(err).Length(list, 1)
+
+	s.Post("/admin/system/backup/"+list[0].Path+"/restore", nil).
+		Header(header.Accept, header.JSON).
+		Header(header.Authorization, auth.BuildToken(auth.Bearer, token)).
+		Do(nil).
+		Status(http.StatusOK).
+		BodyFunc(func(a *assert.Assertion, body []byte) {
+			vo := &restoreVO{}
+			a.NotError(json.Unmarshal(body, vo)).
+				FileExists(filepath.Join(b.Dir, vo.Backup))
+		})
+	a.NotEmpty(restored)
+
+	// 需要停止服务才能恢复
+	b.Offline = true
+	restored = nil
+	s.Post("/admin/system/backup/"+list[0].Path+"/restore", nil).
+		Header(header.Authorization, auth.BuildToken(auth.Bearer, token)).
+		Do(nil).
+		Status(http.StatusNotImplemented)
+	a.Empty(restored)
+	b.Offline = false
+
+	s.Post("/admin/system/backup/not-exists.db/restore", nil).
+		Header(header.Authorization, auth.BuildToken(auth.Bearer, token)).
+		Do(nil).
+		Status(http.StatusNotFound)
+
+	s.Delete("/admin/system/backup/"+list[0].Path).
+		Header(header.Authorization, auth.BuildToken(auth.Bearer, token)).
+		Do(nil).
+		Status(http.StatusNoContent)
+	s.Delete("/admin/system/backup/"+list[0].Path).
+		Header(header.Authorization, auth.BuildToken(auth.Bearer, token)).
+		Do(nil).
+		Status(http.StatusNotFound)
+}
+
+func TestRestoreNow(t *testing.T) {
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+	defer s.Close()
+	defer os.RemoveAll("./testdata")
+
+	var restored []byte
+	b := newBackup(a, "", &restored)
+	conf := &Config{Backup: b}
+	a.NotError(conf.SanitizeConfig())
+
+	_, err := RestoreNow(s.Module(), conf, "")
+	a.True(errors.Is(err, fs.ErrNotExist))
+
+	p, err := BackupNow(s.Module(), conf)
+	a.NotError(err).FileExists(p)
+
+	safety, err := RestoreNow(s.Module(), conf, "")
+	a.NotError(err).FileExists(safety).NotNil(restored)
+}
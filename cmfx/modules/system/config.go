@@ -1,18 +1,22 @@
-// SPDX-FileCopyrightText: 2024 caixw
+// SPDX-FileCopyrightText: 2024-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
 package system
 
 import (
+	"net/netip"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/issue9/scheduled/schedulers/cron"
 	"github.com/issue9/web"
 	"github.com/issue9/web/locales"
+	"github.com/issue9/web/server/config"
 )
 
 // Config 配置项
@@ -24,6 +28,112 @@ type Config struct {
 
 	// Backup 备份数据的选项
 	Backup *Backup `yaml:"backup,omitempty" json:"backup,omitempty" xml:"backup,omitempty" toml:"backup,omitempty"`
+
+	// Metrics 按时间段统计 API 数据的选项
+	//
+	// 为空表示采用默认值。
+	Metrics *Metrics `yaml:"metrics,omitempty" json:"metrics,omitempty" xml:"metrics,omitempty" toml:"metrics,omitempty"`
+
+	// Prometheus 以 OpenMetrics 格式输出监控数据的选项
+	//
+	// 为空表示不输出。
+	Prometheus *Prometheus `yaml:"prometheus,omitempty" json:"prometheus,omitempty" xml:"prometheus,omitempty" toml:"prometheus,omitempty"`
+
+	// Alerts 告警规则
+	//
+	// 为空表示不启用告警。
+	Alerts *Alerts `yaml:"alerts,omitempty" json:"alerts,omitempty" xml:"alerts,omitempty" toml:"alerts,omitempty"`
+
+	// AuditLogs 管理员操作日志的选项
+	//
+	// 为空表示采用默认值。
+	AuditLogs *AuditLogs `yaml:"auditLogs,omitempty" json:"auditLogs,omitempty" xml:"auditLogs,omitempty" toml:"auditLogs,omitempty"`
+}
+
+// AuditLogs 管理员操作日志的相关设置
+type AuditLogs struct {
+	// 日志的保留时间，默认为 180 天。
+	Retention config.Duration `yaml:"retention,omitempty" json:"retention,omitempty" xml:"retention,omitempty" toml:"retention,omitempty"`
+}
+
+// Alerts 告警的相关设置
+type Alerts struct {
+	// 检测告警规则的频率，默认为 1 分钟。
+	Interval config.Duration `yaml:"interval,omitempty" json:"interval,omitempty" xml:"interval,omitempty" toml:"interval,omitempty"`
+
+	// 告警规则列表
+	Rules []*AlertRule `yaml:"rules" json:"rules" xml:"rules>rule" toml:"rules"`
+
+	// 站内通知的类型
+	//
+	// 告警触发和恢复时以此类型向所有管理员发送站内通知，
+	// 值为管理员通知类型的 ID，为 0 表示不发送站内通知。
+	Notice int64 `yaml:"notice,omitempty" json:"notice,omitempty" xml:"notice,omitempty" toml:"notice,omitempty"`
+}
+
+// AlertRule 告警规则
+type AlertRule struct {
+	// 规则的名称，不能重复。
+	Name string `yaml:"name" json:"name" xml:"name,attr" toml:"name"`
+
+	// 规则的类型
+	//
+	// 可以是以下值：
+	//  - cpu 系统的 CPU 使用率，Threshold 为百分比；
+	//  - mem 系统的内存使用量，Threshold 以 MB 为单位；
+	//  - process-cpu 当前进程的 CPU 使用率，Threshold 为百分比；
+	//  - process-mem 当前进程的内存使用量，Threshold 以 MB 为单位；
+	//  - route-errors 两次检测之间接口返回 5xx 的比例，Threshold 的取值范围为 (0,1]；
+	//  - route-latency 两次检测之间接口的平均耗时，Threshold 以毫秒为单位；
+	//  - service 服务或是计划任务处于失败状态，忽略 Threshold；
+	//  - backup 最近一次备份数据库失败，忽略 Threshold；
+	Kind string `yaml:"kind" json:"kind" xml:"kind" toml:"kind"`
+
+	// 阈值，超过此值即满足告警条件。
+	Threshold float64 `yaml:"threshold,omitempty" json:"threshold,omitempty" xml:"threshold,omitempty" toml:"threshold,omitempty"`
+
+	// 仅对 route-errors 和 route-latency 有效，表示需要检测的路由项，为空表示所有的接口。
+	Pattern string `yaml:"pattern,omitempty" json:"pattern,omitempty" xml:"pattern,omitempty" toml:"pattern,omitempty"`
+
+	// 仅对 route-errors 和 route-latency 有效，两次检测之间的请求数量少于此值时不作判断，保持原有的告警状态。
+	//
+	// 用于防止请求量很少时，单个请求的失败即触发告警，默认为 10。
+	MinRequests int `yaml:"minRequests,omitempty" json:"minRequests,omitempty" xml:"minRequests,omitempty" toml:"minRequests,omitempty"`
+
+	// 告警条件需要持续的时间，为零表示满足条件即触发告警。
+	For config.Duration `yaml:"for,omitempty" json:"for,omitempty" xml:"for,omitempty" toml:"for,omitempty"`
+}
+
+// Prometheus 供 Prometheus 等工具采集监控数据的接口设置
+//
+// Token 和 IPs 至少需要指定一项，满足其中之一即可访问。
+type Prometheus struct {
+	// 接口的地址，默认为 /metrics。
+	Path string `yaml:"path,omitempty" json:"path,omitempty" xml:"path,omitempty" toml:"path,omitempty"`
+
+	// 访问令牌
+	//
+	// 客户端需要通过报头 Authorization: Bearer <token> 传递。
+	Token string `yaml:"token,omitempty" json:"token,omitempty" xml:"token,omitempty" toml:"token,omitempty"`
+
+	// 允许访问的客户端 IP
+	//
+	// 可以是具体的 IP 地址，也可以是 CIDR 格式的网段，比如 10.0.0.0/8。
+	IPs []string `yaml:"ips,omitempty" json:"ips,omitempty" xml:"ips>ip,omitempty" toml:"ips,omitempty"`
+
+	prefixes []netip.Prefix
+}
+
+// Metrics 按时间段统计 API 数据的保留时间
+type Metrics struct {
+	// 按分钟统计的数据的保留时间，默认为 24 小时。
+	Minute config.Duration `yaml:"minute,omitempty" json:"minute,omitempty" xml:"minute,omitempty" toml:"minute,omitempty"`
+
+	// 按小时统计的数据的保留时间，默认为 30 天。
+	Hour config.Duration `yaml:"hour,omitempty" json:"hour,omitempty" xml:"hour,omitempty" toml:"hour,omitempty"`
+
+	// 按天统计的数据的保留时间，默认为 365 天。
+	Day config.Duration `yaml:"day,omitempty" json:"day,omitempty" xml:"day,omitempty" toml:"day,omitempty"`
 }
 
 // Backup 备份数据的相关设置项
@@ -37,10 +147,51 @@ type Backup struct {
 	// 备份任务的执行时间
 	Cron string `yaml:"cron" json:"cron" xml:"cron" toml:"cron"`
 
+	// 压缩备份文件的算法
+	//
+	// 可以是 gzip 或是 zstd，为空表示不压缩。压缩后的文件会加上 .gz 或是 .zst 的扩展名。
+	Compress string `json:"compress,omitempty" yaml:"compress,omitempty" xml:"compress,omitempty" toml:"compress,omitempty"`
+
+	// 需要一同备份的上传文件目录
+	//
+	// 为空表示不备份上传文件。
+	Uploads string `json:"uploads,omitempty" yaml:"uploads,omitempty" xml:"uploads,omitempty" toml:"uploads,omitempty"`
+
+	// 备份文件的保留策略
+	//
+	// 为空表示保留所有的备份文件。
+	Retention *Retention `json:"retention,omitempty" yaml:"retention,omitempty" xml:"retention,omitempty" toml:"retention,omitempty"`
+
+	// Restore 从备份文件 src 恢复数据库
+	//
+	// src 为已经解压的数据库备份文件。为空表示不支持恢复数据库，可以由 [DBRestorer] 生成。
+	Restore func(src string) error `json:"-" yaml:"-" xml:"-" toml:"-"`
+
+	// Offline 恢复数据库时是否需要停止服务
+	//
+	// 为 true 时不能通过接口恢复数据库，只能在服务停止之后通过命令行恢复，可以由 [DBRestorer] 生成。
+	Offline bool `json:"-" yaml:"-" xml:"-" toml:"-"`
+
 	// 生成备份文件的文件名
 	buildFile func(time time.Time) string
 }
 
+// Retention 备份文件的保留策略
+//
+// 按备份文件名中的时间进行分组，每一天、每一周和每一月各自保留最新的一份，
+// 三者的并集即为需要保留的备份，其它的备份都将被删除。
+// 文件名无法按 [Backup.Format] 解析的备份不受影响。
+type Retention struct {
+	// 保留最近多少天的每日备份
+	Daily int `json:"daily,omitempty" yaml:"daily,omitempty" xml:"daily,omitempty" toml:"daily,omitempty"`
+
+	// 保留最近多少周的每周备份
+	Weekly int `json:"weekly,omitempty" yaml:"weekly,omitempty" xml:"weekly,omitempty" toml:"weekly,omitempty"`
+
+	// 保留最近多少个月的每月备份
+	Monthly int `json:"monthly,omitempty" yaml:"monthly,omitempty" xml:"monthly,omitempty" toml:"monthly,omitempty"`
+}
+
 func (c *Config) SanitizeConfig() *web.FieldError {
 	if c.URLPrefix == "" {
 		c.URLPrefix = "/system"
@@ -54,9 +205,164 @@ func (c *Config) SanitizeConfig() *web.FieldError {
 		}
 	}
 
+	if c.Metrics == nil {
+		c.Metrics = &Metrics{}
+	}
+	if err := c.Metrics.SanitizeConfig(); err != nil {
+		return err.AddFieldParent("metrics")
+	}
+
+	if c.Prometheus != nil {
+		if err := c.Prometheus.SanitizeConfig(); err != nil {
+			return err.AddFieldParent("prometheus")
+		}
+	}
+
+	if c.Alerts != nil {
+		if err := c.Alerts.SanitizeConfig(); err != nil {
+			return err.AddFieldParent("alerts")
+		}
+	}
+
+	if c.AuditLogs == nil {
+		c.AuditLogs = &AuditLogs{}
+	}
+	if err := c.AuditLogs.SanitizeConfig(); err != nil {
+		return err.AddFieldParent("auditLogs")
+	}
+
+	return nil
+}
+
+func (l *AuditLogs) SanitizeConfig() *web.FieldError {
+	if l.Retention < 0 {
+		return web.NewFieldError("retention", locales.InvalidValue)
+	} else if l.Retention == 0 {
+		l.Retention = config.Duration(180 * 24 * time.Hour)
+	}
+	return nil
+}
+
+func (a *Alerts) SanitizeConfig() *web.FieldError {
+	if a.Interval < 0 {
+		return web.NewFieldError("interval", locales.InvalidValue)
+	} else if a.Interval == 0 {
+		a.Interval = config.Duration(time.Minute)
+	}
+
+	if len(a.Rules) == 0 {
+		return web.NewFieldError("rules", locales.CanNotBeEmpty)
+	}
+
+	if a.Notice < 0 {
+		return web.NewFieldError("notice", locales.InvalidValue)
+	}
+
+	names := make([]string, 0, len(a.Rules))
+	for i, r := range a.Rules {
+		field := "rules[" + strconv.Itoa(i) + "]."
+
+		if r.Name == "" {
+			return web.NewFieldError(field+"name", locales.CanNotBeEmpty)
+		}
+		if slices.Contains(names, r.Name) {
+			return web.NewFieldError(field+"name", locales.DuplicateValue)
+		}
+		names = append(names, r.Name)
+
+		switch r.Kind {
+		case alertKindCPU, alertKindProcessCPU:
+			if r.Threshold <= 0 || r.Threshold > 100 {
+				return web.NewFieldError(field+"threshold", locales.InvalidValue)
+			}
+		case alertKindMem, alertKindProcessMem, alertKindRouteLatency:
+			if r.Threshold <= 0 {
+				return web.NewFieldError(field+"threshold", locales.ShouldGreatThan(0))
+			}
+		case alertKindRouteErrors:
+			if r.Threshold <= 0 || r.Threshold > 1 {
+				return web.NewFieldError(field+"threshold", locales.InvalidValue)
+			}
+		case alertKindService, alertKindBackup:
+		default:
+			return web.NewFieldError(field+"kind", locales.InvalidValue)
+		}
+
+		if r.MinRequests < 0 {
+			return web.NewFieldError(field+"minRequests", locales.InvalidValue)
+		} else if r.MinRequests == 0 && (r.Kind == alertKindRouteErrors || r.Kind == alertKindRouteLatency) {
+			r.MinRequests = 10
+		}
+
+		if r.For < 0 {
+			return web.NewFieldError(field+"for", locales.InvalidValue)
+		}
+	}
+
+	return nil
+}
+
+func (p *Prometheus) SanitizeConfig() *web.FieldError {
+	if p.Path == "" {
+		p.Path = "/metrics"
+	} else if p.Path[0] != '/' {
+		return web.NewFieldError("path", locales.InvalidValue)
+	}
+
+	if p.Token == "" && len(p.IPs) == 0 {
+		return web.NewFieldError("token", locales.CanNotBeEmpty)
+	}
+
+	p.prefixes = make([]netip.Prefix, 0, len(p.IPs))
+	for i, ip := range p.IPs {
+		prefix, err := netip.ParsePrefix(ip)
+		if err != nil {
+			addr, err := netip.ParseAddr(ip)
+			if err != nil {
+				return web.NewFieldError("ips["+strconv.Itoa(i)+"]", locales.InvalidValue)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		p.prefixes = append(p.prefixes, prefix.Masked())
+	}
+
+	return nil
+}
+
+func (m *Metrics) SanitizeConfig() *web.FieldError {
+	if m.Minute < 0 {
+		return web.NewFieldError("minute", locales.InvalidValue)
+	} else if m.Minute == 0 {
+		m.Minute = config.Duration(24 * time.Hour)
+	}
+
+	if m.Hour < 0 {
+		return web.NewFieldError("hour", locales.InvalidValue)
+	} else if m.Hour == 0 {
+		m.Hour = config.Duration(30 * 24 * time.Hour)
+	}
+
+	if m.Day < 0 {
+		return web.NewFieldError("day", locales.InvalidValue)
+	} else if m.Day == 0 {
+		m.Day = config.Duration(365 * 24 * time.Hour)
+	}
+
 	return nil
 }
 
+// 按时间粒度返回数据的保留时间
+func (m *Metrics) retention(p MetricPeriod) time.Duration {
+	switch p {
+	case MetricPeriodMinute:
+		return m.Minute.Duration()
+	case MetricPeriodHour:
+		return m.Hour.Duration()
+	default:
+		return m.Day.Duration()
+	}
+}
+
 func (b *Backup) SanitizeConfig() *web.FieldError {
 	if b.Dir == "" {
 		return web.NewFieldError("dir", locales.CanNotBeEmpty)
@@ -81,6 +387,34 @@ func (b *Backup) SanitizeConfig() *web.FieldError {
 		return web.NewFieldError("format", locales.ErrInvalidFormat())
 	}
 
+	switch b.Compress {
+	case "", compressGzip, compressZstd:
+	default:
+		return web.NewFieldError("compress", locales.InvalidValue)
+	}
+
+	if b.Uploads != "" {
+		stat, err := os.Stat(b.Uploads)
+		if err != nil {
+			return web.NewFieldError("uploads", err)
+		}
+		if !stat.IsDir() {
+			return web.NewFieldError("uploads", web.Phrase("must be a dir"))
+		}
+	}
+
+	if r := b.Retention; r != nil {
+		if r.Daily < 0 {
+			return web.NewFieldError("retention.daily", locales.InvalidValue)
+		}
+		if r.Weekly < 0 {
+			return web.NewFieldError("retention.weekly", locales.InvalidValue)
+		}
+		if r.Monthly < 0 {
+			return web.NewFieldError("retention.monthly", locales.InvalidValue)
+		}
+	}
+
 	b.buildFile = func(now time.Time) string {
 		return filepath.Join(b.Dir, now.Format(b.Format))
 	}
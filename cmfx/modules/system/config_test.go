@@ -1,4 +1,4 @@
-// SPDX-FileCopyrightText: 2024 caixw
+// SPDX-FileCopyrightText: 2024-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
@@ -6,15 +6,26 @@ package system
 
 import (
 	"testing"
+	"time"
 
 	"github.com/issue9/assert/v4"
+	"github.com/issue9/web/server/config"
 )
 
 func TestConfig_SanitizeConfig(t *testing.T) {
 	a := assert.New(t, false)
 
 	conf := &Config{}
-	a.NotError(conf.SanitizeConfig())
+	a.NotError(conf.SanitizeConfig()).
+		Equal(conf.Metrics.Minute, config.Duration(24*time.Hour)).
+		Equal(conf.Metrics.Day, config.Duration(365*24*time.Hour)).
+		Equal(conf.AuditLogs.Retention, config.Duration(180*24*time.Hour))
+
+	conf = &Config{Metrics: &Metrics{Hour: -1}}
+	a.Equal(conf.SanitizeConfig().Field, "metrics.hour")
+
+	conf = &Config{AuditLogs: &AuditLogs{Retention: -1}}
+	a.Equal(conf.SanitizeConfig().Field, "auditLogs.retention")
 
 	conf = &Config{
 		Backup: &Backup{},
@@ -1,10 +1,14 @@
-// SPDX-FileCopyrightText: 2022-2025 caixw
+// SPDX-FileCopyrightText: 2022-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
 package system
 
 import (
+	"io/fs"
+	"strings"
+	"time"
+
 	"github.com/issue9/web"
 
 	"github.com/issue9/cmfx/cmfx"
@@ -14,7 +18,7 @@ import (
 )
 
 func Install(mod *cmfx.Module, conf *Config, adminL *admin.Module) *Module {
-	if err := mod.DB().Create(&healthPO{}); err != nil {
+	if err := mod.DB().Create(&healthPO{}, &healthMetricPO{}, &auditLogPO{}); err != nil {
 		panic(web.SprintError(mod.Server().Locale().Printer(), true, err))
 	}
 
@@ -27,5 +31,70 @@ func Install(mod *cmfx.Module, conf *Config, adminL *admin.Module) *Module {
 		panic(web.SprintError(mod.Server().Locale().Printer(), true, err))
 	}
 
+	if err := Migrations(mod).Install(); err != nil {
+		panic(web.SprintError(mod.Server().Locale().Printer(), true, err))
+	}
+
 	return Load(mod, conf, adminL)
 }
+
+// Uninstall 删除由 [Install] 创建的数据表
+//
+// adminMod 为管理模块，用于删除当前模块在角色中的资源；
+func Uninstall(mod, adminMod *cmfx.Module) error {
+	if err := admin.DeleteResourceGroup(adminMod, mod); err != nil {
+		return err
+	}
+
+	if err := mod.DB().Drop(&healthPO{}, &healthMetricPO{}, &auditLogPO{}); err != nil {
+		return err
+	}
+
+	if err := settings.Uninstall(mod, settingsTableName); err != nil {
+		return err
+	}
+
+	return Migrations(mod).Uninstall()
+}
+
+// BackupNow 按照 conf.Backup 的配置立即备份数据库
+//
+// 返回备份文件的路径，如果未配置 conf.Backup 则返回错误。
+// 一般用于卸载等高风险的操作之前。
+func BackupNow(mod *cmfx.Module, conf *Config) (string, error) {
+	if conf.Backup == nil {
+		return "", web.NewLocaleError("backup is not configured")
+	}
+
+	return conf.Backup.backup(mod.DB(), conf.Backup.buildFile(time.Now()))
+}
+
+// RestoreNow 从备份文件 name 恢复数据
+//
+// name 为 conf.Backup.Dir 中的备份文件名，为空表示最近的一次备份。
+// 在恢复之前会先对当前的数据进行备份，返回该备份文件的路径。
+func RestoreNow(mod *cmfx.Module, conf *Config, name string) (string, error) {
+	if conf.Backup == nil {
+		return "", web.NewLocaleError("backup is not configured")
+	}
+
+	if name == "" {
+		list, err := conf.Backup.list()
+		if err != nil {
+			return "", err
+		}
+
+		var last *backupFileVO
+		for _, f := range list {
+			if !strings.HasPrefix(f.Path, safetyPrefix) && (last == nil || f.Mod.After(last.Mod)) {
+				last = f
+			}
+		}
+		if last == nil {
+			return "", fs.ErrNotExist
+		}
+		name = last.Path
+	}
+
+	return conf.Backup.restore(mod.DB(), name, time.Now())
+}
@@ -1,4 +1,4 @@
-// SPDX-FileCopyrightText: 2024 caixw
+// SPDX-FileCopyrightText: 2024-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
@@ -25,5 +25,37 @@ func TestInstall(t *testing.T) {
 	l := Install(mod, conf, adminL)
 	a.NotNil(l)
 
-	s.TableExists("mod_api_healths")
+	s.TableExists("mod_api_healths").
+		TableExists("mod_audit_logs").
+		TableExists("mod_" + settingsTableName + "_versions").
+		TableExists("mod_" + settingsTableName + "_histories").
+		TableExists("_migrations")
+
+	a.NotError(Migrations(mod).Upgrade())
+	states, err := Migrations(mod).States()
+	a.NotError(err).Length(states, 3).
+		False(states[0].Applied.IsZero()).
+		False(states[1].Applied.IsZero()).
+		False(states[2].Applied.IsZero())
+}
+
+func TestUninstall(t *testing.T) {
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+	adminL := admintest.NewModule(s)
+
+	conf := &Config{}
+	s.Assertion().NotError(conf.SanitizeConfig())
+	mod := s.NewModule("mod")
+	Install(mod, conf, adminL)
+
+	_, err := BackupNow(mod, conf)
+	a.Error(err)
+
+	a.NotError(Uninstall(mod, adminL.UserModule().Module()))
+	s.TableNotExists("mod_api_healths").
+		TableNotExists("mod_audit_logs").
+		TableNotExists("mod_" + settingsTableName).
+		TableNotExists("mod_" + settingsTableName + "_versions").
+		TableNotExists("mod_" + settingsTableName + "_histories")
 }
@@ -0,0 +1,394 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package system
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/issue9/orm/v6"
+	"github.com/issue9/web"
+)
+
+// 统计耗时的区间上限，最后还有一个无上限的区间。
+var metricBounds = []time.Duration{
+	time.Millisecond,
+	2 * time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+var metricPeriods = []MetricPeriod{MetricPeriodMinute, MetricPeriodHour, MetricPeriodDay}
+
+type metricKey struct {
+	router, method, pattern string
+	start                   time.Time // 所在分钟的起始时间
+}
+
+// 一个时间段内的统计数据
+type metricItem struct {
+	count        int
+	userErrors   int
+	serverErrors int
+	min, max     time.Duration
+	spend        time.Duration
+	buckets      []int // 长度为 len(metricBounds)+1
+}
+
+// 按时间段统计 API 的访问数据
+//
+// 请求数据先在内存中按分钟汇总，由 flush 定时合并至数据库中各时间粒度的记录。
+type healthMetrics struct {
+	db    *orm.DB
+	conf  *Metrics
+	mux   sync.Mutex
+	items map[metricKey]*metricItem
+}
+
+func newHealthMetrics(db *orm.DB, conf *Metrics) *healthMetrics {
+	return &healthMetrics{
+		db:    db,
+		conf:  conf,
+		items: make(map[metricKey]*metricItem, 50),
+	}
+}
+
+// 记录一次请求
+func (m *healthMetrics) record(router, method, pattern string, begin time.Time, dur time.Duration, status int) {
+	key := metricKey{router: router, method: method, pattern: pattern, start: metricStart(MetricPeriodMinute, begin)}
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	item, found := m.items[key]
+	if !found {
+		item = &metricItem{min: dur, max: dur, buckets: make([]int, len(metricBounds)+1)}
+		m.items[key] = item
+	}
+
+	item.count++
+	item.spend += dur
+	item.min = min(item.min, dur)
+	item.max = max(item.max, dur)
+	item.buckets[metricBucket(dur)]++
+
+	if status >= http.StatusBadRequest && status < http.StatusInternalServerError {
+		item.userErrors++
+	} else if status >= http.StatusInternalServerError {
+		item.serverErrors++
+	}
+}
+
+// 将内存中的数据合并至数据库并清除过期的数据
+func (m *healthMetrics) flush(now time.Time) error {
+	m.mux.Lock()
+	items := m.items
+	m.items = make(map[metricKey]*metricItem, len(items))
+	m.mux.Unlock()
+
+	if len(items) > 0 {
+		err := m.db.DoTransaction(func(tx *orm.Tx) error {
+			for key, item := range items {
+				for _, p := range metricPeriods {
+					if err := mergeMetric(tx, key, metricStart(p, key.start), p, item); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil { // 放回内存，由下一次 flush 重新写入。
+			m.mux.Lock()
+			for key, item := range items {
+				if curr, found := m.items[key]; found {
+					curr.merge(item)
+				} else {
+					m.items[key] = item
+				}
+			}
+			m.mux.Unlock()
+			return err
+		}
+	}
+
+	for _, p := range metricPeriods {
+		expired := metricStart(p, now.Add(-m.conf.retention(p)))
+		if _, err := m.db.Where("period=?", p).And("start<?", expired).Delete(&healthMetricPO{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 将 item 的数据合并至 i
+func (i *metricItem) merge(item *metricItem) {
+	i.min = min(i.min, item.min)
+	i.max = max(i.max, item.max)
+	i.count += item.count
+	i.userErrors += item.userErrors
+	i.serverErrors += item.serverErrors
+	i.spend += item.spend
+	for index, v := range item.buckets {
+		i.buckets[index] += v
+	}
+}
+
+func mergeMetric(e orm.Engine, key metricKey, start time.Time, p MetricPeriod, item *metricItem) error {
+	po := &healthMetricPO{}
+	_, err := e.Where("router=?", key.router).
+		And("method=?", key.method).
+		And("pattern=?", key.pattern).
+		And("period=?", p).
+		And("start=?", start).
+		Select(true, po)
+	if err != nil {
+		return err
+	}
+
+	if po.ID == 0 {
+		_, err = e.Insert(&healthMetricPO{
+			Router:       key.router,
+			Method:       key.method,
+			Pattern:      key.pattern,
+			Period:       p,
+			Start:        start,
+			Count:        item.count,
+			UserErrors:   item.userErrors,
+			ServerErrors: item.serverErrors,
+			Min:          item.min,
+			Max:          item.max,
+			Spend:        item.spend,
+			Buckets:      joinBuckets(item.buckets),
+		})
+		return err
+	}
+
+	buckets := splitBuckets(po.Buckets)
+	for i, v := range item.buckets {
+		buckets[i] += v
+	}
+
+	if po.Count == 0 {
+		po.Min = item.min
+		po.Max = item.max
+	} else {
+		po.Min = min(po.Min, item.min)
+		po.Max = max(po.Max, item.max)
+	}
+	po.Count += item.count
+	po.UserErrors += item.userErrors
+	po.ServerErrors += item.serverErrors
+	po.Spend += item.spend
+	po.Buckets = joinBuckets(buckets)
+	_, err = e.Update(po)
+	return err
+}
+
+// 返回 t 所在时间段的起始时间
+func metricStart(p MetricPeriod, t time.Time) time.Time {
+	t = t.Local()
+	switch p {
+	case MetricPeriodMinute:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, time.Local)
+	case MetricPeriodHour:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, time.Local)
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+	}
+}
+
+// 返回 dur 所在区间的索引
+func metricBucket(dur time.Duration) int {
+	for i, b := range metricBounds {
+		if dur <= b {
+			return i
+		}
+	}
+	return len(metricBounds)
+}
+
+func joinBuckets(buckets []int) string {
+	s := make([]string, 0, len(buckets))
+	for _, v := range buckets {
+		s = append(s, strconv.Itoa(v))
+	}
+	return strings.Join(s, ",")
+}
+
+// 解析由 joinBuckets 生成的内容，无法解析的项按 0 处理。
+func splitBuckets(s string) []int {
+	buckets := make([]int, len(metricBounds)+1)
+	if s == "" {
+		return buckets
+	}
+
+	for i, v := range strings.Split(s, ",") {
+		if i >= len(buckets) {
+			break
+		}
+		buckets[i], _ = strconv.Atoi(v)
+	}
+	return buckets
+}
+
+// 根据各区间的数量估算百分位数
+//
+// q 的取值范围为 (0,1]，在命中的区间内按线性插值计算，结果限定在 [minDur,maxDur] 之间。
+func metricPercentile(buckets []int, q float64, minDur, maxDur time.Duration) time.Duration {
+	total := 0
+	for _, v := range buckets {
+		total += v
+	}
+	if total == 0 {
+		return 0
+	}
+
+	rank := q * float64(total)
+	cumulative := 0
+	for i, v := range buckets {
+		if v == 0 || float64(cumulative+v) < rank {
+			cumulative += v
+			continue
+		}
+
+		lower := time.Duration(0)
+		if i > 0 {
+			lower = metricBounds[i-1]
+		}
+		upper := maxDur
+		if i < len(metricBounds) {
+			upper = metricBounds[i]
+		}
+		lower = max(lower, minDur)
+		upper = min(upper, maxDur)
+		if upper < lower {
+			return lower
+		}
+
+		ratio := (rank - float64(cumulative)) / float64(v)
+		return lower + time.Duration(math.Round(ratio*float64(upper-lower)))
+	}
+	return maxDur
+}
+
+// 查询 API 统计数据的参数
+type metricsQuery struct {
+	Period  MetricPeriod `query:"period,minute"`
+	Start   time.Time    `query:"start"`
+	End     time.Time    `query:"end"`
+	Router  string       `query:"router"`
+	Method  string       `query:"method"`
+	Pattern string       `query:"pattern"`
+}
+
+func (q *metricsQuery) Filter(ctx *web.FilterContext) {
+	ctx.Add(MetricPeriodFilter("period", &q.Period))
+}
+
+// 某个 API 的时间序列
+type metricSeriesVO struct {
+	Router  string           `json:"router" yaml:"router" cbor:"router"`
+	Method  string           `json:"method" yaml:"method" cbor:"method"`
+	Pattern string           `json:"pattern" yaml:"pattern" cbor:"pattern"`
+	Points  []*metricPointVO `json:"points" yaml:"points" cbor:"points"`
+}
+
+// 某个时间段内的统计数据
+type metricPointVO struct {
+	Start        time.Time     `json:"start" yaml:"start" cbor:"start"` // 时间段的起始时间
+	Count        int           `json:"count" yaml:"count" cbor:"count"`
+	UserErrors   int           `json:"userErrors" yaml:"userErrors" cbor:"userErrors"`
+	ServerErrors int           `json:"serverErrors" yaml:"serverErrors" cbor:"serverErrors"`
+	Min          time.Duration `json:"min" yaml:"min" cbor:"min"`
+	Max          time.Duration `json:"max" yaml:"max" cbor:"max"`
+	Avg          time.Duration `json:"avg" yaml:"avg" cbor:"avg"`
+	P50          time.Duration `json:"p50" yaml:"p50" cbor:"p50"` // 估算值
+	P95          time.Duration `json:"p95" yaml:"p95" cbor:"p95"` // 估算值
+	P99          time.Duration `json:"p99" yaml:"p99" cbor:"p99"` // 估算值
+}
+
+// 补全未指定的时间范围，默认查询最近的一段时间。
+func (q *metricsQuery) sanitize(now time.Time) {
+	if q.End.IsZero() {
+		q.End = now
+	}
+
+	if q.Start.IsZero() {
+		switch q.Period {
+		case MetricPeriodMinute:
+			q.Start = q.End.Add(-time.Hour)
+		case MetricPeriodHour:
+			q.Start = q.End.Add(-24 * time.Hour)
+		default:
+			q.Start = q.End.AddDate(0, 0, -30)
+		}
+	}
+}
+
+// 查询时间序列，尚未写入数据库的数据不会包含在内。
+func (m *healthMetrics) series(q *metricsQuery) ([]*metricSeriesVO, error) {
+	sql := m.db.SQLBuilder().Select().
+		Column("*").
+		From(orm.TableName(&healthMetricPO{})).
+		Where("period=?", q.Period).
+		And("start>=?", metricStart(q.Period, q.Start)).
+		And("start<=?", q.End).
+		Asc("router", "method", "pattern", "start")
+	if q.Router != "" {
+		sql.And("router=?", q.Router)
+	}
+	if q.Method != "" {
+		sql.And("method=?", q.Method)
+	}
+	if q.Pattern != "" {
+		sql.And("pattern=?", q.Pattern)
+	}
+
+	pos := make([]*healthMetricPO, 0, 100)
+	if _, err := sql.QueryObject(true, &pos); err != nil {
+		return nil, err
+	}
+
+	list := make([]*metricSeriesVO, 0, 10)
+	var curr *metricSeriesVO
+	for _, po := range pos {
+		if curr == nil || curr.Router != po.Router || curr.Method != po.Method || curr.Pattern != po.Pattern {
+			curr = &metricSeriesVO{Router: po.Router, Method: po.Method, Pattern: po.Pattern, Points: make([]*metricPointVO, 0, 10)}
+			list = append(list, curr)
+		}
+
+		buckets := splitBuckets(po.Buckets)
+		p := &metricPointVO{
+			Start:        po.Start,
+			Count:        po.Count,
+			UserErrors:   po.UserErrors,
+			ServerErrors: po.ServerErrors,
+			Min:          po.Min,
+			Max:          po.Max,
+			P50:          metricPercentile(buckets, .5, po.Min, po.Max),
+			P95:          metricPercentile(buckets, .95, po.Min, po.Max),
+			P99:          metricPercentile(buckets, .99, po.Min, po.Max),
+		}
+		if po.Count > 0 {
+			p.Avg = po.Spend / time.Duration(po.Count)
+		}
+		curr.Points = append(curr.Points, p)
+	}
+
+	return list, nil
+}
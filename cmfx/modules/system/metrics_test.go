@@ -0,0 +1,168 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package system
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/mux/v9/header"
+	"github.com/issue9/web/server/servertest"
+	"github.com/issue9/webuse/v7/middlewares/auth"
+
+	"github.com/issue9/cmfx/cmfx/initial/test"
+	"github.com/issue9/cmfx/cmfx/modules/admin/admintest"
+)
+
+func TestMetricPercentile(t *testing.T) {
+	a := assert.New(t, false)
+
+	buckets := make([]int, len(metricBounds)+1)
+	a.Equal(metricPercentile(buckets, .5, 0, 0), 0)
+
+	// 100 个请求均落在 (5ms,10ms] 区间
+	buckets[metricBucket(8*time.Millisecond)] = 100
+	a.Equal(metricPercentile(buckets, .5, 6*time.Millisecond, 9*time.Millisecond), 7500*time.Microsecond).
+		Equal(metricPercentile(buckets, 1, 6*time.Millisecond, 9*time.Millisecond), 9*time.Millisecond)
+
+	// 再加 100 个落在无上限区间的请求
+	buckets[len(metricBounds)] = 100
+	a.Equal(metricPercentile(buckets, .5, 6*time.Millisecond, 20*time.Second), 10*time.Millisecond).
+		Equal(metricPercentile(buckets, .99, 6*time.Millisecond, 20*time.Second), 19800*time.Millisecond)
+
+	a.Equal(splitBuckets(joinBuckets(buckets)), buckets).
+		Equal(splitBuckets("1,x"), append([]int{1}, make([]int, len(metricBounds))...))
+}
+
+func TestHealthMetrics_flush(t *testing.T) {
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+	defer s.Close()
+
+	mod := s.NewModule("test")
+	a.NotError(mod.DB().Create(&healthMetricPO{}))
+	conf := &Metrics{}
+	a.NotError(conf.SanitizeConfig())
+	m := newHealthMetrics(mod.DB(), conf)
+
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.Local)
+	m.record("def", http.MethodGet, "/p1", now, 2*time.Millisecond, http.StatusOK)
+	m.record("def", http.MethodGet, "/p1", now.Add(10*time.Second), 4*time.Millisecond, http.StatusNotFound)
+	m.record("def", http.MethodGet, "/p1", now.Add(time.Minute), 6*time.Millisecond, http.StatusInternalServerError)
+	m.record("def", http.MethodPost, "/p1", now, time.Millisecond, http.StatusOK)
+	a.NotError(m.flush(now)).Empty(m.items)
+
+	q := &metricsQuery{Period: MetricPeriodMinute, Method: http.MethodGet}
+	q.sanitize(now.Add(time.Minute))
+	list, err := m.series(q)
+	a.NotError(err).Length(list, 1)
+	a.Length(list[0].Points, 2)
+	p := list[0].Points[0]
+	a.Equal(p.Count, 2).
+		Equal(p.UserErrors, 1).
+		Equal(p.Min, 2*time.Millisecond).
+		Equal(p.Max, 4*time.Millisecond).
+		Equal(p.Avg, 3*time.Millisecond).
+		True(p.Start.Equal(time.Date(2026, 1, 2, 3, 4, 0, 0, time.Local)))
+
+	// 再次写入同一时间段，会合并已有的数据。
+	m.record("def", http.MethodGet, "/p1", now.Add(time.Hour), 10*time.Millisecond, http.StatusOK)
+	a.NotError(m.flush(now))
+
+	list, err = m.series(&metricsQuery{Period: MetricPeriodDay, Start: now, End: now})
+	a.NotError(err).Length(list, 2)
+	p = list[0].Points[0]
+	a.Equal(list[0].Method, http.MethodGet).
+		Equal(p.Count, 4).
+		Equal(p.UserErrors, 1).
+		Equal(p.ServerErrors, 1).
+		Equal(p.Min, 2*time.Millisecond).
+		Equal(p.Max, 10*time.Millisecond).
+		True(p.P50 >= p.Min && p.P50 <= p.P95 && p.P95 <= p.P99 && p.P99 <= p.Max)
+
+	// 超过保留时间的数据被删除
+	a.NotError(m.flush(now.Add(48 * time.Hour)))
+	list, err = m.series(&metricsQuery{Period: MetricPeriodMinute, Start: now, End: now.Add(time.Hour)})
+	a.NotError(err).Empty(list)
+	list, err = m.series(&metricsQuery{Period: MetricPeriodHour, Start: now, End: now.Add(time.Hour)})
+	a.NotError(err).Length(list, 2)
+}
+
+func TestHealthMetrics_flush_error(t *testing.T) {
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+	defer s.Close()
+
+	mod := s.NewModule("test")
+	conf := &Metrics{}
+	a.NotError(conf.SanitizeConfig())
+	m := newHealthMetrics(mod.DB(), conf)
+
+	// 数据表不存在，写入失败之后数据依然保留在内存中。
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.Local)
+	m.record("def", http.MethodGet, "/p1", now, 2*time.Millisecond, http.StatusOK)
+	a.Error(m.flush(now)).Length(m.items, 1)
+
+	// 失败期间新的请求与放回的数据合并
+	m.record("def", http.MethodGet, "/p1", now, 4*time.Millisecond, http.StatusInternalServerError)
+	a.Length(m.items, 1)
+
+	a.NotError(mod.DB().Create(&healthMetricPO{}))
+	a.NotError(m.flush(now)).Empty(m.items)
+
+	q := &metricsQuery{Period: MetricPeriodMinute}
+	q.sanitize(now.Add(time.Minute))
+	list, err := m.series(q)
+	a.NotError(err).Length(list, 1)
+	p := list[0].Points[0]
+	a.Equal(p.Count, 2).
+		Equal(p.ServerErrors, 1).
+		Equal(p.Min, 2*time.Millisecond).
+		Equal(p.Max, 4*time.Millisecond)
+}
+
+func TestModule_adminGetRouteMetrics(t *testing.T) {
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+	l := newModule(s)
+
+	defer servertest.Run(a, s.Module().Server())()
+	defer s.Close()
+
+	token := admintest.GetToken(s, l.admin)
+
+	s.Get("/admin/system/info").
+		Header(header.Authorization, auth.BuildToken(auth.Bearer, token)).
+		Do(nil).
+		Status(http.StatusOK)
+	a.NotError(l.metrics.flush(time.Now()))
+
+	s.Get("/admin/system/routes/metrics?period=hour").
+		Header(header.Accept, header.JSON).
+		Header(header.Authorization, auth.BuildToken(auth.Bearer, token)).
+		Do(nil).
+		Status(http.StatusOK).
+		BodyFunc(func(a *assert.Assertion, body []byte) {
+			list := make([]*metricSeriesVO, 0, 10)
+			a.NotError(json.Unmarshal(body, &list)).NotEmpty(list)
+
+			var found bool
+			for _, item := range list {
+				if item.Method == http.MethodGet && item.Pattern == "/admin/system/info" {
+					found = true
+					a.Length(item.Points, 1).True(item.Points[0].Count > 0)
+				}
+			}
+			a.True(found)
+		})
+
+	s.Get("/admin/system/routes/metrics?period=year").
+		Header(header.Authorization, auth.BuildToken(auth.Bearer, token)).
+		Do(nil).
+		Status(http.StatusBadRequest)
+}
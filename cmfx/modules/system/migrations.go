@@ -0,0 +1,36 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package system
+
+import (
+	"github.com/issue9/web"
+
+	"github.com/issue9/cmfx/cmfx"
+	"github.com/issue9/cmfx/cmfx/user/settings"
+)
+
+// Migrations 当前模块的数据库迁移列表
+func Migrations(mod *cmfx.Module) *cmfx.Migrations {
+	return mod.Migrations(
+		&cmfx.Migration{
+			Version: 1,
+			Desc:    web.Phrase("create api health metrics table"),
+			Up:      func(mod *cmfx.Module) error { return mod.DB().Create(&healthMetricPO{}) },
+			Down:    func(mod *cmfx.Module) error { return mod.DB().Drop(&healthMetricPO{}) },
+		},
+		&cmfx.Migration{
+			Version: 2,
+			Desc:    web.Phrase("create audit logs table"),
+			Up:      func(mod *cmfx.Module) error { return mod.DB().Create(&auditLogPO{}) },
+			Down:    func(mod *cmfx.Module) error { return mod.DB().Drop(&auditLogPO{}) },
+		},
+		&cmfx.Migration{
+			Version: 3,
+			Desc:    web.Phrase("create settings histories table"),
+			Up:      func(mod *cmfx.Module) error { return settings.InstallHistory(mod, settingsTableName) },
+			Down:    func(mod *cmfx.Module) error { return settings.UninstallHistory(mod, settingsTableName) },
+		},
+	)
+}
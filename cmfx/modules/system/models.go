@@ -1,10 +1,16 @@
-// SPDX-FileCopyrightText: 2022-2024 caixw
+// SPDX-FileCopyrightText: 2022-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
 package system
 
-import "time"
+import (
+	"time"
+
+	"github.com/issue9/orm/v6/core"
+)
+
+//go:generate web enum -i=./models.go -o=./models_methods.go -t=MetricPeriod
 
 type healthPO struct {
 	Router       string        `orm:"name(router);len(20);unique(r_m_p)"`
@@ -25,3 +31,50 @@ func (l *healthPO) BeforeUpdate() error {
 	l.Last = time.Now()
 	return nil
 }
+
+// MetricPeriod API 统计数据的时间粒度
+type MetricPeriod int8
+
+const (
+	MetricPeriodMinute MetricPeriod = iota
+	MetricPeriodHour
+	MetricPeriodDay
+)
+
+func (MetricPeriod) PrimitiveType() core.PrimitiveType { return core.String }
+
+// 按时间段统计的 API 数据
+type healthMetricPO struct {
+	ID           int64         `orm:"name(id);ai"`
+	Router       string        `orm:"name(router);len(20);unique(u_metric)"`
+	Method       string        `orm:"name(method);len(10);unique(u_metric)"`
+	Pattern      string        `orm:"name(pattern);len(500);unique(u_metric)"`
+	Period       MetricPeriod  `orm:"name(period);len(10);unique(u_metric);index(i_metric_period_start)"`
+	Start        time.Time     `orm:"name(start);unique(u_metric);index(i_metric_period_start)"` // 时间段的起始时间
+	Count        int           `orm:"name(count)"`
+	UserErrors   int           `orm:"name(user_errors)"`
+	ServerErrors int           `orm:"name(server_errors)"`
+	Min          time.Duration `orm:"name(min)"`
+	Max          time.Duration `orm:"name(max)"`
+	Spend        time.Duration `orm:"name(spend)"`
+	Buckets      string        `orm:"name(buckets);len(500)"` // 落在各个耗时区间的请求数量，以逗号分隔，用于估算百分位数。
+}
+
+func (l *healthMetricPO) TableName() string { return `_api_health_metrics` }
+
+// 管理员的操作记录
+type auditLogPO struct {
+	ID        int64     `orm:"name(id);ai"`
+	UID       int64     `orm:"name(uid);index(i_audit_uid)"`
+	Resource  string    `orm:"name(resource);len(100)"`
+	Method    string    `orm:"name(method);len(10)"`
+	Path      string    `orm:"name(path);len(500)"`
+	Targets   string    `orm:"name(targets);len(500)"` // 地址中的参数，以 JSON 格式保存。
+	Body      string    `orm:"name(body);len(-1)"`     // 已脱敏的提交内容
+	Status    int       `orm:"name(status)"`
+	IP        string    `orm:"name(ip);len(50)"`
+	UserAgent string    `orm:"name(user_agent);len(500)"`
+	Created   time.Time `orm:"name(created);index(i_audit_created)"`
+}
+
+func (l *auditLogPO) TableName() string { return `_audit_logs` }
@@ -0,0 +1,137 @@
+// 当前文件由 web 生成，请勿手动编辑！
+
+package system
+
+import (
+	"database/sql/driver"
+	"fmt"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/issue9/web/filter"
+	"github.com/issue9/web/locales"
+	"github.com/issue9/web/openapi"
+)
+
+//--------------------- MetricPeriod ------------------------
+
+var _MetricPeriodToString = map[MetricPeriod]string{
+	MetricPeriodDay:    "day",
+	MetricPeriodHour:   "hour",
+	MetricPeriodMinute: "minute",
+}
+
+var _MetricPeriodFromString = map[string]MetricPeriod{
+	"day":    MetricPeriodDay,
+	"hour":   MetricPeriodHour,
+	"minute": MetricPeriodMinute,
+}
+
+// String fmt.Stringer
+func (p MetricPeriod) String() string {
+	if v, found := _MetricPeriodToString[p]; found {
+		return v
+	}
+	return fmt.Sprintf("MetricPeriod(%d)", p)
+}
+
+func ParseMetricPeriod(v string) (MetricPeriod, error) {
+	if t, found := _MetricPeriodFromString[v]; found {
+		return t, nil
+	}
+	return 0, locales.ErrInvalidValue()
+}
+
+func (p MetricPeriod) MarshalText() ([]byte, error) {
+	if v, found := _MetricPeriodToString[p]; found {
+		return []byte(v), nil
+	}
+	return nil, locales.ErrInvalidValue()
+}
+
+func (p *MetricPeriod) UnmarshalText(data []byte) error {
+	tmp, err := ParseMetricPeriod(string(data))
+	if err == nil {
+		*p = tmp
+	}
+	return err
+}
+
+func (p MetricPeriod) MarshalCBOR() ([]byte, error) {
+	if v, found := _MetricPeriodToString[p]; found {
+		return cbor.Marshal(v)
+	}
+	return nil, locales.ErrInvalidValue()
+}
+
+func (p *MetricPeriod) UnmarshalCBOR(data []byte) error {
+	var tmp string
+	if err := cbor.Unmarshal(data, &tmp); err != nil {
+		return err
+	}
+
+	if ss, found := _MetricPeriodFromString[tmp]; found {
+		*p = ss
+		return nil
+	}
+	return locales.ErrInvalidValue()
+}
+
+func (p MetricPeriod) IsValid() bool {
+	_, found := _MetricPeriodToString[p]
+	return found
+}
+
+// Scan sql.Scanner
+func (p *MetricPeriod) Scan(src any) error {
+	if src == nil {
+		return locales.ErrInvalidValue()
+	}
+
+	var val string
+	switch v := src.(type) {
+	case string:
+		val = v
+	case []byte:
+		val = string(v)
+	case []rune:
+		val = string(v)
+	default:
+		return locales.ErrInvalidValue()
+	}
+
+	v, err := ParseMetricPeriod(val)
+	if err != nil {
+		return err
+	}
+
+	*p = v
+	return nil
+}
+
+// Value driver.Valuer
+func (p MetricPeriod) Value() (driver.Value, error) {
+	v, err := p.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return string(v), nil
+}
+
+func MetricPeriodValidator(v MetricPeriod) bool { return v.IsValid() }
+
+var (
+	MetricPeriodRule = filter.V(MetricPeriodValidator, locales.InvalidValue)
+
+	MetricPeriodSliceRule = filter.SV[[]MetricPeriod](MetricPeriodValidator, locales.InvalidValue)
+
+	MetricPeriodFilter = filter.NewBuilder(MetricPeriodRule)
+
+	MetricPeriodSliceFilter = filter.NewBuilder(MetricPeriodSliceRule)
+)
+
+func (MetricPeriod) OpenAPISchema(s *openapi.Schema) {
+	s.Type = openapi.TypeString
+	s.Enum = []any{MetricPeriodDay.String(), MetricPeriodHour.String(), MetricPeriodMinute.String()}
+}
+
+//--------------------- end MetricPeriod --------------------
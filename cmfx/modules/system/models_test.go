@@ -1,4 +1,4 @@
-// SPDX-FileCopyrightText: 2022-2024 caixw
+// SPDX-FileCopyrightText: 2022-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
@@ -9,4 +9,5 @@ import "github.com/issue9/orm/v6"
 var (
 	_ orm.TableNamer    = &healthPO{}
 	_ orm.BeforeUpdater = &healthPO{}
+	_ orm.TableNamer    = &healthMetricPO{}
 )
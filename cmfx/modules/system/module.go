@@ -1,4 +1,4 @@
-// SPDX-FileCopyrightText: 2022-2025 caixw
+// SPDX-FileCopyrightText: 2022-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
@@ -18,13 +18,19 @@ import (
 	"github.com/issue9/cmfx/cmfx"
 	"github.com/issue9/cmfx/cmfx/filters"
 	"github.com/issue9/cmfx/cmfx/modules/admin"
+	"github.com/issue9/cmfx/cmfx/outbox"
+	"github.com/issue9/cmfx/cmfx/query"
+	"github.com/issue9/cmfx/cmfx/user"
 	"github.com/issue9/cmfx/cmfx/user/settings"
 )
 
 type Module struct {
-	mod    *cmfx.Module
-	admin  *admin.Module
-	health *health.Health
+	mod     *cmfx.Module
+	admin   *admin.Module
+	health  *health.Health
+	metrics *healthMetrics
+
+	auditLogs *AuditLogs
 
 	stats   events.Subscriber[*systat.Stats]
 	cancels map[int64]context.CancelFunc
@@ -35,6 +41,11 @@ type Module struct {
 
 	// 若配置中未设置，则以下字段为空
 	backupConfig *Backup
+	prometheus   *Prometheus
+	alerts       *alerts
+
+	// 若未声明 [outbox.Outbox]，则为空
+	outbox *outbox.Outbox
 }
 
 // Load 加载当前模块
@@ -47,28 +58,37 @@ func Load(mod *cmfx.Module, conf *Config, adminL *admin.Module) *Module {
 	}
 
 	m := &Module{
-		mod:    mod,
-		admin:  adminL,
-		health: health.New(store),
+		mod:     mod,
+		admin:   adminL,
+		health:  health.New(store),
+		metrics: newHealthMetrics(mod.DB(), conf.Metrics),
+
+		auditLogs: conf.AuditLogs,
 
 		stats:   systat.Init(mod.Server(), time.Minute, 20),
 		cancels: map[int64]context.CancelFunc{},
 
 		settings: settings.New(mod, settingsTableName),
 	}
-	general, err := settings.LoadObject[generalSettings](m.settings, generalSettingName, time.Hour)
+	general, err := settings.LoadObject[generalSettings](m.settings, generalSettingName, web.Phrase("general setting"), time.Hour)
 	if err != nil {
 		panic(web.SprintError(mod.Server().Locale().Printer(), true, err))
 	}
 	m.generalSettings = general
 
-	audit, err := settings.LoadObject[filters.Config](m.settings, auditSettingName, time.Hour)
+	audit, err := settings.LoadObject[filters.Config](m.settings, auditSettingName, web.Phrase("audit setting"), time.Hour)
 	if err != nil {
 		panic(web.SprintError(mod.Server().Locale().Printer(), true, err))
 	}
 	m.auditSettings = audit
 
 	mod.Server().Use(m.health)
+	mod.Server().OnExitContext(m.recordMetric)
+	mod.Server().Services().AddTicker(web.Phrase("flush api metrics"), m.metrics.flush, time.Minute, false, false)
+	mod.Server().OnClose(func() error { return m.metrics.flush(time.Now()) })
+
+	adminL.OnOperation(m.addAuditLog)
+	mod.Server().Services().AddCron(web.Phrase("prune audit logs"), m.pruneAuditLogs, "@daily", true)
 
 	g := adminL.NewResourceGroup(mod)
 	resGetInfo := g.New("get-info", web.Phrase("view system info"))
@@ -78,8 +98,9 @@ func Load(mod *cmfx.Module, conf *Config, adminL *admin.Module) *Module {
 	resBackup := g.New("backup", web.Phrase("backup database"))
 	resGetBackup := g.New("get-backup", web.Phrase("get backup database list"))
 	resDelBackup := g.New("del-backup", web.Phrase("del backup database file"))
-	resSettingsGeneral := g.New("setting-general", web.Phrase("general setting"))
-	resSettingsAudit := g.New("setting-audit", web.Phrase("audit setting"))
+	resRestoreBackup := g.New("restore-backup", web.Phrase("restore database from backup file"))
+	resGetAuditLogs := g.New("get-audit-logs", web.Phrase("view audit logs"))
+	resDelAuditLogs := g.New("del-audit-logs", web.Phrase("delete audit logs"))
 
 	api := adminL.UserModule().Module().API
 	r := adminL.UserModule().Module().Router().Prefix(adminL.URLPrefix()+conf.URLPrefix, m.admin)
@@ -98,6 +119,12 @@ func Load(mod *cmfx.Module, conf *Config, adminL *admin.Module) *Module {
 				Response200([]health.State{}).
 				Desc(web.Phrase("get routes list api"), nil)
 		})).
+		Get("/routes/metrics", m.adminGetRouteMetrics, resGetAPIs, api(func(o *openapi.Operation) {
+			o.Tag("system").
+				QueryObject(metricsQuery{}, nil).
+				Response200([]metricSeriesVO{}).
+				Desc(web.Phrase("get routes metrics api"), nil)
+		})).
 		Post("/systat", m.adminPostSystat, resGetStat, mod.API(func(o *openapi.Operation) {
 			o.Tag("system", "systat", "sse").
 				Desc(web.Phrase("subscribe system stat api"), nil).
@@ -115,25 +142,35 @@ func Load(mod *cmfx.Module, conf *Config, adminL *admin.Module) *Module {
 				Desc(web.Phrase("unsubscribe system stat api"), nil).
 				ResponseEmpty("204")
 		})).
-		Get("/settings/general", m.adminGetSettingGeneral, resSettingsGeneral, mod.API(func(o *openapi.Operation) {
-			o.Tag("settings", "system").
-				Response200(generalSettings{})
+		Get("/audit-logs", m.adminGetAuditLogs, resGetAuditLogs, mod.API(func(o *openapi.Operation) {
+			o.Tag("system", "audit-log").
+				Desc(web.Phrase("get audit logs api"), nil).
+				QueryObject(auditLogQuery{}, nil).
+				Response200(query.Page[auditLogVO]{})
 		})).
-		Put("/settings/general", m.adminPutSettingGeneral, resSettingsGeneral, mod.API(func(o *openapi.Operation) {
-			o.Tag("settings", "system").
-				Body(generalSettings{}, false, nil, nil).
-				ResponseEmpty("204")
+		Get("/audit-logs/csv", m.adminGetAuditLogsCSV, resGetAuditLogs, mod.API(func(o *openapi.Operation) {
+			o.Tag("system", "audit-log").
+				Desc(web.Phrase("export audit logs as csv api"), nil).
+				QueryObject(auditLogQuery{}, nil).
+				Response200("")
 		})).
-		Get("/settings/audit", m.adminGetSettingAudit, resSettingsAudit, mod.API(func(o *openapi.Operation) {
-			o.Tag("settings", "system").
-				Response200(filters.Config{})
+		Get("/audit-logs/retention", m.adminGetAuditLogsRetention, resGetAuditLogs, mod.API(func(o *openapi.Operation) {
+			o.Tag("system", "audit-log").
+				Desc(web.Phrase("get audit logs retention api"), nil).
+				Response200(auditLogRetentionVO{})
 		})).
-		Put("/settings/audit", m.adminPutSettingAudit, resSettingsAudit, mod.API(func(o *openapi.Operation) {
-			o.Tag("settings", "system").
-				Body(filters.Config{}, false, nil, nil).
+		Delete("/audit-logs", m.adminDeleteAuditLogs, resDelAuditLogs, mod.API(func(o *openapi.Operation) {
+			o.Tag("system", "audit-log").
+				Desc(web.Phrase("delete audit logs before the specified time api"), nil).
+				QueryObject(pruneAuditLogsQuery{}, nil).
 				ResponseEmpty("204")
 		}))
 
+	// 系统设置均属于默认用户
+	m.settings.Handle(r.Prefix("/settings"), mod.API, g.New,
+		func(*web.Context) int64 { return user.SpecialUserID },
+		func(ctx *web.Context) int64 { return adminL.CurrentUser(ctx).ID })
+
 	mod.Router().Prefix(conf.URLPrefix).Get("/problems", m.commonGetProblems, mod.OpenAPI().API(func(o *openapi.Operation) {
 		o.Tag("system", "common").
 			Desc(web.Phrase("get system problems api"), nil).
@@ -143,9 +180,7 @@ func Load(mod *cmfx.Module, conf *Config, adminL *admin.Module) *Module {
 	if conf.Backup != nil {
 		m.backupConfig = conf.Backup
 
-		mod.Server().Services().AddCron(web.Phrase("backup database"), func(now time.Time) error {
-			return mod.DB().Backup(m.backupConfig.buildFile(now))
-		}, conf.Backup.Cron, true)
+		mod.Server().Services().AddCron(web.Phrase("backup database"), m.backup, conf.Backup.Cron, true)
 
 		r.Post("/backup", m.adminPostBackup, resBackup, mod.API(func(o *openapi.Operation) {
 			o.Tag("system").
@@ -162,6 +197,72 @@ func Load(mod *cmfx.Module, conf *Config, adminL *admin.Module) *Module {
 					Desc(web.Phrase("delete backup file api"), nil).
 					Path("name", openapi.TypeString, web.Phrase("the backup filename"), nil).
 					ResponseEmpty("204")
+			})).
+			Post("/backup/{name}/restore", m.adminPostRestore, resRestoreBackup, mod.API(func(o *openapi.Operation) {
+				o.Tag("system").
+					Desc(web.Phrase("restore backup file api"), nil).
+					Path("name", openapi.TypeString, web.Phrase("the backup filename"), nil).
+					Response200(restoreVO{})
+			}))
+	}
+
+	if conf.Prometheus != nil {
+		m.prometheus = conf.Prometheus
+		mod.Router().Get(conf.Prometheus.Path, m.getMetrics, mod.API(func(o *openapi.Operation) {
+			o.Tag("system").
+				Desc(web.Phrase("get openmetrics api"), nil).
+				Response("200", nil, nil, func(r *openapi.Response) {
+					r.Body = nil
+					r.Content = map[string]*openapi.Schema{
+						openMetricsContentType: {Type: openapi.TypeString},
+					}
+				})
+		}))
+	}
+
+	if conf.Alerts != nil {
+		m.alerts = newAlerts(m, conf.Alerts)
+		mod.Server().Services().AddTicker(web.Phrase("evaluate alert rules"), m.alerts.evaluate, conf.Alerts.Interval.Duration(), false, true)
+
+		resGetAlerts := g.New("get-alerts", web.Phrase("view alerts"))
+		resSilenceAlert := g.New("silence-alert", web.Phrase("silence alert rule"))
+
+		r.Get("/alerts", m.adminGetAlerts, resGetAlerts, mod.API(func(o *openapi.Operation) {
+			o.Tag("system", "alert").
+				Desc(web.Phrase("get alerts api"), nil).
+				Response200([]Alert{})
+		})).
+			Post("/alerts/{rule}/silence", m.adminPostAlertSilence, resSilenceAlert, mod.API(func(o *openapi.Operation) {
+				o.Tag("system", "alert").
+					Desc(web.Phrase("silence alert rule api"), nil).
+					Path("rule", openapi.TypeString, web.Phrase("the alert rule name"), nil).
+					Body(silenceTO{}, false, nil, nil).
+					ResponseEmpty("204")
+			})).
+			Delete("/alerts/{rule}/silence", m.adminDeleteAlertSilence, resSilenceAlert, mod.API(func(o *openapi.Operation) {
+				o.Tag("system", "alert").
+					Desc(web.Phrase("cancel the silence of alert rule api"), nil).
+					Path("rule", openapi.TypeString, web.Phrase("the alert rule name"), nil).
+					ResponseEmpty("204")
+			}))
+	}
+
+	if ob := outbox.Get(mod.Server()); ob != nil {
+		m.outbox = ob
+		resGetOutbox := g.New("get-outbox", web.Phrase("view outbox deliveries"))
+		resRetryOutbox := g.New("retry-outbox", web.Phrase("retry outbox delivery"))
+
+		r.Get("/outbox/deliveries", ob.HandleGetDeliveries, resGetOutbox, mod.API(func(o *openapi.Operation) {
+			o.Tag("system", "outbox").
+				Desc(web.Phrase("get outbox deliveries api"), nil).
+				QueryObject(outbox.DeliveryQuery{}, nil).
+				Response200(query.Page[outbox.DeliveryVO]{})
+		})).
+			Post("/outbox/deliveries/{id:digit}/retry", m.adminPostOutboxRetry, resRetryOutbox, mod.API(func(o *openapi.Operation) {
+				o.Tag("system", "outbox").
+					Desc(web.Phrase("retry outbox delivery api"), nil).
+					PathID("id:digit", web.Phrase("the delivery id")).
+					ResponseEmpty("201")
 			}))
 	}
 
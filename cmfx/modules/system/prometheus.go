@@ -0,0 +1,209 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package system
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"net/netip"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/issue9/mux/v9/header"
+	"github.com/issue9/web"
+	"github.com/issue9/webuse/v7/middlewares/auth"
+)
+
+const openMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+var labelReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// 生成 OpenMetrics 格式的文本
+//
+// https://github.com/prometheus/OpenMetrics/blob/main/specification/OpenMetrics.md
+type openMetrics struct {
+	buf *bytes.Buffer
+}
+
+// 声明指标
+//
+// typ 可以是 counter、gauge 和 stateset 等，counter 类型的 name 不能带 _total 后缀。
+func (m *openMetrics) family(name, typ, help string) *openMetrics {
+	m.buf.WriteString("# TYPE " + name + " " + typ + "\n")
+	m.buf.WriteString("# HELP " + name + " " + help + "\n")
+	return m
+}
+
+// 写入一条数据
+//
+// labels 为标签的名称和值，依次交替出现。
+func (m *openMetrics) sample(name string, v float64, labels ...string) *openMetrics {
+	m.buf.WriteString(name)
+	if len(labels) > 0 {
+		m.buf.WriteByte('{')
+		for i := 0; i < len(labels); i += 2 {
+			if i > 0 {
+				m.buf.WriteByte(',')
+			}
+			m.buf.WriteString(labels[i] + `="` + labelReplacer.Replace(labels[i+1]) + `"`)
+		}
+		m.buf.WriteByte('}')
+	}
+	m.buf.WriteString(" " + strconv.FormatFloat(v, 'g', -1, 64) + "\n")
+	return m
+}
+
+func (m *openMetrics) bytes() []byte {
+	m.buf.WriteString("# EOF\n")
+	return m.buf.Bytes()
+}
+
+// 客户端是否有权限访问监控数据
+//
+// IP 以 [http.Request.RemoteAddr] 为准，不采用可伪造的 X-Forwarded-For 等报头。
+func (p *Prometheus) allowed(ctx *web.Context) bool {
+	if p.Token != "" {
+		if token := auth.GetBearerToken(ctx, header.Authorization); token != "" &&
+			subtle.ConstantTimeCompare([]byte(token), []byte(p.Token)) == 1 {
+			return true
+		}
+	}
+
+	if len(p.prefixes) == 0 {
+		return false
+	}
+
+	addr, err := netip.ParseAddrPort(ctx.Request().RemoteAddr)
+	if err != nil {
+		return false
+	}
+	ip := addr.Addr().Unmap()
+	for _, prefix := range p.prefixes {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Module) getMetrics(ctx *web.Context) web.Responser {
+	if !m.prometheus.allowed(ctx) {
+		return ctx.Problem(web.ProblemForbidden)
+	}
+
+	data, err := m.buildMetrics(ctx)
+	if err != nil {
+		return ctx.Error(err, "")
+	}
+
+	return web.ResponserFunc(func(ctx *web.Context) {
+		ctx.Header().Set(header.ContentType, openMetricsContentType)
+		if _, err := ctx.Write(data); err != nil {
+			ctx.Logs().ERROR().Error(err)
+		}
+	})
+}
+
+func (m *Module) buildMetrics(ctx *web.Context) ([]byte, error) {
+	u := m.admin.UserModule()
+	users, err := u.Statistic(ctx.Begin())
+	if err != nil {
+		return nil, err
+	}
+
+	w := &openMetrics{buf: &bytes.Buffer{}}
+
+	// API
+	states := m.health.States()
+	w.family("cmfx_http_requests", "counter", "Total number of requests.")
+	for _, s := range states {
+		w.sample("cmfx_http_requests_total", float64(s.Count), "router", s.Router, "method", s.Method, "pattern", s.Pattern)
+	}
+	w.family("cmfx_http_user_errors", "counter", "Total number of requests with 4xx status.")
+	for _, s := range states {
+		w.sample("cmfx_http_user_errors_total", float64(s.UserErrors), "router", s.Router, "method", s.Method, "pattern", s.Pattern)
+	}
+	w.family("cmfx_http_server_errors", "counter", "Total number of requests with 5xx status.")
+	for _, s := range states {
+		w.sample("cmfx_http_server_errors_total", float64(s.ServerErrors), "router", s.Router, "method", s.Method, "pattern", s.Pattern)
+	}
+	w.family("cmfx_http_request_seconds", "counter", "Total time spent on requests.")
+	for _, s := range states {
+		w.sample("cmfx_http_request_seconds_total", s.Spend.Seconds(), "router", s.Router, "method", s.Method, "pattern", s.Pattern)
+	}
+	w.family("cmfx_http_request_min_seconds", "gauge", "Minimum time spent on a request.")
+	for _, s := range states {
+		w.sample("cmfx_http_request_min_seconds", s.Min.Seconds(), "router", s.Router, "method", s.Method, "pattern", s.Pattern)
+	}
+	w.family("cmfx_http_request_max_seconds", "gauge", "Maximum time spent on a request.")
+	for _, s := range states {
+		w.sample("cmfx_http_request_max_seconds", s.Max.Seconds(), "router", s.Router, "method", s.Method, "pattern", s.Pattern)
+	}
+
+	// 数据库
+	db := m.mod.DB().Stats()
+	w.family("cmfx_db_max_open_connections", "gauge", "Maximum number of open connections to the database.").
+		sample("cmfx_db_max_open_connections", float64(db.MaxOpenConnections))
+	w.family("cmfx_db_open_connections", "gauge", "The number of established connections both in use and idle.").
+		sample("cmfx_db_open_connections", float64(db.OpenConnections))
+	w.family("cmfx_db_in_use_connections", "gauge", "The number of connections currently in use.").
+		sample("cmfx_db_in_use_connections", float64(db.InUse))
+	w.family("cmfx_db_idle_connections", "gauge", "The number of idle connections.").
+		sample("cmfx_db_idle_connections", float64(db.Idle))
+	w.family("cmfx_db_waits", "counter", "The total number of connections waited for.").
+		sample("cmfx_db_waits_total", float64(db.WaitCount))
+	w.family("cmfx_db_wait_seconds", "counter", "The total time blocked waiting for a new connection.").
+		sample("cmfx_db_wait_seconds_total", db.WaitDuration.Seconds())
+	w.family("cmfx_db_max_idle_closed", "counter", "The total number of connections closed due to SetMaxIdleConns.").
+		sample("cmfx_db_max_idle_closed_total", float64(db.MaxIdleClosed))
+	w.family("cmfx_db_max_idle_time_closed", "counter", "The total number of connections closed due to SetConnMaxIdleTime.").
+		sample("cmfx_db_max_idle_time_closed_total", float64(db.MaxIdleTimeClosed))
+	w.family("cmfx_db_max_lifetime_closed", "counter", "The total number of connections closed due to SetConnMaxLifetime.").
+		sample("cmfx_db_max_lifetime_closed_total", float64(db.MaxLifetimeClosed))
+
+	w.family("cmfx_goroutines", "gauge", "Number of goroutines that currently exist.").
+		sample("cmfx_goroutines", float64(runtime.NumGoroutine()))
+
+	// 用户
+	w.family("cmfx_user_logins", "counter", "Total number of logins by passport.")
+	for _, s := range u.LoginStats() {
+		w.sample("cmfx_user_logins_total", float64(s.Success), "passport", s.Passport, "result", "success").
+			sample("cmfx_user_logins_total", float64(s.Failure), "passport", s.Passport, "result", "failure")
+	}
+	w.family("cmfx_users_online", "gauge", "Number of users logged in within 10 minutes.").
+		sample("cmfx_users_online", float64(users.Online))
+	w.family("cmfx_users_active", "gauge", "Number of users logged in within 30 days.").
+		sample("cmfx_users_active", float64(users.Active))
+	w.family("cmfx_users", "gauge", "Number of all users.").
+		sample("cmfx_users", float64(users.All))
+
+	w.family("cmfx_sse_connections", "gauge", "Number of SSE connections.").
+		sample("cmfx_sse_connections", float64(m.admin.SSE().Len()))
+
+	// 计划任务
+	p := ctx.Server().Locale().Printer()
+	jobs := make([]*web.Job, 0, 10)
+	ctx.Server().Services().VisitJobs(func(j *web.Job) { jobs = append(jobs, j) })
+	w.family("cmfx_job_state", "stateset", "The state of the scheduled job.")
+	for _, j := range jobs {
+		title := j.Title().LocaleString(p)
+		for _, s := range []web.State{web.Stopped, web.Running, web.Failed} {
+			var v float64
+			if j.State() == s {
+				v = 1
+			}
+			w.sample("cmfx_job_state", v, "job", title, "cmfx_job_state", stateStrings[s])
+		}
+	}
+	w.family("cmfx_job_last_run_timestamp_seconds", "gauge", "The last time the scheduled job was run.")
+	for _, j := range jobs {
+		if !j.Prev().IsZero() {
+			w.sample("cmfx_job_last_run_timestamp_seconds", float64(j.Prev().Unix()), "job", j.Title().LocaleString(p))
+		}
+	}
+
+	return w.bytes(), nil
+}
@@ -0,0 +1,92 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package system
+
+import (
+	"bytes"
+	"net/http"
+	"net/netip"
+	"testing"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/mux/v9/header"
+	"github.com/issue9/web/server/servertest"
+	"github.com/issue9/webuse/v7/middlewares/auth"
+
+	"github.com/issue9/cmfx/cmfx/initial/test"
+	"github.com/issue9/cmfx/cmfx/modules/admin/admintest"
+)
+
+func TestPrometheus_SanitizeConfig(t *testing.T) {
+	a := assert.New(t, false)
+
+	p := &Prometheus{}
+	a.Equal(p.SanitizeConfig().Field, "token")
+
+	p = &Prometheus{Path: "metrics", Token: "t"}
+	a.Equal(p.SanitizeConfig().Field, "path")
+
+	p = &Prometheus{IPs: []string{"10.0.0.0/8", "abc"}}
+	a.Equal(p.SanitizeConfig().Field, "ips[1]")
+
+	p = &Prometheus{IPs: []string{"10.1.0.0/8", "127.0.0.1", "::1"}}
+	a.NotError(p.SanitizeConfig()).
+		Equal(p.Path, "/metrics").
+		Equal(p.prefixes, []netip.Prefix{
+			netip.MustParsePrefix("10.0.0.0/8"),
+			netip.MustParsePrefix("127.0.0.1/32"),
+			netip.MustParsePrefix("::1/128"),
+		})
+}
+
+func TestOpenMetrics(t *testing.T) {
+	a := assert.New(t, false)
+
+	w := &openMetrics{buf: &bytes.Buffer{}}
+	w.family("c", "counter", "help").
+		sample("c_total", 1.5, "l1", `v"1`, "l2", "v\\2\n").
+		sample("c_total", 2)
+	a.Equal(string(w.bytes()), `# TYPE c counter
+# HELP c help
+c_total{l1="v\"1",l2="v\\2\n"} 1.5
+c_total 2
+# EOF
+`)
+}
+
+func TestModule_getMetrics(t *testing.T) {
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+
+	conf := &Config{Prometheus: &Prometheus{Token: "token", IPs: []string{"10.0.0.0/8"}}}
+	a.NotError(conf.SanitizeConfig())
+	l := Install(s.NewModule("test"), conf, admintest.NewModule(s))
+
+	defer servertest.Run(a, s.Module().Server())()
+	defer s.Close()
+
+	s.Get("/metrics").Do(nil).Status(http.StatusForbidden)
+	s.Get("/metrics").
+		Header(header.Authorization, auth.BuildToken(auth.Bearer, "invalid")).
+		Do(nil).
+		Status(http.StatusForbidden)
+
+	s.Get("/metrics").
+		Header(header.Authorization, auth.BuildToken(auth.Bearer, "token")).
+		Do(nil).
+		Status(http.StatusOK).
+		Header(header.ContentType, openMetricsContentType).
+		BodyFunc(func(a *assert.Assertion, body []byte) {
+			a.True(bytes.Contains(body, []byte(`method="GET",pattern="/metrics"} `))).
+				True(bytes.Contains(body, []byte(`cmfx_user_logins_total{passport="password",result="success"} 0`))).
+				True(bytes.Contains(body, []byte("\ncmfx_sse_connections 0\n"))).
+				True(bytes.Contains(body, []byte("\ncmfx_users "))).
+				True(bytes.HasSuffix(body, []byte("# EOF\n")))
+		})
+
+	// IP 在白名单中
+	l.prometheus.prefixes = append(l.prometheus.prefixes, netip.MustParsePrefix("127.0.0.0/8"), netip.MustParsePrefix("::1/128"))
+	s.Get("/metrics").Do(nil).Status(http.StatusOK)
+}
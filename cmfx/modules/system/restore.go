@@ -0,0 +1,110 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package system
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	xm "github.com/go-sql-driver/mysql"
+	"github.com/issue9/web"
+)
+
+// DBRestorer 生成用于 [Backup.Restore] 的函数
+//
+// typ 和 dsn 为数据库的类型和连接参数，typ 可以是 sqlite3、sqlite、mysql、mariadb 和 postgres。
+// 其中 mysql 和 mariadb 依赖 mysql 命令，postgres 依赖 pg_restore 命令。
+// offline 表示恢复时需要停止服务，对应 [Backup.Offline]。
+//
+// NOTE: sqlite 是直接替换数据库文件，只能在服务停止的情况下恢复。
+func DBRestorer(typ, dsn string) (restore func(string) error, offline bool) {
+	switch typ {
+	case "sqlite3", "sqlite":
+		return func(src string) error { return restoreSqlite(dsn, src) }, true
+	case "mysql", "mariadb":
+		return func(src string) error { return restoreMysql(dsn, src) }, false
+	case "postgres":
+		return func(src string) error { return restorePostgres(dsn, src) }, false
+	default:
+		return nil, false
+	}
+}
+
+// 以 src 替换 dsn 指向的数据库文件
+//
+// 先写入同目录下的临时文件再重命名，保证数据库文件始终是完整的；
+// 同时删除 -wal 和 -shm 文件，防止旧数据库的日志被应用到新的数据库。
+func restoreSqlite(dsn, src string) (err error) {
+	if index := strings.IndexByte(dsn, '?'); index >= 0 {
+		dsn = dsn[:index]
+	}
+	dsn = strings.TrimPrefix(dsn, "file:")
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { err = errors.Join(err, in.Close()) }()
+
+	tmp, err := os.CreateTemp(filepath.Dir(dsn), "."+filepath.Base(dsn)+".*.restore")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // 重命名成功之后不再存在
+
+	_, err = io.Copy(tmp, in)
+	if err = errors.Join(err, tmp.Sync(), tmp.Close()); err != nil {
+		return err
+	}
+
+	for _, ext := range []string{"-wal", "-shm"} {
+		if err := os.Remove(dsn + ext); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return os.Rename(tmp.Name(), dsn)
+}
+
+func restoreMysql(dsn, src string) (err error) {
+	conf, err := xm.ParseDSN(dsn)
+	if err != nil {
+		return err
+	}
+	if conf.DBName == "" {
+		return web.NewLocaleError("dsn not specified database name")
+	}
+
+	h, p, err := net.SplitHostPort(conf.Addr)
+	if err != nil {
+		return err
+	}
+
+	input, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { err = errors.Join(err, input.Close()) }()
+
+	cmd := exec.Command("mysql", "--host="+h, "--port="+p, "--protocol="+conf.Net, "--user="+conf.User, conf.DBName)
+	cmd.Env = append(os.Environ(), "MYSQL_PWD="+conf.Passwd) // 不在命令行中暴露密码
+	cmd.Stdin = input
+	cmd.Stdout = io.Discard
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// 备份文件由 pg_dump --format=c 生成
+func restorePostgres(dsn, src string) error {
+	cmd := exec.Command("pg_restore", "--clean", "--if-exists", "--no-owner", "--dbname="+dsn, src)
+	cmd.Stdout = io.Discard
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,53 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package system
+
+import (
+	"os"
+	"testing"
+
+	"github.com/issue9/assert/v4"
+)
+
+func TestDBRestorer(t *testing.T) {
+	a := assert.New(t, false)
+
+	r, offline := DBRestorer("sqlite3", "./test.db")
+	a.NotNil(r).True(offline)
+
+	r, offline = DBRestorer("mysql", "root@/test")
+	a.NotNil(r).False(offline)
+
+	r, offline = DBRestorer("postgres", "postgres://localhost/test")
+	a.NotNil(r).False(offline)
+
+	r, offline = DBRestorer("unknown", "")
+	a.Nil(r).False(offline)
+}
+
+func TestRestoreSqlite(t *testing.T) {
+	a := assert.New(t, false)
+	defer os.RemoveAll("./testdata")
+
+	a.NotError(os.MkdirAll("./testdata/db", os.ModePerm))
+	a.NotError(os.WriteFile("./testdata/db/app.db", []byte("old"), 0o644))
+	a.NotError(os.WriteFile("./testdata/db/app.db-wal", []byte("wal"), 0o644))
+	a.NotError(os.WriteFile("./testdata/db/app.db-shm", []byte("shm"), 0o644))
+	a.NotError(os.WriteFile("./testdata/src.db", []byte("new"), 0o644))
+
+	a.NotError(restoreSqlite("file:./testdata/db/app.db?_pragma=journal_mode(WAL)", "./testdata/src.db"))
+	data, err := os.ReadFile("./testdata/db/app.db")
+	a.NotError(err).Equal(string(data), "new")
+	a.FileNotExists("./testdata/db/app.db-wal").
+		FileNotExists("./testdata/db/app.db-shm")
+
+	entries, err := os.ReadDir("./testdata/db") // 没有残留的临时文件
+	a.NotError(err).Length(entries, 1)
+
+	// 源文件不存在，不影响原有的数据库。
+	a.Error(restoreSqlite("./testdata/db/app.db", "./testdata/not-exists.db"))
+	data, err = os.ReadFile("./testdata/db/app.db")
+	a.NotError(err).Equal(string(data), "new")
+}
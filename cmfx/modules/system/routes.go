@@ -6,10 +6,11 @@ package system
 
 import (
 	"encoding/json"
-	"os"
+	"errors"
+	"io/fs"
+	"net/http"
 	"path/filepath"
 	"runtime"
-	"strings"
 	"time"
 
 	"github.com/fxamacker/cbor/v2"
@@ -20,11 +21,35 @@ import (
 	"github.com/shirou/gopsutil/v4/host"
 
 	"github.com/issue9/cmfx/cmfx"
-	"github.com/issue9/cmfx/cmfx/user"
+	"github.com/issue9/cmfx/cmfx/filters"
 )
 
 func (m *Module) adminGetRoutes(*web.Context) web.Responser { return web.OK(m.health.States()) }
 
+func (m *Module) adminGetRouteMetrics(ctx *web.Context) web.Responser {
+	q := &metricsQuery{}
+	if resp := ctx.QueryObject(true, q, cmfx.BadRequestInvalidQuery); resp != nil {
+		return resp
+	}
+	q.sanitize(ctx.Begin())
+
+	list, err := m.metrics.series(q)
+	if err != nil {
+		return ctx.Error(err, "")
+	}
+	return web.OK(list)
+}
+
+// 记录 API 的统计数据，与 health 插件一样只统计实际存在的接口。
+func (m *Module) recordMetric(ctx *web.Context, status int) {
+	method := ctx.Request().Method
+	route := ctx.Route()
+	if route.Node() == nil || method == http.MethodOptions || method == http.MethodHead {
+		return
+	}
+	m.metrics.record(route.RouterName(), method, route.Node().Pattern(), ctx.Begin(), time.Since(ctx.Begin()), status)
+}
+
 // 数据库的基本信息
 type dbVO struct {
 	Name               string        `json:"name" cbor:"name" yaml:"name"`                                           // 数据库驱动
@@ -241,8 +266,20 @@ func (m *Module) adminDeleteSystat(ctx *web.Context) web.Responser {
 	return web.NoContent()
 }
 
+// 备份数据并按保留策略清理旧的备份
+func (m *Module) backup(now time.Time) error {
+	_, err := m.backupConfig.backup(m.mod.DB(), m.backupConfig.buildFile(now))
+	if m.alerts != nil {
+		m.alerts.setBackupError(err)
+	}
+	if err != nil {
+		return err
+	}
+	return m.backupConfig.prune()
+}
+
 func (m *Module) adminPostBackup(ctx *web.Context) web.Responser {
-	if err := m.mod.DB().Backup(m.backupConfig.buildFile(ctx.Begin())); err != nil {
+	if err := m.backup(ctx.Begin()); err != nil {
 		return ctx.Error(err, web.ProblemInternalServerError)
 	}
 	return web.Created(nil, "")
@@ -256,11 +293,9 @@ func (m *Module) adminDeleteBackup(ctx *web.Context) web.Responser {
 
 	// 防止用户传递 ../ 等格式的数据以造成误删。
 	// 配置中已经限制了备份文件不能包含目录结构。
-	if strings.ContainsAny(p, "/"+string(os.PathSeparator)) {
+	if err := m.backupConfig.remove(p); errors.Is(err, fs.ErrNotExist) {
 		return ctx.NotFound()
-	}
-
-	if err := os.Remove(filepath.Join(m.backupConfig.Dir, p)); err != nil {
+	} else if err != nil {
 		return ctx.Error(err, "")
 	}
 	return web.NoContent()
@@ -272,48 +307,99 @@ type backupListVO struct {
 }
 
 type backupFileVO struct {
-	Path string    `json:"path" cbor:"path" yaml:"path"`
-	Size int       `json:"size" cbor:"size" yaml:"size"`
-	Mod  time.Time `json:"mod" cbor:"mod" yaml:"mod"`
+	Path     string    `json:"path" cbor:"path" yaml:"path"`
+	Size     int       `json:"size" cbor:"size" yaml:"size"`
+	Mod      time.Time `json:"mod" cbor:"mod" yaml:"mod"`
+	Manifest string    `json:"manifest,omitempty" cbor:"manifest,omitempty" yaml:"manifest,omitempty"` // 校验清单，为空表示没有。
+	Uploads  string    `json:"uploads,omitempty" cbor:"uploads,omitempty" yaml:"uploads,omitempty"`    // 上传文件的归档，为空表示没有。
 }
 
 func (m *Module) adminGetBackup(ctx *web.Context) web.Responser {
-	entries, err := os.ReadDir(m.backupConfig.Dir)
+	list, err := m.backupConfig.list()
 	if err != nil {
 		return ctx.Error(err, "")
 	}
 
-	list := make([]*backupFileVO, 0, len(entries))
-	for _, e := range entries {
-		if !e.IsDir() {
-			info, err := e.Info()
-			if err != nil {
-				ctx.Server().Logs().ERROR().Error(err)
-				list = append(list, &backupFileVO{Path: e.Name()})
-				continue
-			}
-			list = append(list, &backupFileVO{Path: e.Name(), Mod: info.ModTime(), Size: int(info.Size())})
-		}
-	}
-
 	return web.OK(&backupListVO{
 		Cron: m.backupConfig.Cron,
 		List: list,
 	})
 }
 
-func (m *Module) adminGetSettingGeneral(ctx *web.Context) web.Responser {
-	return m.generalSettings.HandleGet(ctx, user.SpecialUserID)
+type restoreVO struct {
+	// 恢复之前对当前数据的备份
+	Backup string `json:"backup" cbor:"backup" yaml:"backup"`
+}
+
+func (m *Module) adminPostRestore(ctx *web.Context) web.Responser {
+	name, resp := ctx.PathString("name", "")
+	if resp != nil {
+		return resp
+	}
+
+	if m.backupConfig.Offline {
+		return ctx.Error(errRestoreOffline, web.ProblemNotImplemented)
+	}
+
+	safety, err := m.backupConfig.restore(m.mod.DB(), name, ctx.Begin())
+	if err != nil && safety != "" { // 恢复失败，需要告知管理员可以从哪个文件还原数据。
+		ctx.Server().Logs().ERROR().With("safety", safety).Error(err)
+	}
+	switch {
+	case errors.Is(err, errRestoreNotSupported):
+		return ctx.NotImplemented()
+	case errors.Is(err, fs.ErrNotExist):
+		return ctx.NotFound()
+	case errors.Is(err, errChecksumMismatch):
+		return ctx.Error(err, web.ProblemUnprocessableEntity)
+	case err != nil:
+		return ctx.Error(err, "")
+	}
+
+	ctx.Server().Logs().WARN().With("backup", name).With("safety", safety).
+		LocaleString(web.Phrase("database has been restored"))
+	return web.OK(&restoreVO{Backup: filepath.Base(safety)})
+}
+
+func (m *Module) adminPostOutboxRetry(ctx *web.Context) web.Responser {
+	return m.outbox.HandlePostRetry(ctx, "id")
+}
+
+func (m *Module) adminGetAlerts(*web.Context) web.Responser { return web.OK(m.alerts.list()) }
+
+type silenceTO struct {
+	Until time.Time `json:"until" yaml:"until" cbor:"until" comment:"silence until"` // 静默的截止时间
 }
 
-func (m *Module) adminPutSettingGeneral(ctx *web.Context) web.Responser {
-	return m.generalSettings.HandlePut(ctx, user.SpecialUserID)
+func (to *silenceTO) Filter(ctx *web.FilterContext) {
+	ctx.Add(filters.NotZero[time.Time]()("until", &to.Until))
 }
 
-func (m *Module) adminGetSettingAudit(ctx *web.Context) web.Responser {
-	return m.auditSettings.HandleGet(ctx, user.SpecialUserID)
+func (m *Module) adminPostAlertSilence(ctx *web.Context) web.Responser {
+	to := &silenceTO{}
+	if resp := ctx.Read(true, to, cmfx.BadRequestInvalidBody); resp != nil {
+		return resp
+	}
+
+	rule, resp := ctx.PathString("rule", "")
+	if resp != nil {
+		return resp
+	}
+
+	if !m.alerts.silence(rule, to.Until) {
+		return ctx.NotFound()
+	}
+	return web.NoContent()
 }
 
-func (m *Module) adminPutSettingAudit(ctx *web.Context) web.Responser {
-	return m.auditSettings.HandlePut(ctx, user.SpecialUserID)
+func (m *Module) adminDeleteAlertSilence(ctx *web.Context) web.Responser {
+	rule, resp := ctx.PathString("rule", "")
+	if resp != nil {
+		return resp
+	}
+
+	if !m.alerts.silence(rule, time.Time{}) {
+		return ctx.NotFound()
+	}
+	return web.NoContent()
 }
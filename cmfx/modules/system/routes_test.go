@@ -1,4 +1,4 @@
-// SPDX-FileCopyrightText: 2022-2024 caixw
+// SPDX-FileCopyrightText: 2022-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
@@ -18,6 +18,8 @@ import (
 
 	"github.com/issue9/cmfx/cmfx/initial/test"
 	"github.com/issue9/cmfx/cmfx/modules/admin/admintest"
+	"github.com/issue9/cmfx/cmfx/user"
+	"github.com/issue9/cmfx/cmfx/user/settings"
 )
 
 var _ openapi.OpenAPISchema = state(5)
@@ -67,3 +69,55 @@ func TestSystem_apis(t *testing.T) {
 				NotEmpty(body)
 		})
 }
+
+func TestModule_settingVersions(t *testing.T) {
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+	l := newModule(s)
+
+	defer servertest.Run(a, s.Module().Server())()
+	defer s.Close()
+
+	token := admintest.GetToken(s, l.admin)
+
+	put := func(name string) {
+		s.Put("/admin/system/settings/general", []byte(`{"name":"`+name+`","shortName":"s","description":"d","logo":"https://example.com/logo.png"}`)).
+			Header(header.ContentType, header.JSON).
+			Header(header.Authorization, auth.BuildToken(auth.Bearer, token)).
+			Do(nil).
+			Status(http.StatusNoContent)
+	}
+	put("n1")
+	put("n2")
+
+	vs := make([]*settings.Version, 0, 2)
+	s.Get("/admin/system/settings/general/versions").
+		Header(header.Accept, header.JSON).
+		Header(header.Authorization, auth.BuildToken(auth.Bearer, token)).
+		Do(nil).
+		Status(http.StatusOK).
+		BodyFunc(func(a *assert.Assertion, body []byte) { a.NotError(json.Unmarshal(body, &vs)) })
+	a.Length(vs, 2).
+		Equal(vs[0].Version, 2).
+		Equal(vs[0].Actor, 1).
+		Equal(vs[0].Values, map[string]string{"name": `"n1"`})
+
+	s.Post("/admin/system/settings/general/versions/100/rollback", nil).
+		Header(header.Authorization, auth.BuildToken(auth.Bearer, token)).
+		Do(nil).
+		Status(http.StatusNotFound)
+
+	s.Post("/admin/system/settings/general/versions/2/rollback", nil).
+		Header(header.Authorization, auth.BuildToken(auth.Bearer, token)).
+		Do(nil).
+		Status(http.StatusNoContent)
+	o, err := l.generalSettings.Get(user.SpecialUserID)
+	a.NotError(err).Equal(o.Name, "n1")
+
+	s.Get("/admin/system/settings/audit/versions").
+		Header(header.Accept, header.JSON).
+		Header(header.Authorization, auth.BuildToken(auth.Bearer, token)).
+		Do(nil).
+		Status(http.StatusOK).
+		StringBody("[]")
+}
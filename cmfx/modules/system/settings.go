@@ -19,16 +19,16 @@ const (
 // 后台的常规设置
 type generalSettings struct {
 	// 网站名称
-	Name string `setting:"name" json:"name" cbor:"name" yaml:"name"`
+	Name string `setting:"name" json:"name" cbor:"name" yaml:"name" comment:"site name"`
 
 	// 网站短标题
-	ShortName string `setting:"shortName" json:"shortName" cbor:"shortName" yaml:"shortName"`
+	ShortName string `setting:"shortName" json:"shortName" cbor:"shortName" yaml:"shortName" comment:"site short name"`
 
 	// 网站的 LOGO
-	LOGO string `setting:"logo" json:"logo" cbor:"logo" yaml:"logo"`
+	LOGO string `setting:"logo" json:"logo" cbor:"logo" yaml:"logo" comment:"site logo"`
 
 	// 网站描述
-	Description string `setting:"description" json:"description" cbor:"description" yaml:"description"`
+	Description string `setting:"description" json:"description" cbor:"description" yaml:"description" comment:"site description"`
 }
 
 func (g *generalSettings) Filter(f *web.FilterContext) {
@@ -1,11 +1,22 @@
-// SPDX-FileCopyrightText: 2024 caixw
+// SPDX-FileCopyrightText: 2024-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
 package upload
 
 import (
+	"io/fs"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/issue9/scheduled/schedulers/cron"
+	xupload "github.com/issue9/upload/v5"
 	"github.com/issue9/web"
+	"github.com/issue9/web/server/config"
 
 	"github.com/issue9/cmfx/cmfx/locales"
 )
@@ -18,8 +29,36 @@ type Config struct {
 	// 允许上传的扩展名
 	Exts []string `json:"exts" xml:"exts>ext" yaml:"exts" toml:"exts"`
 
+	// 允许上传的文件类型
+	//
+	// 根据文件内容检测的类型，而不是扩展名。可以使用 image/* 的形式表示某一大类，为空表示不限制。
+	Mimes []string `json:"mimes,omitempty" xml:"mimes>mime,omitempty" yaml:"mimes,omitempty" toml:"mimes,omitempty"`
+
 	// 上传内容中表示文件的字段名
 	Field string `json:"field" xml:"field" yaml:"field" toml:"field"`
+
+	// 是否为私有文件
+	//
+	// 私有文件只能由上传者访问，或是通过 [Module.SignURL] 生成的签名地址访问。
+	Private bool `json:"private,omitempty" xml:"private,attr,omitempty" yaml:"private,omitempty" toml:"private,omitempty"`
+
+	// 上传策略
+	//
+	// 每个策略都会注册一个单独的上传接口，以便对不同用途的文件采用不同的限制。
+	Policies []*Policy `json:"policies,omitempty" xml:"policies>policy,omitempty" yaml:"policies,omitempty" toml:"policies,omitempty"`
+
+	// 每个用户可用的存储空间，为零表示不限制。
+	Quota int64 `json:"quota,omitempty" xml:"quota,attr,omitempty" yaml:"quota,omitempty" toml:"quota,omitempty"`
+
+	// 按角色指定的存储空间
+	//
+	// 用户拥有多个角色时取其中最大的值，没有匹配的角色时采用 Quota。
+	Quotas []*Quota `json:"quotas,omitempty" xml:"quotas>quota,omitempty" yaml:"quotas,omitempty" toml:"quotas,omitempty"`
+
+	// 断点续传
+	//
+	// 为空表示不支持断点续传。
+	Resumable *ResumableConfig `json:"resumable,omitempty" xml:"resumable,omitempty" yaml:"resumable,omitempty" toml:"resumable,omitempty"`
 }
 
 func (u *Config) SanitizeConfig() *web.FieldError {
@@ -27,9 +66,490 @@ func (u *Config) SanitizeConfig() *web.FieldError {
 		return web.NewFieldError("size", locales.MustBeGreaterThan(-1))
 	}
 
+	if err := sanitizeMimes(u.Mimes); err != nil {
+		return err
+	}
+
 	if u.Field == "" {
 		return web.NewFieldError("field", locales.Required)
 	}
 
+	names := make([]string, 0, len(u.Policies))
+	for i, p := range u.Policies {
+		field := "policies[" + strconv.Itoa(i) + "]"
+		if err := p.SanitizeConfig(); err != nil {
+			return err.AddFieldParent(field)
+		}
+		if slices.Contains(names, p.Name) {
+			return web.NewFieldError(field+".name", locales.InvalidValue)
+		}
+		names = append(names, p.Name)
+	}
+
+	if u.Quota < 0 {
+		return web.NewFieldError("quota", locales.MustBeGreaterThan(-1))
+	}
+	roles := make([]string, 0, len(u.Quotas))
+	for i, q := range u.Quotas {
+		field := "quotas[" + strconv.Itoa(i) + "]"
+		if err := q.SanitizeConfig(); err != nil {
+			return err.AddFieldParent(field)
+		}
+		if slices.Contains(roles, q.Role) {
+			return web.NewFieldError(field+".role", locales.InvalidValue)
+		}
+		roles = append(roles, q.Role)
+	}
+
+	if u.Resumable != nil {
+		if err := u.Resumable.SanitizeConfig(); err != nil {
+			return err.AddFieldParent("resumable")
+		}
+	}
+
+	return nil
+}
+
+// HasPrivate 是否存在私有文件的上传策略
+func (u *Config) HasPrivate() bool {
+	return u.Private || slices.ContainsFunc(u.Policies, func(p *Policy) bool { return p.Private })
+}
+
+// 默认的上传策略
+func (u *Config) policy() *Policy {
+	return &Policy{Size: u.Size, Exts: u.Exts, Mimes: u.Mimes, Private: u.Private}
+}
+
+// UserQuota 拥有 roles 角色的用户可用的存储空间，为零表示不限制。
+func (u *Config) UserQuota(roles []string) int64 {
+	quota, found := int64(0), false
+	for _, q := range u.Quotas {
+		if !slices.Contains(roles, q.Role) {
+			continue
+		}
+		if q.Size == 0 {
+			return 0
+		}
+		quota, found = max(quota, q.Size), true
+	}
+
+	if !found {
+		return u.Quota
+	}
+	return quota
+}
+
+// Quota 角色的存储空间
+type Quota struct {
+	// 角色的 ID
+	Role string `json:"role" xml:"role,attr" yaml:"role" toml:"role"`
+
+	// 可用的存储空间，为零表示不限制。
+	Size int64 `json:"size" xml:"size,attr" yaml:"size" toml:"size"`
+}
+
+func (q *Quota) SanitizeConfig() *web.FieldError {
+	if q.Role == "" {
+		return web.NewFieldError("role", locales.Required)
+	}
+	if q.Size < 0 {
+		return web.NewFieldError("size", locales.MustBeGreaterThan(-1))
+	}
+	return nil
+}
+
+// 策略的名称不能与这些值相同，它们已经被用作其它接口的地址。
+var reservedPolicyNames = []string{"resumable", "files", "usage"}
+
+// Policy 上传策略
+//
+// 比如头像只允许小尺寸的图片，附件只允许文档类型的文件。
+type Policy struct {
+	// 策略的名称
+	//
+	// 同时也是上传接口地址的最后一部分，即 {prefix}/{name}，不能为 resumable、files 和 usage。
+	Name string `json:"name" xml:"name,attr" yaml:"name" toml:"name"`
+
+	// 允许上传的文件大小
+	Size int64 `json:"size" xml:"size,attr" yaml:"size" toml:"size"`
+
+	// 允许上传的扩展名
+	Exts []string `json:"exts" xml:"exts>ext" yaml:"exts" toml:"exts"`
+
+	// 允许上传的文件类型，格式与 [Config.Mimes] 相同。
+	Mimes []string `json:"mimes,omitempty" xml:"mimes>mime,omitempty" yaml:"mimes,omitempty" toml:"mimes,omitempty"`
+
+	// 是否为私有文件，与 [Config.Private] 相同。
+	Private bool `json:"private,omitempty" xml:"private,attr,omitempty" yaml:"private,omitempty" toml:"private,omitempty"`
+}
+
+func (p *Policy) SanitizeConfig() *web.FieldError {
+	if p.Name == "" {
+		return web.NewFieldError("name", locales.Required)
+	}
+	if slices.Contains(reservedPolicyNames, p.Name) || strings.ContainsAny(p.Name, "/{}") {
+		return web.NewFieldError("name", locales.InvalidValue)
+	}
+
+	if p.Size < 0 {
+		return web.NewFieldError("size", locales.MustBeGreaterThan(-1))
+	}
+
+	if err := sanitizeMimes(p.Mimes); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// 检测并统一为小写形式
+func sanitizeMimes(mimes []string) *web.FieldError {
+	for i, m := range mimes {
+		typ, sub, found := strings.Cut(m, "/")
+		if !found || typ == "" || sub == "" || strings.Contains(sub, "/") {
+			return web.NewFieldError("mimes["+strconv.Itoa(i)+"]", locales.InvalidValue)
+		}
+		mimes[i] = strings.ToLower(m)
+	}
+	return nil
+}
+
+// ResumableConfig 断点续传的配置项
+type ResumableConfig struct {
+	// 允许上传的文件大小
+	//
+	// 与 [Config.Size] 不同，此值针对整个文件，而不是单次请求。
+	Size int64 `json:"size" xml:"size,attr" yaml:"size" toml:"size"`
+
+	// 上传会话的有效期
+	//
+	// 每次上传数据之后都会重新计算，超时未完成的上传会被删除，为空表示 24 小时。
+	Expires config.Duration `json:"expires,omitempty" xml:"expires,omitempty" yaml:"expires,omitempty" toml:"expires,omitempty"`
+
+	// 未完成的文件的保存目录，为空表示 ./uploads-tmp。
+	Dir string `json:"dir,omitempty" xml:"dir,omitempty" yaml:"dir,omitempty" toml:"dir,omitempty"`
+}
+
+func (c *ResumableConfig) SanitizeConfig() *web.FieldError {
+	if c.Size <= 0 {
+		return web.NewFieldError("size", locales.MustBeGreaterThan(0))
+	}
+
+	if c.Expires < 0 {
+		return web.NewFieldError("expires", locales.MustBeGreaterThan(-1))
+	}
+	if c.Expires == 0 {
+		c.Expires = config.Duration(24 * time.Hour)
+	}
+
+	if c.Dir == "" {
+		c.Dir = "./uploads-tmp"
+	}
+
+	return nil
+}
+
+// StorageConfig 上传文件的存储配置
+type StorageConfig struct {
+	// 本地存储的目录
+	//
+	// 在 S3 为空时有效，为空表示 ./uploads。
+	Dir string `json:"dir,omitempty" xml:"dir,omitempty" yaml:"dir,omitempty" toml:"dir,omitempty"`
+
+	// 兼容 S3 协议的对象存储
+	//
+	// 如果不为空，则文件保存在对象存储中，Dir 将被忽略。
+	S3 *S3Config `json:"s3,omitempty" xml:"s3,omitempty" yaml:"s3,omitempty" toml:"s3,omitempty"`
+
+	// 清理无引用文件的时间
+	//
+	// cron 格式，为空表示不清理。
+	GC string `json:"gc,omitempty" xml:"gc,omitempty" yaml:"gc,omitempty" toml:"gc,omitempty"`
+
+	// 文件上传之后的保留时间
+	//
+	// 在此时间内即使没有引用也不会被清理，为空表示 24 小时。
+	Grace config.Duration `json:"grace,omitempty" xml:"grace,omitempty" yaml:"grace,omitempty" toml:"grace,omitempty"`
+
+	// 图片处理
+	//
+	// 为空表示不对图片作任何处理。
+	Image *ImageConfig `json:"image,omitempty" xml:"image,omitempty" yaml:"image,omitempty" toml:"image,omitempty"`
+
+	// 签名地址的密钥
+	//
+	// 用于生成和验证私有文件的签名地址，为空表示不支持私有文件。
+	// 如果上传的配置中存在私有文件的策略，则不能为空，可以通过 [StorageConfig.CheckSecret] 检测。
+	Secret string `json:"secret,omitempty" xml:"secret,omitempty" yaml:"secret,omitempty" toml:"secret,omitempty"`
+
+	// 文件扫描
+	//
+	// 为空表示不扫描上传的文件。
+	Scan *ScanConfig `json:"scan,omitempty" xml:"scan,omitempty" yaml:"scan,omitempty" toml:"scan,omitempty"`
+}
+
+func (c *StorageConfig) SanitizeConfig() *web.FieldError {
+	if c.GC != "" {
+		if _, err := cron.Parse(c.GC, time.UTC); err != nil {
+			return web.NewFieldError("gc", err)
+		}
+	}
+
+	if c.Grace < 0 {
+		return web.NewFieldError("grace", locales.MustBeGreaterThan(-1))
+	}
+	if c.Grace == 0 {
+		c.Grace = config.Duration(24 * time.Hour)
+	}
+
+	if c.Image != nil {
+		if err := c.Image.SanitizeConfig(); err != nil {
+			return err.AddFieldParent("image")
+		}
+	}
+
+	if c.Scan != nil {
+		if err := c.Scan.SanitizeConfig(); err != nil {
+			return err.AddFieldParent("scan")
+		}
+	}
+
+	if c.S3 != nil {
+		if err := c.S3.SanitizeConfig(); err != nil {
+			return err.AddFieldParent("s3")
+		}
+		return nil
+	}
+
+	if c.Dir == "" {
+		c.Dir = "./uploads"
+	}
+	return nil
+}
+
+// CheckSecret 检测 [StorageConfig.Secret] 是否满足 confs 的要求
+//
+// confs 中存在私有文件的策略时，Secret 不能为空。
+func (c *StorageConfig) CheckSecret(confs ...*Config) *web.FieldError {
+	if c.Secret == "" && slices.ContainsFunc(confs, func(conf *Config) bool { return conf != nil && conf.HasPrivate() }) {
+		return web.NewFieldError("secret", locales.Required)
+	}
+	return nil
+}
+
+// NewSaver 根据配置生成 [xupload.Saver]
+//
+// baseURL 和 filename 参数与 [NewS3Saver] 的同名参数相同。
+func (c *StorageConfig) NewSaver(baseURL string, filename func(fs.FS, string, string) string) (xupload.Saver, error) {
+	if c.S3 != nil {
+		return NewS3Saver(c.S3, baseURL, filename)
+	}
+
+	root, err := os.OpenRoot(c.Dir)
+	if err != nil {
+		return nil, err
+	}
+	return xupload.NewLocalSaver(root, baseURL, filename)
+}
+
+// S3Config 兼容 S3 协议的对象存储配置
+type S3Config struct {
+	// 服务地址，比如 https://s3.us-east-1.amazonaws.com 或是 http://localhost:9000
+	Endpoint string `json:"endpoint" xml:"endpoint" yaml:"endpoint" toml:"endpoint"`
+
+	// 区域，对于不区分区域的服务，一般为 us-east-1。
+	Region string `json:"region" xml:"region" yaml:"region" toml:"region"`
+
+	// 存储桶的名称
+	Bucket string `json:"bucket" xml:"bucket" yaml:"bucket" toml:"bucket"`
+
+	AccessKey string `json:"accessKey" xml:"accessKey" yaml:"accessKey" toml:"accessKey"`
+	SecretKey string `json:"secretKey" xml:"secretKey" yaml:"secretKey" toml:"secretKey"`
+
+	// 是否采用路径形式的地址
+	//
+	// 为 true 时，对象的地址为 endpoint/bucket/key，否则为 bucket.endpoint/key。
+	// MinIO 等自建的服务一般需要设置为 true。
+	PathStyle bool `json:"pathStyle,omitempty" xml:"pathStyle,attr,omitempty" yaml:"pathStyle,omitempty" toml:"pathStyle,omitempty"`
+
+	// 对象名称的前缀，可以为空。
+	Prefix string `json:"prefix,omitempty" xml:"prefix,omitempty" yaml:"prefix,omitempty" toml:"prefix,omitempty"`
+
+	// 请求对象存储的超时时间，为零表示不限制。
+	Timeout config.Duration `json:"timeout,omitempty" xml:"timeout,omitempty" yaml:"timeout,omitempty" toml:"timeout,omitempty"`
+
+	// 预签名下载地址的有效期
+	//
+	// 不为零时，访问上传的文件会重定向到对象存储的预签名地址，最大值为 7 天；
+	// 为零时，由当前服务读取文件内容并返回。
+	Presign config.Duration `json:"presign,omitempty" xml:"presign,omitempty" yaml:"presign,omitempty" toml:"presign,omitempty"`
+}
+
+func (c *S3Config) SanitizeConfig() *web.FieldError {
+	if c.Endpoint == "" {
+		return web.NewFieldError("endpoint", locales.Required)
+	}
+	if u, err := url.Parse(c.Endpoint); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return web.NewFieldError("endpoint", locales.InvalidURLFormat)
+	}
+
+	if c.Region == "" {
+		c.Region = "us-east-1"
+	}
+
+	if c.Bucket == "" {
+		return web.NewFieldError("bucket", locales.Required)
+	}
+	if c.AccessKey == "" {
+		return web.NewFieldError("accessKey", locales.Required)
+	}
+	if c.SecretKey == "" {
+		return web.NewFieldError("secretKey", locales.Required)
+	}
+
+	if c.Timeout < 0 {
+		return web.NewFieldError("timeout", locales.MustBeGreaterThan(-1))
+	}
+	if c.Presign < 0 || c.Presign.Duration() > maxPresign {
+		return web.NewFieldError("presign", locales.MustBeBetweenEqual("0s", maxPresign.String()))
+	}
+
+	return nil
+}
+
+// S3 允许的预签名地址的最长有效期
+const maxPresign = 7 * 24 * time.Hour
+
+// ImageConfig 图片处理的配置
+//
+// 仅对 JPEG 和 PNG 格式的图片进行处理，上传时会重新编码图片，以去除 EXIF 等元数据。
+type ImageConfig struct {
+	// 图片的最大宽度，上传时超出的图片会被等比缩小，为零表示不限制。
+	MaxWidth int `json:"maxWidth,omitempty" xml:"maxWidth,attr,omitempty" yaml:"maxWidth,omitempty" toml:"maxWidth,omitempty"`
+
+	// 图片的最大高度，上传时超出的图片会被等比缩小，为零表示不限制。
+	MaxHeight int `json:"maxHeight,omitempty" xml:"maxHeight,attr,omitempty" yaml:"maxHeight,omitempty" toml:"maxHeight,omitempty"`
+
+	// 允许处理的图片的最大像素数，即宽度与高度的乘积，为零表示 40000000。
+	//
+	// 在解码之前会先读取图片的尺寸，超出的图片不会被解码，上传时返回 413。
+	MaxPixels int64 `json:"maxPixels,omitempty" xml:"maxPixels,attr,omitempty" yaml:"maxPixels,omitempty" toml:"maxPixels,omitempty"`
+
+	// 允许处理的图片文件的最大字节数，为零表示 20MB。
+	//
+	// 处理图片需要将整个文件读入内存，超出的图片上传时返回 413。
+	MaxSize int64 `json:"maxSize,omitempty" xml:"maxSize,attr,omitempty" yaml:"maxSize,omitempty" toml:"maxSize,omitempty"`
+
+	// 上传时统一转换的格式
+	//
+	// 可以是 jpeg 或 png，为空表示保持原有格式。
+	Format string `json:"format,omitempty" xml:"format,omitempty" yaml:"format,omitempty" toml:"format,omitempty"`
+
+	// JPEG 的质量，取值为 [1, 100]，为零表示 85。
+	Quality int `json:"quality,omitempty" xml:"quality,omitempty" yaml:"quality,omitempty" toml:"quality,omitempty"`
+
+	// 缩略图的宽度
+	//
+	// 上传时会生成这些宽度的缩略图，访问时可以通过 ?w=200 的形式获取，
+	// 不在此列表中的宽度会返回 400。
+	Sizes []int `json:"sizes,omitempty" xml:"sizes>size,omitempty" yaml:"sizes,omitempty" toml:"sizes,omitempty"`
+
+	// 缩略图的缓存目录，为空表示 ./uploads-cache。
+	CacheDir string `json:"cacheDir,omitempty" xml:"cacheDir,omitempty" yaml:"cacheDir,omitempty" toml:"cacheDir,omitempty"`
+}
+
+func (c *ImageConfig) SanitizeConfig() *web.FieldError {
+	if c.MaxWidth < 0 {
+		return web.NewFieldError("maxWidth", locales.MustBeGreaterThan(-1))
+	}
+	if c.MaxHeight < 0 {
+		return web.NewFieldError("maxHeight", locales.MustBeGreaterThan(-1))
+	}
+
+	if c.MaxPixels < 0 {
+		return web.NewFieldError("maxPixels", locales.MustBeGreaterThan(-1))
+	}
+	if c.MaxPixels == 0 {
+		c.MaxPixels = 40000000
+	}
+
+	if c.MaxSize < 0 {
+		return web.NewFieldError("maxSize", locales.MustBeGreaterThan(-1))
+	}
+	if c.MaxSize == 0 {
+		c.MaxSize = 20 * 1024 * 1024
+	}
+
+	if c.Format != "" && c.Format != formatJPEG && c.Format != formatPNG {
+		return web.NewFieldError("format", locales.InvalidValue)
+	}
+
+	if c.Quality == 0 {
+		c.Quality = 85
+	}
+	if c.Quality < 1 || c.Quality > 100 {
+		return web.NewFieldError("quality", locales.MustBeBetweenEqual(1, 100))
+	}
+
+	for i, size := range c.Sizes {
+		if size <= 0 {
+			return web.NewFieldError("sizes["+strconv.Itoa(i)+"]", locales.MustBeGreaterThan(0))
+		}
+	}
+	c.Sizes = slices.Compact(slices.Sorted(slices.Values(c.Sizes)))
+
+	if c.CacheDir == "" {
+		c.CacheDir = "./uploads-cache"
+	}
+
+	return nil
+}
+
+// ScanConfig 上传文件的扫描配置
+//
+// 默认通过 clamd 进行扫描，被标记的文件不会被保存，而是移至隔离目录。
+type ScanConfig struct {
+	// clamd 的网络类型，可以是 tcp 或 unix，为空表示 tcp。
+	Network string `json:"network,omitempty" xml:"network,attr,omitempty" yaml:"network,omitempty" toml:"network,omitempty"`
+
+	// clamd 的地址
+	//
+	// 比如 localhost:3310 或是 /run/clamav/clamd.ctl，在 Scanner 为空时不能为空。
+	Address string `json:"address,omitempty" xml:"address,omitempty" yaml:"address,omitempty" toml:"address,omitempty"`
+
+	// 每次扫描的超时时间，为零表示不限制。
+	Timeout config.Duration `json:"timeout,omitempty" xml:"timeout,omitempty" yaml:"timeout,omitempty" toml:"timeout,omitempty"`
+
+	// 隔离目录，为空表示 ./uploads-quarantine。
+	Quarantine string `json:"quarantine,omitempty" xml:"quarantine,omitempty" yaml:"quarantine,omitempty" toml:"quarantine,omitempty"`
+
+	// 自定义的扫描器
+	//
+	// 不为空时，将忽略 Network、Address 和 Timeout。
+	Scanner Scanner `json:"-" xml:"-" yaml:"-" toml:"-"`
+}
+
+func (c *ScanConfig) SanitizeConfig() *web.FieldError {
+	if c.Scanner == nil {
+		if c.Network == "" {
+			c.Network = "tcp"
+		}
+		if c.Network != "tcp" && c.Network != "unix" {
+			return web.NewFieldError("network", locales.InvalidValue)
+		}
+
+		if c.Address == "" {
+			return web.NewFieldError("address", locales.Required)
+		}
+	}
+
+	if c.Timeout < 0 {
+		return web.NewFieldError("timeout", locales.MustBeGreaterThan(-1))
+	}
+
+	if c.Quarantine == "" {
+		c.Quarantine = "./uploads-quarantine"
+	}
+
 	return nil
 }
@@ -1,4 +1,4 @@
-// SPDX-FileCopyrightText: 2024 caixw
+// SPDX-FileCopyrightText: 2024-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
@@ -6,8 +6,10 @@ package upload
 
 import (
 	"testing"
+	"time"
 
 	"github.com/issue9/assert/v4"
+	"github.com/issue9/web/server/config"
 )
 
 func TestConfig_SanitizeConfig(t *testing.T) {
@@ -21,4 +23,138 @@ func TestConfig_SanitizeConfig(t *testing.T) {
 
 	c = &Config{Field: "123"}
 	a.NotError(c.SanitizeConfig())
+
+	c = &Config{Field: "123", Mimes: []string{"Image/*", "text"}}
+	a.Equal(c.SanitizeConfig().Field, "mimes[1]")
+
+	c = &Config{Field: "123", Mimes: []string{"Image/*"}, Policies: []*Policy{{Name: "avatar"}, {Name: "avatar"}}}
+	a.Equal(c.SanitizeConfig().Field, "policies[1].name").Equal(c.Mimes, []string{"image/*"})
+
+	c = &Config{Field: "123", Policies: []*Policy{{Name: "resumable"}}}
+	a.Equal(c.SanitizeConfig().Field, "policies[0].name")
+
+	c = &Config{Field: "123", Policies: []*Policy{{Name: "avatar", Mimes: []string{"image"}}}}
+	a.Equal(c.SanitizeConfig().Field, "policies[0].mimes[0]")
+
+	c = &Config{Field: "123", Policies: []*Policy{{Name: "avatar", Size: 1024, Mimes: []string{"image/*"}}}}
+	a.NotError(c.SanitizeConfig())
+
+	c = &Config{Field: "123", Policies: []*Policy{{Name: "files"}}}
+	a.Equal(c.SanitizeConfig().Field, "policies[0].name")
+
+	c = &Config{Field: "123", Quotas: []*Quota{{Role: "1", Size: 10}, {Role: "1"}}}
+	a.Equal(c.SanitizeConfig().Field, "quotas[1].role")
+
+	c = &Config{Field: "123", Quotas: []*Quota{{Role: "1", Size: -1}}}
+	a.Equal(c.SanitizeConfig().Field, "quotas[0].size")
+}
+
+func TestConfig_UserQuota(t *testing.T) {
+	a := assert.New(t, false)
+
+	c := &Config{Field: "f", Quota: 10, Quotas: []*Quota{{Role: "1", Size: 20}, {Role: "2", Size: 30}, {Role: "3"}}}
+	a.NotError(c.SanitizeConfig())
+
+	a.Equal(c.UserQuota(nil), 10).
+		Equal(c.UserQuota([]string{"4"}), 10).
+		Equal(c.UserQuota([]string{"1"}), 20).
+		Equal(c.UserQuota([]string{"1", "2"}), 30).
+		Equal(c.UserQuota([]string{"1", "3"}), 0)
+}
+
+func TestStorageConfig_SanitizeConfig(t *testing.T) {
+	a := assert.New(t, false)
+
+	c := &StorageConfig{}
+	a.NotError(c.SanitizeConfig()).
+		Equal(c.Dir, "./uploads").
+		Equal(c.Grace, config.Duration(24*time.Hour))
+
+	c = &StorageConfig{GC: "invalid"}
+	a.Equal(c.SanitizeConfig().Field, "gc")
+
+	c = &StorageConfig{GC: "0 0 3 * * *", Grace: -1}
+	a.Equal(c.SanitizeConfig().Field, "grace")
+
+	c = &StorageConfig{S3: &S3Config{}}
+	a.Equal(c.SanitizeConfig().Field, "s3.endpoint")
+}
+
+func TestStorageConfig_CheckSecret(t *testing.T) {
+	a := assert.New(t, false)
+
+	public := &Config{Field: "files"}
+	private := &Config{Field: "files", Policies: []*Policy{{Name: "avatar"}, {Name: "doc", Private: true}}}
+	a.False(public.HasPrivate()).True(private.HasPrivate())
+
+	c := &StorageConfig{}
+	a.NotError(c.CheckSecret(public, nil)).
+		Equal(c.CheckSecret(public, private).Field, "secret")
+
+	c = &StorageConfig{Secret: "secret"}
+	a.NotError(c.CheckSecret(public, private))
+}
+
+func TestScanConfig_SanitizeConfig(t *testing.T) {
+	a := assert.New(t, false)
+
+	c := &ScanConfig{}
+	a.Equal(c.SanitizeConfig().Field, "address")
+
+	c = &ScanConfig{Network: "udp", Address: "localhost:3310"}
+	a.Equal(c.SanitizeConfig().Field, "network")
+
+	c = &ScanConfig{Address: "localhost:3310"}
+	a.NotError(c.SanitizeConfig()).
+		Equal(c.Network, "tcp").
+		Equal(c.Quarantine, "./uploads-quarantine")
+
+	c = &ScanConfig{Scanner: &fakeScanner{}}
+	a.NotError(c.SanitizeConfig())
+}
+
+func TestS3Config_SanitizeConfig(t *testing.T) {
+	a := assert.New(t, false)
+
+	c := &S3Config{Endpoint: "localhost:9000"}
+	a.Equal(c.SanitizeConfig().Field, "endpoint")
+
+	c = &S3Config{Endpoint: "http://localhost:9000"}
+	a.Equal(c.SanitizeConfig().Field, "bucket")
+
+	c = &S3Config{Endpoint: "http://localhost:9000", Bucket: "b", AccessKey: "k", SecretKey: "s", Presign: config.Duration(8 * 24 * time.Hour)}
+	a.Equal(c.SanitizeConfig().Field, "presign")
+
+	c.Presign = config.Duration(time.Hour)
+	a.NotError(c.SanitizeConfig()).Equal(c.Region, "us-east-1")
+}
+
+func TestImageConfig_SanitizeConfig(t *testing.T) {
+	a := assert.New(t, false)
+
+	c := &ImageConfig{Sizes: []int{200, 100, 200}}
+	a.NotError(c.SanitizeConfig()).
+		Equal(c.Quality, 85).
+		Equal(c.Sizes, []int{100, 200}).
+		Equal(c.MaxPixels, 40000000).
+		Equal(c.MaxSize, 20*1024*1024).
+		Equal(c.CacheDir, "./uploads-cache")
+
+	c = &ImageConfig{MaxPixels: -1}
+	a.Equal(c.SanitizeConfig().Field, "maxPixels")
+
+	c = &ImageConfig{MaxSize: -1}
+	a.Equal(c.SanitizeConfig().Field, "maxSize")
+
+	c = &ImageConfig{Format: "gif"}
+	a.Equal(c.SanitizeConfig().Field, "format")
+
+	c = &ImageConfig{Quality: 101}
+	a.Equal(c.SanitizeConfig().Field, "quality")
+
+	c = &ImageConfig{Sizes: []int{100, 0}}
+	a.Equal(c.SanitizeConfig().Field, "sizes[1]")
+
+	s := &StorageConfig{Image: &ImageConfig{MaxWidth: -1}}
+	a.Equal(s.SanitizeConfig().Field, "image.maxWidth")
 }
@@ -0,0 +1,322 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package upload
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/issue9/orm/v6"
+	"github.com/issue9/upload/v5"
+	"github.com/issue9/web"
+)
+
+// FileVO 上传文件的元数据
+type FileVO struct {
+	ID       int64     `json:"id" yaml:"id" cbor:"id"`
+	UID      int64     `json:"uid" yaml:"uid" cbor:"uid"`                // 上传者
+	URL      string    `json:"url" yaml:"url" cbor:"url"`                // 访问地址
+	Filename string    `json:"filename" yaml:"filename" cbor:"filename"` // 上传时的原始文件名
+	Size     int64     `json:"size" yaml:"size" cbor:"size"`             // 文件大小
+	Mime     string    `json:"mime" yaml:"mime" cbor:"mime"`             // 根据文件内容检测的类型
+	Hash     string    `json:"hash" yaml:"hash" cbor:"hash"`             // 文件内容的 SHA-256 值
+	Refs     int64     `json:"refs" yaml:"refs" cbor:"refs"`             // 引用数量
+	Created  time.Time `json:"created" yaml:"created" cbor:"created"`
+}
+
+var errNotAllowMime = errors.New("not allowed mime type")
+
+// 记录文件元数据的 [upload.Saver]
+//
+// 每个请求对应一个实例，prefix 为上传接口的路由前缀，uid 为上传者，quota 为上传者可用的存储空间，
+// policy 为上传策略，仅用到其中的 Mimes 和 Private。
+type recorder struct {
+	fs.FS
+	m      *Module
+	prefix string
+	uid    int64
+	quota  int64
+	policy *Policy
+}
+
+func (m *Module) newRecorder(prefix string, uid, quota int64, p *Policy) *recorder {
+	if p == nil {
+		p = &Policy{}
+	}
+	return &recorder{FS: m.saver, m: m, prefix: prefix, uid: uid, quota: quota, policy: p}
+}
+
+// Save 保存文件并记录其元数据
+//
+// 如果启用了图片处理，图片会先经过处理再保存，超出处理限制的图片返回 [errImageTooLarge]；
+// 根据内容检测的类型不在允许的范围内，返回 [errNotAllowMime]；
+// 如果已经存在内容相同的文件，则直接返回该文件的地址，不会重复保存，私有文件只与同一用户的私有文件比较；
+// 超出用户可用的存储空间，返回 [errQuotaExceeded]；
+// 如果启用了文件扫描，被标记的文件会被隔离，并返回 [errInfected]。
+func (r *recorder) Save(f multipart.File, filename, ext string) (string, error) {
+	var processed []byte // 经过图片处理之后的内容
+	if r.m.images != nil {
+		data, err := r.m.images.read(f)
+		if err != nil {
+			return "", err
+		}
+
+		if data != nil {
+			switch processed, ext, err = r.m.images.process(data, ext); {
+			case errors.Is(err, errNotImage):
+				f = &bytesFile{Reader: bytes.NewReader(data)}
+			case err != nil:
+				return "", err
+			default:
+				f = &bytesFile{Reader: bytes.NewReader(processed)}
+			}
+		}
+	}
+
+	h := sha256.New()
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", err
+	}
+	head = head[:n]
+	h.Write(head)
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return "", err
+	}
+	size += int64(n)
+	hash := hex.EncodeToString(h.Sum(nil))
+
+	if len(r.policy.Mimes) > 0 && !matchMime(r.policy.Mimes, sniffMime(head)) {
+		return "", errNotAllowMime
+	}
+
+	exists := &filePO{}
+	sql := r.m.db.SQLBuilder().Select().
+		From(orm.TableName(exists)).
+		Where("hash=?", hash).
+		And("size=?", size).
+		And("private=?", r.policy.Private)
+	if r.policy.Private {
+		sql.And("prefix=?", r.prefix).And("uid=?", r.uid)
+	}
+	found, err := sql.Limit(1).QueryObject(true, exists)
+	if err != nil {
+		return "", err
+	}
+	if found > 0 {
+		return exists.URL, nil
+	}
+
+	if err := r.m.checkQuota(r.prefix, r.uid, r.quota, size); err != nil {
+		return "", err
+	}
+
+	if r.m.scan != nil {
+		if err := r.m.scanFile(f, hash, ext, r.uid, filename); err != nil {
+			return "", err
+		}
+	}
+
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	u, err := r.m.saver.Save(f, filename, ext)
+	if err != nil {
+		return "", err
+	}
+
+	po := &filePO{
+		Prefix:   r.prefix,
+		UID:      r.uid,
+		Name:     path.Base(u),
+		URL:      u,
+		Filename: filename,
+		Size:     size,
+		Mime:     detectMime(head, ext),
+		Hash:     hash,
+		Private:  r.policy.Private,
+	}
+	if _, err := r.m.db.Insert(po); err != nil {
+		return "", errors.Join(err, r.m.saver.Delete(po.Name))
+	}
+
+	if processed != nil { // 缩略图也可以在访问时生成，所以仅记录错误。
+		if err := r.m.images.thumbnails(po.Name, processed); err != nil {
+			r.m.mod.Server().Logs().ERROR().Error(err)
+		}
+	}
+
+	return u, nil
+}
+
+func (r *recorder) Delete(name string) error { return r.m.saver.Delete(name) }
+
+// 根据文件内容检测其类型，无法检测时根据扩展名判断。
+func detectMime(head []byte, ext string) string {
+	const unknown = "application/octet-stream"
+
+	t := http.DetectContentType(head)
+	if t == unknown || t == "text/plain; charset=utf-8" {
+		if tt := mime.TypeByExtension(ext); tt != "" {
+			return tt
+		}
+	}
+	return t
+}
+
+// 根据文件头部的特征检测其类型，不包含参数部分。
+//
+// 与 [detectMime] 不同，不会根据扩展名判断，无法识别的内容返回 application/octet-stream。
+func sniffMime(head []byte) string {
+	t, _, _ := strings.Cut(http.DetectContentType(head), ";")
+	return t
+}
+
+// t 是否在 allow 之中，allow 中的元素可以是 image/* 的形式。
+func matchMime(allow []string, t string) bool {
+	typ, _, _ := strings.Cut(t, "/")
+	return slices.ContainsFunc(allow, func(a string) bool {
+		return a == t || a == typ+"/*"
+	})
+}
+
+// GetFile 获取地址为 url 的文件元数据
+//
+// 如果文件不是通过 [Module.Handle] 上传的，返回 404 错误。
+func (m *Module) GetFile(url string) (*FileVO, error) {
+	po := &filePO{}
+	size, err := m.db.SQLBuilder().Select().
+		From(orm.TableName(po)).
+		Where("url=?", url).
+		Limit(1).
+		QueryObject(true, po)
+	if err != nil {
+		return nil, err
+	}
+	if size == 0 {
+		return nil, web.NewError(http.StatusNotFound, fs.ErrNotExist)
+	}
+
+	refs, err := m.db.Where("file=?", po.ID).Count(&refPO{})
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileVO{
+		ID:       po.ID,
+		UID:      po.UID,
+		URL:      po.URL,
+		Filename: po.Filename,
+		Size:     po.Size,
+		Mime:     po.Mime,
+		Hash:     po.Hash,
+		Refs:     refs,
+		Created:  po.Created,
+	}, nil
+}
+
+// SetRefs 将 owner 引用的文件设置为 urls
+//
+// owner 为引用者，比如 admin_avatar_1，需要保证在整个系统中唯一，一般以模块 ID 作为前缀；
+// urls 为 owner 当前引用的所有文件地址，之前的引用会被替换，为空表示取消 owner 的所有引用。
+// 不是通过 [Module.Handle] 上传的地址会被忽略。
+func (m *Module) SetRefs(tx *orm.Tx, owner string, urls ...string) error {
+	e := m.mod.Engine(tx)
+
+	if _, err := e.Where("owner=?", owner).Delete(&refPO{}); err != nil {
+		return err
+	}
+
+	urls = slices.Compact(slices.Sorted(slices.Values(urls)))
+	for _, u := range urls {
+		if u == "" {
+			continue
+		}
+
+		po := &filePO{}
+		size, err := e.SQLBuilder().Select().
+			From(orm.TableName(po)).
+			Where("url=?", u).
+			Limit(1).
+			QueryObject(true, po)
+		if err != nil {
+			return err
+		}
+		if size == 0 {
+			continue
+		}
+
+		if _, err := e.Insert(&refPO{File: po.ID, Owner: owner}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GC 删除在 before 之前上传且没有任何引用的文件
+//
+// 返回被删除的文件数量。
+func (m *Module) GC(before time.Time) (int, error) {
+	files := make([]*filePO, 0, 100)
+	_, err := m.db.SQLBuilder().Select().
+		Column("f.*").
+		From(orm.TableName(&filePO{}), "f").
+		Join("LEFT", orm.TableName(&refPO{}), "r", "r.file=f.id").
+		Where("f.created<?", before).
+		And("r.id IS NULL").
+		QueryObject(true, &files)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, f := range files {
+		if err := m.saver.Delete(f.Name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return i, err
+		}
+		if m.images != nil {
+			if err := m.images.deleteThumbnails(f.Name); err != nil {
+				return i, err
+			}
+		}
+		if _, err := m.db.Delete(&filePO{ID: f.ID}); err != nil {
+			return i, err
+		}
+	}
+	return len(files), nil
+}
+
+// AddGCService 添加定时清理无引用文件的服务
+//
+// spec 为 cron 格式的执行时间；
+// grace 为文件上传之后的保留时间，在此时间内即使没有引用也不会被删除，
+// 以便客户端在上传之后有足够的时间提交引用该文件的内容。
+//
+// 过期的断点续传也会一并清理。
+func (m *Module) AddGCService(spec string, grace time.Duration) context.CancelFunc {
+	return m.mod.Server().Services().AddCron(web.Phrase("clean orphaned upload files"), func(now time.Time) error {
+		if _, err := m.ExpireResumables(now); err != nil {
+			return err
+		}
+		_, err := m.GC(now.Add(-grace))
+		return err
+	}, spec, true)
+}
+
+var _ upload.Saver = &recorder{}
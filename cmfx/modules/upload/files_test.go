@@ -0,0 +1,111 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package upload
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+	xupload "github.com/issue9/upload/v5"
+
+	"github.com/issue9/cmfx/cmfx/initial/test"
+)
+
+func newFilesModule(a *assert.Assertion, s *test.Suite) *Module {
+	a.NotError(os.MkdirAll("./testdata/files", fs.ModePerm))
+	root, err := os.OpenRoot("./testdata/files")
+	a.NotError(err)
+
+	var count atomic.Int64
+	saver, err := xupload.NewLocalSaver(root, "/files", func(_ fs.FS, _, ext string) string {
+		return strconv.FormatInt(count.Add(1), 10) + ext
+	})
+	a.NotError(err)
+
+	mod := s.NewModule("upload")
+	Install(mod)
+	return Load(mod, "/files", saver, &StorageConfig{Secret: "secret"})
+}
+
+func TestDetectMime(t *testing.T) {
+	a := assert.New(t, false)
+
+	a.Equal(detectMime([]byte("\x89PNG\x0d\x0a\x1a\x0a"), ".txt"), "image/png").
+		Equal(detectMime([]byte("{}"), ".json"), "application/json").
+		Equal(detectMime([]byte{0, 1, 2}, ".unknown-ext"), "application/octet-stream")
+}
+
+func TestModule_files(t *testing.T) {
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+	defer s.Close()
+	defer os.RemoveAll("./testdata/files")
+
+	m := newFilesModule(a, s)
+
+	// 保存
+
+	r := m.newRecorder("", 5, 0, nil)
+	u1, err := r.Save(&memFile{Reader: bytes.NewReader([]byte("\x89PNG\x0d\x0a\x1a\x0a"))}, "a.png", ".png")
+	a.NotError(err).Equal(u1, "/files/1.png")
+
+	// 内容相同，不会重复保存。
+	u2, err := m.newRecorder("", 6, 0, nil).Save(&memFile{Reader: bytes.NewReader([]byte("\x89PNG\x0d\x0a\x1a\x0a"))}, "b.png", ".png")
+	a.NotError(err).Equal(u2, u1)
+
+	u3, err := r.Save(&memFile{Reader: bytes.NewReader([]byte("hello"))}, "c.txt", ".txt")
+	a.NotError(err).Equal(u3, "/files/2.txt")
+
+	f, err := m.GetFile(u1)
+	a.NotError(err).NotNil(f).
+		Equal(f.UID, 5).
+		Equal(f.Filename, "a.png").
+		Equal(f.Size, 8).
+		Equal(f.Mime, "image/png").
+		Length(f.Hash, 64).
+		Zero(f.Refs)
+
+	_, err = m.GetFile("/files/not-exists")
+	a.True(errors.Is(err, fs.ErrNotExist))
+
+	// 引用
+
+	a.NotError(m.SetRefs(nil, "o1", u1, u1, u3, "https://example.com/a.png"))
+	a.NotError(m.SetRefs(nil, "o2", u1))
+	f, err = m.GetFile(u1)
+	a.NotError(err).Equal(f.Refs, 2)
+	f, err = m.GetFile(u3)
+	a.NotError(err).Equal(f.Refs, 1)
+
+	a.NotError(m.SetRefs(nil, "o1", u1))
+	f, err = m.GetFile(u3)
+	a.NotError(err).Equal(f.Refs, 0)
+
+	// 清理
+
+	size, err := m.GC(time.Now().Add(-time.Hour)) // 都在保留期内
+	a.NotError(err).Zero(size)
+
+	size, err = m.GC(time.Now().Add(time.Second))
+	a.NotError(err).Equal(size, 1)
+	_, err = m.GetFile(u3)
+	a.True(errors.Is(err, fs.ErrNotExist))
+	_, err = fs.Stat(m.saver, "2.txt")
+	a.True(errors.Is(err, fs.ErrNotExist))
+
+	a.NotError(m.SetRefs(nil, "o1")).
+		NotError(m.SetRefs(nil, "o2", ""))
+	size, err = m.GC(time.Now().Add(time.Second))
+	a.NotError(err).Equal(size, 1)
+	_, err = m.GetFile(u1)
+	a.True(errors.Is(err, fs.ErrNotExist))
+}
@@ -0,0 +1,456 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package upload
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/fs"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+
+	"github.com/issue9/web"
+
+	"github.com/issue9/cmfx/cmfx"
+)
+
+const (
+	formatJPEG = "jpeg"
+	formatPNG  = "png"
+)
+
+var (
+	errNotImage      = errors.New("not a supported image")
+	errImageTooLarge = errors.New("image too large")
+)
+
+// 图片处理
+type images struct {
+	conf  *ImageConfig
+	cache *os.Root // 缩略图的缓存，以宽度作为子目录名。
+}
+
+func newImages(conf *ImageConfig) (*images, error) {
+	if err := os.MkdirAll(conf.CacheDir, fs.ModePerm); err != nil {
+		return nil, err
+	}
+	root, err := os.OpenRoot(conf.CacheDir)
+	if err != nil {
+		return nil, err
+	}
+	return &images{conf: conf, cache: root}, nil
+}
+
+// 读取需要处理的图片
+//
+// 仅根据内容检测为 JPEG 或 PNG 的文件才会读入内存，其它文件返回 nil，
+// 超过 [ImageConfig.MaxSize] 的图片返回 [errImageTooLarge]。
+// 返回 nil 时 f 的读取位置会重置到开头。
+func (i *images) read(f multipart.File) ([]byte, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, err
+	}
+
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	if t := sniffMime(head[:n]); t != "image/jpeg" && t != "image/png" {
+		return nil, nil
+	}
+	if size > i.conf.MaxSize {
+		return nil, errImageTooLarge
+	}
+	return io.ReadAll(f)
+}
+
+// 在解码之前检测图片的格式和尺寸
+//
+// 如果不是可处理的图片，返回 [errNotImage]；超过 [ImageConfig.MaxPixels] 的图片返回 [errImageTooLarge]。
+func (i *images) check(r io.Reader) (string, error) {
+	conf, format, err := image.DecodeConfig(r)
+	if err != nil || (format != formatJPEG && format != formatPNG) {
+		return "", errNotImage
+	}
+	if int64(conf.Width)*int64(conf.Height) > i.conf.MaxPixels {
+		return "", errImageTooLarge
+	}
+	return format, nil
+}
+
+// 处理上传的图片
+//
+// 会按 EXIF 中的方向旋转图片，限制图片的大小并转换格式，重新编码后的图片不再包含 EXIF 等元数据。
+// 返回处理后的内容和扩展名，如果不是可处理的图片，返回 [errNotImage]，像素过多的图片返回 [errImageTooLarge]。
+func (i *images) process(data []byte, ext string) ([]byte, string, error) {
+	if _, err := i.check(bytes.NewReader(data)); err != nil {
+		return nil, ext, err
+	}
+
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ext, errNotImage
+	}
+
+	if format == formatJPEG {
+		img = orient(img, exifOrientation(data))
+	}
+	img = fit(img, i.conf.MaxWidth, i.conf.MaxHeight)
+
+	if i.conf.Format != "" && i.conf.Format != format {
+		format = i.conf.Format
+		ext = "." + format
+		if format == formatJPEG {
+			ext = ".jpg"
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	if err := i.encode(buf, img, format); err != nil {
+		return nil, ext, err
+	}
+	return buf.Bytes(), ext, nil
+}
+
+func (i *images) encode(w io.Writer, img image.Image, format string) error {
+	if format == formatPNG {
+		return png.Encode(w, img)
+	}
+
+	// JPEG 不支持透明，以白色作为背景。
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Over)
+	return jpeg.Encode(w, dst, &jpeg.Options{Quality: i.conf.Quality})
+}
+
+// 生成文件 name 的所有缩略图
+//
+// data 为 [images.process] 处理之后的图片内容。
+func (i *images) thumbnails(name string, data []byte) error {
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	for _, w := range i.conf.Sizes {
+		if err := i.writeThumbnail(name, img, format, w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (i *images) writeThumbnail(name string, img image.Image, format string, width int) error {
+	dir := strconv.Itoa(width)
+	if err := i.cache.MkdirAll(dir, fs.ModePerm); err != nil {
+		return err
+	}
+
+	buf := &bytes.Buffer{}
+	if err := i.encode(buf, thumbnail(img, width), format); err != nil {
+		return err
+	}
+
+	// 先写入临时文件再重命名，防止同时访问时读取到不完整的内容。
+	p := path.Join(dir, name)
+	tmp := p + ".tmp"
+	if err := i.cache.WriteFile(tmp, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+	return i.cache.Rename(tmp, p)
+}
+
+// 打开文件 name 宽度为 width 的缩略图，如果不存在，则从 fsys 中读取原图并生成。
+//
+// 原图不是可处理的图片时返回 [errNotImage]，像素过多时返回 [errImageTooLarge]。
+func (i *images) openThumbnail(fsys fs.FS, name string, width int) (*os.File, error) {
+	p := path.Join(strconv.Itoa(width), name)
+	f, err := i.cache.Open(p)
+	if err == nil || !errors.Is(err, fs.ErrNotExist) {
+		return f, err
+	}
+
+	src, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	_, err = i.check(src)
+	if err = errors.Join(err, src.Close()); err != nil {
+		return nil, err
+	}
+
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, errNotImage
+	}
+
+	if err := i.writeThumbnail(name, img, format, width); err != nil {
+		return nil, err
+	}
+	return i.cache.Open(p)
+}
+
+// 删除文件 name 的所有缩略图
+func (i *images) deleteThumbnails(name string) error {
+	for _, w := range i.conf.Sizes {
+		err := i.cache.Remove(path.Join(strconv.Itoa(w), name))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// 如果请求中包含 w 参数，返回对应宽度的缩略图，否则交由 next 处理。
+func (i *images) handler(fsys fs.FS, next web.HandlerFunc) web.HandlerFunc {
+	return func(ctx *web.Context) web.Responser {
+		q := ctx.Request().URL.Query().Get("w")
+		if q == "" {
+			return next(ctx)
+		}
+
+		width, err := strconv.Atoi(q)
+		if err != nil || !slices.Contains(i.conf.Sizes, width) {
+			return ctx.Problem(cmfx.BadRequestInvalidQuery)
+		}
+
+		name, _ := ctx.Route().Params().Get("file")
+		if !fs.ValidPath(name) {
+			return ctx.NotFound()
+		}
+
+		f, err := i.openThumbnail(fsys, name, width)
+		switch {
+		case errors.Is(err, errNotImage), errors.Is(err, errImageTooLarge): // 直接返回原图
+			return next(ctx)
+		case errors.Is(err, fs.ErrNotExist):
+			return ctx.NotFound()
+		case err != nil:
+			return ctx.Error(err, "")
+		}
+
+		return web.ResponserFunc(func(ctx *web.Context) {
+			defer f.Close()
+
+			stat, err := f.Stat()
+			if err != nil {
+				ctx.Logs().ERROR().Error(err)
+				ctx.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			http.ServeContent(ctx, ctx.Request(), name, stat.ModTime(), f)
+		})
+	}
+}
+
+// 缩放 img 使其不超过 maxWidth 和 maxHeight，为零表示不限制。
+func fit(img image.Image, maxWidth, maxHeight int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	if maxWidth > 0 && w > maxWidth {
+		h = max(1, h*maxWidth/w)
+		w = maxWidth
+	}
+	if maxHeight > 0 && h > maxHeight {
+		w = max(1, w*maxHeight/h)
+		h = maxHeight
+	}
+
+	if w == b.Dx() && h == b.Dy() {
+		return img
+	}
+	return resize(img, w, h)
+}
+
+// 生成宽度为 width 的缩略图，如果原图宽度不大于 width，则返回原图。
+func thumbnail(img image.Image, width int) image.Image {
+	b := img.Bounds()
+	if b.Dx() <= width {
+		return img
+	}
+	return resize(img, width, max(1, b.Dy()*width/b.Dx()))
+}
+
+// 将 src 缩小至 w*h
+//
+// 目标的每个像素取原图中对应区域的平均值，仅适用于缩小图片。
+func resize(src image.Image, w, h int) *image.NRGBA {
+	sb := src.Bounds()
+	sw, sh := sb.Dx(), sb.Dy()
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+
+	for y := range h {
+		y0 := sb.Min.Y + y*sh/h
+		y1 := max(y0+1, sb.Min.Y+(y+1)*sh/h)
+
+		for x := range w {
+			x0 := sb.Min.X + x*sw/w
+			x1 := max(x0+1, sb.Min.X+(x+1)*sw/w)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA() // 预乘 alpha 的值
+					r += uint64(cr)
+					g += uint64(cg)
+					b += uint64(cb)
+					a += uint64(ca)
+					n++
+				}
+			}
+
+			i := dst.PixOffset(x, y)
+			if a == 0 {
+				continue
+			}
+			dst.Pix[i+0] = uint8(r * 0xffff / a >> 8)
+			dst.Pix[i+1] = uint8(g * 0xffff / a >> 8)
+			dst.Pix[i+2] = uint8(b * 0xffff / a >> 8)
+			dst.Pix[i+3] = uint8(a / n >> 8)
+		}
+	}
+
+	return dst
+}
+
+// 根据 EXIF 中的方向值 o 调整 img
+//
+// o 的取值为 [1, 8]，1 表示不需要调整。
+func orient(img image.Image, o int) image.Image {
+	if o <= 1 || o > 8 {
+		return img
+	}
+
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if o >= 5 { // 宽高互换
+		dw, dh = h, w
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := range dh {
+		for x := range dw {
+			var sx, sy int
+			switch o {
+			case 2: // 水平翻转
+				sx, sy = w-1-x, y
+			case 3: // 旋转 180 度
+				sx, sy = w-1-x, h-1-y
+			case 4: // 垂直翻转
+				sx, sy = x, h-1-y
+			case 5: // 沿左上至右下的对角线翻转
+				sx, sy = y, x
+			case 6: // 顺时针旋转 90 度
+				sx, sy = y, h-1-x
+			case 7: // 沿右上至左下的对角线翻转
+				sx, sy = w-1-y, h-1-x
+			case 8: // 逆时针旋转 90 度
+				sx, sy = w-1-y, x
+			}
+			dst.Set(x, y, img.At(b.Min.X+sx, b.Min.Y+sy))
+		}
+	}
+	return dst
+}
+
+// 从 JPEG 内容中读取 EXIF 的方向值，不存在时返回 1。
+func exifOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xff || data[1] != 0xd8 {
+		return 1
+	}
+
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xff {
+			return 1
+		}
+
+		marker := data[i+1]
+		if marker == 0xda || marker == 0xd9 { // 图像数据开始或是结束
+			return 1
+		}
+
+		size := int(binary.BigEndian.Uint16(data[i+2:]))
+		if size < 2 || i+2+size > len(data) {
+			return 1
+		}
+
+		if seg := data[i+4 : i+2+size]; marker == 0xe1 && len(seg) > 6 && string(seg[:6]) == "Exif\x00\x00" {
+			return tiffOrientation(seg[6:])
+		}
+		i += 2 + size
+	}
+
+	return 1
+}
+
+// 从 TIFF 格式的 EXIF 内容中读取方向值
+func tiffOrientation(t []byte) int {
+	if len(t) < 8 {
+		return 1
+	}
+
+	var bo binary.ByteOrder
+	switch string(t[:2]) {
+	case "II":
+		bo = binary.LittleEndian
+	case "MM":
+		bo = binary.BigEndian
+	default:
+		return 1
+	}
+
+	offset := int(bo.Uint32(t[4:]))
+	if offset < 8 || offset+2 > len(t) {
+		return 1
+	}
+
+	n := int(bo.Uint16(t[offset:]))
+	for k := range n {
+		e := offset + 2 + k*12
+		if e+12 > len(t) {
+			return 1
+		}
+		if bo.Uint16(t[e:]) == 0x0112 {
+			if v := int(bo.Uint16(t[e+8:])); v >= 1 && v <= 8 {
+				return v
+			}
+			return 1
+		}
+	}
+
+	return 1
+}
+
+// 以 []byte 实现的 [multipart.File]
+type bytesFile struct {
+	*bytes.Reader
+}
+
+func (f *bytesFile) Close() error { return nil }
@@ -0,0 +1,254 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package upload
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io/fs"
+	"net/http"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+	xupload "github.com/issue9/upload/v5"
+	"github.com/issue9/web/server/servertest"
+
+	"github.com/issue9/cmfx/cmfx/initial/test"
+)
+
+func newTestImage(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			img.Set(x, y, color.NRGBA{R: uint8(x * 255 / w), G: uint8(y * 255 / h), B: 100, A: 255})
+		}
+	}
+	return img
+}
+
+// 生成包含 EXIF 方向信息的 JPEG
+func newTestJPEG(a *assert.Assertion, w, h, orientation int) []byte {
+	buf := &bytes.Buffer{}
+	a.NotError(jpeg.Encode(buf, newTestImage(w, h), nil))
+	data := buf.Bytes()
+
+	tiff := []byte("II*\x00")
+	tiff = binary.LittleEndian.AppendUint32(tiff, 8)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 1) // 一个条目
+	tiff = binary.LittleEndian.AppendUint16(tiff, 0x0112)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 3) // SHORT
+	tiff = binary.LittleEndian.AppendUint32(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint16(tiff, uint16(orientation))
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)
+
+	seg := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := []byte{0xff, 0xe1}
+	app1 = binary.BigEndian.AppendUint16(app1, uint16(len(seg)+2))
+	app1 = append(app1, seg...)
+
+	return append(append([]byte{0xff, 0xd8}, app1...), data[2:]...)
+}
+
+func newTestPNG(a *assert.Assertion, w, h int) []byte {
+	buf := &bytes.Buffer{}
+	a.NotError(png.Encode(buf, newTestImage(w, h)))
+	return buf.Bytes()
+}
+
+func TestExifOrientation(t *testing.T) {
+	a := assert.New(t, false)
+
+	a.Equal(exifOrientation(newTestJPEG(a, 4, 2, 6)), 6).
+		Equal(exifOrientation(newTestJPEG(a, 4, 2, 9)), 1).
+		Equal(exifOrientation(newTestPNG(a, 4, 2)), 1).
+		Equal(exifOrientation([]byte{0xff, 0xd8, 0xff}), 1)
+
+	buf := &bytes.Buffer{}
+	a.NotError(jpeg.Encode(buf, newTestImage(4, 2), nil))
+	a.Equal(exifOrientation(buf.Bytes()), 1)
+}
+
+func TestOrient(t *testing.T) {
+	a := assert.New(t, false)
+
+	c1 := color.NRGBA{R: 255, A: 255}
+	c2 := color.NRGBA{G: 255, A: 255}
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, c1)
+	img.Set(1, 0, c2)
+
+	a.Equal(orient(img, 1), img)
+
+	dst := orient(img, 2)
+	a.Equal(dst.At(0, 0), c2).Equal(dst.At(1, 0), c1)
+
+	dst = orient(img, 6)
+	a.Equal(dst.Bounds().Dx(), 1).Equal(dst.Bounds().Dy(), 2).
+		Equal(dst.At(0, 0), c1).Equal(dst.At(0, 1), c2)
+
+	dst = orient(img, 8)
+	a.Equal(dst.Bounds().Dx(), 1).Equal(dst.Bounds().Dy(), 2).
+		Equal(dst.At(0, 0), c2).Equal(dst.At(0, 1), c1)
+}
+
+func TestFit(t *testing.T) {
+	a := assert.New(t, false)
+
+	img := newTestImage(40, 20)
+	a.Equal(fit(img, 0, 0), img).Equal(fit(img, 50, 50), img)
+
+	b := fit(img, 10, 0).Bounds()
+	a.Equal(b.Dx(), 10).Equal(b.Dy(), 5)
+
+	b = fit(img, 30, 5).Bounds()
+	a.Equal(b.Dx(), 10).Equal(b.Dy(), 5)
+
+	a.Equal(thumbnail(img, 50), img)
+	b = thumbnail(img, 20).Bounds()
+	a.Equal(b.Dx(), 20).Equal(b.Dy(), 10)
+
+	// 纯色图片缩小之后颜色不变
+	c := color.NRGBA{R: 10, G: 20, B: 30, A: 128}
+	src := image.NewNRGBA(image.Rect(0, 0, 9, 9))
+	for y := range 9 {
+		for x := range 9 {
+			src.Set(x, y, c)
+		}
+	}
+	a.Equal(resize(src, 4, 4).At(1, 1), c)
+}
+
+func TestImages_process(t *testing.T) {
+	a := assert.New(t, false)
+	defer os.RemoveAll("./testdata/cache")
+
+	conf := &ImageConfig{MaxWidth: 10, CacheDir: "./testdata/cache"}
+	a.NotError(conf.SanitizeConfig())
+	i, err := newImages(conf)
+	a.NotError(err)
+
+	// 旋转、缩小并去除 EXIF
+	data, ext, err := i.process(newTestJPEG(a, 40, 20, 6), ".jpeg")
+	a.NotError(err).Equal(ext, ".jpeg")
+	a.False(bytes.Contains(data, []byte("Exif")))
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	a.NotError(err).Equal(format, formatJPEG).Equal(cfg.Width, 10).Equal(cfg.Height, 20)
+
+	// 转换格式
+	conf.Format = formatJPEG
+	data, ext, err = i.process(newTestPNG(a, 8, 8), ".png")
+	a.NotError(err).Equal(ext, ".jpg")
+	cfg, format, err = image.DecodeConfig(bytes.NewReader(data))
+	a.NotError(err).Equal(format, formatJPEG).Equal(cfg.Width, 8)
+
+	_, ext, err = i.process([]byte("hello"), ".txt")
+	a.Equal(err, errNotImage).Equal(ext, ".txt")
+
+	// 像素过多
+	conf.MaxPixels = 40*20 - 1
+	_, ext, err = i.process(newTestPNG(a, 40, 20), ".png")
+	a.Equal(err, errImageTooLarge).Equal(ext, ".png")
+}
+
+func TestImages_read(t *testing.T) {
+	a := assert.New(t, false)
+	defer os.RemoveAll("./testdata/cache")
+
+	conf := &ImageConfig{CacheDir: "./testdata/cache"}
+	a.NotError(conf.SanitizeConfig())
+	i, err := newImages(conf)
+	a.NotError(err)
+
+	img := newTestPNG(a, 40, 20)
+	data, err := i.read(&memFile{Reader: bytes.NewReader(img)})
+	a.NotError(err).Equal(data, img)
+
+	// 非图片不读入内存
+	f := &memFile{Reader: bytes.NewReader([]byte("hello"))}
+	data, err = i.read(f)
+	a.NotError(err).Nil(data).Equal(f.Len(), 5)
+
+	conf.MaxSize = int64(len(img) - 1)
+	data, err = i.read(&memFile{Reader: bytes.NewReader(img)})
+	a.Equal(err, errImageTooLarge).Nil(data)
+}
+
+func TestLoad_images(t *testing.T) {
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+	defer os.RemoveAll("./testdata")
+
+	a.NotError(os.MkdirAll("./testdata/images", fs.ModePerm))
+	root, err := os.OpenRoot("./testdata/images")
+	a.NotError(err)
+	var count atomic.Int64
+	saver, err := xupload.NewLocalSaver(root, "/images", func(_ fs.FS, _, ext string) string {
+		return strconv.FormatInt(count.Add(1), 10) + ext
+	})
+	a.NotError(err)
+
+	conf := &ImageConfig{Sizes: []int{20, 10}, CacheDir: "./testdata/cache"}
+	a.NotError(conf.SanitizeConfig())
+	mod := s.NewModule("images")
+	Install(mod)
+	m := Load(mod, "/images", saver, &StorageConfig{Image: conf})
+
+	u, err := m.newRecorder("", 1, 0, nil).Save(&memFile{Reader: bytes.NewReader(newTestPNG(a, 40, 20))}, "a.png", ".png")
+	a.NotError(err).Equal(u, "/images/1.png")
+	a.FileExists("./testdata/cache/10/1.png").FileExists("./testdata/cache/20/1.png")
+
+	u, err = m.newRecorder("", 1, 0, nil).Save(&memFile{Reader: bytes.NewReader([]byte("hello"))}, "a.txt", ".txt")
+	a.NotError(err).Equal(u, "/images/2.txt")
+
+	// 超出处理限制的图片
+	conf.MaxPixels = 40*20 - 1
+	_, err = m.newRecorder("", 1, 0, nil).Save(&memFile{Reader: bytes.NewReader(newTestPNG(a, 40, 20))}, "b.png", ".png")
+	a.Equal(err, errImageTooLarge)
+	conf.MaxPixels = 40 * 20
+
+	defer servertest.Run(a, s.Module().Server())()
+	defer s.Close()
+
+	s.Get("/images/1.png?w=10").Do(nil).Status(http.StatusOK).BodyFunc(func(a *assert.Assertion, body []byte) {
+		cfg, _, err := image.DecodeConfig(bytes.NewReader(body))
+		a.NotError(err).Equal(cfg.Width, 10).Equal(cfg.Height, 5)
+	})
+
+	// 缓存被删除之后重新生成
+	a.NotError(os.Remove("./testdata/cache/20/1.png"))
+	s.Get("/images/1.png?w=20").Do(nil).Status(http.StatusOK).BodyFunc(func(a *assert.Assertion, body []byte) {
+		cfg, _, err := image.DecodeConfig(bytes.NewReader(body))
+		a.NotError(err).Equal(cfg.Width, 20)
+	})
+
+	s.Get("/images/1.png").Do(nil).Status(http.StatusOK).BodyFunc(func(a *assert.Assertion, body []byte) {
+		cfg, _, err := image.DecodeConfig(bytes.NewReader(body))
+		a.NotError(err).Equal(cfg.Width, 40)
+	})
+	s.Get("/images/1.png?w=11").Do(nil).Status(http.StatusBadRequest)
+	s.Get("/images/not-exists.png?w=10").Do(nil).Status(http.StatusNotFound)
+	s.Get("/images/2.txt?w=10").Do(nil).Status(http.StatusOK).StringBody("hello") // 非图片返回原文件
+
+	// 原图超出处理限制时返回原图
+	conf.MaxPixels = 40*20 - 1
+	a.NotError(os.Remove("./testdata/cache/10/1.png"))
+	s.Get("/images/1.png?w=10").Do(nil).Status(http.StatusOK).BodyFunc(func(a *assert.Assertion, body []byte) {
+		cfg, _, err := image.DecodeConfig(bytes.NewReader(body))
+		a.NotError(err).Equal(cfg.Width, 40)
+	})
+	a.FileNotExists("./testdata/cache/10/1.png")
+
+	size, err := m.GC(time.Now().Add(time.Second))
+	a.NotError(err).Equal(size, 2)
+	a.FileNotExists("./testdata/cache/10/1.png").FileNotExists("./testdata/cache/20/1.png")
+}
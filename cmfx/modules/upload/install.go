@@ -0,0 +1,30 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package upload
+
+import (
+	"github.com/issue9/web"
+
+	"github.com/issue9/cmfx/cmfx"
+)
+
+// Install 安装记录文件元数据的数据表
+func Install(mod *cmfx.Module) {
+	if err := mod.DB().Create(&filePO{}, &refPO{}, &resumablePO{}); err != nil {
+		panic(web.SprintError(mod.Server().Locale().Printer(), true, err))
+	}
+
+	if err := Migrations(mod).Install(); err != nil {
+		panic(web.SprintError(mod.Server().Locale().Printer(), true, err))
+	}
+}
+
+// Uninstall 删除由 [Install] 创建的数据表
+func Uninstall(mod *cmfx.Module) error {
+	if err := mod.DB().Drop(&filePO{}, &refPO{}, &resumablePO{}); err != nil {
+		return err
+	}
+	return Migrations(mod).Uninstall()
+}
@@ -0,0 +1,91 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package upload
+
+import (
+	"testing"
+
+	"github.com/issue9/assert/v4"
+
+	"github.com/issue9/cmfx/cmfx/initial/test"
+)
+
+func TestInstall(t *testing.T) {
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+	defer s.Close()
+
+	mod := s.NewModule("upload")
+	Install(mod)
+	s.TableExists(mod.ID() + "_files").
+		TableExists(mod.ID() + "_file_refs").
+		TableExists(mod.ID() + "_resumables")
+
+	states, err := Migrations(mod).States()
+	a.NotError(err).Length(states, 3)
+	for _, state := range states {
+		a.False(state.Applied.IsZero())
+	}
+
+	a.NotError(Uninstall(mod))
+	s.TableNotExists(mod.ID() + "_files").
+		TableNotExists(mod.ID() + "_file_refs").
+		TableNotExists(mod.ID() + "_resumables")
+}
+
+func TestUpgrade(t *testing.T) {
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+	defer s.Close()
+
+	// 未安装的情况下，升级会创建数据表。
+	mod := s.NewModule("upload")
+	a.NotError(Migrations(mod).Upgrade())
+	s.TableExists(mod.ID() + "_files").
+		TableExists(mod.ID() + "_file_refs").
+		TableExists(mod.ID() + "_resumables")
+
+	states, err := Migrations(mod).States()
+	a.NotError(err).Length(states, 3)
+	for _, state := range states {
+		a.False(state.Applied.IsZero())
+	}
+
+	_, err = mod.DB().Insert(&filePO{Prefix: "/admin", UID: 1, Name: "1.png", Private: true})
+	a.NotError(err)
+
+	a.NotError(Migrations(mod).Downgrade(0))
+	s.TableNotExists(mod.ID() + "_files").
+		TableNotExists(mod.ID() + "_file_refs").
+		TableNotExists(mod.ID() + "_resumables")
+}
+
+func TestUpgrade_v1(t *testing.T) {
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+	defer s.Close()
+
+	// 回退至版本 1 的数据表
+	mod := s.NewModule("upload")
+	ms := Migrations(mod)
+	a.NotError(ms.Upgrade())
+	a.NotError(ms.Downgrade(1))
+	s.TableNotExists(mod.ID() + "_resumables")
+	_, err := mod.DB().Insert(&fileV1PO{UID: 1, Name: "1.png", URL: "/1.png"})
+	a.NotError(err)
+
+	a.NotError(Migrations(mod).Upgrade())
+	f := &filePO{Name: "1.png"}
+	found, err := mod.DB().Select(f)
+	a.NotError(err).True(found).
+		Equal(f.UID, 1).
+		Empty(f.Prefix).
+		False(f.Private)
+
+	a.NotError(ms.Downgrade(1))
+	f1 := &fileV1PO{Name: "1.png"}
+	found, err = mod.DB().Select(f1)
+	a.NotError(err).True(found).Equal(f1.URL, "/1.png")
+}
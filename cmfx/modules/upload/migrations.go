@@ -0,0 +1,83 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package upload
+
+import (
+	"time"
+
+	"github.com/issue9/orm/v6"
+	"github.com/issue9/orm/v6/core"
+	"github.com/issue9/web"
+
+	"github.com/issue9/cmfx/cmfx"
+)
+
+// Migrations 当前模块的数据库迁移列表
+func Migrations(mod *cmfx.Module) *cmfx.Migrations {
+	return mod.Migrations(
+		&cmfx.Migration{
+			Version: 1,
+			Desc:    web.Phrase("create upload files tables"),
+			Up:      func(mod *cmfx.Module) error { return mod.DB().Create(&fileV1PO{}, &refPO{}) },
+			Down:    func(mod *cmfx.Module) error { return mod.DB().Drop(&fileV1PO{}, &refPO{}) },
+		},
+		&cmfx.Migration{
+			Version: 2,
+			Desc:    web.Phrase("create upload resumables table"),
+			Up:      func(mod *cmfx.Module) error { return mod.DB().Create(&resumablePO{}) },
+			Down:    func(mod *cmfx.Module) error { return mod.DB().Drop(&resumablePO{}) },
+		},
+		&cmfx.Migration{
+			Version: 3,
+			Desc:    web.Phrase("add private and prefix columns to upload files"),
+			Up: func(mod *cmfx.Module) error {
+				sb := mod.DB().SQLBuilder()
+				table := orm.TableName(&filePO{})
+
+				err := sb.AddColumn().Table(table).Column("prefix", core.String, false, false, true, "", 200).Exec()
+				if err != nil {
+					return err
+				}
+
+				err = sb.AddColumn().Table(table).Column("private", core.Bool, false, false, true, false).Exec()
+				if err != nil {
+					return err
+				}
+
+				return sb.CreateIndex().Table(table).Name(filePrefixUIDIndex).Columns("prefix", "uid").Exec()
+			},
+			Down: func(mod *cmfx.Module) error {
+				sb := mod.DB().SQLBuilder()
+				table := orm.TableName(&filePO{})
+
+				if err := sb.DropIndex().Table(table).Name(filePrefixUIDIndex).Exec(); err != nil {
+					return err
+				}
+				if err := sb.DropColumn().Table(table).Column("private").Exec(); err != nil {
+					return err
+				}
+				return sb.DropColumn().Table(table).Column("prefix").Exec()
+			},
+		},
+	)
+}
+
+// 与 [filePO] 中的索引同名，由数据表名称和索引名称组成。
+const filePrefixUIDIndex = "#_files_i_prefix_uid"
+
+// 版本 1 中 [filePO] 的结构
+type fileV1PO struct {
+	ID       int64     `orm:"name(id);ai"`
+	UID      int64     `orm:"name(uid)"`
+	Name     string    `orm:"name(name);len(200);unique(u_name)"`
+	URL      string    `orm:"name(url);len(1000)"`
+	Filename string    `orm:"name(filename);len(200)"`
+	Size     int64     `orm:"name(size);index(i_hash_size)"`
+	Mime     string    `orm:"name(mime);len(100)"`
+	Hash     string    `orm:"name(hash);len(64);index(i_hash_size)"`
+	Created  time.Time `orm:"name(created)"`
+}
+
+func (*fileV1PO) TableName() string { return "_files" }
@@ -0,0 +1,67 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package upload
+
+import "time"
+
+// 上传文件的元数据
+type filePO struct {
+	ID       int64     `orm:"name(id);ai"`
+	Prefix   string    `orm:"name(prefix);len(200);index(i_prefix_uid)"` // 上传接口的路由前缀，用于区分不同的用户体系。
+	UID      int64     `orm:"name(uid);index(i_prefix_uid)"`             // 上传者
+	Name     string    `orm:"name(name);len(200);unique(u_name)"`        // 在 [upload.Saver] 中的文件名
+	URL      string    `orm:"name(url);len(1000)"`                       // 访问地址，即 [upload.Saver.Save] 的返回值。
+	Filename string    `orm:"name(filename);len(200)"`                   // 上传时的原始文件名
+	Size     int64     `orm:"name(size);index(i_hash_size)"`             // 文件大小
+	Mime     string    `orm:"name(mime);len(100)"`                       // 根据文件内容检测的类型
+	Hash     string    `orm:"name(hash);len(64);index(i_hash_size)"`     // 文件内容的 SHA-256 值
+	Private  bool      `orm:"name(private)"`                             // 私有文件，只能由上传者或是签名地址访问。
+	Created  time.Time `orm:"name(created)"`
+}
+
+func (*filePO) TableName() string { return "_files" }
+
+func (p *filePO) BeforeInsert() error {
+	p.Created = time.Now()
+	return nil
+}
+
+// 文件的引用
+//
+// 每个引用者对应一条记录，没有任何引用的文件会被 [Module.GC] 删除。
+type refPO struct {
+	ID      int64     `orm:"name(id);ai"`
+	File    int64     `orm:"name(file);unique(u_file_owner)"`
+	Owner   string    `orm:"name(owner);len(200);unique(u_file_owner);index(i_owner)"`
+	Created time.Time `orm:"name(created)"`
+}
+
+func (*refPO) TableName() string { return "_file_refs" }
+
+func (p *refPO) BeforeInsert() error {
+	p.Created = time.Now()
+	return nil
+}
+
+// 断点续传的上传会话
+type resumablePO struct {
+	ID       int64     `orm:"name(id);ai"`
+	Prefix   string    `orm:"name(prefix);len(200);unique(u_prefix_token)"` // 注册上传接口的路由前缀，不同前缀的用户可能不同。
+	Token    string    `orm:"name(token);len(100);unique(u_prefix_token)"`
+	UID      int64     `orm:"name(uid)"`
+	Filename string    `orm:"name(filename);len(200)"`
+	Size     int64     `orm:"name(size)"`             // 文件的总大小
+	Received int64     `orm:"name(received)"`         // 已经接收的大小
+	Checksum string    `orm:"name(checksum);len(64)"` // 文件的 SHA-256 值，为空表示不验证。
+	Expires  time.Time `orm:"name(expires);index(i_expires)"`
+	Created  time.Time `orm:"name(created)"`
+}
+
+func (*resumablePO) TableName() string { return "_resumables" }
+
+func (p *resumablePO) BeforeInsert() error {
+	p.Created = time.Now()
+	return nil
+}
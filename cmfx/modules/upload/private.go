@@ -0,0 +1,301 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package upload
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/issue9/mux/v9/header"
+	"github.com/issue9/orm/v6"
+	"github.com/issue9/web"
+	"github.com/issue9/web/filter"
+	"github.com/issue9/web/openapi"
+
+	"github.com/issue9/cmfx/cmfx"
+	"github.com/issue9/cmfx/cmfx/locales"
+	"github.com/issue9/cmfx/cmfx/query"
+)
+
+var (
+	errQuotaExceeded  = errors.New("upload quota exceeded")
+	errSecretRequired = errors.New("upload secret is required for private files")
+)
+
+// 签名地址中的查询参数
+const (
+	queryExpires = "expires"
+	querySign    = "sign"
+)
+
+// 签名地址的最长有效期
+const maxSignTTL = 7 * 24 * time.Hour
+
+// SignURL 为地址 u 生成签名地址
+//
+// 签名地址在 ttl 时间内可以直接访问私有文件，u 为 [Module.Handle] 上传文件时返回的地址。
+// 未指定 [StorageConfig.Secret] 时返回错误。
+func (m *Module) SignURL(u string, ttl time.Duration) (string, time.Time, error) {
+	if len(m.secret) == 0 {
+		return "", time.Time{}, errSecretRequired
+	}
+
+	uu, err := url.Parse(u)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	expires := time.Now().Add(ttl).Truncate(time.Second)
+	exp := strconv.FormatInt(expires.Unix(), 10)
+
+	q := uu.Query()
+	q.Set(queryExpires, exp)
+	q.Set(querySign, m.sign(path.Base(uu.Path), exp))
+	uu.RawQuery = q.Encode()
+	return uu.String(), expires, nil
+}
+
+func (m *Module) sign(name, expires string) string {
+	h := hmac.New(sha256.New, m.secret)
+	h.Write([]byte(name))
+	h.Write([]byte{'\n'})
+	h.Write([]byte(expires))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// 验证请求是否带有文件 name 的有效签名
+func (m *Module) verify(ctx *web.Context, name string) bool {
+	q := ctx.Request().URL.Query()
+	exp, sign := q.Get(queryExpires), q.Get(querySign)
+	if exp == "" || sign == "" || len(m.secret) == 0 {
+		return false
+	}
+
+	unix, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || time.Unix(unix, 0).Before(ctx.Begin()) {
+		return false
+	}
+	return hmac.Equal([]byte(sign), []byte(m.sign(name, exp)))
+}
+
+// 获取文件名为 name 的元数据，不存在时返回 nil。
+func (m *Module) getFileByName(name string) (*filePO, error) {
+	po := &filePO{}
+	size, err := m.db.SQLBuilder().Select().
+		From(orm.TableName(po)).
+		Where("name=?", name).
+		Limit(1).
+		QueryObject(true, po)
+	if err != nil || size == 0 {
+		return nil, err
+	}
+	return po, nil
+}
+
+// 私有文件只有在带有效签名时才交由 next 处理
+func (m *Module) privateHandler(next web.HandlerFunc) web.HandlerFunc {
+	return func(ctx *web.Context) web.Responser {
+		name, _ := ctx.Route().Params().Get("file")
+		po, err := m.getFileByName(name)
+		if err != nil {
+			return ctx.Error(err, "")
+		}
+
+		if po != nil && po.Private {
+			if !m.verify(ctx, name) {
+				return ctx.NotFound() // 不暴露文件是否存在
+			}
+			ctx.Header().Set(header.CacheControl, "private")
+		}
+		return next(ctx)
+	}
+}
+
+// SignTO 生成签名地址的参数
+type SignTO struct {
+	TTL int64 `query:"ttl,3600"` // 有效期，单位为秒。
+}
+
+func (to *SignTO) Filter(ctx *web.FilterContext) {
+	v := func(ttl int64) bool { return ttl > 0 && ttl <= int64(maxSignTTL/time.Second) }
+	ctx.Add(filter.NewBuilder(filter.V(v, locales.MustBeBetweenEqual(int64(1), int64(maxSignTTL/time.Second))))("ttl", &to.TTL))
+}
+
+// SignVO 签名地址
+type SignVO struct {
+	URL     string    `json:"url" yaml:"url" cbor:"url"`
+	Expires time.Time `json:"expires" yaml:"expires" cbor:"expires"`
+}
+
+// UsageVO 用户的存储空间使用情况
+type UsageVO struct {
+	UID   int64 `orm:"name(uid)" json:"uid" yaml:"uid" cbor:"uid"`
+	Size  int64 `orm:"name(size)" json:"size" yaml:"size" cbor:"size"`                         // 已经使用的空间
+	Count int64 `orm:"name(count)" json:"count" yaml:"count" cbor:"count"`                     // 文件的数量
+	Quota int64 `orm:"-" json:"quota,omitempty" yaml:"quota,omitempty" cbor:"quota,omitempty"` // 可用空间，为零表示不限制。
+}
+
+// Usage 用户 uid 的存储空间使用情况
+//
+// prefix 为调用 [Module.Handle] 时的路由前缀，用于区分不同的用户体系；
+// 仅统计由该用户上传的文件，内容相同的文件只计算在首次上传的用户名下。
+// 返回值的 [UsageVO.Quota] 为零。
+func (m *Module) Usage(prefix string, uid int64) (*UsageVO, error) {
+	u := &UsageVO{UID: uid}
+	_, err := m.db.SQLBuilder().Select().
+		Column("COALESCE(SUM(size),0) AS size").
+		Column("COUNT(*) AS count").
+		From(orm.TableName(&filePO{})).
+		Where("prefix=?", prefix).
+		And("uid=?", uid).
+		QueryObject(true, u)
+	return u, err
+}
+
+// Usages 用户体系 prefix 中所有用户的存储空间使用情况
+//
+// 按使用的空间从大到小排列；f 用于对每一个元素进行额外处理，比如设置 [UsageVO.Quota]，可以为空。
+func (m *Module) Usages(prefix string, l *query.Limit, f func(*UsageVO)) (*query.Page[UsageVO], error) {
+	table := orm.TableName(&filePO{})
+	count, err := m.db.SQLBuilder().Select().
+		Column("COUNT(DISTINCT uid) AS cnt").
+		From(table).
+		Where("prefix=?", prefix).
+		QueryInt("cnt")
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, nil
+	}
+
+	offset := l.Page * l.Size
+	curr := make([]*UsageVO, 0, l.Size)
+	n, err := m.db.SQLBuilder().Select().
+		Column("uid").
+		Column("SUM(size) AS size").
+		Column("COUNT(*) AS count").
+		From(table).
+		Where("prefix=?", prefix).
+		Group("uid").
+		Desc("size").
+		Asc("uid").
+		Limit(l.Size, offset).
+		QueryObject(true, &curr)
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, nil
+	}
+
+	if f != nil {
+		for _, u := range curr {
+			f(u)
+		}
+	}
+
+	return &query.Page[UsageVO]{
+		Count:   count,
+		Current: curr,
+		More:    int64(offset+n) < count,
+	}, nil
+}
+
+// 注册供上传者使用的文件接口
+//
+// quota 用于获取用户可用的存储空间。
+func (m *Module) handleFiles(prefix *web.Prefix, api func(func(*openapi.Operation)) web.Middleware, uid func(*web.Context) int64, quota func(int64) int64) {
+	pattern := prefix.Pattern()
+
+	// 当前用户上传的文件
+	owned := func(ctx *web.Context) (*filePO, web.Responser) {
+		name, resp := ctx.PathString("file", cmfx.NotFoundInvalidPath)
+		if resp != nil {
+			return nil, resp
+		}
+
+		po, err := m.getFileByName(name)
+		if err != nil {
+			return nil, ctx.Error(err, "")
+		}
+		if po == nil || po.Prefix != pattern || po.UID != uid(ctx) {
+			return nil, ctx.NotFound()
+		}
+		return po, nil
+	}
+
+	prefix.Get(m.prefix+"/files/{file}", func(ctx *web.Context) web.Responser {
+		if _, resp := owned(ctx); resp != nil {
+			return resp
+		}
+		ctx.Header().Set(header.CacheControl, "private")
+		return m.serve(ctx)
+	}, api(func(o *openapi.Operation) {
+		o.Tag("upload").
+			Desc(web.Phrase("get the uploaded file of current user"), nil).
+			Path("file", openapi.TypeString, web.Phrase("the file name"), nil).
+			Query("w", openapi.TypeInteger, web.Phrase("the width of the image thumbnail"), nil).
+			Response("200", nil, nil, nil)
+	})).
+		Get(m.prefix+"/files/{file}/signed", func(ctx *web.Context) web.Responser {
+			po, resp := owned(ctx)
+			if resp != nil {
+				return resp
+			}
+
+			q := &SignTO{}
+			if resp := ctx.QueryObject(true, q, cmfx.BadRequestInvalidQuery); resp != nil {
+				return resp
+			}
+
+			u, expires, err := m.SignURL(po.URL, time.Duration(q.TTL)*time.Second)
+			if err != nil {
+				return ctx.Error(err, "")
+			}
+			return web.OK(&SignVO{URL: u, Expires: expires})
+		}, api(func(o *openapi.Operation) {
+			o.Tag("upload").
+				Desc(web.Phrase("get the signed url of the uploaded file"), nil).
+				Path("file", openapi.TypeString, web.Phrase("the file name"), nil).
+				QueryObject(SignTO{}, nil).
+				Response200(SignVO{})
+		})).
+		Get(m.prefix+"/usage", func(ctx *web.Context) web.Responser {
+			id := uid(ctx)
+			u, err := m.Usage(pattern, id)
+			if err != nil {
+				return ctx.Error(err, "")
+			}
+			u.Quota = quota(id)
+			return web.OK(u)
+		}, api(func(o *openapi.Operation) {
+			o.Tag("upload").
+				Desc(web.Phrase("get the storage usage of current user"), nil).
+				Response200(UsageVO{})
+		}))
+}
+
+// 检测用户 uid 在添加 size 大小的文件之后是否超出 quota
+func (m *Module) checkQuota(prefix string, uid, quota, size int64) error {
+	if quota <= 0 {
+		return nil
+	}
+
+	u, err := m.Usage(prefix, uid)
+	if err != nil {
+		return err
+	}
+	if u.Size+size > quota {
+		return errQuotaExceeded
+	}
+	return nil
+}
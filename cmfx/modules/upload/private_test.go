@@ -0,0 +1,132 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package upload
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/mux/v9/header"
+	"github.com/issue9/web"
+	"github.com/issue9/web/server/servertest"
+
+	"github.com/issue9/cmfx/cmfx/initial/test"
+	"github.com/issue9/cmfx/cmfx/query"
+)
+
+func TestModule_private(t *testing.T) {
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+	defer os.RemoveAll("./testdata")
+
+	m := newFilesModule(a, s)
+	conf := &Config{Field: "files", Quota: 10}
+	a.NotError(conf.SanitizeConfig())
+	uid := func(ctx *web.Context) int64 {
+		id, _ := strconv.ParseInt(ctx.Request().Header.Get("uid"), 10, 64)
+		return id
+	}
+	m.Handle(s.Router().Prefix(""), s.Module().API, conf, uid, nil)
+
+	defer servertest.Run(a, s.Module().Server())()
+	defer s.Close()
+
+	private := &Policy{Private: true}
+	u1, err := m.newRecorder("", 1, 10, private).Save(&memFile{Reader: bytes.NewReader([]byte("12345"))}, "a.txt", ".txt")
+	a.NotError(err).Equal(u1, "/files/1.txt")
+
+	// 其它用户上传相同内容的私有文件，不会共用。
+	u2, err := m.newRecorder("", 2, 10, private).Save(&memFile{Reader: bytes.NewReader([]byte("12345"))}, "a.txt", ".txt")
+	a.NotError(err).Equal(u2, "/files/2.txt")
+
+	// 公开文件
+	u3, err := m.newRecorder("", 1, 10, nil).Save(&memFile{Reader: bytes.NewReader([]byte("abc"))}, "b.txt", ".txt")
+	a.NotError(err).Equal(u3, "/files/3.txt")
+
+	// 超出空间
+	_, err = m.newRecorder("", 1, 10, nil).Save(&memFile{Reader: bytes.NewReader([]byte("abc"))}, "b.txt", ".txt") // 已经存在，不占用空间。
+	a.NotError(err)
+	_, err = m.newRecorder("", 1, 10, nil).Save(&memFile{Reader: bytes.NewReader([]byte("xyz"))}, "c.txt", ".txt")
+	a.Equal(err, errQuotaExceeded)
+
+	u, err := m.Usage("", 1)
+	a.NotError(err).Equal(u.Size, 8).Equal(u.Count, 2)
+
+	p, err := m.Usages("", &query.Limit{Size: 10}, func(u *UsageVO) { u.Quota = 10 })
+	a.NotError(err).Equal(p.Count, 2).Length(p.Current, 2).
+		Equal(p.Current[0].UID, 1).Equal(p.Current[0].Size, 8).Equal(p.Current[0].Quota, 10).
+		Equal(p.Current[1].UID, 2).Equal(p.Current[1].Size, 5)
+
+	p, err = m.Usages("/not-exists", &query.Limit{Size: 10}, nil)
+	a.NotError(err).Nil(p)
+
+	// 公开访问
+
+	s.Get("/files/1.txt").Do(nil).Status(http.StatusNotFound)
+	s.Get("/files/3.txt").Do(nil).Status(http.StatusOK).StringBody("abc")
+
+	signed, _, err := m.SignURL(u1, time.Minute)
+	a.NotError(err)
+	s.Get(signed).Do(nil).Status(http.StatusOK).StringBody("12345")
+
+	uu, err := url.Parse(signed)
+	a.NotError(err)
+	q := uu.Query()
+	q.Set(queryExpires, strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+	s.Get("/files/1.txt?" + q.Encode()).Do(nil).Status(http.StatusNotFound) // 修改了有效期
+
+	signed, _, err = m.SignURL(u1, -time.Minute)
+	a.NotError(err)
+	s.Get(signed).Do(nil).Status(http.StatusNotFound) // 已过期
+
+	// 上传者访问
+
+	s.Get("/files/files/1.txt").Header("uid", "1").Do(nil).Status(http.StatusOK).StringBody("12345")
+	s.Get("/files/files/1.txt").Header("uid", "2").Do(nil).Status(http.StatusNotFound)
+
+	s.Get("/files/files/1.txt/signed?ttl=60").Header("uid", "1").Header(header.Accept, header.JSON).Do(nil).
+		Status(http.StatusOK).
+		BodyFunc(func(a *assert.Assertion, body []byte) {
+			vo := &SignVO{}
+			a.NotError(json.Unmarshal(body, vo))
+			s.Get(vo.URL).Do(nil).Status(http.StatusOK).StringBody("12345")
+		})
+	s.Get("/files/files/1.txt/signed?ttl=0").Header("uid", "1").Header(header.Accept, header.JSON).Do(nil).
+		Status(http.StatusBadRequest)
+
+	s.Get("/files/usage").Header("uid", "1").Header(header.Accept, header.JSON).Do(nil).
+		Status(http.StatusOK).
+		BodyFunc(func(a *assert.Assertion, body []byte) {
+			vo := &UsageVO{}
+			a.NotError(json.Unmarshal(body, vo)).
+				Equal(vo.UID, 1).Equal(vo.Size, 8).Equal(vo.Count, 2).Equal(vo.Quota, 10)
+		})
+}
+
+func TestModule_privateWithoutSecret(t *testing.T) {
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+	defer s.Close()
+
+	mod := s.NewModule("upload")
+	Install(mod)
+	m := Load(mod, "/files", nil, nil)
+
+	_, _, err := m.SignURL("/files/1.txt", time.Minute)
+	a.Equal(err, errSecretRequired)
+
+	conf := &Config{Field: "files", Private: true}
+	a.NotError(conf.SanitizeConfig())
+	a.PanicString(func() {
+		m.Handle(s.Router().Prefix(""), s.Module().API, conf, func(*web.Context) int64 { return 1 }, nil)
+	}, errSecretRequired.Error())
+}
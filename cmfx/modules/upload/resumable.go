@@ -0,0 +1,421 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package upload
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/issue9/mux/v9/header"
+	"github.com/issue9/orm/v6"
+	"github.com/issue9/web"
+	"github.com/issue9/web/filter"
+	"github.com/issue9/web/openapi"
+
+	"github.com/issue9/cmfx/cmfx"
+	"github.com/issue9/cmfx/cmfx/filters"
+	"github.com/issue9/cmfx/cmfx/locales"
+)
+
+const (
+	headerUploadOffset = "Upload-Offset" // 已经接收的字节数
+	headerUploadLength = "Upload-Length" // 文件的总大小
+
+	resumableMimetype = "application/offset+octet-stream" // 上传分块的 Content-Type
+)
+
+// ResumableTO 创建断点续传的参数
+type ResumableTO struct {
+	conf *Config
+
+	Filename string `json:"filename" yaml:"filename" cbor:"filename" comment:"filename"`
+	Size     int64  `json:"size" yaml:"size" cbor:"size" comment:"file size"`
+	Checksum string `json:"checksum,omitempty" yaml:"checksum,omitempty" cbor:"checksum,omitempty" comment:"sha256 checksum of file"`
+}
+
+func (to *ResumableTO) Filter(ctx *web.FilterContext) {
+	extValidator := func(filename string) bool {
+		ext := path.Ext(filename)
+		return len(to.conf.Exts) == 0 || slices.ContainsFunc(to.conf.Exts, func(e string) bool { return strings.EqualFold(e, ext) })
+	}
+	sizeValidator := func(size int64) bool { return size > 0 && size <= to.conf.Resumable.Size }
+	checksumValidator := func(sum string) bool {
+		if sum == "" {
+			return true
+		}
+		_, err := hex.DecodeString(sum)
+		return err == nil && len(sum) == 2*sha256.Size
+	}
+
+	ctx.Add(filters.NotEmpty("filename", &to.Filename)).
+		Add(filter.NewBuilder(filter.V(extValidator, locales.InvalidValue))("filename", &to.Filename)).
+		Add(filter.NewBuilder(filter.V(sizeValidator, locales.MustBeBetweenEqual(int64(1), to.conf.Resumable.Size)))("size", &to.Size)).
+		Add(filter.NewBuilder(filter.V(checksumValidator, locales.InvalidValue))("checksum", &to.Checksum))
+}
+
+// ResumableVO 断点续传的状态
+type ResumableVO struct {
+	Token   string    `json:"token" yaml:"token" cbor:"token"`
+	Size    int64     `json:"size" yaml:"size" cbor:"size"`       // 文件的总大小
+	Offset  int64     `json:"offset" yaml:"offset" cbor:"offset"` // 已经接收的大小
+	Expires time.Time `json:"expires" yaml:"expires" cbor:"expires"`
+}
+
+// 断点续传的接口
+//
+// 流程如下：
+//   - POST {prefix}/resumable 创建上传，返回 token；
+//   - PATCH {prefix}/resumable/{token} 上传分块，报头 Upload-Offset 需要与已接收的大小相同；
+//   - HEAD {prefix}/resumable/{token} 查询已接收的大小，用于中断之后恢复上传；
+//   - POST {prefix}/resumable/{token} 在所有数据上传完成之后，验证并保存文件；
+//   - DELETE {prefix}/resumable/{token} 放弃上传；
+type resumable struct {
+	m      *Module
+	conf   *Config
+	prefix string // 路由的前缀，用于区分不同的用户体系。
+	root   *os.Root
+	uid    func(*web.Context) int64
+	quota  func(int64) int64
+
+	// 正在处理的上传会话，以 token 为键名。
+	//
+	// 写入分块和更新已接收的大小并不是原子操作，同一会话的并发请求可能写入相同的位置，
+	// 所以同一会话同时只能有一个请求在处理。
+	busy sync.Map
+}
+
+func (m *Module) handleResumable(prefix *web.Prefix, api func(func(*openapi.Operation)) web.Middleware, conf *Config, uid func(*web.Context) int64, quota func(int64) int64) {
+	if err := os.MkdirAll(conf.Resumable.Dir, fs.ModePerm); err != nil {
+		panic(web.SprintError(m.mod.Server().Locale().Printer(), true, err))
+	}
+	root, err := os.OpenRoot(conf.Resumable.Dir)
+	if err != nil {
+		panic(web.SprintError(m.mod.Server().Locale().Printer(), true, err))
+	}
+
+	r := &resumable{m: m, conf: conf, prefix: prefix.Pattern(), root: root, uid: uid, quota: quota}
+	m.resumables[r.prefix] = r
+
+	base := m.prefix + "/resumable"
+	prefix.Post(base, r.create, api(func(o *openapi.Operation) {
+		o.Tag("upload").
+			Desc(web.Phrase("create resumable upload"), nil).
+			Body(&ResumableTO{}, false, nil, nil).
+			Response("201", &ResumableVO{}, nil, nil)
+	})).
+		Get(base+"/{token}", r.status, api(func(o *openapi.Operation) {
+			o.Tag("upload").
+				Desc(web.Phrase("get resumable upload status"), nil).
+				Path("token", openapi.TypeString, web.Phrase("the token of resumable upload"), nil).
+				Response200(&ResumableVO{})
+		})).
+		Patch(base+"/{token}", r.exclusive(r.patch), api(func(o *openapi.Operation) {
+			o.Tag("upload").
+				Desc(web.Phrase("upload resumable chunk"), nil).
+				Path("token", openapi.TypeString, web.Phrase("the token of resumable upload"), nil).
+				Header(headerUploadOffset, openapi.TypeInteger, web.Phrase("the offset of the chunk"), nil).
+				ResponseEmpty("204")
+		})).
+		Post(base+"/{token}", r.exclusive(r.finish), api(func(o *openapi.Operation) {
+			o.Tag("upload").
+				Desc(web.Phrase("finish resumable upload"), nil).
+				Path("token", openapi.TypeString, web.Phrase("the token of resumable upload"), nil).
+				Response("201", []string{}, nil, nil)
+		})).
+		Delete(base+"/{token}", r.exclusive(r.delete), api(func(o *openapi.Operation) {
+			o.Tag("upload").
+				Desc(web.Phrase("cancel resumable upload"), nil).
+				Path("token", openapi.TypeString, web.Phrase("the token of resumable upload"), nil).
+				ResponseEmpty("204")
+		}))
+}
+
+func (po *resumablePO) filename() string { return po.Token + ".part" }
+
+func (po *resumablePO) toVO() *ResumableVO {
+	return &ResumableVO{Token: po.Token, Size: po.Size, Offset: po.Received, Expires: po.Expires}
+}
+
+func (r *resumable) create(ctx *web.Context) web.Responser {
+	data := &ResumableTO{conf: r.conf}
+	if resp := ctx.Read(true, data, cmfx.BadRequestInvalidBody); resp != nil {
+		return resp
+	}
+
+	uid := r.uid(ctx)
+	switch err := r.m.checkQuota(r.prefix, uid, r.quota(uid), data.Size); {
+	case errors.Is(err, errQuotaExceeded):
+		return ctx.Problem(cmfx.RequestEntityTooLargeQuotaExceeded)
+	case err != nil:
+		return ctx.Error(err, "")
+	}
+
+	po := &resumablePO{
+		Prefix:   r.prefix,
+		Token:    r.m.mod.Server().UniqueID(),
+		UID:      uid,
+		Filename: data.Filename,
+		Size:     data.Size,
+		Checksum: strings.ToLower(data.Checksum),
+		Expires:  ctx.Begin().Add(r.conf.Resumable.Expires.Duration()),
+	}
+
+	f, err := r.root.Create(po.filename())
+	if err != nil {
+		return ctx.Error(err, "")
+	}
+	if err := f.Close(); err != nil {
+		return ctx.Error(err, "")
+	}
+
+	if _, err := r.m.db.Insert(po); err != nil {
+		return ctx.Error(errors.Join(err, r.root.Remove(po.filename())), "")
+	}
+
+	ctx.Header().Set(headerUploadOffset, "0")
+	ctx.Header().Set(headerUploadLength, strconv.FormatInt(po.Size, 10))
+	return web.Created(po.toVO(), ctx.Request().URL.Path+"/"+po.Token)
+}
+
+// 保证同一上传会话同时只有一个请求在处理
+//
+// 对同一会话的并发请求直接返回 409，客户端可以通过 HEAD 查询已接收的大小之后重试。
+func (r *resumable) exclusive(next web.HandlerFunc) web.HandlerFunc {
+	return func(ctx *web.Context) web.Responser {
+		token, resp := ctx.PathString("token", cmfx.NotFoundInvalidPath)
+		if resp != nil {
+			return resp
+		}
+
+		if _, loaded := r.busy.LoadOrStore(token, struct{}{}); loaded {
+			return ctx.Problem(cmfx.Conflict)
+		}
+		defer r.busy.Delete(token)
+
+		return next(ctx)
+	}
+}
+
+// 获取当前请求对应的上传会话
+func (r *resumable) session(ctx *web.Context) (*resumablePO, web.Responser) {
+	token, resp := ctx.PathString("token", cmfx.NotFoundInvalidPath)
+	if resp != nil {
+		return nil, resp
+	}
+
+	po := &resumablePO{}
+	size, err := r.m.db.SQLBuilder().Select().
+		From(orm.TableName(po)).
+		Where("prefix=?", r.prefix).
+		And("token=?", token).
+		QueryObject(true, po)
+	if err != nil {
+		return nil, ctx.Error(err, "")
+	}
+	if size == 0 || po.UID != r.uid(ctx) || po.Expires.Before(ctx.Begin()) {
+		return nil, ctx.NotFound()
+	}
+	return po, nil
+}
+
+func (r *resumable) status(ctx *web.Context) web.Responser {
+	po, resp := r.session(ctx)
+	if resp != nil {
+		return resp
+	}
+
+	ctx.Header().Set(header.CacheControl, header.NoStore)
+	ctx.Header().Set(headerUploadOffset, strconv.FormatInt(po.Received, 10))
+	ctx.Header().Set(headerUploadLength, strconv.FormatInt(po.Size, 10))
+	return web.OK(po.toVO())
+}
+
+func (r *resumable) patch(ctx *web.Context) web.Responser {
+	po, resp := r.session(ctx)
+	if resp != nil {
+		return resp
+	}
+
+	if ctx.Request().Header.Get(header.ContentType) != resumableMimetype {
+		return ctx.Problem(cmfx.BadRequestInvalidHeader)
+	}
+	offset, err := strconv.ParseInt(ctx.Request().Header.Get(headerUploadOffset), 10, 64)
+	if err != nil {
+		return ctx.Problem(cmfx.BadRequestInvalidHeader)
+	}
+	if offset != po.Received {
+		ctx.Header().Set(headerUploadOffset, strconv.FormatInt(po.Received, 10))
+		return ctx.Problem(cmfx.Conflict)
+	}
+
+	n, tooLarge, copyErr := r.write(po, offset, ctx.Request().Body)
+	if n < 0 {
+		return ctx.Error(copyErr, "")
+	}
+
+	// 仅在 received 未被其它请求修改的情况下更新。
+	rslt, err := r.m.db.SQLBuilder().Update().
+		Table(orm.TableName(po)).
+		Set("received", offset+n).
+		Set("expires", time.Now().Add(r.conf.Resumable.Expires.Duration())).
+		Where("id=?", po.ID).
+		And("received=?", offset).
+		Exec()
+	if err != nil {
+		return ctx.Error(err, "")
+	}
+	if rows, err := rslt.RowsAffected(); err != nil {
+		return ctx.Error(err, "")
+	} else if rows == 0 {
+		return ctx.Problem(cmfx.Conflict)
+	}
+
+	ctx.Header().Set(headerUploadOffset, strconv.FormatInt(offset+n, 10))
+	switch {
+	case tooLarge:
+		return ctx.Problem(cmfx.RequestEntityTooLarge)
+	case copyErr != nil: // 已经接收的部分依然有效，客户端可以从新的位置继续上传。
+		return ctx.Error(copyErr, "")
+	default:
+		return web.NoContent()
+	}
+}
+
+// 将 body 写入 offset 位置
+//
+// 返回写入的字节数，如果为负数，表示无法写入，err 为具体的错误；
+// tooLarge 表示内容超过了文件的总大小，超出的部分会被丢弃。
+func (r *resumable) write(po *resumablePO, offset int64, body io.Reader) (n int64, tooLarge bool, err error) {
+	f, err := r.root.OpenFile(po.filename(), os.O_WRONLY, 0)
+	if err != nil {
+		return -1, false, err
+	}
+	defer f.Close()
+
+	if _, err = f.Seek(offset, io.SeekStart); err != nil {
+		return -1, false, err
+	}
+
+	remain := po.Size - offset
+	n, err = io.Copy(f, io.LimitReader(body, remain+1))
+	if n > remain {
+		n = remain
+		tooLarge = true
+		if err = f.Truncate(po.Size); err != nil {
+			return -1, false, err
+		}
+	}
+	return n, tooLarge, err
+}
+
+func (r *resumable) finish(ctx *web.Context) web.Responser {
+	po, resp := r.session(ctx)
+	if resp != nil {
+		return resp
+	}
+	if po.Received != po.Size {
+		return ctx.Problem(cmfx.Conflict)
+	}
+
+	f, err := r.root.Open(po.filename())
+	if err != nil {
+		return ctx.Error(err, "")
+	}
+	defer f.Close()
+
+	if po.Checksum != "" {
+		h := sha256.New()
+		if _, err := io.Copy(h, f); err != nil {
+			return ctx.Error(err, "")
+		}
+		if hex.EncodeToString(h.Sum(nil)) != po.Checksum { // 内容已经损坏，只能重新上传。
+			if err := r.m.deleteResumable(po); err != nil {
+				return ctx.Error(err, "")
+			}
+			return ctx.Problem(cmfx.BadRequestInvalidBody)
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return ctx.Error(err, "")
+		}
+	}
+
+	u, err := r.m.newRecorder(r.prefix, po.UID, r.quota(po.UID), r.conf.policy()).Save(f, po.Filename, path.Ext(po.Filename))
+	switch {
+	case errors.Is(err, errNotAllowMime), errors.Is(err, errInfected): // 不允许的文件，重新上传也没有意义。
+		if err := r.m.deleteResumable(po); err != nil {
+			return ctx.Error(err, "")
+		}
+		return ctx.Problem(cmfx.BadRequestBodyNotAllowed)
+	case errors.Is(err, errImageTooLarge):
+		if err := r.m.deleteResumable(po); err != nil {
+			return ctx.Error(err, "")
+		}
+		return ctx.Problem(cmfx.RequestEntityTooLarge)
+	case errors.Is(err, errQuotaExceeded): // 保留上传的内容，在释放空间之后可以再次提交。
+		return ctx.Problem(cmfx.RequestEntityTooLargeQuotaExceeded)
+	case err != nil:
+		return ctx.Error(err, "")
+	}
+
+	if err := r.m.deleteResumable(po); err != nil {
+		ctx.Logs().ERROR().Error(err)
+	}
+	return web.Created([]string{u}, "")
+}
+
+func (r *resumable) delete(ctx *web.Context) web.Responser {
+	po, resp := r.session(ctx)
+	if resp != nil {
+		return resp
+	}
+
+	if err := r.m.deleteResumable(po); err != nil {
+		return ctx.Error(err, "")
+	}
+	return web.NoContent()
+}
+
+// 删除上传会话及其临时文件
+func (m *Module) deleteResumable(po *resumablePO) error {
+	if r, found := m.resumables[po.Prefix]; found {
+		if err := r.root.Remove(po.filename()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	_, err := m.db.Delete(&resumablePO{ID: po.ID})
+	return err
+}
+
+// ExpireResumables 删除在 now 之前已经过期的断点续传
+//
+// 返回被删除的数量。[Module.AddGCService] 也会调用此方法。
+func (m *Module) ExpireResumables(now time.Time) (int, error) {
+	items := make([]*resumablePO, 0, 10)
+	_, err := m.db.SQLBuilder().Select().
+		From(orm.TableName(&resumablePO{})).
+		Where("expires<?", now).
+		QueryObject(true, &items)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, po := range items {
+		if err := m.deleteResumable(po); err != nil {
+			return i, err
+		}
+	}
+	return len(items), nil
+}
@@ -0,0 +1,185 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package upload
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/assert/v4/rest"
+	"github.com/issue9/mux/v9/header"
+	"github.com/issue9/web"
+	"github.com/issue9/web/server/servertest"
+
+	"github.com/issue9/cmfx/cmfx/initial/test"
+)
+
+func TestModule_resumable(t *testing.T) {
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+	defer os.RemoveAll("./testdata")
+
+	m := newFilesModule(a, s)
+	conf := &Config{
+		Size:      10,
+		Exts:      []string{".txt"},
+		Field:     "files",
+		Resumable: &ResumableConfig{Size: 20, Dir: "./testdata/tmp"},
+	}
+	a.NotError(conf.SanitizeConfig())
+	uid := func(ctx *web.Context) int64 {
+		id, _ := strconv.ParseInt(ctx.Request().Header.Get("uid"), 10, 64)
+		return id
+	}
+	m.Handle(s.Router().Prefix(""), s.Module().API, conf, uid, nil)
+
+	defer servertest.Run(a, s.Module().Server())()
+	defer s.Close()
+
+	content := []byte("hello world")
+	sum := sha256.Sum256(content)
+	checksum := hex.EncodeToString(sum[:])
+
+	create := func(to *ResumableTO) *ResumableVO {
+		body, err := json.Marshal(to)
+		a.NotError(err)
+		vo := &ResumableVO{}
+		s.Post("/files/resumable", body).
+			Header(header.ContentType, header.JSON).Header(header.Accept, header.JSON).Header("uid", "1").
+			Do(nil).
+			Status(http.StatusCreated).
+			Header(headerUploadOffset, "0").
+			BodyFunc(func(a *assert.Assertion, body []byte) {
+				a.NotError(json.Unmarshal(body, vo))
+			})
+		return vo
+	}
+
+	patch := func(token, offset string, data []byte) *rest.Response {
+		return s.Patch("/files/resumable/"+token, data).
+			Header(header.ContentType, resumableMimetype).Header("uid", "1").Header(headerUploadOffset, offset).
+			Do(nil)
+	}
+
+	// 无效的参数
+
+	s.Post("/files/resumable", []byte(`{"filename":"a.png","size":11}`)).
+		Header(header.ContentType, header.JSON).Header(header.Accept, header.JSON).
+		Do(nil).
+		Status(http.StatusBadRequest)
+	s.Post("/files/resumable", []byte(`{"filename":"a.txt","size":21}`)).
+		Header(header.ContentType, header.JSON).Header(header.Accept, header.JSON).
+		Do(nil).
+		Status(http.StatusBadRequest)
+	s.Post("/files/resumable", []byte(`{"filename":"a.txt","size":11,"checksum":"abc"}`)).
+		Header(header.ContentType, header.JSON).Header(header.Accept, header.JSON).
+		Do(nil).
+		Status(http.StatusBadRequest)
+
+	// 分块上传，总大小超过了 conf.Size。
+
+	vo := create(&ResumableTO{Filename: "a.txt", Size: int64(len(content)), Checksum: checksum})
+	a.NotEmpty(vo.Token).Equal(vo.Size, len(content)).Zero(vo.Offset)
+	a.FileExists("./testdata/tmp/" + vo.Token + ".part")
+
+	patch(vo.Token, "0", content[:5]).Status(http.StatusNoContent).Header(headerUploadOffset, "5")
+	patch(vo.Token, "0", content[5:]).Status(http.StatusConflict).Header(headerUploadOffset, "5")
+	s.Patch("/files/resumable/"+vo.Token, content[5:]).
+		Header(header.ContentType, header.JSON).Header("uid", "1").Header(headerUploadOffset, "5").
+		Do(nil).
+		Status(http.StatusBadRequest)
+
+	s.NewRequest(http.MethodHead, "http://localhost:8080/files/resumable/"+vo.Token).Header("uid", "1").Do(nil).
+		Status(http.StatusOK).
+		Header(headerUploadOffset, "5").
+		Header(headerUploadLength, strconv.Itoa(len(content)))
+
+	// 其它用户无法访问
+	s.NewRequest(http.MethodHead, "http://localhost:8080/files/resumable/"+vo.Token).Header("uid", "2").Do(nil).
+		Status(http.StatusNotFound)
+
+	// 未完成
+	s.Post("/files/resumable/"+vo.Token, nil).Header("uid", "1").Header(header.Accept, header.JSON).Do(nil).
+		Status(http.StatusConflict)
+
+	// 同一会话的其它请求正在处理
+	r := m.resumables[""]
+	r.busy.Store(vo.Token, struct{}{})
+	patch(vo.Token, "5", content[5:]).Status(http.StatusConflict)
+	s.Post("/files/resumable/"+vo.Token, nil).Header("uid", "1").Header(header.Accept, header.JSON).Do(nil).
+		Status(http.StatusConflict)
+	r.busy.Delete(vo.Token)
+
+	// 并发写入相同的位置，只有一个请求成功。
+	var wg sync.WaitGroup
+	var success atomic.Int64
+	for i := range 5 {
+		wg.Go(func() {
+			data := []byte(strings.Repeat(strconv.Itoa(i), len(content)-5))
+			if patch(vo.Token, "5", data).Resp().StatusCode == http.StatusNoContent {
+				success.Add(1)
+			}
+		})
+	}
+	wg.Wait()
+	a.Equal(success.Load(), 1)
+	part, err := os.ReadFile("./testdata/tmp/" + vo.Token + ".part")
+	a.NotError(err).Length(part, len(content))
+	a.Equal(strings.Count(string(part[5:]), string(part[5])), len(content)-5) // 内容来自同一个请求
+
+	// 恢复为正确的内容
+	a.NotError(os.WriteFile("./testdata/tmp/"+vo.Token+".part", content, 0o644))
+	s.Post("/files/resumable/"+vo.Token, nil).Header("uid", "1").Header(header.Accept, header.JSON).Do(nil).
+		Status(http.StatusCreated).
+		BodyFunc(func(a *assert.Assertion, body []byte) {
+			urls := []string{}
+			a.NotError(json.Unmarshal(body, &urls)).Equal(urls, []string{"/files/1.txt"})
+		})
+	a.FileNotExists("./testdata/tmp/" + vo.Token + ".part")
+	s.Get("/files/resumable/"+vo.Token).Header("uid", "1").Do(nil).Status(http.StatusNotFound)
+
+	f, err := m.GetFile("/files/1.txt")
+	a.NotError(err).Equal(f.UID, 1).Equal(f.Filename, "a.txt").Equal(f.Hash, checksum)
+
+	// 超出大小
+
+	vo = create(&ResumableTO{Filename: "b.txt", Size: 5})
+	patch(vo.Token, "0", content).Status(http.StatusRequestEntityTooLarge).Header(headerUploadOffset, "5")
+
+	// 校验失败
+
+	s.Post("/files/resumable/"+vo.Token, nil).Header("uid", "1").Header(header.Accept, header.JSON).Do(nil).
+		Status(http.StatusCreated)
+	vo = create(&ResumableTO{Filename: "c.txt", Size: 5, Checksum: checksum})
+	patch(vo.Token, "0", content[:5]).Status(http.StatusNoContent)
+	s.Post("/files/resumable/"+vo.Token, nil).Header("uid", "1").Header(header.Accept, header.JSON).Do(nil).
+		Status(http.StatusBadRequest)
+	a.FileNotExists("./testdata/tmp/" + vo.Token + ".part")
+
+	// 取消
+
+	vo = create(&ResumableTO{Filename: "d.txt", Size: 5})
+	s.Delete("/files/resumable/"+vo.Token).Header("uid", "1").Do(nil).Status(http.StatusNoContent)
+	a.FileNotExists("./testdata/tmp/" + vo.Token + ".part")
+
+	// 过期
+
+	vo = create(&ResumableTO{Filename: "e.txt", Size: 5})
+	size, err := m.ExpireResumables(time.Now())
+	a.NotError(err).Zero(size)
+	size, err = m.ExpireResumables(time.Now().Add(25 * time.Hour))
+	a.NotError(err).Equal(size, 1)
+	a.FileNotExists("./testdata/tmp/" + vo.Token + ".part")
+}
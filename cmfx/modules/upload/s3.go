@@ -0,0 +1,360 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package upload
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/issue9/mux/v9/header"
+	"github.com/issue9/upload/v5"
+)
+
+const (
+	s3Algorithm   = "AWS4-HMAC-SHA256"
+	s3DateFormat  = "20060102T150405Z"
+	s3Unsigned    = "UNSIGNED-PAYLOAD"
+	s3ServiceName = "s3"
+)
+
+// Presigner 可以生成预签名下载地址的 [upload.Saver]
+//
+// 如果 [Load] 的 saver 参数实现了此接口，访问上传文件时会重定向到预签名地址，
+// 而不是由当前服务中转文件内容。
+type Presigner interface {
+	// PresignURL 生成文件 name 的预签名下载地址
+	//
+	// 如果返回空值，表示不使用预签名地址。
+	PresignURL(name string) (string, error)
+}
+
+// S3Saver 兼容 S3 协议的对象存储
+//
+// 实现了 [upload.Saver] 和 [Presigner] 接口，可用于 AWS S3、MinIO 等兼容 S3 协议的对象存储。
+type S3Saver struct {
+	conf     *S3Config
+	endpoint *url.URL
+	client   *http.Client
+	baseURL  string
+	filename func(fs.FS, string, string) string
+	now      func() time.Time
+}
+
+// NewS3Saver 声明 [S3Saver]
+//
+// baseURL 为 Save 返回地址的前缀，一般为 [Load] 中 prefix 对应的地址；
+// filename 用于生成保存的文件名，与 [upload.NewLocalSaver] 的同名参数相同；
+func NewS3Saver(conf *S3Config, baseURL string, filename func(fs.FS, string, string) string) (*S3Saver, error) {
+	u, err := url.Parse(conf.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	return &S3Saver{
+		conf:     conf,
+		endpoint: u,
+		client:   &http.Client{Timeout: conf.Timeout.Duration()},
+		baseURL:  baseURL,
+		filename: filename,
+		now:      time.Now,
+	}, nil
+}
+
+// Save 保存文件
+//
+// 返回值为 baseURL 加上文件名。
+func (s *S3Saver) Save(f multipart.File, filename, ext string) (string, error) {
+	name := s.filename(s, filename, ext)
+
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	req, err := s.newRequest(http.MethodPut, name, io.NopCloser(f), hex.EncodeToString(h.Sum(nil)))
+	if err != nil {
+		return "", err
+	}
+	req.ContentLength = size
+	if t := mime.TypeByExtension(ext); t != "" {
+		req.Header.Set(header.ContentType, t)
+	}
+
+	if _, err := s.do(req, name); err != nil {
+		return "", err
+	}
+	return s.baseURL + "/" + name, nil
+}
+
+// Delete 删除文件
+func (s *S3Saver) Delete(name string) error {
+	req, err := s.newRequest(http.MethodDelete, name, nil, "")
+	if err != nil {
+		return err
+	}
+	_, err = s.do(req, name)
+	return err
+}
+
+// Open 打开文件
+//
+// 文件内容会被完整地读入内存，较大的文件应该通过 [S3Saver.PresignURL] 直接从对象存储下载。
+func (s *S3Saver) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) || name == "." {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	req, err := s.newRequest(http.MethodGet, name, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(req, name)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &s3File{Reader: bytes.NewReader(data), info: newS3FileInfo(name, resp)}, nil
+}
+
+// Stat 获取文件信息
+//
+// 实现了 [fs.StatFS] 接口，仅发送 HEAD 请求，不会读取文件内容。
+func (s *S3Saver) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) || name == "." {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+
+	req, err := s.newRequest(http.MethodHead, name, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(req, name)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	return newS3FileInfo(name, resp), nil
+}
+
+// PresignURL 生成文件 name 的预签名下载地址
+//
+// 如果 [S3Config.Presign] 为零，返回空值。
+func (s *S3Saver) PresignURL(name string) (string, error) {
+	if s.conf.Presign <= 0 {
+		return "", nil
+	}
+
+	u := s.objectURL(name)
+	now := s.now().UTC()
+	date := now.Format(s3DateFormat)
+
+	q := u.Query()
+	q.Set("X-Amz-Algorithm", s3Algorithm)
+	q.Set("X-Amz-Credential", s.conf.AccessKey+"/"+s.scope(date))
+	q.Set("X-Amz-Date", date)
+	q.Set("X-Amz-Expires", strconv.Itoa(int(s.conf.Presign.Duration().Seconds())))
+	q.Set("X-Amz-SignedHeaders", "host")
+
+	canonical := strings.Join([]string{
+		http.MethodGet,
+		u.RawPath,
+		s3CanonicalQuery(q),
+		"host:" + u.Host + "\n",
+		"host",
+		s3Unsigned,
+	}, "\n")
+
+	q.Set("X-Amz-Signature", s.signature(date, canonical))
+	u.RawQuery = s3CanonicalQuery(q)
+	return u.String(), nil
+}
+
+// 对象 name 的地址
+func (s *S3Saver) objectURL(name string) *url.URL {
+	u := *s.endpoint
+	key := path.Join(s.conf.Prefix, name)
+
+	if s.conf.PathStyle {
+		u.Path = "/" + s.conf.Bucket + "/" + key
+	} else {
+		u.Host = s.conf.Bucket + "." + u.Host
+		u.Path = "/" + key
+	}
+	u.RawPath = s3Escape(u.Path, false)
+	return &u
+}
+
+// 生成已签名的请求
+//
+// payloadHash 为请求内容的 SHA256 值，为空表示请求内容为空。
+func (s *S3Saver) newRequest(method, name string, body io.ReadCloser, payloadHash string) (*http.Request, error) {
+	u := s.objectURL(name)
+	req, err := http.NewRequest(method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Body = body
+
+	if payloadHash == "" {
+		sum := sha256.Sum256(nil)
+		payloadHash = hex.EncodeToString(sum[:])
+	}
+	date := s.now().UTC().Format(s3DateFormat)
+	req.Header.Set("X-Amz-Date", date)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signed := "host;x-amz-content-sha256;x-amz-date"
+	canonical := strings.Join([]string{
+		method,
+		u.RawPath,
+		"",
+		"host:" + u.Host + "\n" + "x-amz-content-sha256:" + payloadHash + "\n" + "x-amz-date:" + date + "\n",
+		signed,
+		payloadHash,
+	}, "\n")
+
+	req.Header.Set(header.Authorization, fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, s.conf.AccessKey, s.scope(date), signed, s.signature(date, canonical)))
+	return req, nil
+}
+
+func (s *S3Saver) do(req *http.Request, name string) (*http.Response, error) {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		resp.Body.Close()
+		return nil, &fs.PathError{Op: strings.ToLower(req.Method), Path: name, Err: fs.ErrNotExist}
+	case resp.StatusCode >= 300:
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("s3 %s %s: %s %s", req.Method, name, resp.Status, msg)
+	default:
+		return resp, nil
+	}
+}
+
+func (s *S3Saver) scope(date string) string {
+	return date[:8] + "/" + s.conf.Region + "/" + s3ServiceName + "/aws4_request"
+}
+
+// 根据规范请求 canonical 计算签名
+func (s *S3Saver) signature(date, canonical string) string {
+	sum := sha256.Sum256([]byte(canonical))
+	toSign := s3Algorithm + "\n" + date + "\n" + s.scope(date) + "\n" + hex.EncodeToString(sum[:])
+
+	key := s3HMAC([]byte("AWS4"+s.conf.SecretKey), date[:8])
+	key = s3HMAC(key, s.conf.Region)
+	key = s3HMAC(key, s3ServiceName)
+	key = s3HMAC(key, "aws4_request")
+	return hex.EncodeToString(s3HMAC(key, toSign))
+}
+
+func s3HMAC(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// 按 S3 的规则对 s 进行编码
+//
+// 除了 A-Za-z0-9-_.~ 之外的字符都需要编码，encodeSlash 表示是否对 / 进行编码。
+func s3Escape(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func s3CanonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	items := make([]string, 0, len(keys))
+	for _, k := range keys {
+		for _, v := range q[k] {
+			items = append(items, s3Escape(k, true)+"="+s3Escape(v, true))
+		}
+	}
+	return strings.Join(items, "&")
+}
+
+type s3File struct {
+	*bytes.Reader
+	info *s3FileInfo
+}
+
+func (f *s3File) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+func (f *s3File) Close() error { return nil }
+
+type s3FileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+func newS3FileInfo(name string, resp *http.Response) *s3FileInfo {
+	modTime, _ := http.ParseTime(resp.Header.Get(header.LastModified))
+	return &s3FileInfo{name: path.Base(name), size: resp.ContentLength, modTime: modTime}
+}
+
+func (i *s3FileInfo) Name() string       { return i.name }
+func (i *s3FileInfo) Size() int64        { return i.size }
+func (i *s3FileInfo) Mode() fs.FileMode  { return 0o444 }
+func (i *s3FileInfo) ModTime() time.Time { return i.modTime }
+func (i *s3FileInfo) IsDir() bool        { return false }
+func (i *s3FileInfo) Sys() any           { return nil }
+
+var (
+	_ upload.Saver = &S3Saver{}
+	_ fs.StatFS    = &S3Saver{}
+	_ Presigner    = &S3Saver{}
+)
@@ -0,0 +1,178 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package upload
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/issue9/web"
+)
+
+var errInfected = errors.New("the file is flagged by scanner")
+
+// Scanner 检测上传的文件是否包含恶意内容
+type Scanner interface {
+	// Scan 扫描 r 的内容
+	//
+	// 如果内容被标记为恶意，返回描述信息，比如病毒的名称，否则返回空字符串。
+	Scan(r io.Reader) (string, error)
+}
+
+// ClamAV 采用 clamd 协议的 [Scanner] 实现
+type ClamAV struct {
+	network string
+	address string
+	timeout time.Duration
+}
+
+// NewClamAV 声明 [ClamAV] 对象
+//
+// network 和 address 为 clamd 的监听地址，比如 tcp 和 localhost:3310；
+// timeout 为每次扫描的超时时间，为零表示不限制。
+func NewClamAV(network, address string, timeout time.Duration) *ClamAV {
+	return &ClamAV{network: network, address: address, timeout: timeout}
+}
+
+// clamd 每个数据块的大小，不能超过 clamd 的 StreamMaxLength。
+const clamChunkSize = 32 * 1024
+
+// Scan 通过 INSTREAM 命令扫描 r 的内容
+func (c *ClamAV) Scan(r io.Reader) (string, error) {
+	conn, err := net.DialTimeout(c.network, c.address, c.timeout)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	if c.timeout > 0 {
+		if err := conn.SetDeadline(time.Now().Add(c.timeout)); err != nil {
+			return "", err
+		}
+	}
+
+	w := bufio.NewWriterSize(conn, clamChunkSize+4)
+	if _, err := w.WriteString("zINSTREAM\x00"); err != nil {
+		return "", err
+	}
+
+	// 每个数据块以 4 字节的长度开头，长度为 0 表示结束。
+	buf := make([]byte, clamChunkSize)
+	size := make([]byte, 4)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size, uint32(n))
+			if _, err := w.Write(size); err != nil {
+				return "", err
+			}
+			if _, err := w.Write(buf[:n]); err != nil {
+				return "", err
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return "", err
+		}
+	}
+	if _, err := w.Write([]byte{0, 0, 0, 0}); err != nil {
+		return "", err
+	}
+	if err := w.Flush(); err != nil {
+		return "", err
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return parseClamReply(reply)
+}
+
+// 解析 clamd 的返回内容
+//
+// 格式为 stream: OK、stream: {name} FOUND 或是 {message} ERROR。
+func parseClamReply(reply string) (string, error) {
+	reply = strings.TrimSpace(strings.TrimSuffix(reply, "\x00"))
+	switch {
+	case reply == "stream: OK":
+		return "", nil
+	case strings.HasPrefix(reply, "stream: ") && strings.HasSuffix(reply, " FOUND"):
+		return strings.TrimSuffix(strings.TrimPrefix(reply, "stream: "), " FOUND"), nil
+	default:
+		return "", fmt.Errorf("clamd: %s", reply)
+	}
+}
+
+// 上传文件的扫描与隔离
+type scan struct {
+	scanner    Scanner
+	quarantine *os.Root
+}
+
+func newScan(conf *ScanConfig) (*scan, error) {
+	if err := os.MkdirAll(conf.Quarantine, fs.ModePerm); err != nil {
+		return nil, err
+	}
+	root, err := os.OpenRoot(conf.Quarantine)
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := conf.Scanner
+	if scanner == nil {
+		scanner = NewClamAV(conf.Network, conf.Address, conf.Timeout.Duration())
+	}
+	return &scan{scanner: scanner, quarantine: root}, nil
+}
+
+// 扫描 f 的内容，如果被标记为恶意，则将其移至隔离目录并返回 [errInfected]。
+//
+// hash 和 ext 用于生成隔离文件的名称，其它参数仅用于记录日志。
+func (m *Module) scanFile(f io.ReadSeeker, hash, ext string, uid int64, filename string) error {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	found, err := m.scan.scanner.Scan(f)
+	if err != nil {
+		return err
+	}
+	if found == "" {
+		return nil
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return errors.Join(errInfected, err)
+	}
+	name := hash + ext
+	if err := m.writeQuarantine(name, f); err != nil {
+		return errors.Join(errInfected, err)
+	}
+
+	m.mod.Server().Logs().WARN().
+		With("uid", uid).
+		With("filename", filename).
+		With("quarantine", name).
+		LocaleString(web.Phrase("upload file is flagged as %s", found))
+	return errInfected
+}
+
+func (m *Module) writeQuarantine(name string, r io.Reader) error {
+	f, err := m.scan.quarantine.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	return errors.Join(err, f.Close())
+}
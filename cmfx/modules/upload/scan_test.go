@@ -0,0 +1,165 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package upload
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+
+	"github.com/issue9/cmfx/cmfx/initial/test"
+)
+
+type fakeScanner struct{}
+
+func (s *fakeScanner) Scan(r io.Reader) (string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	if bytes.Contains(data, []byte("EICAR")) {
+		return "Eicar-Test-Signature", nil
+	}
+	return "", nil
+}
+
+// 模拟 clamd 的 INSTREAM 命令
+func newFakeClamd(a *assert.Assertion) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	a.NotError(err)
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+
+				cmd := make([]byte, len("zINSTREAM\x00"))
+				if _, err := io.ReadFull(conn, cmd); err != nil || string(cmd) != "zINSTREAM\x00" {
+					conn.Write([]byte("UNKNOWN COMMAND ERROR\x00"))
+					return
+				}
+
+				data := &bytes.Buffer{}
+				size := make([]byte, 4)
+				for {
+					if _, err := io.ReadFull(conn, size); err != nil {
+						return
+					}
+					n := binary.BigEndian.Uint32(size)
+					if n == 0 {
+						break
+					}
+					if _, err := io.CopyN(data, conn, int64(n)); err != nil {
+						return
+					}
+				}
+
+				reply, _ := (&fakeScanner{}).Scan(data)
+				if reply == "" {
+					conn.Write([]byte("stream: OK\x00"))
+				} else {
+					conn.Write([]byte("stream: " + reply + " FOUND\x00"))
+				}
+			}()
+		}
+	}()
+
+	return l
+}
+
+func TestParseClamReply(t *testing.T) {
+	a := assert.New(t, false)
+
+	found, err := parseClamReply("stream: OK\x00")
+	a.NotError(err).Empty(found)
+
+	found, err = parseClamReply("stream: Eicar-Test-Signature FOUND\x00")
+	a.NotError(err).Equal(found, "Eicar-Test-Signature")
+
+	_, err = parseClamReply("INSTREAM size limit exceeded. ERROR\x00")
+	a.Error(err)
+}
+
+func TestClamAV_Scan(t *testing.T) {
+	a := assert.New(t, false)
+	l := newFakeClamd(a)
+	defer l.Close()
+
+	c := NewClamAV("tcp", l.Addr().String(), time.Second)
+
+	found, err := c.Scan(strings.NewReader("hello"))
+	a.NotError(err).Empty(found)
+
+	// 超过一个数据块
+	found, err = c.Scan(io.MultiReader(bytes.NewReader(make([]byte, clamChunkSize+10)), strings.NewReader("EICAR")))
+	a.NotError(err).Equal(found, "Eicar-Test-Signature")
+
+	c = NewClamAV("tcp", "127.0.0.1:1", time.Second)
+	_, err = c.Scan(strings.NewReader("hello"))
+	a.Error(err)
+}
+
+func TestModule_scan(t *testing.T) {
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+	defer s.Close()
+	defer os.RemoveAll("./testdata")
+
+	l := newFakeClamd(a)
+	defer l.Close()
+
+	conf := &ScanConfig{Address: l.Addr().String(), Quarantine: "./testdata/quarantine"}
+	a.NotError(conf.SanitizeConfig())
+	m := newFilesModule(a, s)
+	sc, err := newScan(conf)
+	a.NotError(err)
+	m.scan = sc
+
+	u, err := m.newRecorder("", 1, 0, nil).Save(&memFile{Reader: bytes.NewReader([]byte("hello"))}, "a.txt", ".txt")
+	a.NotError(err).Equal(u, "/files/1.txt")
+
+	_, err = m.newRecorder("", 1, 0, nil).Save(&memFile{Reader: bytes.NewReader([]byte("EICAR"))}, "b.txt", ".txt")
+	a.True(errors.Is(err, errInfected))
+	a.FileNotExists("./testdata/files/2.txt")
+	entries, err := os.ReadDir("./testdata/quarantine")
+	a.NotError(err).Length(entries, 1)
+	data, err := os.ReadFile("./testdata/quarantine/" + entries[0].Name())
+	a.NotError(err).Equal(data, []byte("EICAR"))
+
+	// 类型检测
+
+	r := m.newRecorder("", 1, 0, &Policy{Mimes: []string{"image/*"}})
+	_, err = r.Save(&memFile{Reader: bytes.NewReader([]byte("MZ\x90\x00"))}, "c.jpg", ".jpg")
+	a.Equal(err, errNotAllowMime)
+
+	u, err = r.Save(&memFile{Reader: bytes.NewReader([]byte("\x89PNG\x0d\x0a\x1a\x0a"))}, "d.png", ".png")
+	a.NotError(err).Equal(u, "/files/2.png")
+}
+
+func TestMatchMime(t *testing.T) {
+	a := assert.New(t, false)
+
+	a.Equal(sniffMime([]byte("hello")), "text/plain").
+		Equal(sniffMime([]byte("MZ\x90\x00")), "application/octet-stream").
+		Equal(sniffMime([]byte("%PDF-1.7")), "application/pdf")
+
+	a.True(matchMime([]string{"image/*"}, "image/png")).
+		True(matchMime([]string{"text/plain", "application/pdf"}, "application/pdf")).
+		False(matchMime([]string{"image/*"}, "application/octet-stream")).
+		False(matchMime([]string{"image/png"}, "image/jpeg"))
+}
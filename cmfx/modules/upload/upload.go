@@ -1,4 +1,4 @@
-// SPDX-FileCopyrightText: 2024-2025 caixw
+// SPDX-FileCopyrightText: 2024-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
@@ -7,7 +7,9 @@ package upload
 
 import (
 	"errors"
+	"net/http"
 
+	"github.com/issue9/orm/v6"
 	"github.com/issue9/upload/v5"
 	"github.com/issue9/web"
 	"github.com/issue9/web/openapi"
@@ -17,19 +19,70 @@ import (
 )
 
 type Module struct {
+	mod    *cmfx.Module
+	db     *orm.DB
 	saver  upload.Saver
 	prefix string
+	images *images
+	scan   *scan
+	secret []byte          // 签名地址的密钥，为空表示不支持私有文件。
+	serve  web.HandlerFunc // 输出文件内容
+
+	resumables map[string]*resumable // 以路由前缀为键名
 }
 
 // Load 加载上传模块
 //
 // mod 指定上传模块的基本信息；
 // prefix 上传 API 的地址前缀，同时也可能是上传文件组成的静态文件服务的地址前缀；
-func Load(mod *cmfx.Module, prefix string, saver upload.Saver) *Module {
-	mod.Router().Get(prefix+"/{file}", static.ServeFileHandler(saver, "file", "index.html"), mod.API(func(o *openapi.Operation) {
+// saver 文件的保存方式，如果实现了 [Presigner]，访问文件时会重定向到预签名地址；
+// conf 存储的配置，用到其中的图片处理、文件扫描和签名密钥等，为空表示都采用默认值；
+//
+// 文件的元数据保存在由 [Install] 创建的数据表中。
+func Load(mod *cmfx.Module, prefix string, saver upload.Saver, conf *StorageConfig) *Module {
+	if conf == nil {
+		conf = &StorageConfig{}
+	}
+
+	m := &Module{
+		mod:        mod,
+		db:         mod.DB(),
+		saver:      saver,
+		prefix:     prefix,
+		secret:     []byte(conf.Secret),
+		resumables: make(map[string]*resumable, 2),
+	}
+
+	h := static.ServeFileHandler(saver, "file", "index.html")
+	if p, ok := saver.(Presigner); ok {
+		h = presignHandler(p, h)
+	}
+
+	if conf.Image != nil {
+		images, err := newImages(conf.Image)
+		if err != nil {
+			panic(web.SprintError(mod.Server().Locale().Printer(), true, err))
+		}
+		m.images = images
+		h = images.handler(saver, h)
+	}
+	m.serve = h
+
+	if conf.Scan != nil {
+		sc, err := newScan(conf.Scan)
+		if err != nil {
+			panic(web.SprintError(mod.Server().Locale().Printer(), true, err))
+		}
+		m.scan = sc
+	}
+
+	mod.Router().Get(prefix+"/{file}", m.privateHandler(h), mod.API(func(o *openapi.Operation) {
 		o.Tag("upload").
 			Desc(web.Phrase("upload static server"), nil).
 			Path("file", openapi.TypeString, web.Phrase("the file name"), nil).
+			Query("w", openapi.TypeInteger, web.Phrase("the width of the image thumbnail"), nil).
+			Query(queryExpires, openapi.TypeInteger, web.Phrase("the expiration time of the signed url"), nil).
+			Query(querySign, openapi.TypeString, web.Phrase("the signature of the signed url"), nil).
 			Response("200", nil, nil, func(r *openapi.Response) {
 				if r.Content == nil {
 					r.Content = make(map[string]*openapi.Schema, 1)
@@ -41,19 +94,71 @@ func Load(mod *cmfx.Module, prefix string, saver upload.Saver) *Module {
 			})
 	}))
 
-	return &Module{saver: saver, prefix: prefix}
+	return m
+}
+
+// 如果 p 能生成预签名地址，则重定向到该地址，否则交由 next 处理。
+func presignHandler(p Presigner, next web.HandlerFunc) web.HandlerFunc {
+	return func(ctx *web.Context) web.Responser {
+		file, _ := ctx.Route().Params().Get("file")
+		u, err := p.PresignURL(file)
+		switch {
+		case err != nil:
+			return ctx.Error(err, "")
+		case u == "":
+			return next(ctx)
+		default:
+			return web.Redirect(http.StatusFound, u)
+		}
+	}
 }
 
 // Handle 注册上传接口
-func (m *Module) Handle(prefix *web.Prefix, api func(func(*openapi.Operation)) web.Middleware, conf *Config) {
-	up := m.Upload(conf)
+//
+// uid 用于获取当前上传者的 ID，上传的文件会记录上传者、大小、类型等元数据，
+// 内容相同的文件只会保存一份；
+// roles 用于获取用户的角色，以确定其可用的存储空间，为空表示所有用户都采用 [Config.Quota]；
+//
+// [Config.Policies] 中的每个策略都会注册为 {prefix}/{name} 形式的上传接口，
+// 如果存在私有文件的策略，则 [StorageConfig.Secret] 不能为空；
+// 如果 [Config.Resumable] 不为空，还会注册断点续传的接口，同一个 prefix 只能调用一次。
+// 同时还会注册用于访问当前用户上传的文件和查看存储空间的接口。
+func (m *Module) Handle(prefix *web.Prefix, api func(func(*openapi.Operation)) web.Middleware, conf *Config, uid func(*web.Context) int64, roles func(int64) []string) {
+	if conf.HasPrivate() && len(m.secret) == 0 {
+		panic(web.SprintError(m.mod.Server().Locale().Printer(), true, errSecretRequired))
+	}
+
+	quota := func(uid int64) int64 {
+		if roles == nil {
+			return conf.Quota
+		}
+		return conf.UserQuota(roles(uid))
+	}
+
+	m.handlePolicy(prefix, api, m.prefix, conf.Field, conf.policy(), uid, quota)
+	for _, p := range conf.Policies {
+		m.handlePolicy(prefix, api, m.prefix+"/"+p.Name, conf.Field, p, uid, quota)
+	}
+
+	m.handleFiles(prefix, api, uid, quota)
 
-	prefix.Post(m.prefix, func(ctx *web.Context) web.Responser {
-		files, err := up.Do(conf.Field, ctx.Request())
+	if conf.Resumable != nil {
+		m.handleResumable(prefix, api, conf, uid, quota)
+	}
+}
+
+func (m *Module) handlePolicy(prefix *web.Prefix, api func(func(*openapi.Operation)) web.Middleware, path, field string, p *Policy, uid func(*web.Context) int64, quota func(int64) int64) {
+	pattern := prefix.Pattern()
+	prefix.Post(path, func(ctx *web.Context) web.Responser {
+		id := uid(ctx)
+		up := upload.New(m.newRecorder(pattern, id, quota(id), p), p.Size, p.Exts...)
+		files, err := up.Do(field, ctx.Request())
 		switch {
-		case errors.Is(err, upload.ErrNotAllowSize()):
+		case errors.Is(err, upload.ErrNotAllowSize()), errors.Is(err, errImageTooLarge):
 			return ctx.Problem(cmfx.RequestEntityTooLarge)
-		case errors.Is(err, upload.ErrNotAllowExt()):
+		case errors.Is(err, errQuotaExceeded):
+			return ctx.Problem(cmfx.RequestEntityTooLargeQuotaExceeded)
+		case errors.Is(err, upload.ErrNotAllowExt()), errors.Is(err, errNotAllowMime), errors.Is(err, errInfected):
 			return ctx.Problem(cmfx.BadRequestBodyNotAllowed)
 		case err != nil:
 			return ctx.Error(err, "")
@@ -68,6 +173,8 @@ func (m *Module) Handle(prefix *web.Prefix, api func(func(*openapi.Operation)) w
 }
 
 // Upload 提供原始的 [upload.Upload] 对象
+//
+// 通过此对象上传的文件不会记录元数据，也不参与 [Module.GC]。
 func (m *Module) Upload(conf *Config) *upload.Upload {
 	return upload.New(m.saver, conf.Size, conf.Exts...)
 }
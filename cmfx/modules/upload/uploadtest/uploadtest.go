@@ -1,4 +1,4 @@
-// SPDX-FileCopyrightText: 2024 caixw
+// SPDX-FileCopyrightText: 2024-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
@@ -15,9 +15,12 @@ import (
 	"github.com/issue9/cmfx/cmfx/modules/upload"
 )
 
+// NewModule 声明已经安装了数据表的 [upload.Module]
 func NewModule(suite *test.Suite, id string) *upload.Module {
 	baseURL := "/" + id
-	return upload.Load(suite.NewModule(id), baseURL, NewSaver(suite, baseURL))
+	mod := suite.NewModule(id)
+	upload.Install(mod)
+	return upload.Load(mod, baseURL, NewSaver(suite, baseURL), nil)
 }
 
 func NewSaver(suite *test.Suite, baseURL string) xupload.Saver {
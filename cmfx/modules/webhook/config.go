@@ -0,0 +1,65 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package webhook
+
+import (
+	"time"
+
+	"github.com/issue9/web"
+	"github.com/issue9/web/server/config"
+
+	"github.com/issue9/cmfx/cmfx/locales"
+)
+
+// Config 配置项
+type Config struct {
+	// URLPrefix 该模块下的地址前缀
+	//
+	// 默认值为 /webhooks
+	URLPrefix string `yaml:"urlPrefix,omitempty" json:"urlPrefix,omitempty" xml:"urlPrefix,omitempty" toml:"urlPrefix,omitempty"`
+
+	// Timeout 单次请求的超时时间
+	//
+	// 默认值为 10 秒
+	Timeout config.Duration `yaml:"timeout,omitempty" json:"timeout,omitempty" xml:"timeout,omitempty" toml:"timeout,omitempty"`
+
+	// Interval 投递服务的执行间隔，同时也是第一次重试的间隔，之后每次重试的间隔都会翻倍。
+	//
+	// 默认值为 30 秒
+	Interval config.Duration `yaml:"interval,omitempty" json:"interval,omitempty" xml:"interval,omitempty" toml:"interval,omitempty"`
+
+	// Retries 最大的重试次数
+	//
+	// 默认值为 5
+	Retries int `yaml:"retries,omitempty" json:"retries,omitempty" xml:"retries,omitempty" toml:"retries,omitempty"`
+}
+
+func (c *Config) SanitizeConfig() *web.FieldError {
+	if c.URLPrefix == "" {
+		c.URLPrefix = "/webhooks"
+	} else if c.URLPrefix[0] != '/' {
+		return web.NewFieldError("urlPrefix", locales.InvalidValue)
+	}
+
+	if c.Timeout == 0 {
+		c.Timeout = config.Duration(10 * time.Second)
+	} else if c.Timeout < 0 {
+		return web.NewFieldError("timeout", locales.MustBeGreaterThan(0))
+	}
+
+	if c.Interval == 0 {
+		c.Interval = config.Duration(30 * time.Second)
+	} else if c.Interval.Duration() < time.Second {
+		return web.NewFieldError("interval", locales.MustBeGreaterThan(time.Second))
+	}
+
+	if c.Retries == 0 {
+		c.Retries = 5
+	} else if c.Retries < 0 {
+		return web.NewFieldError("retries", locales.MustBeGreaterThan(0))
+	}
+
+	return nil
+}
@@ -0,0 +1,33 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package webhook
+
+import (
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/web/server/config"
+)
+
+func TestConfig_SanitizeConfig(t *testing.T) {
+	a := assert.New(t, false)
+
+	conf := &Config{}
+	a.NotError(conf.SanitizeConfig()).
+		Equal(conf.URLPrefix, "/webhooks").
+		Equal(conf.Timeout, config.Duration(10*time.Second)).
+		Equal(conf.Interval, config.Duration(30*time.Second)).
+		Equal(conf.Retries, 5)
+
+	conf = &Config{URLPrefix: "webhooks"}
+	a.Equal(conf.SanitizeConfig().Field, "urlPrefix")
+
+	conf = &Config{Interval: config.Duration(time.Millisecond)}
+	a.Equal(conf.SanitizeConfig().Field, "interval")
+
+	conf = &Config{Retries: -1}
+	a.Equal(conf.SanitizeConfig().Field, "retries")
+}
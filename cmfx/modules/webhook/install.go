@@ -0,0 +1,31 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package webhook
+
+import (
+	"github.com/issue9/web"
+
+	"github.com/issue9/cmfx/cmfx"
+	"github.com/issue9/cmfx/cmfx/modules/admin"
+)
+
+func Install(mod *cmfx.Module, conf *Config, adminL *admin.Module) *Module {
+	if err := mod.DB().Create(&endpointPO{}, &deliveryPO{}, &attemptPO{}); err != nil {
+		panic(web.SprintError(mod.Server().Locale().Printer(), true, err))
+	}
+
+	return Load(mod, conf, adminL)
+}
+
+// Uninstall 删除由 [Install] 创建的数据表
+//
+// adminMod 为管理模块，用于删除当前模块在角色中的资源；
+func Uninstall(mod, adminMod *cmfx.Module) error {
+	if err := admin.DeleteResourceGroup(adminMod, mod); err != nil {
+		return err
+	}
+
+	return mod.DB().Drop(&endpointPO{}, &deliveryPO{}, &attemptPO{})
+}
@@ -0,0 +1,36 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package webhook
+
+import (
+	"testing"
+
+	"github.com/issue9/assert/v4"
+
+	"github.com/issue9/cmfx/cmfx/initial/test"
+	"github.com/issue9/cmfx/cmfx/modules/admin/admintest"
+)
+
+func TestInstall(t *testing.T) {
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+	defer s.Close()
+	adminL := admintest.NewModule(s)
+
+	conf := &Config{}
+	a.NotError(conf.SanitizeConfig())
+	mod := s.NewModule("mod")
+
+	m := Install(mod, conf, adminL)
+	a.NotNil(m)
+	s.TableExists("mod_endpoints").
+		TableExists("mod_deliveries").
+		TableExists("mod_attempts")
+
+	a.NotError(Uninstall(mod, adminL.UserModule().Module()))
+	s.TableNotExists("mod_endpoints").
+		TableNotExists("mod_deliveries").
+		TableNotExists("mod_attempts")
+}
@@ -0,0 +1,92 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package webhook
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/issue9/orm/v6/core"
+
+	"github.com/issue9/cmfx/cmfx/types"
+)
+
+// 接收端
+type endpointPO struct {
+	ID       int64         `orm:"name(id);ai"`
+	URL      string        `orm:"name(url);len(1000)"`
+	Secret   string        `orm:"name(secret);len(100)"` // 签名的密钥
+	Events   types.Strings `orm:"name(events);len(-1)"`  // 订阅的事件
+	Disabled bool          `orm:"name(disabled)"`        // 是否已禁用
+	Created  time.Time     `orm:"name(created)"`
+	Deleted  sql.NullTime  `orm:"name(deleted);nullable"`
+}
+
+func (*endpointPO) TableName() string { return "_endpoints" }
+
+func (p *endpointPO) BeforeInsert() error {
+	p.Created = time.Now()
+	return nil
+}
+
+func (p *endpointPO) BeforeUpdate() error { return nil }
+
+//go:generate web enum -i=./models.go -o=./models_methods.go -t=State
+
+// State 投递状态
+type State int8
+
+const (
+	StatePending   State = iota // 等待投递
+	StateSucceeded              // 投递成功
+	StateFailed                 // 超过重试次数，不再投递。
+)
+
+func (State) PrimitiveType() core.PrimitiveType { return core.String }
+
+// 投递记录
+type deliveryPO struct {
+	ID       int64     `orm:"name(id);ai"`
+	Endpoint int64     `orm:"name(endpoint);index(i_endpoint)"`
+	Event    string    `orm:"name(event);len(100)"`
+	Payload  string    `orm:"name(payload);len(-1)"` // 请求的内容，重新投递时保持不变。
+	State    State     `orm:"name(state);len(20);index(i_state_next)"`
+	Next     time.Time `orm:"name(next);index(i_state_next)"`
+	Attempts int       `orm:"name(attempts)"` // 已经投递的次数
+	Status   int       `orm:"name(status)"`   // 最后一次投递的状态码，如果未收到响应则为 0。
+	Created  time.Time `orm:"name(created)"`
+	Updated  time.Time `orm:"name(updated)"`
+}
+
+func (*deliveryPO) TableName() string { return "_deliveries" }
+
+func (p *deliveryPO) BeforeInsert() error {
+	p.Created = time.Now()
+	p.Updated = p.Created
+	return nil
+}
+
+func (p *deliveryPO) BeforeUpdate() error {
+	p.Updated = time.Now()
+	return nil
+}
+
+// 每一次投递的日志
+type attemptPO struct {
+	ID       int64     `orm:"name(id);ai"`
+	Delivery int64     `orm:"name(delivery);index(i_delivery)"`
+	Status   int       `orm:"name(status)"`           // 响应的状态码，如果未收到响应则为 0。
+	Response string    `orm:"name(response);len(-1)"` // 响应内容，最多保存 maxResponseSize 字节。
+	Error    string    `orm:"name(error);len(-1)"`
+	Duration int64     `orm:"name(duration)"` // 请求的耗时，单位为毫秒。
+	Created  time.Time `orm:"name(created)"`
+}
+
+func (*attemptPO) TableName() string { return "_attempts" }
+
+func (p *attemptPO) BeforeInsert() error {
+	p.Created = time.Now()
+	return nil
+}
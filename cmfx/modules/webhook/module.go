@@ -0,0 +1,308 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+// Package webhook 向第三方推送平台事件的 Webhook 模块
+//
+// 管理员可以添加接收端的地址并选择需要订阅的事件，
+// 事件发生时会向订阅了该事件的接收端发送 POST 请求，
+// 请求内容以 HMAC-SHA256 签名，签名的计算方式可参考 [Sign]。
+// 投递失败时会按指数退避的方式重试，每一次投递的结果都会被记录，
+// 管理员也可以手动重新投递。
+//
+// 同一事件可能会被投递多次，接收端可以通过 X-Webhook-Delivery 报头去重。
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/issue9/orm/v6"
+	"github.com/issue9/web"
+
+	"github.com/issue9/cmfx/cmfx"
+	"github.com/issue9/cmfx/cmfx/modules/admin"
+	"github.com/issue9/cmfx/cmfx/outbox"
+)
+
+// 与签名相关的报头
+const (
+	HeaderEvent     = "X-Webhook-Event"     // 事件名称
+	HeaderDelivery  = "X-Webhook-Delivery"  // 投递记录的 ID
+	HeaderTimestamp = "X-Webhook-Timestamp" // 发送时的 unix 时间戳，单位为秒。
+	HeaderSignature = "X-Webhook-Signature" // 签名，格式为 sha256=<hex>。
+)
+
+const (
+	batchSize       = 50   // 单次投递的数量
+	maxResponseSize = 2048 // 记录的响应内容的最大长度
+)
+
+// Module Webhook 模块
+type Module struct {
+	mod      *cmfx.Module
+	admin    *admin.Module
+	client   *http.Client
+	interval time.Duration
+	retries  int
+
+	events map[string]web.LocaleStringer
+	names  []string // 事件名称，保证输出时的顺序。
+}
+
+// Payload 发送给接收端的请求内容
+type Payload struct {
+	Event   string          `json:"event"`
+	Created time.Time       `json:"created"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// Load 加载模块
+//
+// conf 当前模块的配置项，需要调用者自先调用 [Config.SanitizeConfig] 对数据进行校正；
+func Load(mod *cmfx.Module, conf *Config, adminL *admin.Module) *Module {
+	m := &Module{
+		mod:      mod,
+		admin:    adminL,
+		client:   &http.Client{Timeout: conf.Timeout.Duration()},
+		interval: conf.Interval.Duration(),
+		retries:  conf.Retries,
+		events:   make(map[string]web.LocaleStringer, 10),
+		names:    make([]string, 0, 10),
+	}
+
+	m.initRoutes(conf)
+
+	mod.Server().Services().AddTicker(web.Phrase("webhook dispatcher"), m.Dispatch, m.interval, false, true)
+
+	return m
+}
+
+// Module 关联的模块
+func (m *Module) Module() *cmfx.Module { return m.mod }
+
+// RegisterEvent 注册事件
+//
+// 只有注册的事件才能被接收端订阅。
+// name 为事件的名称，一旦有接收端订阅，便不应该再修改；
+// desc 为事件的描述；
+func (m *Module) RegisterEvent(name string, desc web.LocaleStringer) {
+	if _, found := m.events[name]; found {
+		panic(fmt.Sprintf("事件 %s 已经存在", name))
+	}
+	m.events[name] = desc
+	m.names = append(m.names, name)
+}
+
+func (m *Module) isEvent(name string) bool {
+	_, found := m.events[name]
+	return found
+}
+
+// Forward 将 [outbox.Outbox] 中的主题 topic 转发为事件 event
+//
+// 主题中的数据会原样作为 [Payload.Data] 发送。
+func (m *Module) Forward(o *outbox.Outbox, topic, event string) {
+	if !m.isEvent(event) {
+		panic(fmt.Sprintf("事件 %s 不存在", event))
+	}
+
+	outbox.Subscribe(o, topic, "webhook_"+event, func(data *json.RawMessage) error {
+		return m.Trigger(nil, event, data)
+	})
+}
+
+// Trigger 触发事件
+//
+// 会为每一个订阅了该事件且未禁用的接收端生成一条投递记录，由后台服务负责投递。
+// tx 如果不为空，则投递记录会在该事务中写入；
+// data 为事件的数据，会以 JSON 格式作为 [Payload.Data] 发送；
+func (m *Module) Trigger(tx *orm.Tx, event string, data any) error {
+	if !m.isEvent(event) {
+		panic(fmt.Sprintf("事件 %s 不存在", event))
+	}
+
+	d, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(&Payload{Event: event, Created: time.Now(), Data: d})
+	if err != nil {
+		return err
+	}
+
+	e := m.mod.Engine(tx)
+
+	endpoints := make([]*endpointPO, 0, 10)
+	_, err = e.SQLBuilder().Select().
+		From(orm.TableName(&endpointPO{})).
+		Where("disabled=?", false).
+		AndIsNull("deleted").
+		QueryObject(true, &endpoints)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	deliveries := make([]orm.TableNamer, 0, len(endpoints))
+	for _, ep := range endpoints {
+		if slices.Contains(ep.Events, event) {
+			deliveries = append(deliveries, &deliveryPO{
+				Endpoint: ep.ID,
+				Event:    event,
+				Payload:  string(payload),
+				State:    StatePending,
+				Next:     now,
+			})
+		}
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return e.InsertMany(10, deliveries...)
+}
+
+// Dispatch 投递所有已经到期的记录
+//
+// 一般由 [Load] 添加的后台服务定时调用，也可以手动调用以立即投递。
+func (m *Module) Dispatch(now time.Time) error {
+	deliveries := make([]*deliveryPO, 0, batchSize)
+	_, err := m.mod.DB().SQLBuilder().Select().
+		From(orm.TableName(&deliveryPO{})).
+		Where("state=?", StatePending).
+		And("next<=?", now).
+		Asc("next").
+		Limit(batchSize).
+		QueryObject(true, &deliveries)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range deliveries {
+		if err := m.deliver(d, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 投递 d
+//
+// 只有与数据库相关的错误才会返回，请求的错误会记录在 attemptPO 中。
+func (m *Module) deliver(d *deliveryPO, now time.Time) error {
+	// 先将下一次投递的时间后移，以防止被其它进程同时投递。
+	rslt, err := m.mod.DB().SQLBuilder().Update().
+		Table(orm.TableName(&deliveryPO{})).
+		Set("next", now.Add(m.backoff(d.Attempts))).
+		Where("id=?", d.ID).
+		And("state=?", StatePending).
+		And("next<=?", now).
+		Exec()
+	if err != nil {
+		return err
+	}
+	if n, err := rslt.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 { // 已经被其它进程处理
+		return nil
+	}
+
+	po := &deliveryPO{ID: d.ID, Attempts: d.Attempts + 1}
+
+	ep := &endpointPO{ID: d.Endpoint}
+	found, err := m.mod.DB().Select(ep)
+	if err != nil {
+		return err
+	}
+
+	var at *attemptPO
+	if !found || ep.Deleted.Valid {
+		at = &attemptPO{Error: fmt.Sprintf("endpoint %d not found", d.Endpoint)}
+		po.State = StateFailed
+	} else {
+		at = m.send(ep, d)
+		switch {
+		case at.Status >= 200 && at.Status < 300:
+			po.State = StateSucceeded
+		case po.Attempts > m.retries:
+			po.State = StateFailed
+		default:
+			po.State = StatePending
+			po.Next = now.Add(m.backoff(po.Attempts))
+		}
+	}
+	po.Status = at.Status
+	at.Delivery = d.ID
+
+	return m.mod.DB().DoTransaction(func(tx *orm.Tx) error {
+		if _, err := tx.Insert(at); err != nil {
+			return err
+		}
+		_, err := tx.Update(po, "status")
+		return err
+	})
+}
+
+// 向 ep 发送 d
+func (m *Module) send(ep *endpointPO, d *deliveryPO) *attemptPO {
+	at := &attemptPO{}
+
+	body := []byte(d.Payload)
+	req, err := http.NewRequest(http.MethodPost, ep.URL, bytes.NewReader(body))
+	if err != nil {
+		at.Error = err.Error()
+		return at
+	}
+
+	ts := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, d.Event)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(d.ID, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(ts, 10))
+	req.Header.Set(HeaderSignature, "sha256="+Sign(ep.Secret, ts, body))
+
+	start := time.Now()
+	resp, err := m.client.Do(req)
+	at.Duration = time.Since(start).Milliseconds()
+	if err != nil {
+		at.Error = err.Error()
+		return at
+	}
+	defer resp.Body.Close()
+
+	at.Status = resp.StatusCode
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		at.Error = err.Error()
+	}
+	at.Response = string(data)
+
+	return at
+}
+
+// 第 attempts 次重试的时间间隔
+func (m *Module) backoff(attempts int) time.Duration {
+	return m.interval << min(attempts, 16)
+}
+
+// Sign 计算签名
+//
+// 签名内容为 timestamp + "." + body，以 secret 为密钥计算 HMAC-SHA256，
+// 返回值为十六进制编码的签名，与 X-Webhook-Signature 报头中 sha256= 之后的内容相同。
+// 接收端可以按相同的方式计算签名，并检测 X-Webhook-Timestamp 以防止重放攻击。
+func Sign(secret string, timestamp int64, body []byte) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	h.Write([]byte{'.'})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,197 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/web"
+
+	"github.com/issue9/cmfx/cmfx/initial/test"
+	"github.com/issue9/cmfx/cmfx/modules/admin/admintest"
+	"github.com/issue9/cmfx/cmfx/outbox"
+	"github.com/issue9/cmfx/cmfx/query"
+	"github.com/issue9/cmfx/cmfx/types"
+)
+
+const secret = "secret-0123456789"
+
+// 接收端，前 fails 次请求返回 500。
+type receiver struct {
+	a        *assert.Assertion
+	fails    int
+	payloads []*Payload
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	r.a.NotError(err)
+
+	ts, err := strconv.ParseInt(req.Header.Get(HeaderTimestamp), 10, 64)
+	r.a.NotError(err).
+		Equal(req.Header.Get(HeaderSignature), "sha256="+Sign(secret, ts, body)).
+		NotEmpty(req.Header.Get(HeaderDelivery))
+
+	if r.fails > 0 {
+		r.fails--
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("fail"))
+		return
+	}
+
+	p := &Payload{}
+	r.a.NotError(json.Unmarshal(body, p)).
+		Equal(req.Header.Get(HeaderEvent), p.Event)
+	r.payloads = append(r.payloads, p)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func newModule(s *test.Suite) *Module {
+	conf := &Config{}
+	s.Assertion().NotError(conf.SanitizeConfig())
+	m := Install(s.NewModule("webhook"), conf, admintest.NewModule(s))
+	m.RegisterEvent("e1", web.Phrase("e1"))
+	m.RegisterEvent("e2", web.Phrase("e2"))
+	return m
+}
+
+func TestSign(t *testing.T) {
+	a := assert.New(t, false)
+
+	s1 := Sign("s1", 1, []byte("body"))
+	a.Length(s1, 64).
+		Equal(s1, Sign("s1", 1, []byte("body"))).
+		NotEqual(s1, Sign("s2", 1, []byte("body"))).
+		NotEqual(s1, Sign("s1", 2, []byte("body")))
+}
+
+func TestModule(t *testing.T) {
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+	defer s.Close()
+	m := newModule(s)
+
+	a.PanicString(func() {
+		m.RegisterEvent("e1", web.Phrase("e1"))
+	}, "事件 e1 已经存在")
+	a.PanicString(func() {
+		m.Trigger(nil, "not-exists", nil)
+	}, "事件 not-exists 不存在")
+
+	r := &receiver{a: a, fails: 1}
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	_, err := m.mod.DB().Insert(&endpointPO{URL: srv.URL, Secret: secret, Events: types.Strings{"e1"}})
+	a.NotError(err)
+	_, err = m.mod.DB().Insert(&endpointPO{URL: srv.URL, Secret: secret, Events: types.Strings{"e1", "e2"}, Disabled: true})
+	a.NotError(err)
+
+	// 无订阅者
+	a.NotError(m.Trigger(nil, "e2", 1))
+	p, err := m.GetDeliveries(&DeliveryQuery{Limit: defaultLimit()})
+	a.NotError(err).Nil(p)
+
+	a.NotError(m.Trigger(nil, "e1", map[string]int{"id": 1}))
+	p, err = m.GetDeliveries(&DeliveryQuery{Limit: defaultLimit()})
+	a.NotError(err).Equal(p.Count, 1)
+	d := p.Current[0]
+
+	// 第一次返回 500
+	now := time.Now()
+	a.NotError(m.Dispatch(now))
+	a.Empty(r.payloads)
+	p, err = m.GetDeliveries(&DeliveryQuery{States: []State{StatePending}, Limit: defaultLimit()})
+	a.NotError(err).Equal(p.Count, 1).
+		Equal(p.Current[0].Attempts, 1).
+		Equal(p.Current[0].Status, http.StatusInternalServerError)
+
+	// 未到重试时间
+	a.NotError(m.Dispatch(now))
+	a.Empty(r.payloads)
+
+	a.NotError(m.Dispatch(now.Add(time.Hour)))
+	a.Length(r.payloads, 1).
+		Equal(r.payloads[0].Event, "e1").
+		Equal(string(r.payloads[0].Data), `{"id":1}`)
+
+	attempts, err := m.GetAttempts(d.ID)
+	a.NotError(err).Length(attempts, 2).
+		Equal(attempts[0].Status, http.StatusInternalServerError).
+		Equal(attempts[0].Response, "fail").
+		Equal(attempts[1].Status, http.StatusNoContent)
+
+	// 重新投递
+	id, err := m.Replay(d.ID)
+	a.NotError(err).NotEqual(id, d.ID)
+	a.NotError(m.Dispatch(time.Now().Add(time.Second)))
+	a.Length(r.payloads, 2).Equal(r.payloads[1], r.payloads[0])
+
+	_, err = m.Replay(1000)
+	a.Error(err)
+}
+
+func TestModule_retries(t *testing.T) {
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+	defer s.Close()
+	m := newModule(s)
+	m.retries = 1
+
+	r := &receiver{a: a, fails: 10}
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	_, err := m.mod.DB().Insert(&endpointPO{URL: srv.URL, Secret: secret, Events: types.Strings{"e1"}})
+	a.NotError(err)
+
+	a.NotError(m.Trigger(nil, "e1", 1))
+	now := time.Now()
+	a.NotError(m.Dispatch(now))
+	a.NotError(m.Dispatch(now.Add(time.Hour)))
+	a.NotError(m.Dispatch(now.Add(2 * time.Hour)))
+	a.Equal(r.fails, 8)
+
+	p, err := m.GetDeliveries(&DeliveryQuery{States: []State{StateFailed}, Limit: defaultLimit()})
+	a.NotError(err).Equal(p.Count, 1).Equal(p.Current[0].Attempts, 2)
+}
+
+func TestModule_Forward(t *testing.T) {
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+	defer s.Close()
+	m := newModule(s)
+
+	mod := s.NewModule("outbox")
+	outbox.Install(mod)
+	o := outbox.New(mod, time.Minute, 1)
+
+	m.Forward(o, "t1", "e2")
+	a.PanicString(func() {
+		m.Forward(o, "t1", "not-exists")
+	}, "事件 not-exists 不存在")
+
+	r := &receiver{a: a}
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+	_, err := m.mod.DB().Insert(&endpointPO{URL: srv.URL, Secret: secret, Events: types.Strings{"e2"}})
+	a.NotError(err)
+
+	a.NotError(o.Publish(nil, "t1", map[string]string{"no": "1"}))
+	a.NotError(o.Dispatch(time.Now()))
+	a.NotError(m.Dispatch(time.Now()))
+	a.Length(r.payloads, 1).
+		Equal(r.payloads[0].Event, "e2").
+		Equal(string(r.payloads[0].Data), `{"no":"1"}`)
+}
+
+func defaultLimit() query.Limit { return query.Limit{Size: 20} }
@@ -0,0 +1,384 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package webhook
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/issue9/orm/v6"
+	"github.com/issue9/orm/v6/sqlbuilder"
+	"github.com/issue9/sliceutil"
+	"github.com/issue9/web"
+	"github.com/issue9/web/filter"
+	"github.com/issue9/web/openapi"
+	v "github.com/issue9/webuse/v7/filters/validator"
+
+	"github.com/issue9/cmfx/cmfx"
+	"github.com/issue9/cmfx/cmfx/filters"
+	"github.com/issue9/cmfx/cmfx/locales"
+	"github.com/issue9/cmfx/cmfx/query"
+	"github.com/issue9/cmfx/cmfx/types"
+)
+
+func (m *Module) initRoutes(conf *Config) {
+	g := m.admin.NewResourceGroup(m.mod)
+	resGet := g.New("get-webhooks", web.Phrase("view webhooks"))
+	resPost := g.New("post-webhooks", web.Phrase("add webhook endpoint"))
+	resPut := g.New("put-webhooks", web.Phrase("edit webhook endpoint"))
+	resDel := g.New("del-webhooks", web.Phrase("delete webhook endpoint"))
+	resReplay := g.New("replay-webhooks", web.Phrase("replay webhook delivery"))
+
+	api := m.admin.UserModule().Module().API
+	r := m.admin.UserModule().Module().Router().Prefix(m.admin.URLPrefix()+conf.URLPrefix, m.admin)
+	r.Get("/events", m.adminGetEvents, resGet, api(func(o *openapi.Operation) {
+		o.Tag("webhook").
+			Desc(web.Phrase("get webhook events api"), nil).
+			Response200([]eventVO{})
+	})).
+		Get("/endpoints", m.adminGetEndpoints, resGet, api(func(o *openapi.Operation) {
+			o.Tag("webhook").
+				Desc(web.Phrase("get webhook endpoints api"), nil).
+				Response200([]endpointVO{})
+		})).
+		Post("/endpoints", m.adminPostEndpoints, resPost, api(func(o *openapi.Operation) {
+			o.Tag("webhook").
+				Desc(web.Phrase("add webhook endpoint api"), nil).
+				Body(endpointTO{}, false, nil, nil).
+				ResponseEmpty("201")
+		})).
+		Put("/endpoints/{id:digit}", m.adminPutEndpoint, resPut, api(func(o *openapi.Operation) {
+			o.Tag("webhook").
+				Desc(web.Phrase("edit webhook endpoint api"), nil).
+				PathID("id:digit", web.Phrase("the webhook endpoint id")).
+				Body(endpointTO{}, false, nil, nil).
+				ResponseEmpty("204")
+		})).
+		Delete("/endpoints/{id:digit}", m.adminDeleteEndpoint, resDel, api(func(o *openapi.Operation) {
+			o.Tag("webhook").
+				Desc(web.Phrase("delete webhook endpoint api"), nil).
+				PathID("id:digit", web.Phrase("the webhook endpoint id")).
+				ResponseEmpty("204")
+		})).
+		Get("/deliveries", m.adminGetDeliveries, resGet, api(func(o *openapi.Operation) {
+			o.Tag("webhook").
+				Desc(web.Phrase("get webhook deliveries api"), nil).
+				QueryObject(DeliveryQuery{}, nil).
+				Response200(query.Page[DeliveryVO]{})
+		})).
+		Get("/deliveries/{id:digit}/attempts", m.adminGetAttempts, resGet, api(func(o *openapi.Operation) {
+			o.Tag("webhook").
+				Desc(web.Phrase("get webhook delivery attempts api"), nil).
+				PathID("id:digit", web.Phrase("the webhook delivery id")).
+				Response200([]AttemptVO{})
+		})).
+		Post("/deliveries/{id:digit}/replay", m.adminPostReplay, resReplay, api(func(o *openapi.Operation) {
+			o.Tag("webhook").
+				Desc(web.Phrase("replay webhook delivery api"), nil).
+				PathID("id:digit", web.Phrase("the webhook delivery id")).
+				ResponseEmpty("201")
+		}))
+}
+
+type eventVO struct {
+	Name  string `json:"name" yaml:"name" cbor:"name"`
+	Title string `json:"title" yaml:"title" cbor:"title"`
+}
+
+func (m *Module) adminGetEvents(ctx *web.Context) web.Responser {
+	p := ctx.LocalePrinter()
+	events := make([]eventVO, 0, len(m.names))
+	for _, name := range m.names {
+		events = append(events, eventVO{Name: name, Title: m.events[name].LocaleString(p)})
+	}
+	return web.OK(events)
+}
+
+type endpointTO struct {
+	m        *Module
+	update   bool     // 修改时 Secret 可以为空，表示不修改。
+	URL      string   `json:"url" yaml:"url" cbor:"url" comment:"the url of endpoint"`
+	Secret   string   `json:"secret" yaml:"secret" cbor:"secret" comment:"the secret of signature"`
+	Events   []string `json:"events" yaml:"events" cbor:"events" comment:"subscribed events"`
+	Disabled bool     `json:"disabled,omitempty" yaml:"disabled,omitempty" cbor:"disabled,omitempty" comment:"disabled"`
+}
+
+func (to *endpointTO) Filter(ctx *web.FilterContext) {
+	events := filter.NewBuilder(
+		v.V(func(e []string) bool { return len(e) > 0 }, locales.Required),
+		v.SV[[]string](to.m.isEvent, locales.InvalidValue),
+	)
+
+	ctx.Add(filters.URL("url", &to.URL)).
+		When(!to.update, func(v *web.FilterContext) {
+			v.Add(filters.NotEmpty("secret", &to.Secret))
+		}).
+		Add(events("events", &to.Events))
+}
+
+type endpointVO struct {
+	ID       int64     `orm:"name(id)" json:"id" yaml:"id" cbor:"id"`
+	URL      string    `orm:"name(url)" json:"url" yaml:"url" cbor:"url"`
+	Secret   string    `orm:"name(secret)" json:"secret" yaml:"secret" cbor:"secret"` // 脱敏之后的签名密钥，参考 maskSecret。
+	Events   []string  `orm:"name(events)" json:"events" yaml:"events" cbor:"events"`
+	Disabled bool      `orm:"name(disabled)" json:"disabled,omitempty" yaml:"disabled,omitempty" cbor:"disabled,omitempty"`
+	Created  time.Time `orm:"name(created)" json:"created" yaml:"created" cbor:"created"`
+}
+
+func (m *Module) adminGetEndpoints(ctx *web.Context) web.Responser {
+	endpoints := make([]*endpointPO, 0, 10)
+	_, err := m.mod.DB().SQLBuilder().Select().
+		From(orm.TableName(&endpointPO{})).
+		AndIsNull("deleted").
+		Desc("id").
+		QueryObject(true, &endpoints)
+	if err != nil {
+		return ctx.Error(err, "")
+	}
+
+	vo := make([]*endpointVO, 0, len(endpoints))
+	for _, ep := range endpoints {
+		vo = append(vo, &endpointVO{
+			ID:       ep.ID,
+			URL:      ep.URL,
+			Secret:   maskSecret(ep.Secret),
+			Events:   ep.Events,
+			Disabled: ep.Disabled,
+			Created:  ep.Created,
+		})
+	}
+	return web.OK(vo)
+}
+
+// 将签名密钥 s 脱敏，仅保留最后 4 个字符用于辨识。
+//
+// 密钥只在添加时由管理员提交，之后的接口不再返回完整的内容。
+func maskSecret(s string) string {
+	const mask = "******"
+	if len(s) <= 8 {
+		return mask
+	}
+	return mask + s[len(s)-4:]
+}
+
+func (m *Module) adminPostEndpoints(ctx *web.Context) web.Responser {
+	data := &endpointTO{m: m}
+	if resp := ctx.Read(true, data, cmfx.BadRequestInvalidBody); resp != nil {
+		return resp
+	}
+
+	_, err := m.mod.DB().Insert(&endpointPO{
+		URL:      data.URL,
+		Secret:   data.Secret,
+		Events:   types.Strings(data.Events),
+		Disabled: data.Disabled,
+	})
+	if err != nil {
+		return ctx.Error(err, "")
+	}
+	return web.Created(nil, "")
+}
+
+func (m *Module) adminPutEndpoint(ctx *web.Context) web.Responser {
+	ep, resp := m.getEndpointFromPath(ctx)
+	if resp != nil {
+		return resp
+	}
+
+	data := &endpointTO{m: m, update: true}
+	if resp := ctx.Read(true, data, cmfx.BadRequestInvalidBody); resp != nil {
+		return resp
+	}
+
+	// Secret 为空时不会被更新
+	_, err := m.mod.DB().Update(&endpointPO{
+		ID:       ep.ID,
+		URL:      data.URL,
+		Secret:   data.Secret,
+		Events:   types.Strings(data.Events),
+		Disabled: data.Disabled,
+	}, "disabled")
+	if err != nil {
+		return ctx.Error(err, "")
+	}
+	return web.NoContent()
+}
+
+// 删除接收端
+//
+// 仅作软删除，以保留投递记录。
+func (m *Module) adminDeleteEndpoint(ctx *web.Context) web.Responser {
+	ep, resp := m.getEndpointFromPath(ctx)
+	if resp != nil {
+		return resp
+	}
+
+	if _, err := m.mod.DB().Update(&endpointPO{ID: ep.ID, Deleted: sql.NullTime{Time: ctx.Begin(), Valid: true}}); err != nil {
+		return ctx.Error(err, "")
+	}
+	return web.NoContent()
+}
+
+func (m *Module) getEndpointFromPath(ctx *web.Context) (*endpointPO, web.Responser) {
+	id, resp := ctx.PathID("id", cmfx.NotFoundInvalidPath)
+	if resp != nil {
+		return nil, resp
+	}
+
+	ep := &endpointPO{ID: id}
+	found, err := m.mod.DB().Select(ep)
+	if err != nil {
+		return nil, ctx.Error(err, "")
+	}
+	if !found || ep.Deleted.Valid {
+		return nil, ctx.NotFound()
+	}
+	return ep, nil
+}
+
+// DeliveryQuery 查询投递记录的参数
+type DeliveryQuery struct {
+	query.Limit
+	Endpoint int64   `query:"endpoint"`
+	Event    string  `query:"event"`
+	States   []State `query:"state"`
+}
+
+func (q *DeliveryQuery) Filter(ctx *web.FilterContext) {
+	q.Limit.Filter(ctx)
+	ctx.Add(StateSliceFilter("state", &q.States))
+}
+
+// DeliveryVO 投递记录
+type DeliveryVO struct {
+	ID       int64     `orm:"name(id)" json:"id" yaml:"id" cbor:"id"`
+	Endpoint int64     `orm:"name(endpoint)" json:"endpoint" yaml:"endpoint" cbor:"endpoint"`
+	Event    string    `orm:"name(event)" json:"event" yaml:"event" cbor:"event"`
+	Payload  string    `orm:"name(payload)" json:"payload" yaml:"payload" cbor:"payload"`
+	State    State     `orm:"name(state)" json:"state" yaml:"state" cbor:"state"`
+	Attempts int       `orm:"name(attempts)" json:"attempts" yaml:"attempts" cbor:"attempts"`
+	Status   int       `orm:"name(status)" json:"status,omitempty" yaml:"status,omitempty" cbor:"status,omitempty"`
+	Next     time.Time `orm:"name(next)" json:"next" yaml:"next" cbor:"next"`
+	Created  time.Time `orm:"name(created)" json:"created" yaml:"created" cbor:"created"`
+	Updated  time.Time `orm:"name(updated)" json:"updated" yaml:"updated" cbor:"updated"`
+}
+
+// GetDeliveries 查询投递记录
+func (m *Module) GetDeliveries(q *DeliveryQuery) (*query.Page[DeliveryVO], error) {
+	return query.Paging[DeliveryVO](&q.Limit, m.deliveriesSQL(q), nil)
+}
+
+func (m *Module) deliveriesSQL(q *DeliveryQuery) *sqlbuilder.SelectStmt {
+	sql := m.mod.DB().SQLBuilder().Select().
+		Column("*").
+		From(orm.TableName(&deliveryPO{})).
+		Desc("id")
+
+	if q.Endpoint > 0 {
+		sql.And("endpoint=?", q.Endpoint)
+	}
+	if q.Event != "" {
+		sql.And("event=?", q.Event)
+	}
+	if len(q.States) > 0 {
+		sql.AndIn("state", sliceutil.AnySlice(q.States)...)
+	}
+
+	return sql
+}
+
+func (m *Module) adminGetDeliveries(ctx *web.Context) web.Responser {
+	q := &DeliveryQuery{}
+	if resp := ctx.QueryObject(true, q, cmfx.BadRequestInvalidQuery); resp != nil {
+		return resp
+	}
+
+	return query.PagingResponser[DeliveryVO](ctx, &q.Limit, m.deliveriesSQL(q), nil)
+}
+
+// AttemptVO 单次投递的记录
+type AttemptVO struct {
+	ID       int64     `orm:"name(id)" json:"id" yaml:"id" cbor:"id"`
+	Status   int       `orm:"name(status)" json:"status,omitempty" yaml:"status,omitempty" cbor:"status,omitempty"`
+	Response string    `orm:"name(response)" json:"response,omitempty" yaml:"response,omitempty" cbor:"response,omitempty"`
+	Error    string    `orm:"name(error)" json:"error,omitempty" yaml:"error,omitempty" cbor:"error,omitempty"`
+	Duration int64     `orm:"name(duration)" json:"duration" yaml:"duration" cbor:"duration"`
+	Created  time.Time `orm:"name(created)" json:"created" yaml:"created" cbor:"created"`
+}
+
+// GetAttempts 获取投递记录 delivery 的每一次投递结果
+func (m *Module) GetAttempts(delivery int64) ([]*AttemptVO, error) {
+	attempts := make([]*AttemptVO, 0, 10)
+	_, err := m.mod.DB().SQLBuilder().Select().
+		Column("*").
+		From(orm.TableName(&attemptPO{})).
+		Where("delivery=?", delivery).
+		Asc("id").
+		QueryObject(true, &attempts)
+	return attempts, err
+}
+
+func (m *Module) adminGetAttempts(ctx *web.Context) web.Responser {
+	id, resp := ctx.PathID("id", cmfx.NotFoundInvalidPath)
+	if resp != nil {
+		return resp
+	}
+
+	attempts, err := m.GetAttempts(id)
+	if err != nil {
+		return ctx.Error(err, "")
+	}
+	if len(attempts) == 0 {
+		return ctx.NotFound()
+	}
+	return web.OK(attempts)
+}
+
+// Replay 重新投递 id 指定的记录
+//
+// 会以相同的内容生成一条新的投递记录，并在下一次 [Module.Dispatch] 时投递，
+// 原记录保持不变。返回新记录的 ID。
+func (m *Module) Replay(id int64) (int64, error) {
+	d := &deliveryPO{ID: id}
+	found, err := m.mod.DB().Select(d)
+	if err != nil {
+		return 0, err
+	}
+	if !found {
+		return 0, web.NewLocaleError("webhook delivery %d not found", id)
+	}
+	return m.replay(d)
+}
+
+func (m *Module) replay(d *deliveryPO) (int64, error) {
+	return m.mod.DB().LastInsertID(&deliveryPO{
+		Endpoint: d.Endpoint,
+		Event:    d.Event,
+		Payload:  d.Payload,
+		State:    StatePending,
+		Next:     time.Now(),
+	})
+}
+
+func (m *Module) adminPostReplay(ctx *web.Context) web.Responser {
+	id, resp := ctx.PathID("id", cmfx.NotFoundInvalidPath)
+	if resp != nil {
+		return resp
+	}
+
+	d := &deliveryPO{ID: id}
+	found, err := m.mod.DB().Select(d)
+	if err != nil {
+		return ctx.Error(err, "")
+	}
+	if !found {
+		return ctx.NotFound()
+	}
+
+	if _, err := m.replay(d); err != nil {
+		return ctx.Error(err, "")
+	}
+	return web.Created(nil, "")
+}
@@ -0,0 +1,133 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package webhook
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/mux/v9/header"
+	"github.com/issue9/web/server/servertest"
+	"github.com/issue9/webuse/v7/middlewares/auth"
+
+	"github.com/issue9/cmfx/cmfx/initial/test"
+	"github.com/issue9/cmfx/cmfx/modules/admin/admintest"
+)
+
+func TestModule_routes(t *testing.T) {
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+	m := newModule(s)
+
+	r := &receiver{a: a}
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	defer servertest.Run(a, s.Module().Server())()
+	defer s.Close()
+
+	token := auth.BuildToken(auth.Bearer, admintest.GetToken(s, m.admin))
+
+	s.Get("/admin/webhooks/events").
+		Header(header.Authorization, token).
+		Header(header.Accept, header.JSON).
+		Do(nil).
+		Status(http.StatusOK).
+		StringBody(`[{"name":"e1","title":"e1"},{"name":"e2","title":"e2"}]`)
+
+	s.Post("/admin/webhooks/endpoints", []byte(`{"url":"`+srv.URL+`","secret":"`+secret+`","events":["not-exists"]}`)).
+		Header(header.Authorization, token).
+		Header(header.ContentType, header.JSON).
+		Header(header.Accept, header.JSON).
+		Do(nil).
+		Status(http.StatusBadRequest)
+
+	s.Post("/admin/webhooks/endpoints", []byte(`{"url":"`+srv.URL+`","secret":"`+secret+`","events":["e1"]}`)).
+		Header(header.Authorization, token).
+		Header(header.ContentType, header.JSON).
+		Header(header.Accept, header.JSON).
+		Do(nil).
+		Status(http.StatusCreated)
+
+	// 未指定 secret，保持原值。
+	s.Put("/admin/webhooks/endpoints/1", []byte(`{"url":"`+srv.URL+`","events":["e1","e2"]}`)).
+		Header(header.Authorization, token).
+		Header(header.ContentType, header.JSON).
+		Header(header.Accept, header.JSON).
+		Do(nil).
+		Status(http.StatusNoContent)
+	ep := &endpointPO{ID: 1}
+	found, err := m.mod.DB().Select(ep)
+	a.NotError(err).True(found).Equal(ep.Secret, secret)
+
+	s.Get("/admin/webhooks/endpoints").
+		Header(header.Authorization, token).
+		Header(header.Accept, header.JSON).
+		Do(nil).
+		Status(http.StatusOK).
+		BodyFunc(func(a *assert.Assertion, body []byte) {
+			a.Contains(string(body), `"secret":"******6789"`).
+				NotContains(string(body), secret)
+		})
+
+	a.NotError(m.Trigger(nil, "e2", 1))
+	a.NotError(m.Dispatch(time.Now()))
+	a.Length(r.payloads, 1)
+
+	s.Get("/admin/webhooks/deliveries?state=succeeded").
+		Header(header.Authorization, token).
+		Header(header.Accept, header.JSON).
+		Do(nil).
+		Status(http.StatusOK)
+
+	s.Get("/admin/webhooks/deliveries/1/attempts").
+		Header(header.Authorization, token).
+		Header(header.Accept, header.JSON).
+		Do(nil).
+		Status(http.StatusOK)
+
+	s.Post("/admin/webhooks/deliveries/1/replay", nil).
+		Header(header.Authorization, token).
+		Header(header.Accept, header.JSON).
+		Do(nil).
+		Status(http.StatusCreated)
+
+	s.Post("/admin/webhooks/deliveries/100/replay", nil).
+		Header(header.Authorization, token).
+		Header(header.Accept, header.JSON).
+		Do(nil).
+		Status(http.StatusNotFound)
+
+	a.NotError(m.Dispatch(time.Now()))
+	a.Length(r.payloads, 2)
+
+	s.Delete("/admin/webhooks/endpoints/1").
+		Header(header.Authorization, token).
+		Header(header.Accept, header.JSON).
+		Do(nil).
+		Status(http.StatusNoContent)
+
+	s.Put("/admin/webhooks/endpoints/1", []byte(`{"url":"`+srv.URL+`","secret":"`+secret+`","events":["e1"]}`)).
+		Header(header.Authorization, token).
+		Header(header.ContentType, header.JSON).
+		Header(header.Accept, header.JSON).
+		Do(nil).
+		Status(http.StatusNotFound)
+
+	a.NotError(m.Trigger(nil, "e2", 1))
+	p, err := m.GetDeliveries(&DeliveryQuery{States: []State{StatePending}, Limit: defaultLimit()})
+	a.NotError(err).Nil(p)
+}
+
+func TestMaskSecret(t *testing.T) {
+	a := assert.New(t, false)
+
+	a.Equal(maskSecret(""), "******").
+		Equal(maskSecret("12345678"), "******").
+		Equal(maskSecret("123456789"), "******6789")
+}
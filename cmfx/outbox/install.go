@@ -0,0 +1,23 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package outbox
+
+import (
+	"github.com/issue9/web"
+
+	"github.com/issue9/cmfx/cmfx"
+)
+
+// Install 安装数据表
+func Install(mod *cmfx.Module) {
+	if err := mod.DB().Create(&eventPO{}, &deliveryPO{}); err != nil {
+		panic(web.SprintError(mod.Server().Locale().Printer(), true, err))
+	}
+}
+
+// Uninstall 删除由 [Install] 创建的数据表
+func Uninstall(mod *cmfx.Module) error {
+	return mod.DB().Drop(&eventPO{}, &deliveryPO{})
+}
@@ -0,0 +1,28 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package outbox
+
+import (
+	"testing"
+
+	"github.com/issue9/assert/v4"
+
+	"github.com/issue9/cmfx/cmfx/initial/test"
+)
+
+func TestInstall(t *testing.T) {
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+	defer s.Close()
+
+	mod := s.NewModule("outbox")
+	Install(mod)
+	s.TableExists(mod.ID() + "_outbox_events").
+		TableExists(mod.ID() + "_outbox_deliveries")
+
+	a.NotError(Uninstall(mod))
+	s.TableNotExists(mod.ID() + "_outbox_events").
+		TableNotExists(mod.ID() + "_outbox_deliveries")
+}
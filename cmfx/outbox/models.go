@@ -0,0 +1,65 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package outbox
+
+import (
+	"time"
+
+	"github.com/issue9/orm/v6/core"
+)
+
+// 事件表
+type eventPO struct {
+	ID      int64     `orm:"name(id);ai"`
+	Topic   string    `orm:"name(topic);len(100)"`
+	Payload string    `orm:"name(payload);len(-1)"` // JSON 格式的事件内容
+	Created time.Time `orm:"name(created)"`
+}
+
+func (*eventPO) TableName() string { return "_outbox_events" }
+
+func (p *eventPO) BeforeInsert() error {
+	p.Created = time.Now()
+	return nil
+}
+
+//go:generate web enum -i=./models.go -o=./models_methods.go -t=State
+
+// State 投递状态
+type State int8
+
+const (
+	StatePending   State = iota // 等待投递
+	StateSucceeded              // 投递成功
+	StateDead                   // 超过重试次数，不再投递。
+)
+
+func (State) PrimitiveType() core.PrimitiveType { return core.String }
+
+// 投递表
+//
+// 每个订阅者对应一条记录。
+type deliveryPO struct {
+	ID         int64     `orm:"name(id);ai"`
+	Event      int64     `orm:"name(event);unique(event_subscriber)"`
+	Subscriber string    `orm:"name(subscriber);len(100);unique(event_subscriber)"`
+	State      State     `orm:"name(state);len(20);index(i_state_next)"`
+	Next       time.Time `orm:"name(next);index(i_state_next)"` // 下一次投递的时间
+	Retries    int       `orm:"name(retries)"`                  // 已经失败的次数
+	Error      string    `orm:"name(error);len(-1)"`            // 最后一次失败的原因
+	Updated    time.Time `orm:"name(updated)"`
+}
+
+func (*deliveryPO) TableName() string { return "_outbox_deliveries" }
+
+func (p *deliveryPO) BeforeInsert() error {
+	p.Updated = time.Now()
+	return nil
+}
+
+func (p *deliveryPO) BeforeUpdate() error {
+	p.Updated = time.Now()
+	return nil
+}
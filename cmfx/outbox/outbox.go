@@ -0,0 +1,235 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+// Package outbox 基于数据库的事件发件箱
+//
+// 事件与业务数据在同一事务中写入数据库，再由后台服务投递给各个订阅者，
+// 投递失败会按指数退避的方式重试，超过重试次数的投递会被标记为 [StateDead]，
+// 可由管理员查看并手动重新投递。
+//
+// 同一事件可能会被投递多次，订阅者需要自行保证幂等性。
+package outbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/issue9/orm/v6"
+	"github.com/issue9/web"
+
+	"github.com/issue9/cmfx/cmfx"
+)
+
+type serverVarsKey int
+
+const outboxKey serverVarsKey = 0
+
+// 单次投递的事件数量
+const batchSize = 50
+
+// Outbox 事件发件箱
+type Outbox struct {
+	mod      *cmfx.Module
+	interval time.Duration
+	retries  int
+
+	// 主题 -> 订阅者名称 -> 处理函数
+	subscribers map[string]map[string]func([]byte) error
+}
+
+// New 声明 [Outbox] 对象
+//
+// 同一个 [web.Server] 只能声明一个 [Outbox]，之后可以通过 [Get] 获取。
+// 声明之后会添加一个后台服务，每隔 interval 投递一次事件。
+//
+// interval 投递的间隔，同时也是第一次重试的间隔，之后每次重试的间隔都会翻倍；
+// retries 最大的重试次数，超过此值的投递会被标记为 [StateDead]；
+func New(mod *cmfx.Module, interval time.Duration, retries int) *Outbox {
+	o := &Outbox{
+		mod:         mod,
+		interval:    interval,
+		retries:     retries,
+		subscribers: make(map[string]map[string]func([]byte) error, 10),
+	}
+
+	if _, loaded := mod.Server().Vars().LoadOrStore(outboxKey, o); loaded {
+		panic("已经存在 Outbox 对象")
+	}
+
+	mod.Server().Services().AddTicker(web.Phrase("outbox dispatcher"), o.Dispatch, interval, false, true)
+
+	return o
+}
+
+// Get 获取由 [New] 声明的对象
+//
+// 如果未声明，返回 nil。
+func Get(s web.Server) *Outbox {
+	if v, found := s.Vars().Load(outboxKey); found {
+		return v.(*Outbox)
+	}
+	return nil
+}
+
+// Module 关联的模块
+func (o *Outbox) Module() *cmfx.Module { return o.mod }
+
+// Subscribe 订阅主题 topic
+//
+// name 为订阅者的名称，在同一主题下必须是唯一的，投递记录以此区分不同的订阅者，
+// 所以一旦有数据产生，便不应该再修改；
+// f 为处理函数，返回错误时会在稍后重试；
+//
+// NOTE: 只有在发布事件时已经订阅的订阅者才会收到该事件。
+func Subscribe[T any](o *Outbox, topic, name string, f func(*T) error) {
+	subs, found := o.subscribers[topic]
+	if !found {
+		subs = make(map[string]func([]byte) error, 5)
+		o.subscribers[topic] = subs
+	}
+
+	if _, found := subs[name]; found {
+		panic(fmt.Sprintf("主题 %s 已经存在订阅者 %s", topic, name))
+	}
+
+	subs[name] = func(data []byte) error {
+		v := new(T)
+		if err := json.Unmarshal(data, v); err != nil {
+			return err
+		}
+		return f(v)
+	}
+}
+
+// Publish 发布事件
+//
+// tx 如果不为空，则事件会在该事务中写入，只有在事务提交之后才会被投递；
+// topic 事件的主题；
+// data 事件的内容，会以 JSON 格式保存；
+func (o *Outbox) Publish(tx *orm.Tx, topic string, data any) error {
+	subs := o.subscribers[topic]
+	if len(subs) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	if tx != nil {
+		return o.publish(tx, topic, string(payload), subs)
+	}
+	return o.mod.DB().DoTransaction(func(tx *orm.Tx) error {
+		return o.publish(tx, topic, string(payload), subs)
+	})
+}
+
+func (o *Outbox) publish(tx *orm.Tx, topic, payload string, subs map[string]func([]byte) error) error {
+	e := o.mod.Engine(tx)
+
+	id, err := e.LastInsertID(&eventPO{Topic: topic, Payload: payload})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	deliveries := make([]orm.TableNamer, 0, len(subs))
+	for name := range subs {
+		deliveries = append(deliveries, &deliveryPO{Event: id, Subscriber: name, State: StatePending, Next: now})
+	}
+	return e.InsertMany(10, deliveries...)
+}
+
+// Dispatch 投递所有已经到期的事件
+//
+// 一般由 [New] 添加的后台服务定时调用，也可以手动调用以立即投递。
+func (o *Outbox) Dispatch(now time.Time) error {
+	deliveries := make([]*deliveryPO, 0, batchSize)
+	_, err := o.mod.DB().SQLBuilder().Select().
+		From(orm.TableName(&deliveryPO{})).
+		Where("state=?", StatePending).
+		And("next<=?", now).
+		Asc("next").
+		Limit(batchSize).
+		QueryObject(true, &deliveries)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range deliveries {
+		if err := o.deliver(d, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 投递 d
+//
+// 只有与数据库相关的错误才会返回，订阅者返回的错误会记录在 d 中。
+func (o *Outbox) deliver(d *deliveryPO, now time.Time) error {
+	// 先将下一次投递的时间后移，以防止被其它进程同时投递。
+	lease := now.Add(o.backoff(d.Retries))
+	rslt, err := o.mod.DB().SQLBuilder().Update().
+		Table(orm.TableName(&deliveryPO{})).
+		Set("next", lease).
+		Where("id=?", d.ID).
+		And("state=?", StatePending).
+		And("next<=?", now).
+		Exec()
+	if err != nil {
+		return err
+	}
+	if n, err := rslt.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 { // 已经被其它进程处理
+		return nil
+	}
+
+	ev := &eventPO{ID: d.Event}
+	found, err := o.mod.DB().Select(ev)
+	if err != nil {
+		return err
+	}
+
+	po := &deliveryPO{ID: d.ID}
+	switch f := o.handler(ev.Topic, d.Subscriber); {
+	case !found:
+		po.State = StateDead
+		po.Error = fmt.Sprintf("event %d not found", d.Event)
+	case f == nil:
+		// 订阅者可能只是在当前进程中未订阅，保持 StatePending 状态，等待其它进程处理。
+		return nil
+	default:
+		if err := f([]byte(ev.Payload)); err != nil {
+			po.Retries = d.Retries + 1
+			po.Error = err.Error()
+			po.State = StatePending
+			po.Next = now.Add(o.backoff(po.Retries))
+			if po.Retries > o.retries {
+				po.State = StateDead
+			}
+		} else {
+			po.State = StateSucceeded
+			po.Error = ""
+		}
+	}
+
+	_, err = o.mod.DB().Update(po, "state", "error")
+	return err
+}
+
+func (o *Outbox) handler(topic, name string) func([]byte) error {
+	if subs, found := o.subscribers[topic]; found {
+		return subs[name]
+	}
+	return nil
+}
+
+// 第 retries 次重试的时间间隔
+func (o *Outbox) backoff(retries int) time.Duration {
+	return o.interval << min(retries, 16)
+}
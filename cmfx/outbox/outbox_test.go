@@ -0,0 +1,149 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package outbox
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/orm/v6"
+	"github.com/issue9/web"
+	"github.com/issue9/web/server/servertest"
+
+	"github.com/issue9/cmfx/cmfx"
+	"github.com/issue9/cmfx/cmfx/initial/test"
+	"github.com/issue9/cmfx/cmfx/query"
+)
+
+type payload struct {
+	ID int64 `json:"id"`
+}
+
+func newOutbox(s *test.Suite) *Outbox {
+	mod := s.NewModule("outbox")
+	Install(mod)
+	s.TableExists(mod.ID() + "_outbox_events").
+		TableExists(mod.ID() + "_outbox_deliveries")
+
+	o := New(mod, time.Minute, 1)
+	s.Assertion().NotNil(o).Equal(Get(mod.Server()), o)
+	return o
+}
+
+func TestOutbox(t *testing.T) {
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+	defer s.Close()
+	o := newOutbox(s)
+
+	a.PanicString(func() {
+		New(o.Module(), time.Minute, 1)
+	}, "已经存在 Outbox 对象")
+
+	var ok []int64
+	Subscribe(o, "t1", "ok", func(p *payload) error {
+		ok = append(ok, p.ID)
+		return nil
+	})
+	fail := errors.New("fail")
+	var failed int
+	Subscribe(o, "t1", "fail", func(p *payload) error {
+		failed++
+		return fail
+	})
+	a.PanicString(func() {
+		Subscribe(o, "t1", "ok", func(p *payload) error { return nil })
+	}, "主题 t1 已经存在订阅者 ok")
+
+	// 无订阅者
+	a.NotError(o.Publish(nil, "t2", &payload{ID: 1}))
+	cnt, err := o.mod.DB().Where("1=1").Count(&eventPO{})
+	a.NotError(err).Zero(cnt)
+
+	// 事务回滚
+	err = o.mod.DB().DoTransaction(func(tx *orm.Tx) error {
+		a.NotError(o.Publish(tx, "t1", &payload{ID: 1}))
+		return fail
+	})
+	a.ErrorIs(err, fail)
+	cnt, err = o.mod.DB().Where("1=1").Count(&eventPO{})
+	a.NotError(err).Zero(cnt)
+
+	err = o.mod.DB().DoTransaction(func(tx *orm.Tx) error {
+		return o.Publish(tx, "t1", &payload{ID: 2})
+	})
+	a.NotError(err)
+
+	now := time.Now()
+	a.NotError(o.Dispatch(now))
+	a.Equal(ok, []int64{2}).Equal(failed, 1)
+
+	// 未到重试时间
+	a.NotError(o.Dispatch(now))
+	a.Equal(ok, []int64{2}).Equal(failed, 1)
+
+	// 第一次重试，依然失败，超过了重试次数。
+	a.NotError(o.Dispatch(now.Add(time.Hour)))
+	a.Equal(ok, []int64{2}).Equal(failed, 2)
+
+	a.NotError(o.Dispatch(now.Add(2 * time.Hour)))
+	a.Equal(failed, 2)
+
+	p, err := o.GetDeliveries(&DeliveryQuery{States: []State{StateDead}, Limit: defaultLimit()})
+	a.NotError(err).Equal(p.Count, 1)
+	dead := p.Current[0]
+	a.Equal(dead.Subscriber, "fail").
+		Equal(dead.Topic, "t1").
+		Equal(dead.Payload, `{"id":2}`).
+		Equal(dead.Retries, 2).
+		Equal(dead.Error, "fail")
+
+	p, err = o.GetDeliveries(&DeliveryQuery{States: []State{StateSucceeded}, Limit: defaultLimit()})
+	a.NotError(err).Equal(p.Count, 1).Equal(p.Current[0].Subscriber, "ok")
+
+	// 重新投递
+
+	a.NotError(o.Retry(dead.ID))
+	p, err = o.GetDeliveries(&DeliveryQuery{States: []State{StatePending}, Limit: defaultLimit()})
+	a.NotError(err).Equal(p.Count, 1).
+		Equal(p.Current[0].Retries, 0).
+		Empty(p.Current[0].Error)
+	a.Equal(o.Retry(dead.ID), cmfx.ErrNotFound()) // 非 StateDead 状态
+	a.Equal(o.Retry(10000), cmfx.ErrNotFound())
+	a.NotError(o.Dispatch(time.Now().Add(time.Second)))
+	a.Equal(failed, 3).Equal(ok, []int64{2})
+}
+
+func TestOutbox_Handle(t *testing.T) {
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+	o := newOutbox(s)
+
+	Subscribe(o, "t1", "fail", func(p *payload) error { return errors.New("fail") })
+	a.NotError(o.Publish(nil, "t1", &payload{ID: 1}))
+	a.NotError(o.Dispatch(time.Now()))
+	a.NotError(o.Dispatch(time.Now().Add(time.Hour)))
+
+	s.Router().Get("/deliveries", o.HandleGetDeliveries).
+		Post("/deliveries/{id}/retry", func(ctx *web.Context) web.Responser { return o.HandlePostRetry(ctx, "id") })
+
+	defer servertest.Run(a, s.Module().Server())()
+	defer s.Close()
+
+	s.Get("/deliveries").Do(nil).Status(http.StatusOK)
+	s.Get("/deliveries?state=succeeded").Do(nil).Status(http.StatusNotFound)
+	s.Get("/deliveries?state=invalid").Do(nil).Status(http.StatusBadRequest)
+
+	s.Post("/deliveries/1/retry", nil).Do(nil).Status(http.StatusCreated)
+	s.Post("/deliveries/1/retry", nil).Do(nil).Status(http.StatusNotFound)
+	s.Post("/deliveries/100/retry", nil).Do(nil).Status(http.StatusNotFound)
+	s.Get("/deliveries").Do(nil).Status(http.StatusNotFound)
+	s.Get("/deliveries?state=pending").Do(nil).Status(http.StatusOK)
+}
+
+func defaultLimit() query.Limit { return query.Limit{Size: 20} }
@@ -0,0 +1,120 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package outbox
+
+import (
+	"errors"
+	"time"
+
+	"github.com/issue9/orm/v6"
+	"github.com/issue9/orm/v6/sqlbuilder"
+	"github.com/issue9/sliceutil"
+	"github.com/issue9/web"
+
+	"github.com/issue9/cmfx/cmfx"
+	"github.com/issue9/cmfx/cmfx/query"
+)
+
+// DeliveryQuery 查询投递记录的参数
+type DeliveryQuery struct {
+	query.Limit
+	States []State `query:"state,dead"`
+	Topic  string  `query:"topic"`
+}
+
+func (q *DeliveryQuery) Filter(ctx *web.FilterContext) {
+	q.Limit.Filter(ctx)
+	ctx.Add(StateSliceFilter("state", &q.States))
+}
+
+// DeliveryVO 投递记录
+type DeliveryVO struct {
+	ID         int64     `orm:"name(id)" json:"id" yaml:"id" cbor:"id"`
+	Event      int64     `orm:"name(event)" json:"event" yaml:"event" cbor:"event"`
+	Topic      string    `orm:"name(topic)" json:"topic" yaml:"topic" cbor:"topic"`
+	Payload    string    `orm:"name(payload)" json:"payload" yaml:"payload" cbor:"payload"`
+	Subscriber string    `orm:"name(subscriber)" json:"subscriber" yaml:"subscriber" cbor:"subscriber"`
+	State      State     `orm:"name(state)" json:"state" yaml:"state" cbor:"state"`
+	Retries    int       `orm:"name(retries)" json:"retries" yaml:"retries" cbor:"retries"`
+	Error      string    `orm:"name(error)" json:"error,omitempty" yaml:"error,omitempty" cbor:"error,omitempty"`
+	Next       time.Time `orm:"name(next)" json:"next" yaml:"next" cbor:"next"`
+	Created    time.Time `orm:"name(created)" json:"created" yaml:"created" cbor:"created"`
+	Updated    time.Time `orm:"name(updated)" json:"updated" yaml:"updated" cbor:"updated"`
+}
+
+// GetDeliveries 查询投递记录
+func (o *Outbox) GetDeliveries(q *DeliveryQuery) (*query.Page[DeliveryVO], error) {
+	return query.Paging[DeliveryVO](&q.Limit, o.deliveriesSQL(q), nil)
+}
+
+func (o *Outbox) deliveriesSQL(q *DeliveryQuery) *sqlbuilder.SelectStmt {
+	sql := o.mod.DB().SQLBuilder().Select().
+		Column("d.*").
+		Column("e.topic").
+		Column("e.payload").
+		Column("e.created").
+		From(orm.TableName(&deliveryPO{}), "d").
+		Join("LEFT", orm.TableName(&eventPO{}), "e", "e.id=d.event").
+		Desc("d.id")
+
+	if len(q.States) > 0 {
+		sql.AndIn("d.state", sliceutil.AnySlice(q.States)...)
+	}
+	if q.Topic != "" {
+		sql.And("e.topic=?", q.Topic)
+	}
+
+	return sql
+}
+
+// Retry 重新投递 id 指定的记录
+//
+// 只有 [StateDead] 状态的记录才可以重新投递，会清除失败次数和失败原因，并在下一次 [Outbox.Dispatch] 时投递。
+// 如果记录不存在或是状态不为 [StateDead]，返回 [cmfx.ErrNotFound]。
+func (o *Outbox) Retry(id int64) error {
+	rslt, err := o.mod.DB().Where("id=?", id).And("state=?", StateDead).
+		Update(&deliveryPO{State: StatePending, Next: time.Now()}, "state", "retries", "error")
+	if err != nil {
+		return err
+	}
+
+	if n, err := rslt.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return cmfx.ErrNotFound()
+	}
+	return nil
+}
+
+// HandleGetDeliveries 查询投递记录的路由
+//
+// 查询参数为 [DeliveryQuery]，默认只返回 [StateDead] 状态的记录。
+func (o *Outbox) HandleGetDeliveries(ctx *web.Context) web.Responser {
+	q := &DeliveryQuery{}
+	if resp := ctx.QueryObject(true, q, cmfx.BadRequestInvalidQuery); resp != nil {
+		return resp
+	}
+
+	return query.PagingResponser[DeliveryVO](ctx, &q.Limit, o.deliveriesSQL(q), nil)
+}
+
+// HandlePostRetry 重新投递的路由
+//
+// idKey 路由中表示投递记录 ID 的名称；
+func (o *Outbox) HandlePostRetry(ctx *web.Context, idKey string) web.Responser {
+	id, resp := ctx.PathID(idKey, cmfx.NotFoundInvalidPath)
+	if resp != nil {
+		return resp
+	}
+
+	switch err := o.Retry(id); {
+	case errors.Is(err, cmfx.ErrNotFound()):
+		return ctx.NotFound()
+	case err != nil:
+		return ctx.Error(err, "")
+	default:
+		return web.Created(nil, "")
+	}
+}
@@ -0,0 +1,227 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package cmfx
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/issue9/web"
+)
+
+// 可由 [Registry.Exec] 执行的指令
+const (
+	ActionServe     = "serve"     // 加载模块，即调用 [Lifecycle.Load]
+	ActionInstall   = "install"   // 安装模块，即调用 [Lifecycle.Install]
+	ActionUpgrade   = "upgrade"   // 升级模块，即执行 [Lifecycle.Migrations] 并调用 [Lifecycle.Upgrade]
+	ActionUninstall = "uninstall" // 卸载模块，即调用 [Lifecycle.Uninstall]
+)
+
+// Lifecycle 模块的生命周期函数
+//
+// 所有的函数都可以为空，表示在该阶段不需要执行任何操作。
+type Lifecycle struct {
+	// Deps 依赖的模块 ID
+	//
+	// 依赖的模块总是会在当前模块之前执行 Install、Upgrade 和 Load，
+	// 而 Uninstall 则是在当前模块之后执行。
+	Deps []string
+
+	// Install 安装当前模块
+	//
+	// 安装完成之后，模块应该处于与 Load 相同的可用状态。
+	Install func(*Module) error
+
+	// Migrations 当前模块的数据库迁移列表
+	//
+	// 执行 [ActionUpgrade] 时会调用其 [Migrations.Upgrade]。
+	Migrations func(*Module) *Migrations
+
+	// Upgrade 升级当前模块
+	//
+	// 在 Migrations 之后执行，用于数据库迁移之外的升级操作。
+	Upgrade func(*Module) error
+
+	// Load 加载当前模块
+	Load func(*Module) error
+
+	// Uninstall 卸载当前模块
+	Uninstall func(*Module) error
+}
+
+func (l *Lifecycle) upgrade(mod *Module) error {
+	if err := l.Migrations(mod).Upgrade(); err != nil {
+		return err
+	}
+
+	if l.Upgrade != nil {
+		return l.Upgrade(mod)
+	}
+	return nil
+}
+
+// States 当前模块的数据库迁移状态
+//
+// 如果未指定 [Lifecycle.Migrations]，返回空值。
+func (l *Lifecycle) States(mod *Module) ([]*MigrationState, error) {
+	if l.Migrations == nil {
+		return nil, nil
+	}
+	return l.Migrations(mod).States()
+}
+
+// Registry 模块的注册表
+//
+// 根据 [Lifecycle.Deps] 对模块进行排序，并按顺序执行各个模块的生命周期函数。
+type Registry struct {
+	items []*registryItem // 按注册顺序保存
+}
+
+type registryItem struct {
+	mod *Module
+	l   *Lifecycle
+}
+
+// Registry 与当前 [web.Server] 关联的模块注册表
+//
+// 由同一个 [web.Server] 派生的模块共用同一个注册表。
+func (m *Module) Registry() *Registry {
+	r, _ := m.Server().Vars().LoadOrStore(registryKey, &Registry{})
+	return r.(*Registry)
+}
+
+// Register 将当前模块的生命周期函数注册到 [Module.Registry]
+//
+// 同一模块只能注册一次，否则会 panic。
+func (m *Module) Register(l *Lifecycle) {
+	r := m.Registry()
+	if slices.ContainsFunc(r.items, func(item *registryItem) bool { return item.mod.ID() == m.ID() }) {
+		panic("模块 " + m.ID() + " 已经注册")
+	}
+	r.items = append(r.items, &registryItem{mod: m, l: l})
+}
+
+// 按依赖关系进行拓扑排序，被依赖的模块排在前面。
+//
+// 没有依赖关系的模块之间保持注册时的顺序。
+func (r *Registry) sort() ([]*registryItem, error) {
+	items := make(map[string]*registryItem, len(r.items))
+	for _, item := range r.items {
+		items[item.mod.ID()] = item
+	}
+
+	for _, item := range r.items {
+		for _, dep := range item.l.Deps {
+			if _, found := items[dep]; !found {
+				return nil, web.NewLocaleError("the dependency %s of module %s not found", dep, item.mod.ID())
+			}
+		}
+	}
+
+	sorted := make([]*registryItem, 0, len(r.items))
+	done := make(map[string]bool, len(r.items))
+	for len(sorted) < len(r.items) {
+		var found bool
+		for _, item := range r.items {
+			if done[item.mod.ID()] {
+				continue
+			}
+
+			if slices.ContainsFunc(item.l.Deps, func(dep string) bool { return !done[dep] }) {
+				continue
+			}
+
+			done[item.mod.ID()] = true
+			sorted = append(sorted, item)
+			found = true
+			break // 每次都从头开始，以保证注册的顺序。
+		}
+
+		if !found { // 剩余的模块之间存在循环依赖
+			cycle := make([]string, 0, len(r.items)-len(sorted))
+			for _, item := range r.items {
+				if !done[item.mod.ID()] {
+					cycle = append(cycle, item.mod.ID())
+				}
+			}
+			return nil, web.NewLocaleError("cyclic dependency between modules %s", strings.Join(cycle, ","))
+		}
+	}
+
+	return sorted, nil
+}
+
+// Modules 按依赖顺序返回所有已注册的模块
+func (r *Registry) Modules() ([]*Module, error) {
+	items, err := r.sort()
+	if err != nil {
+		return nil, err
+	}
+
+	mods := make([]*Module, 0, len(items))
+	for _, item := range items {
+		mods = append(mods, item.mod)
+	}
+	return mods, nil
+}
+
+// Visit 按依赖顺序访问所有已注册的模块
+//
+// f 返回错误时会中断访问并返回该错误。
+func (r *Registry) Visit(f func(*Module, *Lifecycle) error) error {
+	items, err := r.sort()
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		if err := f(item.mod, item.l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Exec 对所有已注册的模块执行 action 指令
+//
+// action 可以是 [ActionServe]、[ActionInstall]、[ActionUpgrade] 和 [ActionUninstall]，
+// 其中 [ActionUninstall] 按依赖的相反顺序执行，其它则按依赖顺序执行。
+func (r *Registry) Exec(action string) error {
+	var get func(*Lifecycle) func(*Module) error
+	switch action {
+	case ActionServe:
+		get = func(l *Lifecycle) func(*Module) error { return l.Load }
+	case ActionInstall:
+		get = func(l *Lifecycle) func(*Module) error { return l.Install }
+	case ActionUpgrade:
+		get = func(l *Lifecycle) func(*Module) error {
+			if l.Migrations == nil {
+				return l.Upgrade
+			}
+			return l.upgrade
+		}
+	case ActionUninstall:
+		get = func(l *Lifecycle) func(*Module) error { return l.Uninstall }
+	default:
+		return web.NewLocaleError("invalid action %s", action)
+	}
+
+	items, err := r.sort()
+	if err != nil {
+		return err
+	}
+	if action == ActionUninstall {
+		slices.Reverse(items)
+	}
+
+	for _, item := range items {
+		if f := get(item.l); f != nil {
+			if err := f(item.mod); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
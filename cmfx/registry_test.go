@@ -0,0 +1,103 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package cmfx
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/orm/v6"
+	"github.com/issue9/orm/v6/dialect"
+	"github.com/issue9/web"
+	"github.com/issue9/web/openapi"
+)
+
+func TestRegistry(t *testing.T) {
+	a := assert.New(t, false)
+	srv := newServer(a)
+
+	const dbFile = "./registry.db"
+
+	db, err := orm.NewDB("", dbFile, dialect.Sqlite3("sqlite3"))
+	a.NotError(err).NotNil(db)
+	defer func() {
+		a.NotError(db.Close())
+		a.NotError(os.Remove(dbFile))
+	}()
+
+	root := NewModule("", web.Phrase("root"), srv, db, srv.Routers().New("def", nil), openapi.New(srv, web.Phrase("test")))
+	m1 := root.New("m1", web.Phrase("m1"))
+	m2 := root.New("m2", web.Phrase("m2"))
+	m3 := root.New("m3", web.Phrase("m3"))
+	m4 := root.New("m4", web.Phrase("m4"))
+	a.Equal(m1.Registry(), m2.Registry()).Equal(root.Registry(), m2.Registry())
+
+	var ids []string
+	f := func(mod *Module) error {
+		ids = append(ids, mod.ID())
+		return nil
+	}
+	l := func(deps ...string) *Lifecycle {
+		return &Lifecycle{Deps: deps, Install: f, Upgrade: f, Load: f, Uninstall: f}
+	}
+
+	m3.Register(l("m1", "m2"))
+	m2.Register(l("m4"))
+	m1.Register(&Lifecycle{}) // 所有函数都为空
+	a.PanicString(func() {
+		m1.Register(&Lifecycle{})
+	}, "模块 m1 已经注册")
+
+	a.Error(root.Registry().Exec(ActionServe)) // m4 未注册
+
+	m4.Register(l())
+	mods, err := root.Registry().Modules()
+	a.NotError(err).Length(mods, 4).
+		Equal(mods[0], m1).
+		Equal(mods[1], m4).
+		Equal(mods[2], m2).
+		Equal(mods[3], m3)
+
+	a.NotError(root.Registry().Exec(ActionServe))
+	a.Equal(ids, []string{"m4", "m2", "m3"})
+
+	ids = ids[:0]
+	a.NotError(root.Registry().Exec(ActionInstall))
+	a.Equal(ids, []string{"m4", "m2", "m3"})
+
+	ids = ids[:0]
+	a.NotError(root.Registry().Exec(ActionUpgrade))
+	a.Equal(ids, []string{"m4", "m2", "m3"})
+
+	ids = ids[:0]
+	a.NotError(root.Registry().Exec(ActionUninstall))
+	a.Equal(ids, []string{"m3", "m2", "m4"})
+
+	a.Error(root.Registry().Exec("not-exists"))
+
+	// Visit
+
+	ids = ids[:0]
+	fail := errors.New("fail")
+	err = root.Registry().Visit(func(mod *Module, l *Lifecycle) error {
+		if mod == m2 {
+			return fail
+		}
+		ids = append(ids, mod.ID())
+		return nil
+	})
+	a.ErrorIs(err, fail).Equal(ids, []string{"m1", "m4"})
+
+	// 循环依赖
+
+	m5 := root.New("m5", web.Phrase("m5"))
+	m6 := root.New("m6", web.Phrase("m6"))
+	m5.Register(l("m6"))
+	m6.Register(l("m5"))
+	err = root.Registry().Exec(ActionServe)
+	a.Error(err).Contains(err.Error(), "m5,m6")
+}
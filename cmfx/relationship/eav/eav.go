@@ -43,6 +43,11 @@ func Install(mod *cmfx.Module, prefix string) *EAVs {
 	return NewEAVs(mod, prefix)
 }
 
+// Uninstall 删除由 [Install] 创建的数据表
+func Uninstall(mod *cmfx.Module, prefix string) error {
+	return buildDB(mod, prefix).Drop(&attrPO{}, &valueIntPO{}, &valueStringPO{}, &valueDatetimePO{}, &valueFloatPO{})
+}
+
 func (m *EAVs) AddAttribute(name string) (int64, error) {
 	return m.db.LastInsertID(&attrPO{Name: name})
 }
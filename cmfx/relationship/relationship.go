@@ -1,4 +1,4 @@
-// SPDX-FileCopyrightText: 2025 caixw
+// SPDX-FileCopyrightText: 2025-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
@@ -45,6 +45,11 @@ func Install[T1, T2 T](mod *cmfx.Module, tableName string) *Relationships[T1, T2
 	return NewRelationships[T1, T2](mod, tableName)
 }
 
+// Uninstall 删除由 [Install] 创建的数据表
+func Uninstall[T1, T2 T](mod *cmfx.Module, tableName string) error {
+	return buildDB(mod, tableName).Drop(&relationshipPO[T1, T2]{})
+}
+
 func (m *Relationships[T1, T2]) engine(tx *orm.Tx) orm.Engine {
 	if tx == nil {
 		return m.db
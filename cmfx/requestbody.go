@@ -0,0 +1,54 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package cmfx
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	"github.com/issue9/web"
+)
+
+type recordedBody struct {
+	io.ReadCloser
+	buf *bytes.Buffer
+}
+
+func (b *recordedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	return n, err
+}
+
+// RecordRequestBody 记录提交内容的 [http.Handler]
+//
+// [web.Context] 在创建时即已确定读取提交内容的对象，中间件无法在不影响后续处理的情况下读取提交的内容，
+// 所以只能在 [http.Handler] 层面对 [http.Request.Body] 进行包装。
+//
+// 仅记录 Content-Length 在 (0, size] 之间的非 GET、HEAD 和 OPTIONS 请求，
+// 记录的内容可通过 [RequestBody] 获取。
+func RecordRequestBody(h http.Handler, size int64) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+		default:
+			if r.ContentLength > 0 && r.ContentLength <= size {
+				r.Body = &recordedBody{ReadCloser: r.Body, buf: bytes.NewBuffer(make([]byte, 0, r.ContentLength))}
+			}
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// RequestBody 获取由 [RecordRequestBody] 记录的提交内容
+//
+// 仅包含处理过程中已经读取的内容，如果未记录，返回 nil。
+func RequestBody(ctx *web.Context) []byte {
+	if b, ok := ctx.Request().Body.(*recordedBody); ok {
+		return b.buf.Bytes()
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package cmfx
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/web"
+)
+
+func TestRecordRequestBody(t *testing.T) {
+	a := assert.New(t, false)
+	s := newServer(a)
+
+	var read, recorded string
+	r := s.Routers().New("default", nil)
+	h := RecordRequestBody(r, 10)
+	r.Post("/body", func(ctx *web.Context) web.Responser {
+		data, err := io.ReadAll(ctx.RequestBody())
+		a.NotError(err)
+		read = string(data)
+		recorded = string(RequestBody(ctx))
+		return web.NoContent()
+	})
+
+	do := func(body string) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/body", strings.NewReader(body)))
+		a.Equal(w.Code, http.StatusNoContent)
+	}
+
+	do("12345")
+	a.Equal(read, "12345").Equal(recorded, "12345")
+
+	do("12345678901") // 超过大小限制，不记录。
+	a.Equal(read, "12345678901").Empty(recorded)
+
+	do("")
+	a.Empty(read).Empty(recorded)
+}
@@ -6,11 +6,16 @@
 package currency
 
 import (
+	"database/sql"
 	"time"
 
+	"github.com/issue9/events"
 	"github.com/issue9/orm/v6"
+	"github.com/issue9/orm/v6/sqlbuilder"
+	"github.com/issue9/sliceutil"
 	"github.com/issue9/web"
 
+	"github.com/issue9/cmfx/cmfx/outbox"
 	"github.com/issue9/cmfx/cmfx/query"
 	"github.com/issue9/cmfx/cmfx/user"
 )
@@ -19,6 +24,9 @@ type Currency struct {
 	user *user.Users
 	id   string
 	db   *orm.DB
+
+	expiringEvent *events.Event[*ExpireEvent]
+	expiredEvent  *events.Event[*ExpireEvent]
 }
 
 type OverviewsVO struct {
@@ -46,13 +54,34 @@ func New(u *user.Users, id string) *Currency {
 		user: u,
 		id:   id,
 		db:   buildDB(u.Module().DB(), id),
+
+		expiringEvent: events.New[*ExpireEvent](),
+		expiredEvent:  events.New[*ExpireEvent](),
 	}
 
-	u.OnAdd(func(u *user.User) { m.initOverview(nil, u.ID) }) // 添加用户时创建一个关联的初始表
+	// 添加用户时创建一个关联的初始表
+	if o := u.Outbox(); o != nil {
+		outbox.Subscribe(o, u.Topic(user.TopicAdd), "currency_"+id, m.onUserAdded)
+	} else {
+		u.OnAdd(func(u *user.User) { m.initOverview(nil, u.ID) })
+	}
 
 	return m
 }
 
+// 由 [outbox.Outbox] 投递的添加用户事件
+//
+// 同一事件可能被多次投递，已经存在的记录不再重复添加。
+func (m *Currency) onUserAdded(e *user.Event) error {
+	n, err := m.db.Where("uid=?", e.ID).Count(&overviewPO{})
+	if err != nil || n > 0 {
+		return err
+	}
+
+	_, err = m.db.Insert(&overviewPO{UID: e.ID})
+	return err
+}
+
 func (m *Currency) initOverview(tx *orm.Tx, u int64) *overviewPO {
 	e := m.engine(tx)
 
@@ -77,20 +106,33 @@ func (m *Currency) engine(tx *orm.Tx) orm.Engine {
 
 // GetOverviews 获取所有用户的摘要信息
 func (m *Currency) GetOverviews(q *query.Text) (*query.Page[OverviewsVO], error) {
+	return query.PagingWithConvert(&q.Limit, m.overviewsSQL(q), overviewsVO)
+}
+
+func (m *Currency) overviewsSQL(q *query.Text) *sqlbuilder.SelectStmt {
 	sql := m.db.SQLBuilder().Select().From(orm.TableName(&overviewPO{}), "ov")
 
 	m.user.LeftJoin(sql, "u", "u.id=ov.uid", []user.State{user.StateLocked, user.StateNormal})
 
-	return query.PagingWithConvert(&q.Limit, sql, func(o *overviewsPO) *OverviewsVO {
-		return &OverviewsVO{
-			UID:       o.UID,
-			NO:        o.NO,
-			Username:  o.Username,
-			Available: o.Available,
-			Freeze:    o.Freeze,
-			Used:      o.Used,
-		}
-	})
+	if q.Text != "" {
+		text := "%" + q.Text + "%"
+		sql.AndGroup(func(ws *sqlbuilder.WhereStmt) {
+			ws.Or("u.username LIKE ?", text).Or("u.no LIKE ?", text)
+		})
+	}
+
+	return sql
+}
+
+func overviewsVO(o *overviewsPO) *OverviewsVO {
+	return &OverviewsVO{
+		UID:       o.UID,
+		NO:        o.NO,
+		Username:  o.Username,
+		Available: o.Available,
+		Freeze:    o.Freeze,
+		Used:      o.Used,
+	}
 }
 
 type OverviewVO struct {
@@ -109,7 +151,7 @@ type OverviewExpireVO struct {
 //
 // expire Expire 字段返回在此之前过期的积分，如果为空，则 Expire 不返回；
 func (m *Currency) GetOverview(uid int64, expire time.Time) (*OverviewVO, error) {
-	p := &overviewPO{ID: uid}
+	p := &overviewPO{UID: uid}
 	if found, err := m.db.Select(p); err != nil {
 		return nil, err
 	} else if !found {
@@ -147,13 +189,27 @@ func (m *Currency) GetOverview(uid int64, expire time.Time) (*OverviewVO, error)
 
 // Add 添加金额
 //
-// expire 如果不为空值，表示该积分在到达该时间还未被使用时，将不能再使用。
-func (m *Currency) Add(tx *orm.Tx, uid int64, val uint, memo string, expire time.Time) error {
+// expire 如果不为空值，表示该积分在到达该时间还未被使用时，将不能再使用；
+// key 为幂等键，如果不为空，相同的值只会执行一次，重复调用直接返回 nil，
+// 如果该值已经被其它操作使用，则返回 [ErrIdempotencyKeyConflict]；
+// tx 如果为空，会在一个新的事务中执行；
+func (m *Currency) Add(tx *orm.Tx, uid int64, val uint, memo string, expire time.Time, key string) error {
+	if !expire.IsZero() && expire.Before(time.Now()) {
+		return web.NewLocaleError("expire must b after now")
+	}
+
+	return m.idempotent(tx, key, uid, TypeNormal, int64(val), func(tx *orm.Tx) error {
+		return m.add(tx, uid, val, memo, expire, key)
+	})
+}
+
+func (m *Currency) add(tx *orm.Tx, uid int64, val uint, memo string, expire time.Time, key string) error {
 	isExpire := !expire.IsZero()
 	e := m.engine(tx)
 
-	if isExpire && expire.Before(time.Now()) {
-		return web.NewLocaleError("expire must b after now")
+	v := int64(val)
+	if replayed, err := m.replayed(e, key, uid, TypeNormal, v); err != nil || replayed {
+		return err
 	}
 
 	overview := &overviewPO{UID: uid}
@@ -163,14 +219,12 @@ func (m *Currency) Add(tx *orm.Tx, uid int64, val uint, memo string, expire time
 		overview = m.initOverview(tx, uid)
 	}
 
-	v := int64(val)
-
 	ov := &overviewPO{
 		ID:        overview.ID,
 		Available: overview.Available + v,
 	}
 	if isExpire {
-		ov.Expire += v
+		ov.Expire = overview.Expire + v
 	}
 	if _, err := e.Update(ov, "available"); err != nil {
 		return err
@@ -182,8 +236,14 @@ func (m *Currency) Add(tx *orm.Tx, uid int64, val uint, memo string, expire time
 		After:  ov.Available,
 		Value:  v,
 		Memo:   memo,
+		Key:    idempotencyKey(key),
 	}
-	if _, err := e.Insert(log); err != nil {
+	if _, err := m.insertLog(tx, log); err != nil {
+		return err
+	}
+
+	err := m.post(e, TypeNormal, memo, entry{systemAccount(SystemIssued), -v}, entry{userAccount(uid), v})
+	if err != nil {
 		return err
 	}
 
@@ -193,17 +253,19 @@ func (m *Currency) Add(tx *orm.Tx, uid int64, val uint, memo string, expire time
 			return err
 		}
 
-		if ee.ID > 0 { // 存在一条同一时间的记录
-			v += ee.Value
-		}
-
-		exp := &expirePO{
-			UID:     overview.UID,
-			Value:   v,
-			Expired: expire,
-		}
-		if _, _, err := e.Save(exp); err != nil {
-			return err
+		if ee.ID > 0 { // 存在一条同一时间的记录，合并金额并重新通知。
+			if _, err := e.Update(&expirePO{ID: ee.ID, Value: ee.Value + v}, "noticed"); err != nil {
+				return err
+			}
+		} else {
+			exp := &expirePO{
+				UID:     overview.UID,
+				Value:   v,
+				Expired: expire,
+			}
+			if _, err := e.Insert(exp); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -211,10 +273,23 @@ func (m *Currency) Add(tx *orm.Tx, uid int64, val uint, memo string, expire time
 }
 
 // Del 减少金额
-func (m *Currency) Del(tx *orm.Tx, uid int64, val uint, memo string) error {
+//
+// key 为幂等键，与 [Currency.Add] 中的相同；
+// tx 如果为空，会在一个新的事务中执行；
+func (m *Currency) Del(tx *orm.Tx, uid int64, val uint, memo, key string) error {
+	return m.idempotent(tx, key, uid, TypeNormal, -int64(val), func(tx *orm.Tx) error {
+		return m.del(tx, uid, val, memo, key)
+	})
+}
+
+func (m *Currency) del(tx *orm.Tx, uid int64, val uint, memo, key string) error {
 	e := m.engine(tx)
 	v := int64(val)
 
+	if replayed, err := m.replayed(e, key, uid, TypeNormal, -v); err != nil || replayed {
+		return err
+	}
+
 	overview := &overviewPO{UID: uid}
 	if found, err := e.Select(overview); err != nil {
 		return err
@@ -240,8 +315,8 @@ func (m *Currency) Del(tx *orm.Tx, uid int64, val uint, memo string) error {
 			return err
 		}
 		switch {
-		case size == 1: // 就一条记录
-			if _, err := e.Update(&expirePO{ID: expires[0].ID, Value: 0, Expired: time.Time{}}, "value", "expired"); err != nil {
+		case size == 1: // 就一条记录，且该记录的值大于 v。
+			if _, err := e.Update(&expirePO{ID: expires[0].ID, Value: expires[0].Value - v}); err != nil {
 				return err
 			}
 		case size > 1:
@@ -289,20 +364,33 @@ func (m *Currency) Del(tx *orm.Tx, uid int64, val uint, memo string) error {
 		After:  o2.Available,
 		Value:  -v, // 减少
 		Memo:   memo,
+		Key:    idempotencyKey(key),
 	}
-	if _, err := e.Insert(log); err != nil {
+	if _, err := m.insertLog(tx, log); err != nil {
 		return err
 	}
 
-	return nil
+	return m.post(e, TypeNormal, memo, entry{userAccount(uid), -v}, entry{systemAccount(SystemUsed), v})
 }
 
 // Freeze 冻结资金
 //
-// 会过期的资金无法冻结
-func (m *Currency) Freeze(tx *orm.Tx, u *user.User, val uint, memo string) error {
+// 会过期的资金无法冻结。
+// key 为幂等键，与 [Currency.Add] 中的相同；
+// tx 如果为空，会在一个新的事务中执行；
+func (m *Currency) Freeze(tx *orm.Tx, u *user.User, val uint, memo, key string) error {
+	return m.idempotent(tx, key, u.ID, TypeFreeze, -int64(val), func(tx *orm.Tx) error {
+		return m.freeze(tx, u, val, memo, key)
+	})
+}
+
+func (m *Currency) freeze(tx *orm.Tx, u *user.User, val uint, memo, key string) error {
 	e := m.engine(tx)
 
+	if replayed, err := m.replayed(e, key, u.ID, TypeFreeze, -int64(val)); err != nil || replayed {
+		return err
+	}
+
 	overview := &overviewPO{UID: u.ID}
 	if _, err := e.Select(overview); err != nil {
 		return err
@@ -329,14 +417,32 @@ func (m *Currency) Freeze(tx *orm.Tx, u *user.User, val uint, memo string) error
 		Value:  -v,
 		Memo:   memo,
 		Type:   TypeFreeze,
+		Key:    idempotencyKey(key),
 	}
-	_, err := e.Insert(log)
-	return err
+	if _, err := m.insertLog(tx, log); err != nil {
+		return err
+	}
+
+	return m.post(e, TypeFreeze, memo, entry{userAccount(u.ID), -v}, entry{frozenAccount(u.ID), v})
 }
 
-func (m *Currency) Unfreeze(tx *orm.Tx, u *user.User, val int64, memo string) error {
+// Unfreeze 解冻资金
+//
+// key 为幂等键，与 [Currency.Add] 中的相同；
+// tx 如果为空，会在一个新的事务中执行；
+func (m *Currency) Unfreeze(tx *orm.Tx, u *user.User, val int64, memo, key string) error {
+	return m.idempotent(tx, key, u.ID, TypeUnfreeze, val, func(tx *orm.Tx) error {
+		return m.unfreeze(tx, u, val, memo, key)
+	})
+}
+
+func (m *Currency) unfreeze(tx *orm.Tx, u *user.User, val int64, memo, key string) error {
 	e := m.engine(tx)
 
+	if replayed, err := m.replayed(e, key, u.ID, TypeUnfreeze, val); err != nil || replayed {
+		return err
+	}
+
 	overview := &overviewPO{UID: u.ID}
 	if _, err := e.Select(overview); err != nil {
 		return err
@@ -362,23 +468,102 @@ func (m *Currency) Unfreeze(tx *orm.Tx, u *user.User, val int64, memo string) er
 		Value:  +val,
 		Memo:   memo,
 		Type:   TypeUnfreeze,
+		Key:    idempotencyKey(key),
+	}
+	if _, err := m.insertLog(tx, log); err != nil {
+		return err
+	}
+
+	return m.post(e, TypeUnfreeze, memo, entry{frozenAccount(u.ID), -val}, entry{userAccount(u.ID), val})
+}
+
+// 在事务中执行操作 f
+//
+// tx 不为空时直接在 tx 中执行 f，否则会在一个新的事务中执行。
+// 新事务执行失败时，可能是并发的相同请求已经先一步写入了幂等键 key，
+// 此时事务已经回滚，重新检测 key 并返回已执行的结果。
+// key、uid、typ 和 value 的含义与 [Currency.replayed] 相同。
+func (m *Currency) idempotent(tx *orm.Tx, key string, uid int64, typ Type, value int64, f func(*orm.Tx) error) error {
+	if tx != nil {
+		return f(tx)
+	}
+
+	err := m.db.DoTransaction(f)
+	if err == nil || key == "" {
+		return err
+	}
+
+	if replayed, err2 := m.replayed(m.db, key, uid, typ, value); err2 != nil {
+		return err2
+	} else if replayed {
+		return nil
 	}
-	_, err := e.Insert(log)
 	return err
 }
 
+// 写入日志 log 并发布 [TopicChanged] 事件，返回日志的 ID。
+func (m *Currency) insertLog(tx *orm.Tx, log *LogPO) (int64, error) {
+	id, err := m.engine(tx).LastInsertID(log)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, m.publish(tx, TopicChanged, &ChangeEvent{
+		UID:    log.UID,
+		Type:   log.Type,
+		Before: log.Before,
+		After:  log.After,
+		Value:  log.Value,
+		Memo:   log.Memo,
+	})
+}
+
+// 检测幂等键 key 是否已经被使用
+//
+// 如果已经被相同的操作使用，返回 true；如果被其它操作使用，返回 [ErrIdempotencyKeyConflict]。
+// uid、typ 和 value 用于判断是否为相同的操作。
+func (m *Currency) replayed(e orm.Engine, key string, uid int64, typ Type, value int64) (bool, error) {
+	if key == "" {
+		return false, nil
+	}
+
+	log := &LogPO{}
+	size, err := e.Where("idempotency_key=?", key).Select(true, log)
+	if err != nil || size == 0 {
+		return false, err
+	}
+
+	if log.UID != uid || log.Type != typ || log.Value != value {
+		return false, ErrIdempotencyKeyConflict()
+	}
+	return true, nil
+}
+
+func idempotencyKey(key string) sql.NullString {
+	return sql.NullString{String: key, Valid: key != ""}
+}
+
+// LogQuery 查询日志的参数
+//
+// Text 匹配日志的备注；Types 为空表示不限制日志的类型。
 type LogQuery struct {
 	query.Text
-	Types []Type `query:"type,normal"`
+	Types []Type `query:"type" comment:"type of action"`
+}
+
+// GetLogs 查询用户 uid 的日志
+func (m *Currency) GetLogs(uid int64, q *LogQuery) (*query.Page[LogPO], error) {
+	return query.Paging[LogPO](&q.Limit, m.logsSQL(uid, q), nil)
 }
 
-// GetLogs 查询日志
-func (m *Currency) GetLogs(u *user.User, q *LogQuery) (*query.Page[LogPO], error) {
-	sql := m.db.SQLBuilder().Select().Where("uid=?", u.ID).From(orm.TableName(&LogPO{}))
+func (m *Currency) logsSQL(uid int64, q *LogQuery) *sqlbuilder.SelectStmt {
+	sql := m.db.SQLBuilder().Select().Where("uid=?", uid).From(orm.TableName(&LogPO{})).Desc("id")
 	if q.Text.Text != "" {
 		text := "%" + q.Text.Text + "%"
 		sql.And("memo LIKE ?", text)
 	}
-
-	return query.Paging[LogPO](&q.Limit, sql, nil)
+	if len(q.Types) > 0 {
+		sql.AndIn("type", sliceutil.AnySlice(q.Types)...)
+	}
+	return sql
 }
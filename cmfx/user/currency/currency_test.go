@@ -1,4 +1,4 @@
-// SPDX-FileCopyrightText: 2024-2025 caixw
+// SPDX-FileCopyrightText: 2024-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
@@ -11,6 +11,7 @@ import (
 	"github.com/issue9/assert/v4"
 
 	"github.com/issue9/cmfx/cmfx/initial/test"
+	"github.com/issue9/cmfx/cmfx/outbox"
 	"github.com/issue9/cmfx/cmfx/query"
 	"github.com/issue9/cmfx/cmfx/user"
 	"github.com/issue9/cmfx/cmfx/user/usertest"
@@ -37,6 +38,36 @@ func TestNew(t *testing.T) {
 	a.NotError(err).Equal(size, 1)
 }
 
+func TestNew_outbox(t *testing.T) {
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+	defer s.Close()
+
+	obMod := s.NewModule("outbox")
+	outbox.Install(obMod)
+	ob := outbox.New(obMod, time.Minute, 3)
+
+	u := usertest.NewModule(s)
+	a.Equal(u.Outbox(), ob)
+
+	m := Install(u, "point")
+
+	id, err := u.New(user.StateNormal, "u2", "123", "", "", "add")
+	a.NotError(err).NotZero(id)
+
+	size, err := m.db.Where("true").Count(&overviewPO{})
+	a.NotError(err).Zero(size) // 尚未投递
+
+	a.NotError(ob.Dispatch(time.Now()))
+	size, err = m.db.Where("true").Count(&overviewPO{})
+	a.NotError(err).Equal(size, 1)
+
+	// 重复投递
+	a.NotError(m.onUserAdded(&user.Event{ID: id.ID}))
+	size, err = m.db.Where("true").Count(&overviewPO{})
+	a.NotError(err).Equal(size, 1)
+}
+
 func TestCurrency(t *testing.T) {
 	a := assert.New(t, false)
 	s := test.NewSuite(a)
@@ -53,7 +84,7 @@ func TestCurrency(t *testing.T) {
 	expire := time.Now().Add(time.Hour)
 
 	t.Run("Add", func(t *testing.T) {
-		a.NotError(m.Add(nil, u1.ID, 10, "+10", time.Time{}))
+		a.NotError(m.Add(nil, u1.ID, 10, "+10", time.Time{}, ""))
 		ov := &overviewPO{UID: u1.ID}
 		_, err = m.db.Select(ov)
 		a.NotError(err).Equal(ov.Available, 10).
@@ -71,7 +102,7 @@ func TestCurrency(t *testing.T) {
 
 		// add with expire
 
-		a.NotError(m.Add(nil, u1.ID, 10, "+10", expire))
+		a.NotError(m.Add(nil, u1.ID, 10, "+10", expire, ""))
 		ov = &overviewPO{UID: u1.ID}
 		_, err = m.db.Select(ov)
 		a.NotError(err).Equal(ov.Available, 20).
@@ -103,7 +134,7 @@ func TestCurrency(t *testing.T) {
 	})
 
 	t.Run("Del", func(t *testing.T) {
-		m.Del(nil, u1.ID, 5, "-5")
+		m.Del(nil, u1.ID, 5, "-5", "")
 
 		ov := &overviewPO{UID: u1.ID}
 		_, err = m.db.Select(ov)
@@ -115,7 +146,7 @@ func TestCurrency(t *testing.T) {
 		size, err := m.db.Where("uid=?", u1.ID).Count(&LogPO{})
 		a.NotError(err).Equal(size, 3)
 
-		m.Del(nil, u1.ID, 10, "-10")
+		m.Del(nil, u1.ID, 10, "-10", "")
 
 		ov = &overviewPO{UID: u1.ID}
 		_, err = m.db.Select(ov)
@@ -129,9 +160,9 @@ func TestCurrency(t *testing.T) {
 	})
 
 	t.Run("Freeze", func(t *testing.T) {
-		a.Equal(m.Freeze(nil, u1, 50, "freeze"), ErrBalanceNotEnough())
+		a.ErrorIs(m.Freeze(nil, u1, 50, "freeze", ""), ErrBalanceNotEnough())
 
-		a.NotError(m.Freeze(nil, u1, 5, "freeze"))
+		a.NotError(m.Freeze(nil, u1, 5, "freeze", ""))
 
 		ov := &overviewPO{UID: u1.ID}
 		_, err = m.db.Select(ov)
@@ -145,9 +176,9 @@ func TestCurrency(t *testing.T) {
 	})
 
 	t.Run("Unfreeze", func(t *testing.T) {
-		a.Equal(m.Unfreeze(nil, u1, 50, "unfreeze"), ErrBalanceNotEnough())
+		a.ErrorIs(m.Unfreeze(nil, u1, 50, "unfreeze", ""), ErrBalanceNotEnough())
 
-		a.NotError(m.Unfreeze(nil, u1, 5, "unfreeze"))
+		a.NotError(m.Unfreeze(nil, u1, 5, "unfreeze", ""))
 
 		ov := &overviewPO{UID: u1.ID}
 		_, err = m.db.Select(ov)
@@ -160,3 +191,24 @@ func TestCurrency(t *testing.T) {
 		a.NotError(err).Equal(size, 6)
 	})
 }
+
+// 添加用户 username，并等待由 [user.Users.OnAdd] 异步添加的 overviewPO，
+// 防止异步的写入操作与之后的事务产生冲突。
+func newUser(a *assert.Assertion, u *user.Users, m *Currency, username string) *user.User {
+	a.TB().Helper()
+
+	usr, err := u.New(user.StateNormal, username, "123", "", "", "add")
+	a.NotError(err).NotNil(usr)
+
+	for range 100 {
+		n, err := m.db.Where("uid=?", usr.ID).Count(&overviewPO{})
+		a.NotError(err)
+		if n > 0 {
+			return usr
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	a.TB().Fatalf("未添加用户 %s 的 overview", username)
+	return nil
+}
@@ -1,4 +1,4 @@
-// SPDX-FileCopyrightText: 2024 caixw
+// SPDX-FileCopyrightText: 2024-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
@@ -6,7 +6,25 @@ package currency
 
 import "github.com/issue9/web"
 
-var errBalanceNotEnough = web.NewLocaleError("balance not enough")
+var (
+	errBalanceNotEnough = web.NewLocaleError("balance not enough")
+	errTransferToSelf   = web.NewLocaleError("can not transfer to self")
+	errKeyConflict      = web.NewLocaleError("idempotency key has been used by other operation")
+	errRateNotFound     = web.NewLocaleError("exchange rate not found")
+	errExchangeTooSmall = web.NewLocaleError("exchange amount too small")
+)
 
 // ErrBalanceNotEnough 余额不足
 func ErrBalanceNotEnough() error { return errBalanceNotEnough }
+
+// ErrTransferToSelf 转账的双方是同一用户
+func ErrTransferToSelf() error { return errTransferToSelf }
+
+// ErrIdempotencyKeyConflict 幂等键已经被其它的操作使用
+func ErrIdempotencyKeyConflict() error { return errKeyConflict }
+
+// ErrRateNotFound 不存在可用的汇率
+func ErrRateNotFound() error { return errRateNotFound }
+
+// ErrExchangeTooSmall 兑换后的金额不足 1
+func ErrExchangeTooSmall() error { return errExchangeTooSmall }
@@ -0,0 +1,358 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package currency
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/issue9/orm/v6"
+
+	"github.com/issue9/cmfx/cmfx/user"
+)
+
+const (
+	RateScale = 1_000_000 // 汇率的单位，[RatePO.Rate] 为 RateScale 表示 1:1。
+	FeeScale  = 10_000    // 手续费的单位，[RatePO.Fee] 为 100 表示 1%。
+)
+
+// 兑换相关的系统账户
+const (
+	SystemExchange    = "exchange"     // 兑换，源货币扣除的金额进入该账户，目标货币增加的金额来自该账户。
+	SystemExchangeFee = "exchange_fee" // 兑换的手续费，以目标货币计。
+)
+
+// Rounding 兑换时的舍入方式
+type Rounding int8
+
+const (
+	RoundDown   Rounding = iota // 舍去小数部分
+	RoundUp                     // 小数部分进一
+	RoundHalfUp                 // 四舍五入
+)
+
+// Exchange 货币之间的兑换
+//
+// 汇率和兑换记录保存在 [user.Users] 的数据库中，由所有注册的 [Currency] 共享。
+type Exchange struct {
+	user       *user.Users
+	rounding   Rounding
+	currencies map[string]*Currency
+}
+
+// NewExchange 声明 [Exchange]
+//
+// rounding 为兑换金额与手续费的舍入方式；
+// c 为参与兑换的货币，必须都属于 u；
+func NewExchange(u *user.Users, rounding Rounding, c ...*Currency) *Exchange {
+	ex := &Exchange{
+		user:       u,
+		rounding:   rounding,
+		currencies: make(map[string]*Currency, len(c)),
+	}
+
+	for _, item := range c {
+		if item.user != u {
+			panic(fmt.Sprintf("货币 %s 不属于当前用户模块", item.id))
+		}
+		if _, found := ex.currencies[item.id]; found {
+			panic(fmt.Sprintf("货币 %s 已经存在", item.id))
+		}
+		ex.currencies[item.id] = item
+	}
+
+	return ex
+}
+
+func (ex *Exchange) currency(id string) *Currency {
+	c, found := ex.currencies[id]
+	if !found {
+		panic(fmt.Sprintf("货币 %s 不存在", id))
+	}
+	return c
+}
+
+func (ex *Exchange) engine(tx *orm.Tx) orm.Engine { return ex.user.Module().Engine(tx) }
+
+// SetRate 添加 from 到 to 的汇率
+//
+// rate 以 [RateScale] 为单位，即 1 个 from 可兑换 rate/[RateScale] 个 to；
+// fee 以 [FeeScale] 为单位，从兑换后的金额中扣除；
+// effective 为生效时间，之前的兑换依然使用旧的汇率；
+//
+// 汇率是单向的，to 到 from 的兑换需要另外添加。
+func (ex *Exchange) SetRate(from, to string, rate, fee uint, effective time.Time) error {
+	ex.currency(from)
+	ex.currency(to)
+	if from == to {
+		panic("参数 from 和 to 不能相同")
+	}
+	if rate == 0 {
+		panic("参数 rate 必须大于 0")
+	}
+	if fee >= FeeScale {
+		panic(fmt.Sprintf("参数 fee 必须小于 %d", FeeScale))
+	}
+
+	_, err := ex.user.Module().DB().Insert(&RatePO{
+		From:      from,
+		To:        to,
+		Rate:      int64(rate),
+		Fee:       int64(fee),
+		Effective: effective,
+	})
+	return err
+}
+
+// Rate 获取在 at 时刻 from 到 to 的汇率
+//
+// 如果不存在，返回 [ErrRateNotFound]。
+func (ex *Exchange) Rate(from, to string, at time.Time) (*RatePO, error) {
+	return ex.rate(nil, from, to, at)
+}
+
+func (ex *Exchange) rate(tx *orm.Tx, from, to string, at time.Time) (*RatePO, error) {
+	r := &RatePO{}
+	size, err := ex.engine(tx).SQLBuilder().Select().
+		From(orm.TableName(r)).
+		Where("from_currency=?", from).
+		And("to_currency=?", to).
+		And("effective<=?", at).
+		Desc("effective").
+		Desc("id").
+		Limit(1).
+		QueryObject(true, r)
+	if err != nil {
+		return nil, err
+	}
+	if size == 0 {
+		return nil, ErrRateNotFound()
+	}
+	return r, nil
+}
+
+// GetRates 获取 from 到 to 的所有汇率
+//
+// 按生效时间倒序排列。
+func (ex *Exchange) GetRates(from, to string) ([]*RatePO, error) {
+	rates := make([]*RatePO, 0, 10)
+	_, err := ex.user.Module().DB().SQLBuilder().Select().
+		From(orm.TableName(&RatePO{})).
+		Where("from_currency=?", from).
+		And("to_currency=?", to).
+		Desc("effective").
+		Desc("id").
+		QueryObject(true, &rates)
+	return rates, err
+}
+
+// Exchange 将用户 uid 的 val 个 from 兑换成 to
+//
+// 兑换在一个事务中完成，会从 from 中扣除 val，并按当前的汇率向 to 中增加扣除手续费之后的金额，
+// 两个货币中各产生一条 [TypeExchange] 类型的日志，并通过返回的 [ExchangePO] 相互关联。
+//
+// tx 如果为空，会在一个新的事务中执行；
+// key 为幂等键，如果不为空，相同的值只会执行一次，重复调用时返回第一次的兑换记录；
+//
+// 会过期的金额不能用于兑换；
+// 如果兑换后的金额不足 1，返回 [ErrExchangeTooSmall]。
+func (ex *Exchange) Exchange(tx *orm.Tx, uid int64, from, to string, val uint, memo, key string) (*ExchangePO, error) {
+	src := ex.currency(from)
+	dst := ex.currency(to)
+
+	if tx != nil {
+		return ex.exchange(tx, uid, src, dst, val, memo, key)
+	}
+
+	var rslt *ExchangePO
+	err := ex.user.Module().DB().DoTransaction(func(tx *orm.Tx) (err error) {
+		rslt, err = ex.exchange(tx, uid, src, dst, val, memo, key)
+		return err
+	})
+	if err == nil || key == "" {
+		return rslt, err
+	}
+
+	// 可能是并发的相同请求已经先一步写入了幂等键 key，此时事务已经回滚，重新检测 key。
+	if po, err2 := ex.replayed(ex.engine(nil), key, uid, src, dst, int64(val)); err2 != nil {
+		return nil, err2
+	} else if po != nil {
+		return po, nil
+	}
+	return nil, err
+}
+
+func (ex *Exchange) exchange(tx *orm.Tx, uid int64, src, dst *Currency, val uint, memo, key string) (*ExchangePO, error) {
+	e := ex.engine(tx)
+	v := int64(val)
+
+	if po, err := ex.replayed(e, key, uid, src, dst, v); err != nil || po != nil {
+		return po, err
+	}
+
+	rate, err := ex.rate(tx, src.id, dst.id, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	gross := ex.round(v, rate.Rate, RateScale)
+	fee := ex.round(gross, rate.Fee, FeeScale)
+	result := gross - fee
+	if result <= 0 {
+		return nil, ErrExchangeTooSmall()
+	}
+
+	id, err := e.LastInsertID(&ExchangePO{
+		UID:   uid,
+		From:  src.id,
+		To:    dst.id,
+		Value: v,
+		Rate:  rate.Rate,
+		Fee:   fee,
+		Memo:  memo,
+		Key:   idempotencyKey(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fromLog, err := src.exchangeOut(tx, id, uid, v, memo, key)
+	if err != nil {
+		return nil, err
+	}
+
+	toLog, err := dst.exchangeIn(tx, id, uid, gross, fee, memo)
+	if err != nil {
+		return nil, err
+	}
+
+	po := &ExchangePO{ID: id, Result: result, FromLog: fromLog, ToLog: toLog}
+	if _, err := e.Update(po); err != nil {
+		return nil, err
+	}
+
+	po = &ExchangePO{ID: id}
+	if _, err := e.Select(po); err != nil {
+		return nil, err
+	}
+	return po, nil
+}
+
+// 按舍入方式计算 v*num/den
+func (ex *Exchange) round(v, num, den int64) int64 {
+	n := new(big.Int).Mul(big.NewInt(v), big.NewInt(num))
+	q, r := new(big.Int).QuoRem(n, big.NewInt(den), new(big.Int))
+
+	switch {
+	case r.Sign() == 0:
+	case ex.rounding == RoundUp:
+		q.Add(q, big.NewInt(1))
+	case ex.rounding == RoundHalfUp && r.Mul(r, big.NewInt(2)).Cmp(big.NewInt(den)) >= 0:
+		q.Add(q, big.NewInt(1))
+	}
+
+	return q.Int64()
+}
+
+// 兑换时从用户 uid 扣除 val
+//
+// 返回日志的 ID。
+func (m *Currency) exchangeOut(tx *orm.Tx, exchange, uid, val int64, memo, key string) (int64, error) {
+	e := m.engine(tx)
+
+	if replayed, err := m.replayed(e, key, uid, TypeExchange, -val); err != nil {
+		return 0, err
+	} else if replayed { // 未能在 ExchangePO 中找到，却在日志中找到了相同的键。
+		return 0, ErrIdempotencyKeyConflict()
+	}
+
+	ov := &overviewPO{UID: uid}
+	if found, err := e.Select(ov); err != nil {
+		return 0, err
+	} else if !found || (ov.Available-ov.Expire) < val {
+		return 0, ErrBalanceNotEnough()
+	}
+
+	ov2 := &overviewPO{ID: ov.ID, Available: ov.Available - val}
+	if _, err := e.Update(ov2, "available"); err != nil {
+		return 0, err
+	}
+
+	id, err := m.insertLog(tx, &LogPO{
+		UID:      uid,
+		Before:   ov.Available,
+		After:    ov2.Available,
+		Value:    -val,
+		Memo:     memo,
+		Type:     TypeExchange,
+		Key:      idempotencyKey(key),
+		Exchange: exchange,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return id, m.post(e, TypeExchange, memo, entry{userAccount(uid), -val}, entry{systemAccount(SystemExchange), val})
+}
+
+// 兑换时向用户 uid 增加 gross-fee
+//
+// 返回日志的 ID。
+func (m *Currency) exchangeIn(tx *orm.Tx, exchange, uid, gross, fee int64, memo string) (int64, error) {
+	e := m.engine(tx)
+	val := gross - fee
+
+	ov := &overviewPO{UID: uid}
+	if found, err := e.Select(ov); err != nil {
+		return 0, err
+	} else if !found {
+		ov = m.initOverview(tx, uid)
+	}
+
+	ov2 := &overviewPO{ID: ov.ID, Available: ov.Available + val}
+	if _, err := e.Update(ov2, "available"); err != nil {
+		return 0, err
+	}
+
+	id, err := m.insertLog(tx, &LogPO{
+		UID:      uid,
+		Before:   ov.Available,
+		After:    ov2.Available,
+		Value:    val,
+		Memo:     memo,
+		Type:     TypeExchange,
+		Exchange: exchange,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return id, m.post(e, TypeExchange, memo,
+		entry{systemAccount(SystemExchange), -gross},
+		entry{userAccount(uid), val},
+		entry{systemAccount(SystemExchangeFee), fee},
+	)
+}
+
+// 检测幂等键 key 是否已经被使用
+//
+// 如果已经被相同的兑换使用，返回该兑换记录；如果被其它操作使用，返回 [ErrIdempotencyKeyConflict]。
+func (ex *Exchange) replayed(e orm.Engine, key string, uid int64, src, dst *Currency, v int64) (*ExchangePO, error) {
+	if key == "" {
+		return nil, nil
+	}
+
+	po := &ExchangePO{}
+	size, err := e.Where("idempotency_key=?", key).Select(true, po)
+	if err != nil || size == 0 {
+		return nil, err
+	}
+
+	if po.UID != uid || po.From != src.id || po.To != dst.id || po.Value != v {
+		return nil, ErrIdempotencyKeyConflict()
+	}
+	return po, nil
+}
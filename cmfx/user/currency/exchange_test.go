@@ -0,0 +1,200 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package currency
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/orm/v6"
+
+	"github.com/issue9/cmfx/cmfx/initial/test"
+	"github.com/issue9/cmfx/cmfx/user/usertest"
+)
+
+func TestExchange_round(t *testing.T) {
+	a := assert.New(t, false)
+
+	ex := &Exchange{rounding: RoundDown}
+	a.Equal(ex.round(15, 1000, FeeScale), 1).
+		Equal(ex.round(19, 1000, FeeScale), 1).
+		Equal(ex.round(20, 1000, FeeScale), 2)
+
+	ex.rounding = RoundUp
+	a.Equal(ex.round(15, 1000, FeeScale), 2).
+		Equal(ex.round(11, 1000, FeeScale), 2).
+		Equal(ex.round(20, 1000, FeeScale), 2)
+
+	ex.rounding = RoundHalfUp
+	a.Equal(ex.round(15, 1000, FeeScale), 2).
+		Equal(ex.round(14, 1000, FeeScale), 1).
+		Equal(ex.round(20, 1000, FeeScale), 2)
+
+	// 不会溢出
+	a.Equal(ex.round(1<<60, RateScale, RateScale), 1<<60)
+}
+
+func TestExchange(t *testing.T) {
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+	defer s.Close()
+
+	u := usertest.NewModule(s)
+	point := Install(u, "point")
+	coin := Install(u, "coin")
+	ex := InstallExchange(u, RoundDown, point, coin)
+	s.TableExists(u.Module().ID() + "_currency_rates").
+		TableExists(u.Module().ID() + "_currency_exchanges")
+
+	a.PanicString(func() {
+		NewExchange(u, RoundDown, point, point)
+	}, "货币 point 已经存在")
+	a.PanicString(func() {
+		ex.SetRate("point", "not-exists", RateScale, 0, time.Now())
+	}, "货币 not-exists 不存在")
+
+	u1, err := u.GetUserByUsername("u1")
+	a.NotError(err).NotNil(u1)
+
+	now := time.Now()
+	a.NotError(ex.SetRate("point", "coin", RateScale/2, 1000, now.Add(-time.Hour))).
+		NotError(ex.SetRate("point", "coin", RateScale, 0, now.Add(time.Hour))) // 未生效
+
+	r, err := ex.Rate("point", "coin", now)
+	a.NotError(err).Equal(r.Rate, RateScale/2).Equal(r.Fee, 1000)
+	r, err = ex.Rate("coin", "point", now)
+	a.ErrorIs(err, ErrRateNotFound()).Nil(r)
+
+	rates, err := ex.GetRates("point", "coin")
+	a.NotError(err).Length(rates, 2).Equal(rates[0].Rate, RateScale)
+
+	_, err = ex.Exchange(nil, u1.ID, "point", "coin", 30, "exchange", "")
+	a.ErrorIs(err, ErrBalanceNotEnough())
+
+	a.NotError(point.Add(nil, u1.ID, 100, "+100", time.Time{}, ""))
+	a.NotError(point.Add(nil, u1.ID, 100, "+100", now.Add(time.Hour), "")) // 会过期的不能兑换
+
+	po, err := ex.Exchange(nil, u1.ID, "point", "coin", 30, "exchange", "k1")
+	a.NotError(err).NotNil(po).
+		Equal(po.Value, 30).
+		Equal(po.Rate, RateScale/2).
+		Equal(po.Fee, 1).     // 15 * 10% 舍去
+		Equal(po.Result, 14). // 15 - 1
+		NotZero(po.FromLog).
+		NotZero(po.ToLog)
+
+	ov, err := point.GetOverview(u1.ID, time.Time{})
+	a.NotError(err).Equal(ov.Available, 170)
+	ov, err = coin.GetOverview(u1.ID, time.Time{})
+	a.NotError(err).Equal(ov.Available, 14)
+
+	// 日志相互关联
+
+	fromLog := &LogPO{ID: po.FromLog}
+	found, err := point.db.Select(fromLog)
+	a.NotError(err).True(found).
+		Equal(fromLog.Exchange, po.ID).
+		Equal(fromLog.Type, TypeExchange).
+		Equal(fromLog.Value, -30)
+
+	toLog := &LogPO{ID: po.ToLog}
+	found, err = coin.db.Select(toLog)
+	a.NotError(err).True(found).
+		Equal(toLog.Exchange, po.ID).
+		Equal(toLog.Type, TypeExchange).
+		Equal(toLog.Value, 14)
+
+	// 幂等
+
+	po2, err := ex.Exchange(nil, u1.ID, "point", "coin", 30, "exchange", "k1")
+	a.NotError(err).Equal(po2.ID, po.ID)
+	_, err = ex.Exchange(nil, u1.ID, "point", "coin", 31, "exchange", "k1")
+	a.ErrorIs(err, ErrIdempotencyKeyConflict())
+	ov, err = point.GetOverview(u1.ID, time.Time{})
+	a.NotError(err).Equal(ov.Available, 170)
+
+	// 超出不过期的部分
+	_, err = ex.Exchange(nil, u1.ID, "point", "coin", 71, "exchange", "")
+	a.ErrorIs(err, ErrBalanceNotEnough())
+
+	_, err = ex.Exchange(nil, u1.ID, "point", "coin", 1, "exchange", "")
+	a.ErrorIs(err, ErrExchangeTooSmall())
+
+	// 账目
+
+	b, err := point.SystemBalance(SystemExchange)
+	a.NotError(err).Equal(b, 30)
+	b, err = coin.SystemBalance(SystemExchange)
+	a.NotError(err).Equal(b, -15)
+	b, err = coin.SystemBalance(SystemExchangeFee)
+	a.NotError(err).Equal(b, 1)
+
+	mismatches, err := point.Reconcile()
+	a.NotError(err).Empty(mismatches)
+	mismatches, err = coin.Reconcile()
+	a.NotError(err).Empty(mismatches)
+}
+
+func TestExchange_concurrent(t *testing.T) {
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+	defer s.Close()
+
+	u := usertest.NewModule(s)
+	point := Install(u, "point")
+	coin := Install(u, "coin")
+	ex := InstallExchange(u, RoundDown, point, coin)
+
+	u1, err := u.GetUserByUsername("u1")
+	a.NotError(err).NotNil(u1)
+
+	a.NotError(ex.SetRate("point", "coin", RateScale, 0, time.Now().Add(-time.Hour))).
+		NotError(point.Add(nil, u1.ID, 100, "+100", time.Time{}, ""))
+
+	const size = 10
+	type result struct {
+		po  *ExchangePO
+		err error
+	}
+	rslts := make(chan result, size)
+	wg := &sync.WaitGroup{}
+	for range size {
+		wg.Go(func() {
+			po, err := ex.Exchange(nil, u1.ID, "point", "coin", 30, "exchange", "k1")
+			rslts <- result{po: po, err: err}
+		})
+	}
+	wg.Wait()
+	close(rslts)
+
+	// 并发的事务可能因为数据库锁而失败，但至少有一个成功，且只会兑换一次。
+	var id int64
+	for r := range rslts {
+		if r.err == nil {
+			a.NotNil(r.po)
+			if id == 0 {
+				id = r.po.ID
+			}
+			a.Equal(r.po.ID, id)
+		}
+	}
+	a.NotZero(id)
+
+	ov, err := point.GetOverview(u1.ID, time.Time{})
+	a.NotError(err).Equal(ov.Available, 70)
+	ov, err = coin.GetOverview(u1.ID, time.Time{})
+	a.NotError(err).Equal(ov.Available, 30)
+
+	// 幂等键的唯一约束保证了并发请求中只有一个能写入
+	err = u.Module().DB().DoTransaction(func(tx *orm.Tx) error {
+		_, err := ex.engine(tx).Insert(&ExchangePO{UID: u1.ID, From: "point", To: "coin", Value: 30, Key: idempotencyKey("k1")})
+		return err
+	})
+	a.Error(err)
+	po, err := ex.Exchange(nil, u1.ID, "point", "coin", 30, "exchange", "k1")
+	a.NotError(err).Equal(po.ID, id)
+}
@@ -0,0 +1,201 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package currency
+
+import (
+	"context"
+	"time"
+
+	"github.com/issue9/orm/v6"
+	"github.com/issue9/web"
+)
+
+// 发布至 [outbox.Outbox] 的事件名称
+const (
+	TopicExpiring = "expiring" // 即将过期
+	TopicExpired  = "expired"  // 已经过期并扣除
+	TopicChanged  = "changed"  // 余额发生变化，每一条日志都会产生一个事件。
+)
+
+// ExpireEvent 与过期相关的事件
+type ExpireEvent struct {
+	UID     int64     `json:"uid"`
+	Value   int64     `json:"value"` // 即将过期或是已经扣除的金额
+	Expired time.Time `json:"expired"`
+}
+
+// ChangeEvent 余额变化的事件
+//
+// 与 [LogPO] 一一对应，仅发布至 [outbox.Outbox]。
+type ChangeEvent struct {
+	UID    int64  `json:"uid"`
+	Type   Type   `json:"type"`
+	Before int64  `json:"before"`
+	After  int64  `json:"after"`
+	Value  int64  `json:"value"`
+	Memo   string `json:"memo,omitempty"`
+}
+
+// ID 货币的 ID
+func (m *Currency) ID() string { return m.id }
+
+// Topic 将事件名称 name 转换成当前货币在 [outbox.Outbox] 中的主题
+//
+// name 可以是 [TopicExpiring]、[TopicExpired] 或 [TopicChanged]。
+func (m *Currency) Topic(name string) string {
+	return m.user.Module().ID() + "_currency_" + m.id + "_" + name
+}
+
+// OnExpiring 即将过期的事件
+//
+// 只有通过 [Currency.AddExpireService] 指定了 notice 参数才会触发，
+// 事件在 [Currency.Expire] 中同步执行，订阅者不应该执行耗时的操作。
+func (m *Currency) OnExpiring(f func(*ExpireEvent)) context.CancelFunc {
+	return m.expiringEvent.Subscribe(f)
+}
+
+// OnExpired 过期金额被扣除之后的事件
+//
+// 事件在 [Currency.Expire] 中同步执行，订阅者不应该执行耗时的操作。
+func (m *Currency) OnExpired(f func(*ExpireEvent)) context.CancelFunc {
+	return m.expiredEvent.Subscribe(f)
+}
+
+func (m *Currency) publish(tx *orm.Tx, name string, e any) error {
+	if o := m.user.Outbox(); o != nil {
+		return o.Publish(tx, m.Topic(name), e)
+	}
+	return nil
+}
+
+// AddExpireService 添加定时处理过期金额的服务
+//
+// spec 为 cron 格式的执行时间；
+// notice 表示在过期之前多久触发 [Currency.OnExpiring] 事件，为 0 表示不触发；
+func (m *Currency) AddExpireService(spec string, notice time.Duration) context.CancelFunc {
+	return m.user.Module().Server().Services().AddCron(web.Phrase("expire currency %s", m.id), func(now time.Time) error {
+		return m.Expire(now, notice)
+	}, spec, true)
+}
+
+// Expire 扣除在 now 之前已经过期的金额
+//
+// 同时会对在 now+notice 之前即将过期且未通知过的金额触发 [Currency.OnExpiring] 事件。
+// 一般由 [Currency.AddExpireService] 定时调用。
+func (m *Currency) Expire(now time.Time, notice time.Duration) error {
+	if notice > 0 {
+		if err := m.notice(now, notice); err != nil {
+			return err
+		}
+	}
+
+	expires := make([]*expirePO, 0, 50)
+	if _, err := m.db.Where("value>0").And("expired<=?", now).Select(true, &expires); err != nil {
+		return err
+	}
+
+	for _, exp := range expires {
+		var event *ExpireEvent
+		err := m.db.DoTransaction(func(tx *orm.Tx) (err error) {
+			event, err = m.expire(tx, exp.ID)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		if event != nil {
+			m.expiredEvent.Publish(false, event)
+		}
+	}
+
+	return nil
+}
+
+// 扣除 ID 为 id 的过期记录中的金额
+//
+// 记录会在事务中重新读取，并以读取到的金额作为删除条件，
+// 如果该记录已经被其它进程处理或是金额已经发生变化，则不作任何处理。
+// 如果返回的事件为空，表示未扣除任何金额。
+func (m *Currency) expire(tx *orm.Tx, id int64) (*ExpireEvent, error) {
+	e := m.engine(tx)
+
+	exp := &expirePO{ID: id}
+	if found, err := e.Select(exp); err != nil || !found || exp.Value <= 0 {
+		return nil, err
+	}
+
+	rslt, err := e.Where("id=?", exp.ID).And("value=?", exp.Value).Delete(&expirePO{})
+	if err != nil {
+		return nil, err
+	}
+	if n, err := rslt.RowsAffected(); err != nil || n == 0 {
+		return nil, err
+	}
+
+	overview := &overviewPO{UID: exp.UID}
+	if found, err := e.Select(overview); err != nil || !found {
+		return nil, err
+	}
+
+	// 部分金额可能已经被冻结，最多只能扣除可用的金额。
+	v := min(exp.Value, overview.Available)
+	o2 := &overviewPO{
+		ID:        overview.ID,
+		Available: overview.Available - v,
+		Expire:    max(overview.Expire-exp.Value, 0),
+	}
+	if _, err := e.Update(o2, "available", "expire"); err != nil {
+		return nil, err
+	}
+
+	log := &LogPO{
+		UID:    overview.UID,
+		Before: overview.Available,
+		After:  o2.Available,
+		Value:  -v,
+		Type:   TypeExpired,
+	}
+	if _, err := m.insertLog(tx, log); err != nil {
+		return nil, err
+	}
+
+	if err := m.post(e, TypeExpired, "", entry{userAccount(exp.UID), -v}, entry{systemAccount(SystemExpired), v}); err != nil {
+		return nil, err
+	}
+
+	event := &ExpireEvent{UID: exp.UID, Value: v, Expired: exp.Expired}
+	return event, m.publish(tx, TopicExpired, event)
+}
+
+// 对即将过期的金额发送通知
+func (m *Currency) notice(now time.Time, notice time.Duration) error {
+	expires := make([]*expirePO, 0, 50)
+	_, err := m.db.Where("value>0").
+		And("noticed=?", false).
+		And("expired>?", now).
+		And("expired<=?", now.Add(notice)).
+		Select(true, &expires)
+	if err != nil {
+		return err
+	}
+
+	for _, exp := range expires {
+		event := &ExpireEvent{UID: exp.UID, Value: exp.Value, Expired: exp.Expired}
+		err := m.db.DoTransaction(func(tx *orm.Tx) error {
+			if _, err := m.engine(tx).Update(&expirePO{ID: exp.ID, Noticed: true}); err != nil {
+				return err
+			}
+			return m.publish(tx, TopicExpiring, event)
+		})
+		if err != nil {
+			return err
+		}
+
+		m.expiringEvent.Publish(false, event)
+	}
+
+	return nil
+}
@@ -0,0 +1,133 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package currency
+
+import (
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/orm/v6"
+
+	"github.com/issue9/cmfx/cmfx/initial/test"
+	"github.com/issue9/cmfx/cmfx/outbox"
+	"github.com/issue9/cmfx/cmfx/user"
+	"github.com/issue9/cmfx/cmfx/user/usertest"
+)
+
+func TestCurrency_Expire(t *testing.T) {
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+	defer s.Close()
+
+	u := usertest.NewModule(s)
+	m := Install(u, "point")
+
+	u1, err := u.GetUserByUsername("u1")
+	a.NotError(err).NotNil(u1)
+
+	var expiring, expired []*ExpireEvent
+	m.OnExpiring(func(e *ExpireEvent) { expiring = append(expiring, e) })
+	m.OnExpired(func(e *ExpireEvent) { expired = append(expired, e) })
+
+	now := time.Now()
+	e1 := now.Add(time.Hour).Truncate(time.Second)
+	e2 := now.Add(48 * time.Hour).Truncate(time.Second)
+	a.NotError(m.Add(nil, u1.ID, 10, "+10", time.Time{}, "")).
+		NotError(m.Add(nil, u1.ID, 5, "+5", e1, "")).
+		NotError(m.Add(nil, u1.ID, 5, "+5", e1, "")). // 合并至同一条记录
+		NotError(m.Add(nil, u1.ID, 20, "+20", e2, ""))
+
+	ov := &overviewPO{UID: u1.ID}
+	_, err = m.db.Select(ov)
+	a.NotError(err).Equal(ov.Available, 40).Equal(ov.Expire, 30)
+	cnt, err := m.db.Where("uid=?", u1.ID).Count(&expirePO{})
+	a.NotError(err).Equal(cnt, 2)
+
+	// 通知 e1
+	a.NotError(m.Expire(now, 2*time.Hour))
+	a.Length(expiring, 1).Equal(expiring[0].Value, 10).Empty(expired)
+
+	// 已经通知过
+	a.NotError(m.Expire(now, 2*time.Hour))
+	a.Length(expiring, 1).Empty(expired)
+
+	// e1 过期，通知 e2
+	a.NotError(m.Expire(e1.Add(time.Second), 48*time.Hour))
+	a.Length(expiring, 2).Equal(expiring[1].Value, 20).
+		Length(expired, 1).Equal(expired[0].Value, 10).Equal(expired[0].UID, u1.ID)
+
+	ov = &overviewPO{UID: u1.ID}
+	_, err = m.db.Select(ov)
+	a.NotError(err).Equal(ov.Available, 30).Equal(ov.Expire, 20)
+
+	log := &LogPO{}
+	_, err = m.db.Where("uid=?", u1.ID).And("type=?", TypeExpired).Select(true, log)
+	a.NotError(err).Equal(log.Value, -10).Equal(log.Before, 40).Equal(log.After, 30)
+
+	// 部分冻结之后过期，只能扣除可用部分。
+	a.NotError(m.Freeze(nil, u1, 10, "freeze", "")) // 不会过期的 10 被冻结
+	a.NotError(m.Del(nil, u1.ID, 15, "-15", ""))
+	a.NotError(m.Expire(e2.Add(time.Second), 0))
+	a.Length(expired, 2).Equal(expired[1].Value, 5)
+
+	ov = &overviewPO{UID: u1.ID}
+	_, err = m.db.Select(ov)
+	a.NotError(err).Zero(ov.Available).Zero(ov.Expire).Equal(ov.Freeze, 10)
+
+	// 同一条记录被多次处理，只扣除一次。
+	e3 := now.Add(72 * time.Hour).Truncate(time.Second)
+	a.NotError(m.Add(nil, u1.ID, 8, "+8", e3, ""))
+	exp := &expirePO{}
+	size, err := m.db.Where("uid=?", u1.ID).And("expired=?", e3).Select(true, exp)
+	a.NotError(err).Equal(size, 1)
+	for range 2 {
+		a.NotError(m.db.DoTransaction(func(tx *orm.Tx) error {
+			_, err := m.expire(tx, exp.ID)
+			return err
+		}))
+	}
+	ov = &overviewPO{UID: u1.ID}
+	_, err = m.db.Select(ov)
+	a.NotError(err).Zero(ov.Available).Zero(ov.Expire)
+	cnt, err = m.db.Where("uid=?", u1.ID).And("type=?", TypeExpired).Count(&LogPO{})
+	a.NotError(err).Equal(cnt, 3)
+}
+
+func TestCurrency_Expire_outbox(t *testing.T) {
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+	defer s.Close()
+
+	obMod := s.NewModule("outbox")
+	outbox.Install(obMod)
+	ob := outbox.New(obMod, time.Minute, 3)
+
+	u := usertest.NewModule(s)
+	m := Install(u, "point")
+
+	var expired []int64
+	outbox.Subscribe(ob, m.Topic(TopicExpired), "test", func(e *ExpireEvent) error {
+		expired = append(expired, e.Value)
+		return nil
+	})
+
+	var changed []int64
+	outbox.Subscribe(ob, m.Topic(TopicChanged), "test", func(e *ChangeEvent) error {
+		changed = append(changed, e.Value)
+		return nil
+	})
+
+	id, err := u.New(user.StateNormal, "u2", "123", "", "", "add")
+	a.NotError(err)
+	a.NotError(ob.Dispatch(time.Now()))
+
+	expire := time.Now().Add(time.Hour)
+	a.NotError(m.Add(nil, id.ID, 10, "+10", expire, ""))
+	a.NotError(m.Expire(expire.Add(time.Second), 0))
+	a.NotError(ob.Dispatch(time.Now()))
+	a.Equal(expired, []int64{10}).
+		Equal(changed, []int64{10, -10})
+}
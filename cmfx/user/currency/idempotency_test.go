@@ -0,0 +1,132 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package currency
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/orm/v6"
+
+	"github.com/issue9/cmfx/cmfx/initial/test"
+	"github.com/issue9/cmfx/cmfx/user/usertest"
+)
+
+func TestCurrency_idempotency(t *testing.T) {
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+	defer s.Close()
+
+	u := usertest.NewModule(s)
+	m := Install(u, "point")
+
+	u1, err := u.GetUserByUsername("u1")
+	a.NotError(err).NotNil(u1)
+	u2 := newUser(a, u, m, "u2")
+
+	available := func(uid int64) int64 {
+		ov, err := m.GetOverview(uid, time.Time{})
+		a.NotError(err)
+		return ov.Available
+	}
+
+	a.NotError(m.Add(nil, u1.ID, 10, "+10", time.Time{}, "k1")).
+		NotError(m.Add(nil, u1.ID, 10, "+10", time.Time{}, "k1"))
+	a.Equal(available(u1.ID), 10)
+
+	// 相同的键用于不同的操作
+	a.Equal(m.Add(nil, u1.ID, 20, "+20", time.Time{}, "k1"), ErrIdempotencyKeyConflict()).
+		Equal(m.Add(nil, u2.ID, 10, "+10", time.Time{}, "k1"), ErrIdempotencyKeyConflict()).
+		Equal(m.Del(nil, u1.ID, 10, "-10", "k1"), ErrIdempotencyKeyConflict())
+
+	a.NotError(m.Del(nil, u1.ID, 2, "-2", "k2")).
+		NotError(m.Del(nil, u1.ID, 2, "-2", "k2"))
+	a.Equal(available(u1.ID), 8)
+
+	a.NotError(m.Freeze(nil, u1, 2, "freeze", "k3")).
+		NotError(m.Freeze(nil, u1, 2, "freeze", "k3"))
+	a.Equal(available(u1.ID), 6)
+
+	a.NotError(m.Unfreeze(nil, u1, 1, "unfreeze", "k4")).
+		NotError(m.Unfreeze(nil, u1, 1, "unfreeze", "k4"))
+	a.Equal(available(u1.ID), 7)
+
+	a.NotError(m.Transfer(nil, u1.ID, u2.ID, 3, 1, "fees", "transfer", "k5")).
+		NotError(m.Transfer(nil, u1.ID, u2.ID, 3, 1, "fees", "transfer", "k5"))
+	a.Equal(available(u1.ID), 3).Equal(available(u2.ID), 3)
+
+	// 空值不作幂等处理
+	a.NotError(m.Add(nil, u2.ID, 1, "+1", time.Time{}, "")).
+		NotError(m.Add(nil, u2.ID, 1, "+1", time.Time{}, ""))
+	a.Equal(available(u2.ID), 5)
+
+	mismatches, err := m.Reconcile()
+	a.NotError(err).Empty(mismatches)
+}
+
+func TestCurrency_idempotency_concurrent(t *testing.T) {
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+	defer s.Close()
+
+	u := usertest.NewModule(s)
+	m := Install(u, "point")
+
+	u1, err := u.GetUserByUsername("u1")
+	a.NotError(err).NotNil(u1)
+
+	_, err = m.GetOverview(u1.ID, time.Time{}) // 初始化 overview
+	a.NotError(err)
+
+	const size = 10
+	errs := make(chan error, size)
+	wg := &sync.WaitGroup{}
+	for range size {
+		wg.Go(func() {
+			errs <- m.Add(nil, u1.ID, 10, "+10", time.Time{}, "k1")
+		})
+	}
+	wg.Wait()
+	close(errs)
+
+	// 并发的事务可能因为数据库锁而失败，但至少有一个成功，且金额只会被添加一次。
+	succeeded := 0
+	for err := range errs {
+		if err == nil {
+			succeeded++
+		}
+	}
+	a.True(succeeded > 0)
+
+	ov, err := m.GetOverview(u1.ID, time.Time{})
+	a.NotError(err).Equal(ov.Available, 10)
+
+	// 模拟在检测幂等键之后，其它请求才写入相同幂等键的情况：
+	// 唯一约束导致事务回滚，并返回已执行的结果。
+	err = m.idempotent(nil, "k1", u1.ID, TypeNormal, 10, func(tx *orm.Tx) error {
+		e := m.engine(tx)
+		if _, err := e.Update(&overviewPO{ID: 1, Available: 20}, "available"); err != nil {
+			return err
+		}
+		_, err := e.Insert(&LogPO{UID: u1.ID, Before: 10, After: 20, Value: 10, Key: idempotencyKey("k1")})
+		return err
+	})
+	a.NotError(err)
+
+	ov, err = m.GetOverview(u1.ID, time.Time{})
+	a.NotError(err).Equal(ov.Available, 10)
+
+	// 不同的操作
+	err = m.idempotent(nil, "k1", u1.ID, TypeNormal, 20, func(tx *orm.Tx) error {
+		_, err := m.engine(tx).Insert(&LogPO{UID: u1.ID, Value: 20, Key: idempotencyKey("k1")})
+		return err
+	})
+	a.Equal(err, ErrIdempotencyKeyConflict())
+
+	mismatches, err := m.Reconcile()
+	a.NotError(err).Empty(mismatches)
+}
@@ -1,4 +1,4 @@
-// SPDX-FileCopyrightText: 2024-2025 caixw
+// SPDX-FileCopyrightText: 2024-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
@@ -11,12 +11,24 @@ import (
 )
 
 // Install 安装当前的环境
+//
+// 由旧版本安装的数据表可以通过 [Migrations] 升级至当前的结构。
 func Install(mod *user.Users, id string) *Currency {
 	db := buildDB(mod.Module().DB(), id)
 
-	if err := db.Create(&overviewPO{}, &expirePO{}, &LogPO{}); err != nil {
+	if err := db.Create(&overviewPO{}, &expirePO{}, &LogPO{}, &transactionPO{}, &entryPO{}, &StatementPO{}); err != nil {
 		panic(web.SprintError(mod.Module().Server().Locale().Printer(), true, err))
 	}
 
 	return New(mod, id)
 }
+
+// InstallExchange 安装 [Exchange] 所需的环境
+//
+// 由旧版本安装的模块可以通过 [ExchangeMigration] 添加相关的数据表。
+func InstallExchange(u *user.Users, rounding Rounding, c ...*Currency) *Exchange {
+	if err := u.Module().DB().Create(&RatePO{}, &ExchangePO{}); err != nil {
+		panic(web.SprintError(u.Module().Server().Locale().Printer(), true, err))
+	}
+	return NewExchange(u, rounding, c...)
+}
@@ -1,4 +1,4 @@
-// SPDX-FileCopyrightText: 2024 caixw
+// SPDX-FileCopyrightText: 2024-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
@@ -23,5 +23,7 @@ func TestInstall(t *testing.T) {
 
 	suite.TableExists(u.Module().ID() + "_point_overviews").
 		TableExists(u.Module().ID() + "_point_expires").
-		TableExists(u.Module().ID() + "_point_logs")
+		TableExists(u.Module().ID() + "_point_logs").
+		TableExists(u.Module().ID() + "_point_transactions").
+		TableExists(u.Module().ID() + "_point_entries")
 }
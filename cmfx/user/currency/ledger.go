@@ -0,0 +1,182 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package currency
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/issue9/orm/v6"
+)
+
+// 系统账户
+//
+// 所有的交易都以复式记账的方式记录，用户余额的变化必然对应系统账户或其它用户的反向变化。
+const (
+	SystemIssued  = "issued"  // 发行，通过 [Currency.Add] 增加的金额都来自该账户。
+	SystemUsed    = "used"    // 消费，通过 [Currency.Del] 减少的金额都进入该账户。
+	SystemExpired = "expired" // 过期，过期扣除的金额都进入该账户。
+	SystemOpening = "opening" // 期初，启用复式记账之前已有的余额都来自该账户。
+)
+
+func userAccount(uid int64) string     { return "user:" + strconv.FormatInt(uid, 10) }
+func frozenAccount(uid int64) string   { return "frozen:" + strconv.FormatInt(uid, 10) }
+func systemAccount(name string) string { return "system:" + name }
+
+// 交易中的一条分录
+type entry struct {
+	account string
+	amount  int64
+}
+
+// 记录一笔交易
+//
+// entries 的金额之和必须为零，否则 panic。
+func (m *Currency) post(e orm.Engine, typ Type, memo string, entries ...entry) error {
+	var sum int64
+	for _, item := range entries {
+		sum += item.amount
+	}
+	if sum != 0 {
+		panic(fmt.Sprintf("交易的借贷不平衡：%d", sum))
+	}
+
+	id, err := e.LastInsertID(&transactionPO{Type: typ, Memo: memo})
+	if err != nil {
+		return err
+	}
+
+	for _, item := range entries {
+		if item.amount == 0 {
+			continue
+		}
+		if _, err := e.Insert(&entryPO{Transaction: id, Account: item.account, Amount: item.amount}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 为已有的余额记录期初交易
+//
+// 在启用复式记账之前产生的余额没有对应的分录，每个用户会产生一笔交易，
+// 由 [SystemOpening] 转入其可用和冻结的金额。
+func (m *Currency) opening(tx *orm.Tx) error {
+	e := m.engine(tx)
+
+	overviews := make([]*overviewPO, 0, 100)
+	if _, err := e.Where("available<>0").Or("freeze<>0").Select(true, &overviews); err != nil {
+		return err
+	}
+
+	for _, o := range overviews {
+		err := m.post(e, TypeNormal, "",
+			entry{systemAccount(SystemOpening), -(o.Available + o.Freeze)},
+			entry{userAccount(o.UID), o.Available},
+			entry{frozenAccount(o.UID), o.Freeze},
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SystemBalance 系统账户 name 的余额
+//
+// 系统账户的余额与所有用户账户余额的总和为零。
+func (m *Currency) SystemBalance(name string) (int64, error) {
+	return m.balance(systemAccount(name))
+}
+
+func (m *Currency) balance(account string) (int64, error) {
+	return m.db.SQLBuilder().Select().
+		Column("COALESCE(SUM(amount),0) AS total").
+		From(orm.TableName(&entryPO{})).
+		Where("account=?", account).
+		QueryInt("total")
+}
+
+// Mismatch 对账时发现的不一致
+type Mismatch struct {
+	Transaction int64  `json:"transaction,omitempty" yaml:"transaction,omitempty" cbor:"transaction,omitempty"` // 借贷不平衡的交易，为零表示不是交易的问题。
+	UID         int64  `json:"uid,omitempty" yaml:"uid,omitempty" cbor:"uid,omitempty"`
+	Account     string `json:"account,omitempty" yaml:"account,omitempty" cbor:"account,omitempty"`
+	Expected    int64  `json:"expected" yaml:"expected" cbor:"expected"` // 总览表中的值，如果是交易的问题则为零。
+	Actual      int64  `json:"actual" yaml:"actual" cbor:"actual"`       // 分录的合计
+}
+
+type txnTotal struct {
+	Transaction int64 `orm:"name(txn)"`
+	Total       int64 `orm:"name(total)"`
+}
+
+type accountTotal struct {
+	Account string `orm:"name(account)"`
+	Total   int64  `orm:"name(total)"`
+}
+
+// Reconcile 对账
+//
+// 检测每一笔交易是否借贷平衡，以及每个用户的可用和冻结金额是否与分录的合计相同，
+// 返回所有不一致的记录，交易的记录在前，用户的记录按 uid 排序，如果都一致，返回空值。
+func (m *Currency) Reconcile() ([]*Mismatch, error) {
+	mismatches := make([]*Mismatch, 0, 10)
+
+	txns := make([]*txnTotal, 0, 100)
+	_, err := m.db.SQLBuilder().Select().
+		Column("txn").
+		Column("SUM(amount) AS total").
+		From(orm.TableName(&entryPO{})).
+		Group("txn").
+		QueryObject(true, &txns)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range txns {
+		if t.Total == 0 {
+			continue
+		}
+		mismatches = append(mismatches, &Mismatch{Transaction: t.Transaction, Actual: t.Total})
+	}
+
+	accounts := make([]*accountTotal, 0, 100)
+	_, err = m.db.SQLBuilder().Select().
+		Column("account").
+		Column("SUM(amount) AS total").
+		From(orm.TableName(&entryPO{})).
+		Group("account").
+		QueryObject(true, &accounts)
+	if err != nil {
+		return nil, err
+	}
+	totals := make(map[string]int64, len(accounts))
+	for _, a := range accounts {
+		totals[a.Account] = a.Total
+	}
+
+	overviews := make([]*overviewPO, 0, 100)
+	_, err = m.db.SQLBuilder().Select().
+		Column("*").
+		From(orm.TableName(&overviewPO{})).
+		Asc("uid").
+		QueryObject(true, &overviews)
+	if err != nil {
+		return nil, err
+	}
+	for _, ov := range overviews {
+		if acc := userAccount(ov.UID); totals[acc] != ov.Available {
+			mismatches = append(mismatches, &Mismatch{UID: ov.UID, Account: acc, Expected: ov.Available, Actual: totals[acc]})
+		}
+		if acc := frozenAccount(ov.UID); totals[acc] != ov.Freeze {
+			mismatches = append(mismatches, &Mismatch{UID: ov.UID, Account: acc, Expected: ov.Freeze, Actual: totals[acc]})
+		}
+	}
+
+	if len(mismatches) == 0 {
+		return nil, nil
+	}
+	return mismatches, nil
+}
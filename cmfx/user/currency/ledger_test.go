@@ -0,0 +1,103 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package currency
+
+import (
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+
+	"github.com/issue9/cmfx/cmfx"
+	"github.com/issue9/cmfx/cmfx/initial/test"
+	"github.com/issue9/cmfx/cmfx/user"
+	"github.com/issue9/cmfx/cmfx/user/usertest"
+)
+
+func TestCurrency_post(t *testing.T) {
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+	defer s.Close()
+
+	m := Install(usertest.NewModule(s), "point")
+
+	a.PanicString(func() {
+		m.post(m.db, TypeNormal, "", entry{userAccount(1), 5}, entry{systemAccount(SystemIssued), -4})
+	}, "交易的借贷不平衡：1")
+
+	a.NotError(m.post(m.db, TypeNormal, "", entry{userAccount(1), 5}, entry{systemAccount(SystemIssued), -5}))
+	b, err := m.SystemBalance(SystemIssued)
+	a.NotError(err).Equal(b, -5)
+
+	b, err = m.SystemBalance("not-exists")
+	a.NotError(err).Zero(b)
+}
+
+func TestCurrency_Transfer(t *testing.T) {
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+	defer s.Close()
+
+	u := usertest.NewModule(s)
+	m := Install(u, "point")
+
+	u1, err := u.GetUserByUsername("u1")
+	a.NotError(err).NotNil(u1)
+	u2 := newUser(a, u, m, "u2")
+
+	a.NotError(m.Add(nil, u1.ID, 100, "+100", time.Time{}, "")).
+		NotError(m.Add(nil, u1.ID, 50, "+50", time.Now().Add(time.Hour), ""))
+
+	a.Equal(m.Transfer(nil, u1.ID, u1.ID, 10, 0, "", "self", ""), ErrTransferToSelf())
+	a.ErrorIs(m.Transfer(nil, u1.ID, 10000, 10, 0, "", "not exists", ""), cmfx.ErrNotFound())
+
+	u3 := newUser(a, u, m, "u3")
+	a.NotError(u.SetState(nil, u3, user.StateDeleted)).
+		ErrorIs(m.Transfer(nil, u1.ID, u3.ID, 10, 0, "", "deleted", ""), cmfx.ErrNotFound())
+	a.ErrorIs(m.Transfer(nil, u1.ID, u2.ID, 100, 1, "fees", "too much", ""), ErrBalanceNotEnough()) // 会过期的金额不能转账
+	a.ErrorIs(m.Transfer(nil, u2.ID, u1.ID, 1, 0, "", "empty", ""), ErrBalanceNotEnough())
+	a.PanicString(func() {
+		m.Transfer(nil, u1.ID, u2.ID, 10, 1, "", "fee", "")
+	}, "参数 feeAccount 不能为空")
+
+	a.NotError(m.Transfer(nil, u1.ID, u2.ID, 90, 5, "fees", "transfer", ""))
+
+	ov1, err := m.GetOverview(u1.ID, time.Time{})
+	a.NotError(err).Equal(ov1.Available, 55)
+	ov2, err := m.GetOverview(u2.ID, time.Time{})
+	a.NotError(err).Equal(ov2.Available, 90)
+
+	fees, err := m.SystemBalance("fees")
+	a.NotError(err).Equal(fees, 5)
+
+	a.NotError(m.Freeze(nil, u2, 30, "freeze", "")).
+		NotError(m.Unfreeze(nil, u2, 10, "unfreeze", "")).
+		NotError(m.Del(nil, u2.ID, 20, "-20", ""))
+
+	issued, err := m.SystemBalance(SystemIssued)
+	a.NotError(err).Equal(issued, -150)
+	used, err := m.SystemBalance(SystemUsed)
+	a.NotError(err).Equal(used, 20)
+
+	mismatches, err := m.Reconcile()
+	a.NotError(err).Empty(mismatches)
+
+	// 直接修改总览表，造成不一致。
+	ov := &overviewPO{UID: u2.ID}
+	_, err = m.db.Select(ov)
+	a.NotError(err)
+	_, err = m.db.Update(&overviewPO{ID: ov.ID, Available: ov.Available + 1})
+	a.NotError(err)
+
+	// 不平衡的交易
+	_, err = m.db.Insert(&entryPO{Transaction: 1000, Account: userAccount(u1.ID), Amount: 3})
+	a.NotError(err)
+
+	mismatches, err = m.Reconcile()
+	a.NotError(err).Length(mismatches, 3).
+		Equal(mismatches[0], &Mismatch{Transaction: 1000, Actual: 3}).
+		Equal(mismatches[1], &Mismatch{UID: u1.ID, Account: userAccount(u1.ID), Expected: 55, Actual: 58}).
+		Equal(mismatches[2], &Mismatch{UID: u2.ID, Account: userAccount(u2.ID), Expected: 51, Actual: 50})
+}
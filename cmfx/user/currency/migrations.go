@@ -0,0 +1,120 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package currency
+
+import (
+	"github.com/issue9/orm/v6"
+	"github.com/issue9/orm/v6/core"
+	"github.com/issue9/web"
+
+	"github.com/issue9/cmfx/cmfx"
+)
+
+// Migrations 货币 id 的数据库迁移操作
+//
+// 用于将由旧版本 [Install] 创建的数据表升级至当前的结构，每一次数据表结构的变更对应一个操作。
+// 返回的操作需要追加至 [user.Users.Module] 所属模块的迁移列表中，版本号从 version 开始依次递增；
+// 由当前版本 [Install] 安装的货币已经是最新的结构，调用 [cmfx.Migrations.Install] 标记为已执行即可。
+func Migrations(id string, version int64) []*cmfx.Migration {
+	items := []*cmfx.Migration{
+		{
+			Desc: web.Phrase("add noticed column to currency %s expires", id),
+			Up: func(mod *cmfx.Module) error {
+				return buildDB(mod.DB(), id).SQLBuilder().AddColumn().
+					Table(orm.TableName(&expirePO{})).
+					Column("noticed", core.Bool, false, false, true, false).
+					Exec()
+			},
+			Down: func(mod *cmfx.Module) error {
+				return buildDB(mod.DB(), id).SQLBuilder().DropColumn().
+					Table(orm.TableName(&expirePO{})).
+					Column("noticed").
+					Exec()
+			},
+		},
+		{
+			Desc: web.Phrase("create currency %s ledger tables", id),
+			Up: func(mod *cmfx.Module) error {
+				db := buildDB(mod.DB(), id)
+				if err := db.Create(&transactionPO{}, &entryPO{}); err != nil {
+					return err
+				}
+
+				m := &Currency{id: id, db: db}
+				return db.DoTransaction(m.opening) // 已有的余额需要记录期初交易，否则对账无法通过。
+			},
+			Down: func(mod *cmfx.Module) error { return buildDB(mod.DB(), id).Drop(&transactionPO{}, &entryPO{}) },
+		},
+		{
+			Desc: web.Phrase("add idempotency key column to currency %s logs", id),
+			Up: func(mod *cmfx.Module) error {
+				sb := buildDB(mod.DB(), id).SQLBuilder()
+				err := sb.AddColumn().
+					Table(orm.TableName(&LogPO{})).
+					Column("idempotency_key", core.String, false, true, false, nil, 100).
+					Exec()
+				if err != nil {
+					return err
+				}
+
+				return sb.CreateIndex().
+					Table(orm.TableName(&LogPO{})).
+					Name(logKeyIndex).
+					Type(core.IndexUnique).
+					Columns("idempotency_key").
+					Exec()
+			},
+			Down: func(mod *cmfx.Module) error {
+				sb := buildDB(mod.DB(), id).SQLBuilder()
+				err := sb.DropIndex().Table(orm.TableName(&LogPO{})).Name(logKeyIndex).Exec()
+				if err != nil {
+					return err
+				}
+				return sb.DropColumn().Table(orm.TableName(&LogPO{})).Column("idempotency_key").Exec()
+			},
+		},
+		{
+			Desc: web.Phrase("add exchange column to currency %s logs", id),
+			Up: func(mod *cmfx.Module) error {
+				return buildDB(mod.DB(), id).SQLBuilder().AddColumn().
+					Table(orm.TableName(&LogPO{})).
+					Column("exchange", core.Int64, false, false, true, 0).
+					Exec()
+			},
+			Down: func(mod *cmfx.Module) error {
+				return buildDB(mod.DB(), id).SQLBuilder().DropColumn().
+					Table(orm.TableName(&LogPO{})).
+					Column("exchange").
+					Exec()
+			},
+		},
+		{
+			Desc: web.Phrase("create currency %s statements table", id),
+			Up:   func(mod *cmfx.Module) error { return buildDB(mod.DB(), id).Create(&StatementPO{}) },
+			Down: func(mod *cmfx.Module) error { return buildDB(mod.DB(), id).Drop(&StatementPO{}) },
+		},
+	}
+
+	for i, item := range items {
+		item.Version = version + int64(i)
+	}
+	return items
+}
+
+// 与 [LogPO] 中的唯一约束同名，由数据表名称和约束名称组成。
+const logKeyIndex = "#_logs_idempotency_key"
+
+// ExchangeMigration 创建 [Exchange] 数据表的迁移操作
+//
+// 用于在由旧版本创建的模块中添加 [Exchange] 的功能，
+// 返回的操作需要追加至 [user.Users.Module] 所属模块的迁移列表中，版本号为 version。
+func ExchangeMigration(version int64) *cmfx.Migration {
+	return &cmfx.Migration{
+		Version: version,
+		Desc:    web.Phrase("create currency exchange tables"),
+		Up:      func(mod *cmfx.Module) error { return mod.DB().Create(&RatePO{}, &ExchangePO{}) },
+		Down:    func(mod *cmfx.Module) error { return mod.DB().Drop(&RatePO{}, &ExchangePO{}) },
+	}
+}
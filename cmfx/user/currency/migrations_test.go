@@ -0,0 +1,106 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package currency
+
+import (
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+
+	"github.com/issue9/cmfx/cmfx/initial/test"
+	"github.com/issue9/cmfx/cmfx/user/usertest"
+)
+
+// 旧版本的 expirePO
+type expireV0PO struct {
+	ID      int64     `orm:"name(id);ai"`
+	UID     int64     `orm:"name(uid)"`
+	Value   int64     `orm:"name(value)"`
+	Expired time.Time `orm:"name(expired)"`
+}
+
+func (p *expireV0PO) TableName() string { return "_expires" }
+
+// 旧版本的 LogPO
+type logV0PO struct {
+	ID      int64     `orm:"name(id);ai"`
+	UID     int64     `orm:"name(uid)"`
+	Created time.Time `orm:"name(created)"`
+	Before  int64     `orm:"name(before)"`
+	After   int64     `orm:"name(after)"`
+	Value   int64     `orm:"name(value)"`
+	Memo    string    `orm:"name(memo);len(1000)"`
+	Type    Type      `orm:"name(type);len(10)"`
+}
+
+func (p *logV0PO) TableName() string { return "_logs" }
+
+func TestMigrations(t *testing.T) {
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+	defer s.Close()
+
+	u := usertest.NewModule(s)
+	mod := u.Module()
+
+	// 由旧版本安装的数据表
+	a.NotError(buildDB(mod.DB(), "point").Create(&overviewPO{}, &expireV0PO{}, &logV0PO{}))
+	u1, err := u.GetUserByUsername("u1")
+	a.NotError(err).NotNil(u1)
+	_, err = buildDB(mod.DB(), "point").Insert(&overviewPO{UID: u1.ID, Available: 7, Freeze: 3})
+	a.NotError(err)
+
+	ms := mod.Migrations(append(Migrations("point", 1), ExchangeMigration(6))...)
+	a.NotError(ms.Upgrade())
+	s.TableExists(mod.ID() + "_point_transactions").
+		TableExists(mod.ID() + "_point_entries").
+		TableExists(mod.ID() + "_point_statements").
+		TableExists(mod.ID() + "_currency_rates").
+		TableExists(mod.ID() + "_currency_exchanges")
+
+	states, err := ms.States()
+	a.NotError(err).Length(states, 6)
+	for _, state := range states {
+		a.False(state.Applied.IsZero())
+	}
+
+	m := New(u, "point")
+	opening, err := m.SystemBalance(SystemOpening)
+	a.NotError(err).Equal(opening, -10)
+	mismatches, err := m.Reconcile()
+	a.NotError(err).Empty(mismatches)
+
+	u2 := newUser(a, u, m, "u2")
+
+	expire := time.Now().Add(time.Hour)
+	a.NotError(m.Add(nil, u1.ID, 10, "+10", expire, "k1")).
+		NotError(m.Add(nil, u1.ID, 10, "+10", expire, "k1")).
+		NotError(m.Add(nil, u1.ID, 5, "+5", time.Time{}, "")).
+		NotError(m.Transfer(nil, u1.ID, u2.ID, 2, 0, "", "transfer", "k2")).
+		NotError(m.Expire(time.Now(), 2*time.Hour))
+
+	ex := NewExchange(u, RoundDown, m, New(u, "coin"))
+	a.NotError(buildDB(mod.DB(), "coin").Create(&overviewPO{}, &expirePO{}, &LogPO{}, &transactionPO{}, &entryPO{}, &StatementPO{}))
+	a.NotError(ex.SetRate("point", "coin", RateScale, 0, time.Now().Add(-time.Hour)))
+	_, err = ex.Exchange(nil, u1.ID, "point", "coin", 1, "exchange", "")
+	a.NotError(err)
+
+	// 唯一约束
+	_, err = m.db.Insert(&LogPO{UID: u1.ID, Key: idempotencyKey("k1")})
+	a.Error(err)
+
+	ov, err := m.GetOverview(u1.ID, time.Time{})
+	a.NotError(err).Equal(ov.Available, 19).Equal(ov.Freeze, 3)
+
+	a.NotError(m.GenerateStatements(time.Now().Add(24 * time.Hour)))
+	mismatches, err = m.Reconcile()
+	a.NotError(err).Empty(mismatches)
+
+	a.NotError(ms.Downgrade(0))
+	s.TableNotExists(mod.ID() + "_point_transactions").
+		TableNotExists(mod.ID() + "_point_statements").
+		TableNotExists(mod.ID() + "_currency_rates")
+}
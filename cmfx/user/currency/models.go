@@ -5,6 +5,7 @@
 package currency
 
 import (
+	"database/sql"
 	"time"
 
 	"github.com/issue9/orm/v6/core"
@@ -33,13 +34,14 @@ type expirePO struct {
 	UID     int64     `orm:"name(uid)"`
 	Value   int64     `orm:"name(value)"`
 	Expired time.Time `orm:"name(expired)"`
+	Noticed bool      `orm:"name(noticed)"` // 是否已经发送过即将过期的通知
 }
 
 func (p *expirePO) TableName() string { return "_expires" }
 
 //------------------------------------- log ---------------------------------------
 
-//go:generate web enum -i=./models.go -o=./models_methods.go -t=Type
+//go:generate web enum -i=./models.go -o=./models_methods.go -t=Type,Period
 
 // Type 日志类型
 type Type int8
@@ -48,6 +50,9 @@ const (
 	TypeNormal Type = iota
 	TypeFreeze
 	TypeUnfreeze
+	TypeExpired  // 过期扣除
+	TypeTransfer // 用户之间的转账
+	TypeExchange // 不同货币之间的兑换
 )
 
 func (Type) PrimitiveType() core.PrimitiveType { return core.String }
@@ -63,6 +68,12 @@ type LogPO struct {
 	Value   int64     `orm:"name(value)" json:"value" yaml:"value" cbor:"value" comment:"add currency value"`
 	Memo    string    `orm:"name(memo);len(1000)" json:"memo" yaml:"memo" cbor:"memo" comment:"memo of action"`
 	Type    Type      `orm:"name(type);len(10)" json:"type" yaml:"type" cbor:"type" comment:"type of action"`
+
+	// 幂等键，相同的值只能执行一次操作。
+	Key sql.NullString `orm:"name(idempotency_key);len(100);nullable;unique(idempotency_key)" json:"-" yaml:"-" cbor:"-"`
+
+	// 关联的兑换记录，仅 [TypeExchange] 类型的日志有值。
+	Exchange int64 `orm:"name(exchange)" json:"exchange,omitempty" yaml:"exchange,omitempty" cbor:"exchange,omitempty" comment:"id of exchange record"`
 }
 
 func (p *LogPO) TableName() string { return "_logs" }
@@ -71,3 +82,121 @@ func (p *LogPO) BeforeInsert() error {
 	p.Created = time.Now()
 	return nil
 }
+
+//------------------------------------- ledger ---------------------------------------
+
+// 复式记账的交易
+//
+// 每一笔交易包含至少两条 entryPO，且所有 entryPO 的金额之和必须为零。
+type transactionPO struct {
+	ID      int64     `orm:"name(id);ai"`
+	Type    Type      `orm:"name(type);len(10)"`
+	Memo    string    `orm:"name(memo);len(1000)"`
+	Created time.Time `orm:"name(created)"`
+}
+
+func (p *transactionPO) TableName() string { return "_transactions" }
+
+func (p *transactionPO) BeforeInsert() error {
+	p.Created = time.Now()
+	return nil
+}
+
+// 交易的分录
+//
+// Amount 为正表示贷记(该账户增加)，为负表示借记(该账户减少)。
+type entryPO struct {
+	ID          int64  `orm:"name(id);ai"`
+	Transaction int64  `orm:"name(txn);index(i_txn)"`
+	Account     string `orm:"name(account);len(100);index(i_account)"`
+	Amount      int64  `orm:"name(amount)"`
+}
+
+func (p *entryPO) TableName() string { return "_entries" }
+
+//------------------------------------- exchange ---------------------------------------
+
+// RatePO 货币之间的汇率
+//
+// 同一对货币可以有多条记录，以 Effective 不大于当前时间的最后一条为准。
+type RatePO struct {
+	ID        int64     `orm:"name(id);ai" json:"id" yaml:"id" cbor:"id"`
+	From      string    `orm:"name(from_currency);len(50);index(i_pair)" json:"from" yaml:"from" cbor:"from" comment:"source currency"`
+	To        string    `orm:"name(to_currency);len(50);index(i_pair)" json:"to" yaml:"to" cbor:"to" comment:"target currency"`
+	Rate      int64     `orm:"name(rate)" json:"rate" yaml:"rate" cbor:"rate" comment:"exchange rate, in unit of RateScale"`
+	Fee       int64     `orm:"name(fee)" json:"fee" yaml:"fee" cbor:"fee" comment:"exchange fee, in unit of FeeScale"`
+	Effective time.Time `orm:"name(effective)" json:"effective" yaml:"effective" cbor:"effective" comment:"effective time of rate"`
+	Created   time.Time `orm:"name(created)" json:"created" yaml:"created" cbor:"created"`
+}
+
+func (p *RatePO) TableName() string { return "_currency_rates" }
+
+func (p *RatePO) BeforeInsert() error {
+	p.Created = time.Now()
+	return nil
+}
+
+// ExchangePO 兑换记录
+//
+// 兑换会在源货币和目标货币中各产生一条 [TypeExchange] 类型的日志，
+// 两条日志的 Exchange 字段均指向此记录，而此记录的 FromLog 和 ToLog 又指向这两条日志。
+type ExchangePO struct {
+	ID      int64     `orm:"name(id);ai" json:"id" yaml:"id" cbor:"id"`
+	UID     int64     `orm:"name(uid)" json:"-" yaml:"-" cbor:"-"`
+	From    string    `orm:"name(from_currency);len(50)" json:"from" yaml:"from" cbor:"from"`
+	To      string    `orm:"name(to_currency);len(50)" json:"to" yaml:"to" cbor:"to"`
+	Value   int64     `orm:"name(value)" json:"value" yaml:"value" cbor:"value"`     // 扣除的源货币
+	Rate    int64     `orm:"name(rate)" json:"rate" yaml:"rate" cbor:"rate"`         // 兑换时采用的汇率
+	Fee     int64     `orm:"name(fee)" json:"fee" yaml:"fee" cbor:"fee"`             // 以目标货币计的手续费
+	Result  int64     `orm:"name(result)" json:"result" yaml:"result" cbor:"result"` // 实际到账的目标货币
+	FromLog int64     `orm:"name(from_log)" json:"fromLog" yaml:"fromLog" cbor:"fromLog"`
+	ToLog   int64     `orm:"name(to_log)" json:"toLog" yaml:"toLog" cbor:"toLog"`
+	Memo    string    `orm:"name(memo);len(1000)" json:"memo" yaml:"memo" cbor:"memo"`
+	Created time.Time `orm:"name(created)" json:"created" yaml:"created" cbor:"created"`
+
+	Key sql.NullString `orm:"name(idempotency_key);len(100);nullable;unique(idempotency_key)" json:"-" yaml:"-" cbor:"-"`
+}
+
+func (p *ExchangePO) TableName() string { return "_currency_exchanges" }
+
+func (p *ExchangePO) BeforeInsert() error {
+	p.Created = time.Now()
+	return nil
+}
+
+//------------------------------------- statement ---------------------------------------
+
+// Period 对账单的周期
+type Period int8
+
+const (
+	PeriodDay Period = iota
+	PeriodMonth
+)
+
+func (Period) PrimitiveType() core.PrimitiveType { return core.String }
+
+// StatementPO 对账单
+//
+// 统计的是所有用户的可用余额在一个周期内的变化，
+// 满足 Closing = Opening + Credits - Debits - Freezes + Unfreezes - Expired。
+type StatementPO struct {
+	ID        int64     `orm:"name(id);ai" json:"-" yaml:"-" cbor:"-"`
+	Period    Period    `orm:"name(period);len(10);unique(u_period_date)" json:"period" yaml:"period" cbor:"period" comment:"period of statement"`
+	Date      time.Time `orm:"name(date);unique(u_period_date)" json:"date" yaml:"date" cbor:"date" comment:"start time of period"`
+	Opening   int64     `orm:"name(opening)" json:"opening" yaml:"opening" cbor:"opening" comment:"opening balance"`
+	Credits   int64     `orm:"name(credits)" json:"credits" yaml:"credits" cbor:"credits" comment:"total credits"`
+	Debits    int64     `orm:"name(debits)" json:"debits" yaml:"debits" cbor:"debits" comment:"total debits"`
+	Freezes   int64     `orm:"name(freezes)" json:"freezes" yaml:"freezes" cbor:"freezes" comment:"total freezes"`
+	Unfreezes int64     `orm:"name(unfreezes)" json:"unfreezes" yaml:"unfreezes" cbor:"unfreezes" comment:"total unfreezes"`
+	Expired   int64     `orm:"name(expired)" json:"expired" yaml:"expired" cbor:"expired" comment:"total expired"`
+	Closing   int64     `orm:"name(closing)" json:"closing" yaml:"closing" cbor:"closing" comment:"closing balance"`
+	Created   time.Time `orm:"name(created)" json:"created" yaml:"created" cbor:"created"`
+}
+
+func (p *StatementPO) TableName() string { return "_statements" }
+
+func (p *StatementPO) BeforeInsert() error {
+	p.Created = time.Now()
+	return nil
+}
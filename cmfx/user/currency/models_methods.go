@@ -15,14 +15,20 @@ import (
 //--------------------- Type ------------------------
 
 var _TypeToString = map[Type]string{
+	TypeExchange: "exchange",
+	TypeExpired:  "expired",
 	TypeFreeze:   "freeze",
 	TypeNormal:   "normal",
+	TypeTransfer: "transfer",
 	TypeUnfreeze: "unfreeze",
 }
 
 var _TypeFromString = map[string]Type{
+	"exchange": TypeExchange,
+	"expired":  TypeExpired,
 	"freeze":   TypeFreeze,
 	"normal":   TypeNormal,
+	"transfer": TypeTransfer,
 	"unfreeze": TypeUnfreeze,
 }
 
@@ -131,7 +137,129 @@ var (
 
 func (Type) OpenAPISchema(s *openapi.Schema) {
 	s.Type = openapi.TypeString
-	s.Enum = []any{TypeFreeze.String(), TypeNormal.String(), TypeUnfreeze.String()}
+	s.Enum = []any{TypeExchange.String(), TypeExpired.String(), TypeFreeze.String(), TypeNormal.String(), TypeTransfer.String(), TypeUnfreeze.String()}
 }
 
 //--------------------- end Type --------------------
+
+//--------------------- Period ------------------------
+
+var _PeriodToString = map[Period]string{
+	PeriodDay:   "day",
+	PeriodMonth: "month",
+}
+
+var _PeriodFromString = map[string]Period{
+	"day":   PeriodDay,
+	"month": PeriodMonth,
+}
+
+// String fmt.Stringer
+func (p Period) String() string {
+	if v, found := _PeriodToString[p]; found {
+		return v
+	}
+	return fmt.Sprintf("Period(%d)", p)
+}
+
+func ParsePeriod(v string) (Period, error) {
+	if t, found := _PeriodFromString[v]; found {
+		return t, nil
+	}
+	return 0, locales.ErrInvalidValue()
+}
+
+func (p Period) MarshalText() ([]byte, error) {
+	if v, found := _PeriodToString[p]; found {
+		return []byte(v), nil
+	}
+	return nil, locales.ErrInvalidValue()
+}
+
+func (p *Period) UnmarshalText(data []byte) error {
+	tmp, err := ParsePeriod(string(data))
+	if err == nil {
+		*p = tmp
+	}
+	return err
+}
+
+func (p Period) MarshalCBOR() ([]byte, error) {
+	if v, found := _PeriodToString[p]; found {
+		return cbor.Marshal(v)
+	}
+	return nil, locales.ErrInvalidValue()
+}
+
+func (p *Period) UnmarshalCBOR(data []byte) error {
+	var tmp string
+	if err := cbor.Unmarshal(data, &tmp); err != nil {
+		return err
+	}
+
+	if ss, found := _PeriodFromString[tmp]; found {
+		*p = ss
+		return nil
+	}
+	return locales.ErrInvalidValue()
+}
+
+func (p Period) IsValid() bool {
+	_, found := _PeriodToString[p]
+	return found
+}
+
+// Scan sql.Scanner
+func (p *Period) Scan(src any) error {
+	if src == nil {
+		return locales.ErrInvalidValue()
+	}
+
+	var val string
+	switch v := src.(type) {
+	case string:
+		val = v
+	case []byte:
+		val = string(v)
+	case []rune:
+		val = string(v)
+	default:
+		return locales.ErrInvalidValue()
+	}
+
+	v, err := ParsePeriod(val)
+	if err != nil {
+		return err
+	}
+
+	*p = v
+	return nil
+}
+
+// Value driver.Valuer
+func (p Period) Value() (driver.Value, error) {
+	v, err := p.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return string(v), nil
+}
+
+func PeriodValidator(v Period) bool { return v.IsValid() }
+
+var (
+	PeriodRule = filter.V(PeriodValidator, locales.InvalidValue)
+
+	PeriodSliceRule = filter.SV[[]Period](PeriodValidator, locales.InvalidValue)
+
+	PeriodFilter = filter.NewBuilder(PeriodRule)
+
+	PeriodSliceFilter = filter.NewBuilder(PeriodSliceRule)
+)
+
+func (Period) OpenAPISchema(s *openapi.Schema) {
+	s.Type = openapi.TypeString
+	s.Enum = []any{PeriodDay.String(), PeriodMonth.String()}
+}
+
+//--------------------- end Period --------------------
@@ -1,4 +1,4 @@
-// SPDX-FileCopyrightText: 2024-2025 caixw
+// SPDX-FileCopyrightText: 2024-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
@@ -10,4 +10,6 @@ var (
 	_ orm.TableNamer = &overviewPO{}
 	_ orm.TableNamer = &LogPO{}
 	_ orm.TableNamer = &expirePO{}
+	_ orm.TableNamer = &transactionPO{}
+	_ orm.TableNamer = &entryPO{}
 )
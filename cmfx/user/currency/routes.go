@@ -0,0 +1,330 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package currency
+
+import (
+	"errors"
+	"time"
+
+	"github.com/issue9/mux/v9/header"
+	"github.com/issue9/web"
+	"github.com/issue9/web/openapi"
+
+	"github.com/issue9/cmfx/cmfx"
+	"github.com/issue9/cmfx/cmfx/filters"
+	"github.com/issue9/cmfx/cmfx/query"
+)
+
+// Handle 注册管理货币的接口
+//
+// admin 为后台接口的路由前缀，会注册以下接口：
+//   - GET {admin}/overviews：所有用户的摘要信息；
+//   - GET {admin}/users/{uid}：用户的摘要信息；
+//   - GET {admin}/users/{uid}/logs：用户的日志；
+//   - POST {admin}/users/{uid}/adjust：调整用户的余额；
+//   - POST {admin}/users/{uid}/freeze：冻结用户的余额；
+//   - POST {admin}/users/{uid}/unfreeze：解冻用户的余额；
+//   - GET {admin}/statements：对账单；
+//   - GET {admin}/statements/csv：以 CSV 格式导出对账单；
+//   - GET {admin}/statements/check：检测对账单的一致性；
+//
+// member 为用户接口的路由前缀，会注册以下接口：
+//   - GET {member}：当前用户的摘要信息；
+//   - GET {member}/logs：当前用户的日志；
+//
+// res 用于生成后台接口的 RBAC 资源，资源 ID 均以 -currency-{id} 结尾；
+// uid 用于获取用户接口的当前登录用户。
+//
+// 此方法只是提供了一套默认的接口，也可以直接使用各个 HandleXxx 方法自行注册。
+func (m *Currency) Handle(admin, member *web.Prefix, api func(func(*openapi.Operation)) web.Middleware, res func(string, web.LocaleStringer) web.MiddlewareFunc, uid func(*web.Context) int64) {
+	suffix := "-currency-" + m.id
+	resGet := res("get"+suffix, web.Phrase("view currency %s", m.id))
+	resAdjust := res("adjust"+suffix, web.Phrase("adjust currency %s", m.id))
+	resFreeze := res("freeze"+suffix, web.Phrase("freeze currency %s", m.id))
+	resStatement := res("statement"+suffix, web.Phrase("view currency %s statements", m.id))
+
+	tag := "currency_" + m.id
+	admin.
+		Get("/overviews", m.HandleGetOverviews, resGet, api(func(o *openapi.Operation) {
+			o.Tag(tag).
+				Desc(web.Phrase("get currency overviews api"), nil).
+				QueryObject(query.Text{}, nil).
+				Response200(query.Page[OverviewsVO]{})
+		})).
+		Get("/users/{uid:digit}", adminHandler(m.HandleGetOverview), resGet, api(func(o *openapi.Operation) {
+			o.Tag(tag).
+				Desc(web.Phrase("get user currency overview api"), nil).
+				PathID("uid:digit", web.Phrase("the user id")).
+				QueryObject(overviewQuery{}, nil).
+				Response200(OverviewVO{})
+		})).
+		Get("/users/{uid:digit}/logs", adminHandler(m.HandleGetLogs), resGet, api(func(o *openapi.Operation) {
+			o.Tag(tag).
+				Desc(web.Phrase("get user currency logs api"), nil).
+				PathID("uid:digit", web.Phrase("the user id")).
+				QueryObject(LogQuery{}, nil).
+				Response200(query.Page[LogPO]{})
+		})).
+		Post("/users/{uid:digit}/adjust", adminHandler(m.HandlePostAdjust), resAdjust, cmfx.Idempotency(false), api(func(o *openapi.Operation) {
+			o.Tag(tag).
+				Desc(web.Phrase("adjust user currency api"), nil).
+				PathID("uid:digit", web.Phrase("the user id")).
+				Body(AdjustTO{}, false, nil, nil).
+				ResponseEmpty("201")
+		})).
+		Post("/users/{uid:digit}/freeze", adminHandler(m.HandlePostFreeze), resFreeze, cmfx.Idempotency(false), api(func(o *openapi.Operation) {
+			o.Tag(tag).
+				Desc(web.Phrase("freeze user currency api"), nil).
+				PathID("uid:digit", web.Phrase("the user id")).
+				Body(FreezeTO{}, false, nil, nil).
+				ResponseEmpty("201")
+		})).
+		Post("/users/{uid:digit}/unfreeze", adminHandler(m.HandlePostUnfreeze), resFreeze, cmfx.Idempotency(false), api(func(o *openapi.Operation) {
+			o.Tag(tag).
+				Desc(web.Phrase("unfreeze user currency api"), nil).
+				PathID("uid:digit", web.Phrase("the user id")).
+				Body(FreezeTO{}, false, nil, nil).
+				ResponseEmpty("201")
+		})).
+		Get("/statements", m.HandleGetStatements, resStatement, api(func(o *openapi.Operation) {
+			o.Tag(tag).
+				Desc(web.Phrase("get currency statements api"), nil).
+				QueryObject(StatementQuery{}, nil).
+				Response200(query.Page[StatementPO]{})
+		})).
+		Get("/statements/csv", m.HandleGetStatementsCSV, resStatement, api(func(o *openapi.Operation) {
+			o.Tag(tag).
+				Desc(web.Phrase("export currency statements as csv api"), nil).
+				QueryObject(StatementQuery{}, nil).
+				Response200("")
+		})).
+		Get("/statements/check", m.HandleGetStatementsCheck, resStatement, api(func(o *openapi.Operation) {
+			o.Tag(tag).
+				Desc(web.Phrase("check currency statements api"), nil).
+				QueryObject(StatementQuery{}, nil).
+				Response200([]StatementMismatch{}).
+				ResponseEmpty("204")
+		}))
+
+	member.
+		Get("", memberHandler(uid, m.HandleGetOverview), api(func(o *openapi.Operation) {
+			o.Tag(tag).
+				Desc(web.Phrase("get login user currency overview api"), nil).
+				QueryObject(overviewQuery{}, nil).
+				Response200(OverviewVO{})
+		})).
+		Get("/logs", memberHandler(uid, m.HandleGetLogs), api(func(o *openapi.Operation) {
+			o.Tag(tag).
+				Desc(web.Phrase("get login user currency logs api"), nil).
+				QueryObject(LogQuery{}, nil).
+				Response200(query.Page[LogPO]{})
+		}))
+}
+
+// 将 uid 参数转换为从路径中的 uid 获取
+func adminHandler(f func(*web.Context, int64) web.Responser) web.HandlerFunc {
+	return func(ctx *web.Context) web.Responser {
+		uid, resp := ctx.PathID("uid", cmfx.NotFoundInvalidPath)
+		if resp != nil {
+			return resp
+		}
+		return f(ctx, uid)
+	}
+}
+
+// 将 uid 参数转换为当前登录的用户
+func memberHandler(uid func(*web.Context) int64, f func(*web.Context, int64) web.Responser) web.HandlerFunc {
+	return func(ctx *web.Context) web.Responser {
+		return f(ctx, uid(ctx))
+	}
+}
+
+// HandleGetOverviews 获取所有用户摘要信息的接口
+//
+// 查询参数为 [query.Text]，可按用户名或编号搜索。
+func (m *Currency) HandleGetOverviews(ctx *web.Context) web.Responser {
+	q := &query.Text{}
+	if resp := ctx.QueryObject(true, q, cmfx.BadRequestInvalidQuery); resp != nil {
+		return resp
+	}
+
+	return query.PagingResponserWithConvert(ctx, &q.Limit, m.overviewsSQL(q), overviewsVO)
+}
+
+type overviewQuery struct {
+	Expire time.Time `query:"expire"`
+}
+
+// HandleGetOverview 获取用户 uid 摘要信息的接口
+//
+// 查询参数 expire 的含义与 [Currency.GetOverview] 的 expire 参数相同。
+func (m *Currency) HandleGetOverview(ctx *web.Context, uid int64) web.Responser {
+	q := &overviewQuery{}
+	if resp := ctx.QueryObject(true, q, cmfx.BadRequestInvalidQuery); resp != nil {
+		return resp
+	}
+
+	ov, err := m.GetOverview(uid, q.Expire)
+	if err != nil {
+		return ctx.Error(err, "")
+	}
+	return web.OK(ov)
+}
+
+// HandleGetLogs 获取用户 uid 日志的接口
+//
+// 查询参数为 [LogQuery]。
+func (m *Currency) HandleGetLogs(ctx *web.Context, uid int64) web.Responser {
+	q := &LogQuery{}
+	if resp := ctx.QueryObject(true, q, cmfx.BadRequestInvalidQuery); resp != nil {
+		return resp
+	}
+
+	return query.PagingResponser[LogPO](ctx, &q.Limit, m.logsSQL(uid, q), nil)
+}
+
+// AdjustTO 手动调整余额的参数
+type AdjustTO struct {
+	Value  int64     `json:"value" yaml:"value" cbor:"value" comment:"adjust value, negative number means deduction"`
+	Memo   string    `json:"memo" yaml:"memo" cbor:"memo" comment:"memo of action"`
+	Expire time.Time `json:"expire,omitempty" yaml:"expire,omitempty" cbor:"expire,omitempty" comment:"expire time of added value"`
+}
+
+func (to *AdjustTO) Filter(ctx *web.FilterContext) {
+	ctx.Add(filters.NotZeroNumber[int64]()("value", &to.Value)).
+		Add(filters.NotEmpty("memo", &to.Memo))
+}
+
+// HandlePostAdjust 手动调整用户 uid 余额的接口
+//
+// 提交的数据为 [AdjustTO]，Value 为正数时调用 [Currency.Add]，否则调用 [Currency.Del]。
+// 如果请求中包含 [cmfx.IdempotencyKeyHeader]，会将其作为幂等键。
+func (m *Currency) HandlePostAdjust(ctx *web.Context, uid int64) web.Responser {
+	data := &AdjustTO{}
+	if resp := ctx.Read(true, data, cmfx.BadRequestInvalidBody); resp != nil {
+		return resp
+	}
+
+	if _, err := m.user.GetUser(uid); err != nil {
+		return ctx.Error(err, "")
+	}
+
+	key := cmfx.IdempotencyKey(ctx)
+	if data.Value > 0 {
+		return created(ctx, m.Add(nil, uid, uint(data.Value), data.Memo, data.Expire, key))
+	}
+	return created(ctx, m.Del(nil, uid, uint(-data.Value), data.Memo, key))
+}
+
+// FreezeTO 冻结与解冻的参数
+type FreezeTO struct {
+	Value uint   `json:"value" yaml:"value" cbor:"value" comment:"freeze or unfreeze value"`
+	Memo  string `json:"memo" yaml:"memo" cbor:"memo" comment:"memo of action"`
+}
+
+func (to *FreezeTO) Filter(ctx *web.FilterContext) {
+	ctx.Add(filters.NotZeroNumber[uint]()("value", &to.Value)).
+		Add(filters.NotEmpty("memo", &to.Memo))
+}
+
+// HandlePostFreeze 冻结用户 uid 资金的接口
+//
+// 提交的数据为 [FreezeTO]，幂等键的处理与 [Currency.HandlePostAdjust] 相同。
+func (m *Currency) HandlePostFreeze(ctx *web.Context, uid int64) web.Responser {
+	data := &FreezeTO{}
+	if resp := ctx.Read(true, data, cmfx.BadRequestInvalidBody); resp != nil {
+		return resp
+	}
+
+	u, err := m.user.GetUser(uid)
+	if err != nil {
+		return ctx.Error(err, "")
+	}
+
+	key := cmfx.IdempotencyKey(ctx)
+	return created(ctx, m.Freeze(nil, u, data.Value, data.Memo, key))
+}
+
+// HandlePostUnfreeze 解冻用户 uid 资金的接口
+//
+// 提交的数据为 [FreezeTO]，幂等键的处理与 [Currency.HandlePostAdjust] 相同。
+func (m *Currency) HandlePostUnfreeze(ctx *web.Context, uid int64) web.Responser {
+	data := &FreezeTO{}
+	if resp := ctx.Read(true, data, cmfx.BadRequestInvalidBody); resp != nil {
+		return resp
+	}
+
+	u, err := m.user.GetUser(uid)
+	if err != nil {
+		return ctx.Error(err, "")
+	}
+
+	key := cmfx.IdempotencyKey(ctx)
+	return created(ctx, m.Unfreeze(nil, u, int64(data.Value), data.Memo, key))
+}
+
+// HandleGetStatements 查询对账单的接口
+//
+// 查询参数为 [StatementQuery]。
+func (m *Currency) HandleGetStatements(ctx *web.Context) web.Responser {
+	q := &StatementQuery{}
+	if resp := ctx.QueryObject(true, q, cmfx.BadRequestInvalidQuery); resp != nil {
+		return resp
+	}
+
+	return query.PagingResponser[StatementPO](ctx, &q.Limit, m.statementsSQL(q, false), nil)
+}
+
+// HandleGetStatementsCSV 以 CSV 格式导出对账单的接口
+//
+// 查询参数为 [StatementQuery]，其中的分页参数会被忽略。
+func (m *Currency) HandleGetStatementsCSV(ctx *web.Context) web.Responser {
+	q := &StatementQuery{}
+	if resp := ctx.QueryObject(true, q, cmfx.BadRequestInvalidQuery); resp != nil {
+		return resp
+	}
+
+	return web.ResponserFunc(func(ctx *web.Context) {
+		filename := m.id + "-" + q.Period.String() + ".csv"
+		ctx.Header().Set(header.ContentType, "text/csv; charset=utf-8")
+		ctx.Header().Set(header.ContentDisposition, `attachment; filename="`+filename+`"`)
+		if err := m.WriteStatementsCSV(ctx, q); err != nil {
+			ctx.Logs().ERROR().Error(err)
+		}
+	})
+}
+
+// HandleGetStatementsCheck 检测对账单一致性的接口
+//
+// 查询参数中仅 period 有效，如果都一致，返回 204。
+func (m *Currency) HandleGetStatementsCheck(ctx *web.Context) web.Responser {
+	q := &StatementQuery{}
+	if resp := ctx.QueryObject(true, q, cmfx.BadRequestInvalidQuery); resp != nil {
+		return resp
+	}
+
+	mismatches, err := m.CheckStatements(q.Period)
+	switch {
+	case err != nil:
+		return ctx.Error(err, "")
+	case len(mismatches) == 0:
+		return web.NoContent()
+	default:
+		return web.OK(mismatches)
+	}
+}
+
+func created(ctx *web.Context, err error) web.Responser {
+	switch {
+	case errors.Is(err, ErrBalanceNotEnough()):
+		return ctx.Problem(cmfx.BadRequest)
+	case errors.Is(err, ErrIdempotencyKeyConflict()):
+		return ctx.Problem(cmfx.Conflict)
+	case err != nil:
+		return ctx.Error(err, "")
+	default:
+		return web.Created(nil, "")
+	}
+}
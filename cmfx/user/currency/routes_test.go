@@ -0,0 +1,154 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package currency
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/assert/v4/rest"
+	"github.com/issue9/mux/v9/header"
+	"github.com/issue9/web"
+	"github.com/issue9/web/server/servertest"
+	"github.com/issue9/webuse/v7/middlewares/auth"
+
+	"github.com/issue9/cmfx/cmfx"
+	"github.com/issue9/cmfx/cmfx/initial/test"
+	"github.com/issue9/cmfx/cmfx/modules/admin/admintest"
+	"github.com/issue9/cmfx/cmfx/user/usertest"
+)
+
+func TestCurrency_Handle(t *testing.T) {
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+
+	adminL := admintest.NewModule(s)
+	u := usertest.NewModule(s)
+	m := Install(u, "point")
+	mod := u.Module().New("_currency_point", web.Phrase("currency point"))
+	m.Handle(
+		adminL.UserModule().Module().Router().Prefix(adminL.URLPrefix()+"/point", adminL),
+		u.Module().Router().Prefix(u.URLPrefix()+"/point", u),
+		mod.API, adminL.NewResourceGroup(mod).New,
+		func(ctx *web.Context) int64 { return u.CurrentUser(ctx).ID },
+	)
+
+	u1, err := u.GetUserByUsername("u1")
+	a.NotError(err).NotNil(u1)
+
+	defer servertest.Run(a, s.Module().Server())()
+	defer s.Close()
+
+	adminToken := auth.BuildToken(auth.Bearer, admintest.GetToken(s, adminL))
+	prefix := adminL.URLPrefix() + "/point/users/1"
+
+	post := func(path, body string) *rest.Request {
+		return s.Post(prefix+path, []byte(body)).
+			Header(header.Authorization, adminToken).
+			Header(header.ContentType, header.JSON+";charset=utf-8").
+			Header(header.Accept, header.JSON)
+	}
+
+	// adjust
+
+	post("/adjust", `{"value":100,"memo":"+100"}`).Header(cmfx.IdempotencyKeyHeader, "k1").Do(nil).Status(http.StatusCreated)
+	post("/adjust", `{"value":100,"memo":"+100"}`).Header(cmfx.IdempotencyKeyHeader, "k1").Do(nil).Status(http.StatusCreated)
+	post("/adjust", `{"value":-10,"memo":"-10"}`).Header(cmfx.IdempotencyKeyHeader, "k1").Do(nil).Status(http.StatusConflict)
+	post("/adjust", `{"value":-10,"memo":"-10"}`).Do(nil).Status(http.StatusCreated)
+	post("/adjust", `{"value":-1000,"memo":"-1000"}`).Do(nil).Status(http.StatusBadRequest)
+	post("/adjust", `{"value":0,"memo":"0"}`).Do(nil).Status(http.StatusBadRequest)
+	s.Post(adminL.URLPrefix()+"/point/users/100/adjust", []byte(`{"value":1,"memo":"+1"}`)).
+		Header(header.Authorization, adminToken).
+		Header(header.ContentType, header.JSON+";charset=utf-8").
+		Header(header.Accept, header.JSON).
+		Do(nil).Status(http.StatusNotFound)
+
+	ov, err := m.GetOverview(u1.ID, time.Time{})
+	a.NotError(err).Equal(ov.Available, 90)
+
+	// freeze
+
+	post("/freeze", `{"value":50,"memo":"freeze"}`).Do(nil).Status(http.StatusCreated)
+	post("/freeze", `{"value":50,"memo":"freeze"}`).Do(nil).Status(http.StatusBadRequest)
+	post("/unfreeze", `{"value":20,"memo":"unfreeze"}`).Do(nil).Status(http.StatusCreated)
+
+	ov, err = m.GetOverview(u1.ID, time.Time{})
+	a.NotError(err).Equal(ov.Available, 60).Equal(ov.Freeze, 30)
+
+	// get
+
+	s.Get(adminL.URLPrefix()+"/point/overviews").
+		Header(header.Authorization, adminToken).
+		Header(header.Accept, header.JSON).
+		Do(nil).Status(http.StatusOK)
+
+	s.Get(prefix).
+		Header(header.Authorization, adminToken).
+		Header(header.Accept, header.JSON).
+		Do(nil).Status(http.StatusOK).StringBody(`{"available":60,"freeze":30,"used":10}`)
+
+	s.Get(prefix+"/logs").
+		Header(header.Authorization, adminToken).
+		Header(header.Accept, header.JSON).
+		Do(nil).Status(http.StatusOK)
+
+	s.Get(prefix + "/logs").Do(nil).Status(http.StatusUnauthorized)
+
+	// statements
+
+	a.NotError(m.GenerateStatements(time.Now().AddDate(0, 0, 1)))
+	statements := adminL.URLPrefix() + "/point/statements"
+
+	s.Get(statements+"?period=day").
+		Header(header.Authorization, adminToken).
+		Header(header.Accept, header.JSON).
+		Do(nil).Status(http.StatusOK)
+
+	s.Get(statements+"?period=invalid").
+		Header(header.Authorization, adminToken).
+		Header(header.Accept, header.JSON).
+		Do(nil).Status(http.StatusBadRequest)
+
+	s.Get(statements+"/csv?period=day").
+		Header(header.Authorization, adminToken).
+		Do(nil).Status(http.StatusOK).
+		Header(header.ContentType, "text/csv; charset=utf-8").
+		BodyFunc(func(a *assert.Assertion, body []byte) {
+			a.True(strings.HasPrefix(string(body), "date,opening,credits,debits,freezes,unfreezes,expired,closing\n"))
+		})
+
+	s.Get(statements+"/check?period=month").
+		Header(header.Authorization, adminToken).
+		Header(header.Accept, header.JSON).
+		Do(nil).Status(http.StatusNoContent)
+
+	// member
+
+	memberToken := auth.BuildToken(auth.Bearer, usertest.GetToken(s, u))
+
+	s.Get(u.URLPrefix()+"/point").
+		Header(header.Authorization, memberToken).
+		Header(header.Accept, header.JSON).
+		Do(nil).Status(http.StatusOK).StringBody(`{"available":60,"freeze":30,"used":10}`)
+
+	s.Get(u.URLPrefix()+"/point/logs?text=freeze").
+		Header(header.Authorization, memberToken).
+		Header(header.Accept, header.JSON).
+		Do(nil).Status(http.StatusOK)
+
+	s.Get(u.URLPrefix()+"/point/logs?type=freeze").
+		Header(header.Authorization, memberToken).
+		Header(header.Accept, header.JSON).
+		Do(nil).Status(http.StatusOK).BodyFunc(func(a *assert.Assertion, body []byte) {
+		a.Contains(string(body), `"type":"freeze"`).
+			NotContains(string(body), `"type":"unfreeze"`).
+			NotContains(string(body), `"type":"normal"`)
+	})
+
+	s.Get(u.URLPrefix() + "/point").Do(nil).Status(http.StatusUnauthorized)
+}
@@ -0,0 +1,302 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package currency
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/issue9/orm/v6"
+	"github.com/issue9/orm/v6/sqlbuilder"
+	"github.com/issue9/web"
+
+	"github.com/issue9/cmfx/cmfx/query"
+)
+
+// 周期 p 中包含 t 的起始时间
+func (p Period) start(t time.Time) time.Time {
+	if p == PeriodMonth {
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// 以 start 为起始时间的下一个周期的起始时间
+func (p Period) next(start time.Time) time.Time {
+	if p == PeriodMonth {
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+func (p Period) layout() string {
+	if p == PeriodMonth {
+		return "2006-01"
+	}
+	return time.DateOnly
+}
+
+// AddStatementService 添加定时生成对账单的服务
+//
+// spec 为 cron 格式的执行时间，一般为每天凌晨执行一次。
+func (m *Currency) AddStatementService(spec string) context.CancelFunc {
+	return m.user.Module().Server().Services().AddCron(web.Phrase("generate currency %s statements", m.id), m.GenerateStatements, spec, true)
+}
+
+// GenerateStatements 生成在 now 之前已经结束且尚未生成的对账单
+//
+// 日结单和月结单都会生成，第一份对账单从第一条日志所在的周期开始。
+// 一般由 [Currency.AddStatementService] 定时调用。
+func (m *Currency) GenerateStatements(now time.Time) error {
+	first := &LogPO{}
+	size, err := m.db.SQLBuilder().Select().
+		From(orm.TableName(first)).
+		Asc("id").
+		Limit(1).
+		QueryObject(true, first)
+	if err != nil || size == 0 {
+		return err
+	}
+
+	for _, p := range []Period{PeriodDay, PeriodMonth} {
+		last, err := m.lastStatement(p)
+		if err != nil {
+			return err
+		}
+
+		var start time.Time
+		if last == nil {
+			start = p.start(first.Created.In(now.Location()))
+		} else {
+			start = p.next(last.Date.In(now.Location()))
+		}
+
+		for end := p.next(start); !end.After(now); start, end = end, p.next(end) {
+			if last, err = m.generateStatement(p, start, end, last); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (m *Currency) lastStatement(p Period) (*StatementPO, error) {
+	last := &StatementPO{}
+	size, err := m.db.SQLBuilder().Select().
+		From(orm.TableName(last)).
+		Where("period=?", p).
+		Desc("date").
+		Limit(1).
+		QueryObject(true, last)
+	if err != nil || size == 0 {
+		return nil, err
+	}
+	return last, nil
+}
+
+type typeTotal struct {
+	Type    Type  `orm:"name(type)"`
+	Credits int64 `orm:"name(credits)"`
+	Debits  int64 `orm:"name(debits)"`
+}
+
+// 生成 [start, end) 之间的对账单
+//
+// prev 为上一期的对账单，如果为空，则期初余额从日志中计算。
+func (m *Currency) generateStatement(p Period, start, end time.Time, prev *StatementPO) (*StatementPO, error) {
+	st := &StatementPO{Period: p, Date: start}
+
+	if prev != nil {
+		st.Opening = prev.Closing
+	} else {
+		opening, err := m.logsTotal(time.Time{}, start)
+		if err != nil {
+			return nil, err
+		}
+		st.Opening = opening
+	}
+
+	totals := make([]*typeTotal, 0, 10)
+	_, err := m.db.SQLBuilder().Select().
+		Column("type").
+		Column("COALESCE(SUM(CASE WHEN value>0 THEN value ELSE 0 END),0) AS credits").
+		Column("COALESCE(SUM(CASE WHEN value<0 THEN -value ELSE 0 END),0) AS debits").
+		From(orm.TableName(&LogPO{})).
+		Where("created>=?", start).
+		And("created<?", end).
+		Group("type").
+		QueryObject(true, &totals)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range totals {
+		switch t.Type {
+		case TypeFreeze:
+			st.Freezes += t.Debits - t.Credits
+		case TypeUnfreeze:
+			st.Unfreezes += t.Credits - t.Debits
+		case TypeExpired:
+			st.Expired += t.Debits - t.Credits
+		default:
+			st.Credits += t.Credits
+			st.Debits += t.Debits
+		}
+	}
+	st.Closing = st.Opening + st.Credits - st.Debits - st.Freezes + st.Unfreezes - st.Expired
+
+	if _, err := m.db.Insert(st); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// [start, end) 之间所有日志的金额之和
+//
+// start 和 end 为零值表示不限制。
+func (m *Currency) logsTotal(start, end time.Time) (int64, error) {
+	sql := m.db.SQLBuilder().Select().
+		Column("COALESCE(SUM(value),0) AS total").
+		From(orm.TableName(&LogPO{}))
+	if !start.IsZero() {
+		sql.And("created>=?", start)
+	}
+	if !end.IsZero() {
+		sql.And("created<?", end)
+	}
+	return sql.QueryInt("total")
+}
+
+// StatementQuery 查询对账单的参数
+type StatementQuery struct {
+	query.Limit
+	Period Period    `query:"period,day"`
+	Start  time.Time `query:"start"`
+	End    time.Time `query:"end"`
+}
+
+func (q *StatementQuery) Filter(ctx *web.FilterContext) {
+	q.Limit.Filter(ctx)
+	ctx.Add(PeriodFilter("period", &q.Period))
+}
+
+func (m *Currency) statementsSQL(q *StatementQuery, asc bool) *sqlbuilder.SelectStmt {
+	sql := m.db.SQLBuilder().Select().
+		From(orm.TableName(&StatementPO{})).
+		Where("period=?", q.Period)
+	if asc {
+		sql.Asc("date")
+	} else {
+		sql.Desc("date")
+	}
+	if !q.Start.IsZero() {
+		sql.And("date>=?", q.Start)
+	}
+	if !q.End.IsZero() {
+		sql.And("date<?", q.End)
+	}
+	return sql
+}
+
+// GetStatements 查询对账单
+func (m *Currency) GetStatements(q *StatementQuery) (*query.Page[StatementPO], error) {
+	return query.Paging[StatementPO](&q.Limit, m.statementsSQL(q, false), nil)
+}
+
+// WriteStatementsCSV 将对账单以 CSV 格式写入 w
+//
+// 与 [Currency.GetStatements] 不同，会忽略 q 中的分页参数，按时间正序输出所有符合条件的记录。
+func (m *Currency) WriteStatementsCSV(w io.Writer, q *StatementQuery) error {
+	statements := make([]*StatementPO, 0, 100)
+	if _, err := m.statementsSQL(q, true).QueryObject(true, &statements); err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"date", "opening", "credits", "debits", "freezes", "unfreezes", "expired", "closing"}); err != nil {
+		return err
+	}
+	for _, st := range statements {
+		err := cw.Write([]string{
+			st.Date.Local().Format(q.Period.layout()),
+			strconv.FormatInt(st.Opening, 10),
+			strconv.FormatInt(st.Credits, 10),
+			strconv.FormatInt(st.Debits, 10),
+			strconv.FormatInt(st.Freezes, 10),
+			strconv.FormatInt(st.Unfreezes, 10),
+			strconv.FormatInt(st.Expired, 10),
+			strconv.FormatInt(st.Closing, 10),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// StatementMismatch 对账单的不一致
+type StatementMismatch struct {
+	Date     time.Time `json:"date,omitempty" yaml:"date,omitempty" cbor:"date,omitempty"` // 期初余额与上一期不一致的对账单，为零表示与总览表不一致。
+	Expected int64     `json:"expected" yaml:"expected" cbor:"expected"`                   // 上一期的期末余额或是总览表中可用余额之和
+	Actual   int64     `json:"actual" yaml:"actual" cbor:"actual"`                         // 当前期的期初余额或是根据对账单推算的可用余额之和
+}
+
+// CheckStatements 检测周期为 p 的对账单的一致性
+//
+// 每一期的期初余额应当与上一期的期末余额相同；
+// 最后一期的期末余额加上之后的日志金额，应当与总览表中所有用户的可用余额之和相同。
+// 返回所有不一致的记录，如果都一致，返回空值。
+func (m *Currency) CheckStatements(p Period) ([]*StatementMismatch, error) {
+	statements := make([]*StatementPO, 0, 100)
+	_, err := m.db.SQLBuilder().Select().
+		From(orm.TableName(&StatementPO{})).
+		Where("period=?", p).
+		Asc("date").
+		QueryObject(true, &statements)
+	if err != nil {
+		return nil, err
+	}
+
+	mismatches := make([]*StatementMismatch, 0, 10)
+	for i := 1; i < len(statements); i++ {
+		if prev, curr := statements[i-1], statements[i]; prev.Closing != curr.Opening {
+			mismatches = append(mismatches, &StatementMismatch{Date: curr.Date, Expected: prev.Closing, Actual: curr.Opening})
+		}
+	}
+
+	var actual int64
+	var after time.Time
+	if l := len(statements); l > 0 {
+		last := statements[l-1]
+		actual = last.Closing
+		after = p.next(last.Date)
+	}
+	rest, err := m.logsTotal(after, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	actual += rest
+
+	expected, err := m.db.SQLBuilder().Select().
+		Column("COALESCE(SUM(available),0) AS total").
+		From(orm.TableName(&overviewPO{})).
+		QueryInt("total")
+	if err != nil {
+		return nil, err
+	}
+	if expected != actual {
+		mismatches = append(mismatches, &StatementMismatch{Expected: expected, Actual: actual})
+	}
+
+	if len(mismatches) == 0 {
+		return nil, nil
+	}
+	return mismatches, nil
+}
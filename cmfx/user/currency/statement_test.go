@@ -0,0 +1,123 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package currency
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+
+	"github.com/issue9/cmfx/cmfx/initial/test"
+	"github.com/issue9/cmfx/cmfx/query"
+	"github.com/issue9/cmfx/cmfx/user/usertest"
+)
+
+func TestPeriod(t *testing.T) {
+	a := assert.New(t, false)
+
+	now := time.Date(2026, 1, 31, 10, 11, 12, 0, time.Local)
+	a.Equal(PeriodDay.start(now), time.Date(2026, 1, 31, 0, 0, 0, 0, time.Local)).
+		Equal(PeriodMonth.start(now), time.Date(2026, 1, 1, 0, 0, 0, 0, time.Local)).
+		Equal(PeriodDay.next(PeriodDay.start(now)), time.Date(2026, 2, 1, 0, 0, 0, 0, time.Local)).
+		Equal(PeriodMonth.next(PeriodMonth.start(now)), time.Date(2026, 2, 1, 0, 0, 0, 0, time.Local))
+}
+
+func TestCurrency_GenerateStatements(t *testing.T) {
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+	defer s.Close()
+
+	u := usertest.NewModule(s)
+	m := Install(u, "point")
+
+	u1, err := u.GetUserByUsername("u1")
+	a.NotError(err).NotNil(u1)
+
+	// 没有日志
+	a.NotError(m.GenerateStatements(time.Now()))
+	size, err := m.db.Where("1=1").Count(&StatementPO{})
+	a.NotError(err).Zero(size)
+
+	day1 := time.Date(2026, 1, 30, 10, 0, 0, 0, time.Local)
+	day3 := time.Date(2026, 2, 1, 10, 0, 0, 0, time.Local)
+	setCreated := func(created time.Time) {
+		_, err := m.db.SQLBuilder().Update().
+			Table(m.db.TablePrefix()+(&LogPO{}).TableName()).
+			Set("created", created).
+			Where("created>?", created).
+			Exec()
+		a.NotError(err)
+	}
+
+	a.NotError(m.Add(nil, u1.ID, 100, "+100", time.Time{}, "")).
+		NotError(m.Add(nil, u1.ID, 50, "+50", time.Time{}, "")).
+		NotError(m.Freeze(nil, u1, 20, "freeze", "")).
+		NotError(m.Unfreeze(nil, u1, 5, "unfreeze", ""))
+	setCreated(day1)
+	a.NotError(m.Del(nil, u1.ID, 30, "-30", ""))
+	setCreated(day3)
+
+	now := time.Date(2026, 2, 2, 1, 0, 0, 0, time.Local)
+	a.NotError(m.GenerateStatements(now))
+
+	p, err := m.GetStatements(&StatementQuery{Period: PeriodDay, Limit: query.Limit{Size: 10}})
+	a.NotError(err).Equal(p.Count, 3)
+	a.Equal(p.Current[2].Date.Unix(), PeriodDay.start(day1).Unix()).
+		Equal(p.Current[2].Opening, 0).
+		Equal(p.Current[2].Credits, 150).
+		Equal(p.Current[2].Freezes, 20).
+		Equal(p.Current[2].Unfreezes, 5).
+		Equal(p.Current[2].Closing, 135)
+	a.Equal(p.Current[1].Opening, 135).Equal(p.Current[1].Closing, 135)
+	a.Equal(p.Current[0].Opening, 135).
+		Equal(p.Current[0].Debits, 30).
+		Equal(p.Current[0].Closing, 105)
+
+	p, err = m.GetStatements(&StatementQuery{Period: PeriodMonth, Limit: query.Limit{Size: 10}})
+	a.NotError(err).Equal(p.Count, 1)
+	a.Equal(p.Current[0].Opening, 0).Equal(p.Current[0].Closing, 135)
+
+	// 重复生成
+	a.NotError(m.GenerateStatements(now))
+	size, err = m.db.Where("1=1").Count(&StatementPO{})
+	a.NotError(err).Equal(size, 4)
+
+	mismatches, err := m.CheckStatements(PeriodDay)
+	a.NotError(err).Empty(mismatches)
+	mismatches, err = m.CheckStatements(PeriodMonth)
+	a.NotError(err).Empty(mismatches)
+
+	// csv
+
+	buf := &bytes.Buffer{}
+	a.NotError(m.WriteStatementsCSV(buf, &StatementQuery{Period: PeriodDay}))
+	a.Equal(buf.String(), "date,opening,credits,debits,freezes,unfreezes,expired,closing\n"+
+		"2026-01-30,0,150,0,20,5,0,135\n"+
+		"2026-01-31,135,0,0,0,0,0,135\n"+
+		"2026-02-01,135,0,30,0,0,0,105\n")
+
+	// 不一致
+
+	_, err = m.db.SQLBuilder().Update().
+		Table(m.db.TablePrefix()+(&StatementPO{}).TableName()).
+		Set("opening", 100).
+		Where("period=?", PeriodDay).
+		And("date=?", PeriodDay.next(PeriodDay.start(day1))).
+		Exec()
+	a.NotError(err)
+	_, err = m.db.SQLBuilder().Update().
+		Table(m.db.TablePrefix()+(&overviewPO{}).TableName()).
+		Set("available", 1).
+		Where("uid=?", u1.ID).
+		Exec()
+	a.NotError(err)
+
+	mismatches, err = m.CheckStatements(PeriodDay)
+	a.NotError(err).Length(mismatches, 2)
+	a.Equal(mismatches[0].Expected, 135).Equal(mismatches[0].Actual, 100)
+	a.True(mismatches[1].Date.IsZero()).Equal(mismatches[1].Expected, 1).Equal(mismatches[1].Actual, 105)
+}
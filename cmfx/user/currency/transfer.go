@@ -0,0 +1,92 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package currency
+
+import (
+	"net/http"
+
+	"github.com/issue9/orm/v6"
+	"github.com/issue9/web"
+
+	"github.com/issue9/cmfx/cmfx"
+	"github.com/issue9/cmfx/cmfx/user"
+)
+
+// Transfer 从用户 from 向用户 to 转账
+//
+// tx 如果为空，会在一个新的事务中执行；
+// val 为 to 收到的金额；
+// fee 为手续费，从 from 额外扣除并进入系统账户 feeAccount，fee 不为零时 feeAccount 不能为空；
+// key 为幂等键，与 [Currency.Add] 中的相同；
+//
+// 会过期的金额不能用于转账；to 不存在或是已经被删除时返回 [cmfx.ErrNotFound]。
+func (m *Currency) Transfer(tx *orm.Tx, from, to int64, val, fee uint, feeAccount, memo, key string) error {
+	if fee > 0 && feeAccount == "" {
+		panic("参数 feeAccount 不能为空")
+	}
+
+	if from == to {
+		return ErrTransferToSelf()
+	}
+
+	if u, err := m.user.GetUser(to); err != nil {
+		return err
+	} else if u.State == user.StateDeleted {
+		return web.NewError(http.StatusNotFound, cmfx.ErrNotFound())
+	}
+
+	return m.idempotent(tx, key, from, TypeTransfer, -int64(val+fee), func(tx *orm.Tx) error {
+		return m.transfer(tx, from, to, val, fee, feeAccount, memo, key)
+	})
+}
+
+func (m *Currency) transfer(tx *orm.Tx, from, to int64, val, fee uint, feeAccount, memo, key string) error {
+	e := m.engine(tx)
+	v := int64(val)
+	total := v + int64(fee)
+
+	if replayed, err := m.replayed(e, key, from, TypeTransfer, -total); err != nil || replayed {
+		return err
+	}
+
+	src := &overviewPO{UID: from}
+	if found, err := e.Select(src); err != nil {
+		return err
+	} else if !found || (src.Available-src.Expire) < total {
+		return ErrBalanceNotEnough()
+	}
+
+	dst := &overviewPO{UID: to}
+	if found, err := e.Select(dst); err != nil {
+		return err
+	} else if !found {
+		dst = m.initOverview(tx, to)
+	}
+
+	src2 := &overviewPO{ID: src.ID, Available: src.Available - total}
+	if _, err := e.Update(src2, "available"); err != nil {
+		return err
+	}
+	dst2 := &overviewPO{ID: dst.ID, Available: dst.Available + v}
+	if _, err := e.Update(dst2, "available"); err != nil {
+		return err
+	}
+
+	logs := []*LogPO{
+		{UID: from, Before: src.Available, After: src2.Available, Value: -total, Memo: memo, Type: TypeTransfer, Key: idempotencyKey(key)},
+		{UID: to, Before: dst.Available, After: dst2.Available, Value: v, Memo: memo, Type: TypeTransfer},
+	}
+	for _, log := range logs {
+		if _, err := m.insertLog(tx, log); err != nil {
+			return err
+		}
+	}
+
+	entries := []entry{{userAccount(from), -total}, {userAccount(to), v}}
+	if fee > 0 {
+		entries = append(entries, entry{systemAccount(feeAccount), int64(fee)})
+	}
+	return m.post(e, TypeTransfer, memo, entries...)
+}
@@ -1,10 +1,14 @@
-// SPDX-FileCopyrightText: 2022-2025 caixw
+// SPDX-FileCopyrightText: 2022-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
 package user
 
 import (
+	"errors"
+
+	"github.com/issue9/cache"
+	"github.com/issue9/orm/v6"
 	"github.com/issue9/web"
 
 	"github.com/issue9/cmfx/cmfx"
@@ -16,3 +20,20 @@ func Install(mod *cmfx.Module) {
 		panic(web.SprintError(mod.Server().Locale().Printer(), true, err))
 	}
 }
+
+// Uninstall 删除由 [Install] 创建的数据表以及缓存中所有用户的令牌
+func Uninstall(mod *cmfx.Module) error {
+	users := make([]*User, 0, 100)
+	if _, err := mod.DB().SQLBuilder().Select().Column("no").From(orm.TableName(&User{})).QueryObject(false, &users); err != nil {
+		return err
+	}
+
+	store := newTokenStore(mod)
+	for _, u := range users {
+		if err := store.DeleteUID(u.GetUID()); err != nil && !errors.Is(err, cache.ErrCacheMiss()) {
+			return err
+		}
+	}
+
+	return mod.DB().Drop(&User{}, &logPO{})
+}
@@ -1,4 +1,4 @@
-// SPDX-FileCopyrightText: 2022-2024 caixw
+// SPDX-FileCopyrightText: 2022-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
@@ -6,8 +6,10 @@ package user
 
 import (
 	"testing"
+	"time"
 
 	"github.com/issue9/assert/v4"
+	"github.com/issue9/webuse/v7/middlewares/auth/token"
 
 	"github.com/issue9/cmfx/cmfx/initial/test"
 )
@@ -23,3 +25,28 @@ func TestInstall(t *testing.T) {
 	suite.TableExists(mod.ID() + "_users").
 		TableExists(mod.ID() + "_securitylogs")
 }
+
+func TestUninstall(t *testing.T) {
+	a := assert.New(t, false)
+	suite := test.NewSuite(a)
+	defer suite.Close()
+
+	mod := suite.NewModule("test")
+	Install(mod)
+
+	u := &User{NO: "u1", Username: "u1", Password: []byte("pwd")}
+	_, err := mod.DB().Insert(u)
+	a.NotError(err)
+	store := newTokenStore(mod)
+	a.NotError(store.Save("access", token.Item[*User]{UserData: u}, time.Hour)).
+		NotError(store.Save("refresh", token.Item[*User]{UserData: u, Access: "access"}, time.Hour))
+
+	a.NotError(Uninstall(mod))
+	suite.TableNotExists(mod.ID() + "_users").
+		TableNotExists(mod.ID() + "_securitylogs")
+
+	_, found, err := store.Get("access")
+	a.NotError(err).False(found)
+	_, found, err = store.Get("refresh")
+	a.NotError(err).False(found)
+}
@@ -0,0 +1,87 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package user
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/issue9/web"
+)
+
+// LoginStat 登录方式的登录次数统计
+//
+// 仅统计自服务启动以来的数据。
+type LoginStat struct {
+	Passport string // 登录方式，即 [Passport.ID]
+	Success  int64  // 登录成功的次数
+	Failure  int64  // 登录失败的次数
+}
+
+type loginCounter struct {
+	success atomic.Int64
+	failure atomic.Int64
+}
+
+type loginStats struct {
+	mux      sync.RWMutex
+	counters map[string]*loginCounter
+}
+
+func (s *loginStats) get(passport string) *loginCounter {
+	s.mux.RLock()
+	c, found := s.counters[passport]
+	s.mux.RUnlock()
+	if found {
+		return c
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if c, found = s.counters[passport]; !found {
+		c = &loginCounter{}
+		s.counters[passport] = c
+	}
+	return c
+}
+
+// CountLogin 统计登录接口的成功与失败次数的中间件
+//
+// passport 为登录方式的 ID，应用于各个 [Passport] 的登录接口，
+// 接口返回的状态码大于等于 400 即视为登录失败。
+func (m *Users) CountLogin(passport string) web.Middleware {
+	c := m.logins.get(passport)
+
+	return web.MiddlewareFunc(func(next web.HandlerFunc, _, _, _ string) web.HandlerFunc {
+		return func(ctx *web.Context) web.Responser {
+			ctx.OnExit(func(_ *web.Context, status int) {
+				if status >= http.StatusBadRequest {
+					c.failure.Add(1)
+				} else {
+					c.success.Add(1)
+				}
+			})
+			return next(ctx)
+		}
+	})
+}
+
+// LoginStats 各登录方式的登录次数统计
+//
+// 仅包含通过 [Users.CountLogin] 注册的登录方式，按 [LoginStat.Passport] 排序。
+func (m *Users) LoginStats() []*LoginStat {
+	m.logins.mux.RLock()
+	list := make([]*LoginStat, 0, len(m.logins.counters))
+	for id, c := range m.logins.counters {
+		list = append(list, &LoginStat{Passport: id, Success: c.success.Load(), Failure: c.failure.Load()})
+	}
+	m.logins.mux.RUnlock()
+
+	slices.SortFunc(list, func(a, b *LoginStat) int { return cmp.Compare(a.Passport, b.Passport) })
+	return list
+}
@@ -0,0 +1,43 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package user_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/mux/v9/header"
+	"github.com/issue9/web/server/servertest"
+
+	"github.com/issue9/cmfx/cmfx/initial/test"
+	"github.com/issue9/cmfx/cmfx/user"
+	"github.com/issue9/cmfx/cmfx/user/usertest"
+)
+
+func TestUsers_LoginStats(t *testing.T) {
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+	u := usertest.NewModule(s)
+
+	defer servertest.Run(a, s.Module().Server())()
+	defer s.Close()
+
+	a.Equal(u.LoginStats(), []*user.LoginStat{{Passport: "password"}})
+
+	usertest.GetToken(s, u)
+	s.Post("/user/passports/password/login", []byte(`{"username":"u1","password":"invalid"}`)).
+		Header(header.Accept, header.JSON).
+		Header(header.ContentType, header.JSON+"; charset=utf-8").
+		Do(nil).
+		Status(http.StatusUnauthorized)
+	s.Post("/user/passports/password/login", []byte(`{"username":"not-exists","password":"123"}`)).
+		Header(header.Accept, header.JSON).
+		Header(header.ContentType, header.JSON+"; charset=utf-8").
+		Do(nil).
+		Status(http.StatusUnauthorized)
+
+	a.Equal(u.LoginStats(), []*user.LoginStat{{Passport: "password", Success: 1, Failure: 2}})
+}
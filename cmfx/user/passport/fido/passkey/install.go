@@ -1,4 +1,4 @@
-// SPDX-FileCopyrightText: 2025 caixw
+// SPDX-FileCopyrightText: 2025-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
@@ -17,3 +17,8 @@ func Install(mod *cmfx.Module, tableName string) {
 		panic(web.SprintError(mod.Server().Locale().Printer(), true, err))
 	}
 }
+
+// Uninstall 删除由 [Install] 创建的数据表
+func Uninstall(mod *cmfx.Module, tableName string) error {
+	return utils.BuildDB(mod, tableName).Drop(&accountPO{})
+}
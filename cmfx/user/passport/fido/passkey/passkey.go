@@ -1,4 +1,4 @@
-// SPDX-FileCopyrightText: 2025 caixw
+// SPDX-FileCopyrightText: 2025-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
@@ -97,7 +97,7 @@ func Init(u *user.Users, id string, desc web.LocaleStringer, ttl time.Duration,
 				Response200(protocol.CredentialAssertion{}).
 				Path("username", openapi.TypeString, web.Phrase("username"), nil)
 		})).
-		Post("/login/{username}", p.loginFinish, u.Module().API(func(o *openapi.Operation) {
+		Post("/login/{username}", p.loginFinish, u.CountLogin(id), u.Module().API(func(o *openapi.Operation) {
 			o.Tag("auth").Desc(web.Phrase("passkey login for %s api", id), nil).
 				Body(protocol.CredentialAssertionResponse{}, false, nil, nil).
 				ResponseEmpty("201")
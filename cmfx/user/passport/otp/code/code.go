@@ -1,4 +1,4 @@
-// SPDX-FileCopyrightText: 2024-2025 caixw
+// SPDX-FileCopyrightText: 2024-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
@@ -71,7 +71,7 @@ func Init(
 	rate := utils.BuildRate(user, id)
 
 	user.Module().Router().Prefix(prefix).
-		Post("/login", c.postLogin, rate, cmfx.Unlimit(user.Module().Server()), user.Module().API(func(o *openapi.Operation) {
+		Post("/login", c.postLogin, rate, cmfx.Unlimit(user.Module().Server()), user.CountLogin(id), user.Module().API(func(o *openapi.Operation) {
 			o.Tag("auth").
 				Desc(web.Phrase("login by %s api", id), nil).
 				Body(accountTO{}, false, nil, nil).
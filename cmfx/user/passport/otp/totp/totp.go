@@ -1,4 +1,4 @@
-// SPDX-FileCopyrightText: 2024-2025 caixw
+// SPDX-FileCopyrightText: 2024-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
@@ -55,7 +55,7 @@ func Init(user *user.Users, id string, desc web.LocaleStringer) user.Passport {
 
 	prefix := utils.BuildPrefix(user, id)
 	rate := utils.BuildRate(user, id)
-	user.Module().Router().Post(prefix+"/login", p.login, rate, cmfx.Unlimit(user.Module().Server()), user.Module().API(func(o *openapi.Operation) {
+	user.Module().Router().Post(prefix+"/login", p.login, rate, cmfx.Unlimit(user.Module().Server()), user.CountLogin(id), user.Module().API(func(o *openapi.Operation) {
 		o.Tag("auth").
 			Desc(web.Phrase("login by %s api", id), nil).
 			Body(accountTO{}, false, nil, nil).
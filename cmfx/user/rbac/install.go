@@ -1,10 +1,14 @@
-// SPDX-FileCopyrightText: 2022-2024 caixw
+// SPDX-FileCopyrightText: 2022-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
 package rbac
 
 import (
+	"slices"
+	"strings"
+
+	"github.com/issue9/orm/v6"
 	"github.com/issue9/web"
 
 	"github.com/issue9/cmfx/cmfx"
@@ -15,3 +19,40 @@ func Install(mod *cmfx.Module) {
 		panic(web.SprintError(mod.Server().Locale().Printer(), true, err))
 	}
 }
+
+// Uninstall 删除由 [Install] 创建的数据表
+func Uninstall(mod *cmfx.Module) error {
+	return mod.DB().Drop(&rolePO{}, &linkPO{})
+}
+
+// DeleteResourceGroup 从所有角色中删除资源组 gid 下的资源
+//
+// mod 为调用 [New] 时传入的模块；
+// gid 为资源组的 ID，即 [RBAC.NewResourceGroup] 的 id 参数。
+//
+// 资源组本身只存在于内存中，卸载模块时需要调用此方法清除数据库中残留的资源 ID。
+func DeleteResourceGroup(mod *cmfx.Module, gid string) error {
+	prefix := gid + "_"
+
+	return mod.DB().DoTransaction(func(tx *orm.Tx) error {
+		e := mod.Engine(tx)
+
+		roles := make([]*rolePO, 0, 100)
+		if _, err := e.Where("1=1").Select(true, &roles); err != nil {
+			return err
+		}
+
+		for _, r := range roles {
+			size := len(r.Resources)
+			r.Resources = slices.DeleteFunc(r.Resources, func(res string) bool { return strings.HasPrefix(res, prefix) })
+			if len(r.Resources) == size {
+				continue
+			}
+
+			if _, err := e.Update(r, "resources"); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -1,4 +1,4 @@
-// SPDX-FileCopyrightText: 2022-2024 caixw
+// SPDX-FileCopyrightText: 2022-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
@@ -10,6 +10,7 @@ import (
 	"github.com/issue9/assert/v4"
 
 	"github.com/issue9/cmfx/cmfx/initial/test"
+	"github.com/issue9/cmfx/cmfx/types"
 )
 
 func TestInstall(t *testing.T) {
@@ -23,3 +24,40 @@ func TestInstall(t *testing.T) {
 	suite.TableExists(mod.ID() + "_rbac_links").
 		TableExists(mod.ID() + "_rbac_roles")
 }
+
+func TestUninstall(t *testing.T) {
+	a := assert.New(t, false)
+	suite := test.NewSuite(a)
+	defer suite.Close()
+
+	mod := suite.NewModule("rbac")
+	Install(mod)
+
+	a.NotError(Uninstall(mod))
+	suite.TableNotExists(mod.ID() + "_rbac_links").
+		TableNotExists(mod.ID() + "_rbac_roles")
+}
+
+func TestDeleteResourceGroup(t *testing.T) {
+	a := assert.New(t, false)
+	suite := test.NewSuite(a)
+	defer suite.Close()
+
+	mod := suite.NewModule("rbac")
+	Install(mod)
+
+	_, err := mod.DB().Insert(&rolePO{GID: "0", ID: "r1", Resources: types.Strings{"g1_r1", "g1_r2", "g2_r1", "g11_r1"}})
+	a.NotError(err)
+	_, err = mod.DB().Insert(&rolePO{GID: "0", ID: "r2", Resources: types.Strings{"g2_r1"}})
+	a.NotError(err)
+
+	a.NotError(DeleteResourceGroup(mod, "g1"))
+
+	r1 := &rolePO{ID: "r1"}
+	found, err := mod.DB().Select(r1)
+	a.NotError(err).True(found).Equal(r1.Resources, types.Strings{"g2_r1", "g11_r1"})
+
+	r2 := &rolePO{ID: "r2"}
+	found, err = mod.DB().Select(r2)
+	a.NotError(err).True(found).Equal(r2.Resources, types.Strings{"g2_r1"})
+}
@@ -1,12 +1,17 @@
-// SPDX-FileCopyrightText: 2024 caixw
+// SPDX-FileCopyrightText: 2024-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
 package settings
 
 import (
+	"database/sql"
+	"errors"
+	"strconv"
+
 	"github.com/issue9/conv"
 	"github.com/issue9/orm/v6"
+	"github.com/issue9/orm/v6/fetch"
 	"github.com/issue9/web"
 
 	"github.com/issue9/cmfx/cmfx"
@@ -14,13 +19,25 @@ import (
 
 func Install(mod *cmfx.Module, tableName string) *Settings {
 	db := buildDB(mod, tableName)
-	if err := db.Create(&settingPO{}); err != nil {
+	if err := db.Create(&settingPO{}, &versionPO{}, &historyPO{}); err != nil {
 		panic(web.SprintError(mod.Server().Locale().Printer(), true, err))
 	}
 
 	return New(mod, tableName)
 }
 
+// InstallHistory 创建保存历史记录的数据表
+//
+// 用于在由旧版本 [Install] 创建的数据表上添加历史记录的功能。
+func InstallHistory(mod *cmfx.Module, tableName string) error {
+	return buildDB(mod, tableName).Create(&versionPO{}, &historyPO{})
+}
+
+// UninstallHistory 删除由 [InstallHistory] 创建的数据表
+func UninstallHistory(mod *cmfx.Module, tableName string) error {
+	return buildDB(mod, tableName).Drop(&versionPO{}, &historyPO{})
+}
+
 // InstallObject 向数据表中安装一个设置对象
 //
 // preset 默认值；
@@ -38,3 +55,28 @@ func InstallObject[T any](s *Settings, id string, preset *T) error {
 	}
 	return s.db.InsertMany(50, items...)
 }
+
+// Uninstall 删除由 [Install] 创建的数据表以及相关的缓存
+func Uninstall(mod *cmfx.Module, tableName string) error {
+	s := New(mod, tableName)
+
+	rows, err := s.db.SQLBuilder().Select().Distinct().Column("uid").From(orm.TableName(&settingPO{})).Query()
+	if err != nil {
+		return err
+	}
+	uids, err := fetch.Column[sql.NullInt64](false, "uid", rows)
+	if err = errors.Join(err, rows.Close()); err != nil {
+		return err
+	}
+
+	for _, uid := range uids {
+		if !uid.Valid {
+			continue
+		}
+		if err := s.c.Delete(strconv.FormatInt(uid.Int64, 10)); err != nil {
+			return err
+		}
+	}
+
+	return s.db.Drop(&settingPO{}, &versionPO{}, &historyPO{})
+}
@@ -1,4 +1,4 @@
-// SPDX-FileCopyrightText: 2024 caixw
+// SPDX-FileCopyrightText: 2024-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
@@ -19,7 +19,26 @@ func TestInstall(t *testing.T) {
 	mod := s.NewModule("test")
 	Install(mod, "settings")
 
-	s.TableExists("test_settings")
+	s.TableExists("test_settings").
+		TableExists("test_settings_versions").
+		TableExists("test_settings_histories")
+}
+
+func TestInstallHistory(t *testing.T) {
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+	mod := s.NewModule("test")
+	a.NotError(buildDB(mod, "settings").Create(&settingPO{}))
+	s.TableNotExists("test_settings_histories")
+
+	a.NotError(InstallHistory(mod, "settings"))
+	s.TableExists("test_settings_versions").
+		TableExists("test_settings_histories")
+
+	a.NotError(UninstallHistory(mod, "settings"))
+	s.TableNotExists("test_settings_versions").
+		TableNotExists("test_settings_histories").
+		TableExists("test_settings")
 }
 
 func TestInstallObject(t *testing.T) {
@@ -43,3 +62,19 @@ func TestInstallObject(t *testing.T) {
 	found, err = ss.db.Select(f2)
 	a.NotError(err).True(found)
 }
+
+func TestUninstall(t *testing.T) {
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+	mod := s.NewModule("test")
+	Install(mod, "settings")
+	ss := New(mod, "settings")
+	a.NotError(InstallObject(ss, "opt", &options{F1: "f1"}))
+	a.NotError(ss.c.Set("0", &options{F1: "f1"}, 0))
+
+	a.NotError(Uninstall(mod, "settings"))
+	s.TableNotExists("test_settings").
+		TableNotExists("test_settings_versions").
+		TableNotExists("test_settings_histories")
+	a.False(ss.c.Exists("0"))
+}
@@ -1,4 +1,4 @@
-// SPDX-FileCopyrightText: 2024 caixw
+// SPDX-FileCopyrightText: 2024-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
@@ -26,3 +26,32 @@ func (l *settingPO) BeforeInsert() error {
 }
 
 func (l *settingPO) TableName() string { return `` }
+
+// 设置对象的历史版本
+//
+// 每次修改设置对象时产生一条记录，被修改的设置项记录在 historyPO 中。
+// 版本号在同一用户的同一设置对象中唯一，多个进程同时写入时，后提交的操作会因唯一约束而失败。
+type versionPO struct {
+	ID      int64         `orm:"name(id);ai"`
+	Group   string        `orm:"name(group);len(20);unique(u_version)"`
+	UID     sql.NullInt64 `orm:"name(uid);unique(u_version)"`
+	Version int64         `orm:"name(version);unique(u_version)"`
+	Actor   int64         `orm:"name(actor)"` // 执行修改操作的用户
+	Created time.Time     `orm:"name(created)"`
+}
+
+func (l *versionPO) TableName() string { return `_versions` }
+
+// 设置项的历史记录
+//
+// 被修改的设置项的旧值，以 versionPO 中的版本号保存在此表中。
+type historyPO struct {
+	ID      int64         `orm:"name(id);ai"`
+	Group   string        `orm:"name(group);len(20);index(i_history_group_uid);unique(u_history_version)"`
+	UID     sql.NullInt64 `orm:"name(uid);index(i_history_group_uid);unique(u_history_version)"`
+	Version int64         `orm:"name(version);unique(u_history_version)"`
+	Key     string        `orm:"name(key);len(20);unique(u_history_version)"`
+	Value   string        `orm:"name(value);nullable"` // 修改之前的值
+}
+
+func (l *historyPO) TableName() string { return `_histories` }
@@ -1,4 +1,4 @@
-// SPDX-FileCopyrightText: 2024-2025 caixw
+// SPDX-FileCopyrightText: 2024-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
@@ -13,6 +13,7 @@ import (
 	"reflect"
 	"slices"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/issue9/cache"
@@ -32,7 +33,7 @@ type Sanitizer interface {
 //
 // T 为实际的设置对象，必须得是一个结构体类型。
 // 如果 T 的指针还实现了 [Sanitizer] 接口，那么在加载或是写入数据源成功之后会调用该接口对数据进行修正。
-// 用户对 T 的修改应该及时采用 [Object.Put] 写入数据源，否则在重启后这些修改不会生效。
+// 用户对 T 的修改应该及时采用 [Object.Set] 写入数据源，否则在重启后这些修改不会生效。
 //
 // 其每一个公开字段在数据库中表示为一条记录。如果要改变在数据库对应的字段名，
 // 可以为每一个字段添加 setting 标签，比如：
@@ -45,9 +46,11 @@ type Sanitizer interface {
 // 如果字段是非内置的类型，可以实现 json 的相关接口实现自定义的存储和读取功能。
 type Object[T any] struct {
 	id     string
+	title  web.LocaleStringer
 	s      *Settings
 	preset []*settingPO // 保存着从数据库中加载的默认用户的设置对象
 	ttl    time.Duration
+	mux    sync.Mutex // 保证写入操作的版本号不重复
 }
 
 func checkObjectType[T any]() {
@@ -59,8 +62,9 @@ func checkObjectType[T any]() {
 // LoadObject 从 [Settings] 加载设置对象的数据
 //
 // id 表示当前设置对象的 ID，每一个设置象需要有一个唯一的 id；
-func LoadObject[T any](s *Settings, id string, ttl time.Duration) (*Object[T], error) {
-	if slices.Index(s.objects, id) >= 0 {
+// title 为设置对象的名称，用于 [Settings.Handle] 生成的接口和 RBAC 资源；
+func LoadObject[T any](s *Settings, id string, title web.LocaleStringer, ttl time.Duration) (*Object[T], error) {
+	if slices.ContainsFunc(s.objects, func(o object) bool { return o.ID() == id }) {
 		panic(fmt.Sprintf("已经存在相同的 ID：%s", id))
 	}
 	checkObjectType[T]()
@@ -74,14 +78,48 @@ func LoadObject[T any](s *Settings, id string, ttl time.Duration) (*Object[T], e
 		panic(fmt.Sprintf("设置对象 %s 未安装", id))
 	}
 
-	s.objects = append(s.objects, id)
-
-	return &Object[T]{
+	obj := &Object[T]{
 		s:      s,
 		id:     id,
+		title:  title,
 		preset: ss,
 		ttl:    ttl,
-	}, nil
+	}
+	s.objects = append(s.objects, obj)
+	return obj, nil
+}
+
+// ID 设置对象的 ID
+func (obj *Object[T]) ID() string { return obj.id }
+
+// Title 设置对象的名称
+func (obj *Object[T]) Title() web.LocaleStringer { return obj.title }
+
+// Schema 生成设置对象的 JSON Schema
+//
+// 返回的内容主要描述各字段的类型。如果 *T 实现了 [web.Filter]，会以 T 的零值调用一次该接口，
+// 未通过验证的顶层字段将出现在 [Schema.Required] 中，其错误信息作为该字段的 [Schema.Description]。
+// 零值可以通过的约束，比如取值范围、长度和枚举等，不会体现在返回的内容中；
+// 嵌套对象中字段的错误也不会被标记。实际的验证以提交时的 [web.Filter] 为准。
+func (obj *Object[T]) Schema(ctx *web.Context) *Schema {
+	s := newSchema(ctx.LocalePrinter(), reflect.TypeFor[T]())
+	s.Title = obj.title.LocaleString(ctx.LocalePrinter())
+
+	var zero T
+	if f, ok := any(&zero).(web.Filter); ok {
+		fc := ctx.NewFilterContext(false)
+		f.Filter(fc)
+		if p, ok := fc.Problem(cmfx.BadRequestInvalidBody).(*web.Problem); ok {
+			for _, param := range p.Params {
+				if item, found := s.Properties[param.Name]; found {
+					item.Description = param.Reason
+					s.Required = append(s.Required, param.Name)
+				}
+			}
+		}
+	}
+
+	return s
 }
 
 // Get 加载用户 uid 的配置项
@@ -115,33 +153,247 @@ func (obj *Object[T]) Get(uid int64) (*T, error) {
 }
 
 // Set 保存 o 的设置对象
-func (obj *Object[T]) Set(uid int64, o *T) error {
+//
+// actor 为执行修改操作的用户，被修改的设置项的旧值会以一个新的版本号记录在历史记录中，
+// 如果没有任何设置项被修改，则不会产生新的版本。
+//
+// 同一进程内的写入操作是串行的；多个进程同时写入同一用户的设置对象并产生新的版本时，
+// 版本号由同一用户的版本记录分配，后提交的操作会因为版本号重复而返回错误，整个操作不会生效。
+func (obj *Object[T]) Set(actor, uid int64, o *T) error {
 	mods, err := toModels(o, uid, obj.id)
 	if err != nil {
 		return err
 	}
 
+	obj.mux.Lock()
+	defer obj.mux.Unlock()
+
+	err = obj.s.db.DoTransaction(func(tx *orm.Tx) error {
+		ss := make([]*settingPO, 0, 10)
+		size, err := tx.Where("uid=?", uid).And("{group}=?", obj.id).Select(true, &ss)
+		if err != nil {
+			return err
+		}
+
+		olds := ss
+		if size == 0 {
+			olds = obj.preset
+		}
+		if err := obj.addHistories(tx, actor, uid, olds, mods); err != nil {
+			return err
+		}
+
+		// NOTE: 不能采用 tx.Save，它会先用数据库中的旧值覆盖 mod 的内容。
+		for _, mod := range mods {
+			if slices.ContainsFunc(ss, func(s *settingPO) bool { return s.Key == mod.Key }) {
+				_, err = tx.Where("uid=?", uid).And("{group}=?", obj.id).And("{key}=?", mod.Key).
+					Update(&settingPO{Value: mod.Value}, "value")
+			} else {
+				_, err = tx.Insert(mod)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
 	if uid == obj.s.presetUID {
 		obj.preset = mods
 	}
+	return obj.s.c.Set(strconv.FormatInt(uid, 10), *o, obj.ttl)
+}
 
-	if err := obj.s.c.Set(strconv.FormatInt(uid, 10), *o, obj.ttl); err != nil {
+// 将 mods 中与 olds 相比值有变化的设置项的旧值写入历史记录
+//
+// 不存在于 olds 中的设置项没有旧值可以记录，比如设置对象中新加的字段，会被忽略。
+func (obj *Object[T]) addHistories(tx *orm.Tx, actor, uid int64, olds, mods []*settingPO) error {
+	hs := make([]orm.TableNamer, 0, len(mods))
+	for _, mod := range mods {
+		index := slices.IndexFunc(olds, func(s *settingPO) bool { return s.Key == mod.Key })
+		if index < 0 || olds[index].Value == mod.Value {
+			continue
+		}
+
+		hs = append(hs, &historyPO{
+			Group: obj.id,
+			UID:   mod.UID,
+			Key:   mod.Key,
+			Value: olds[index].Value,
+		})
+	}
+	if len(hs) == 0 {
+		return nil
+	}
+
+	v, err := tx.SQLBuilder().Select().Column("COALESCE(MAX(version),0) AS v").
+		From(orm.TableName(&versionPO{})).
+		Where("uid=?", uid).And("{group}=?", obj.id).
+		QueryInt("v")
+	if err != nil {
+		return err
+	}
+	v++
+
+	// 并发写入时，版本记录的唯一约束保证同一个版本号只能被一个操作使用。
+	_, err = tx.Insert(&versionPO{
+		Group:   obj.id,
+		UID:     sql.NullInt64{Valid: true, Int64: uid},
+		Version: v,
+		Actor:   actor,
+		Created: time.Now(),
+	})
+	if err != nil {
 		return err
 	}
 
-	err = obj.s.db.DoTransaction(func(tx *orm.Tx) error {
-		for _, mod := range mods {
-			if _, _, err := tx.Save(mod, "value"); err != nil {
-				return errors.Join(err, tx.Rollback())
+	for _, h := range hs {
+		h.(*historyPO).Version = v
+	}
+	return tx.InsertMany(50, hs...)
+}
+
+// Version 设置对象的历史版本
+type Version struct {
+	Version int64             `json:"version" yaml:"version" cbor:"version"`
+	Actor   int64             `json:"actor" yaml:"actor" cbor:"actor"`       // 执行修改操作的用户
+	Created time.Time         `json:"created" yaml:"created" cbor:"created"` // 修改时间
+	Values  map[string]string `json:"values" yaml:"values" cbor:"values"`    // 被修改的设置项在修改之前的值，以 JSON 格式保存。
+}
+
+// Versions 返回用户 uid 的设置对象的所有历史版本
+//
+// 按版本号从大到小排列。
+func (obj *Object[T]) Versions(uid int64) ([]*Version, error) {
+	pos := make([]*versionPO, 0, 20)
+	_, err := obj.s.db.SQLBuilder().Select().Columns("*").From(orm.TableName(&versionPO{})).
+		Where("uid=?", uid).And("{group}=?", obj.id).
+		Desc("version").
+		QueryObject(true, &pos)
+	if err != nil {
+		return nil, err
+	}
+
+	hs := make([]*historyPO, 0, len(pos)*2)
+	_, err = obj.s.db.SQLBuilder().Select().Columns("*").From(orm.TableName(&historyPO{})).
+		Where("uid=?", uid).And("{group}=?", obj.id).
+		QueryObject(true, &hs)
+	if err != nil {
+		return nil, err
+	}
+
+	vs := make([]*Version, 0, len(pos))
+	for _, po := range pos {
+		v := &Version{Version: po.Version, Actor: po.Actor, Created: po.Created, Values: make(map[string]string, 5)}
+		for _, h := range hs {
+			if h.Version == po.Version {
+				v.Values[h.Key] = h.Value
 			}
 		}
+		vs = append(vs, v)
+	}
+	return vs, nil
+}
+
+// Rollback 将用户 uid 的设置对象回滚到 version 版本修改之前的状态
+//
+// 回滚操作本身也会以新的版本号记录在历史记录中。
+// 如果 version 不存在，返回 [cmfx.ErrNotFound]。
+func (obj *Object[T]) Rollback(actor, uid, version int64) error {
+	hs := make([]*historyPO, 0, 20)
+	_, err := obj.s.db.SQLBuilder().Select().Columns("*").From(orm.TableName(&historyPO{})).
+		Where("uid=?", uid).And("{group}=?", obj.id).And("version>=?", version).
+		Asc("version").
+		QueryObject(true, &hs)
+	if err != nil {
+		return err
+	}
+	if len(hs) == 0 || hs[0].Version != version {
+		return cmfx.ErrNotFound()
+	}
+
+	curr := make([]*settingPO, 0, 10)
+	size, err := obj.s.db.Where("uid=?", uid).And("{group}=?", obj.id).Select(true, &curr)
+	if err != nil {
+		return err
+	}
+	if size == 0 {
+		curr = obj.preset
+	}
+
+	// 每个设置项在 version 之后第一次被修改之前的值，即为 version 之前的值。
+	ss := make([]*settingPO, 0, len(curr))
+	for _, s := range curr {
+		val := s.Value
+		if index := slices.IndexFunc(hs, func(h *historyPO) bool { return h.Key == s.Key }); index >= 0 {
+			val = hs[index].Value
+		}
+		ss = append(ss, &settingPO{Group: s.Group, Key: s.Key, UID: s.UID, Value: val})
+	}
+
+	var o T
+	if err := obj.fromModels(ss, &o); err != nil {
+		return err
+	}
+	return obj.Set(actor, uid, &o)
+}
+
+// Reset 将用户 uid 的设置对象重置为默认值
+//
+// 普通用户会删除其所有的设置项，之后采用默认用户的设置对象，默认用户的修改也会同步体现；
+// 默认用户则恢复到第一个版本修改之前的值，即安装时的值。
+// 重置操作也会以新的版本号记录在历史记录中。
+func (obj *Object[T]) Reset(actor, uid int64) error {
+	if uid != obj.s.presetUID {
+		return obj.reset(actor, uid)
+	}
+
+	v, err := obj.s.db.SQLBuilder().Select().Column("COALESCE(MIN(version),0) AS v").
+		From(orm.TableName(&versionPO{})).
+		Where("uid=?", uid).And("{group}=?", obj.id).
+		QueryInt("v")
+	if err != nil {
+		return err
+	}
+	if v == 0 { // 从未修改过
 		return nil
+	}
+	return obj.Rollback(actor, uid, v)
+}
+
+// 删除普通用户 uid 的设置项，与默认用户的值不同的设置项会记录在历史记录中。
+func (obj *Object[T]) reset(actor, uid int64) error {
+	obj.mux.Lock()
+	defer obj.mux.Unlock()
+
+	err := obj.s.db.DoTransaction(func(tx *orm.Tx) error {
+		ss := make([]*settingPO, 0, 10)
+		size, err := tx.Where("uid=?", uid).And("{group}=?", obj.id).Select(true, &ss)
+		if err != nil {
+			return err
+		}
+		if size == 0 { // 已经在使用默认值
+			return nil
+		}
+
+		mods := make([]*settingPO, 0, len(obj.preset))
+		for _, p := range obj.preset {
+			mods = append(mods, &settingPO{Group: p.Group, Key: p.Key, UID: sql.NullInt64{Valid: true, Int64: uid}, Value: p.Value})
+		}
+		if err := obj.addHistories(tx, actor, uid, ss, mods); err != nil {
+			return err
+		}
+
+		_, err = tx.Where("uid=?", uid).And("{group}=?", obj.id).Delete(&settingPO{})
+		return err
 	})
 	if err != nil {
 		return err
 	}
-
-	return nil
+	return obj.s.c.Delete(strconv.FormatInt(uid, 10))
 }
 
 // HandleGet 用于处理 Get 的 HTTP 请求
@@ -154,18 +406,63 @@ func (obj *Object[T]) HandleGet(ctx *web.Context, uid int64) web.Responser {
 }
 
 // HandlePut 用于处理 Put 的 HTTP 请求
-func (obj *Object[T]) HandlePut(ctx *web.Context, uid int64) web.Responser {
+//
+// actor 为执行修改操作的用户。
+func (obj *Object[T]) HandlePut(ctx *web.Context, actor, uid int64) web.Responser {
 	var data T
 	if resp := ctx.Read(true, &data, cmfx.BadRequestInvalidBody); resp != nil {
 		return resp
 	}
 
-	if err := obj.Set(uid, &data); err != nil {
+	if err := obj.Set(actor, uid, &data); err != nil {
 		return ctx.Error(err, "")
 	}
 	return web.NoContent()
 }
 
+// HandleGetVersions 用于处理 Versions 的 HTTP 请求
+func (obj *Object[T]) HandleGetVersions(ctx *web.Context, uid int64) web.Responser {
+	vs, err := obj.Versions(uid)
+	if err != nil {
+		return ctx.Error(err, "")
+	}
+	return web.OK(vs)
+}
+
+// HandleRollback 用于处理 Rollback 的 HTTP 请求
+//
+// actor 为执行回滚操作的用户；versionKey 为路径中表示版本号的参数名称。
+func (obj *Object[T]) HandleRollback(ctx *web.Context, actor, uid int64, versionKey string) web.Responser {
+	version, resp := ctx.PathID(versionKey, cmfx.NotFoundInvalidPath)
+	if resp != nil {
+		return resp
+	}
+
+	switch err := obj.Rollback(actor, uid, version); {
+	case errors.Is(err, cmfx.ErrNotFound()):
+		return ctx.NotFound()
+	case err != nil:
+		return ctx.Error(err, "")
+	default:
+		return web.NoContent()
+	}
+}
+
+// HandleReset 用于处理 Reset 的 HTTP 请求
+//
+// actor 为执行重置操作的用户。
+func (obj *Object[T]) HandleReset(ctx *web.Context, actor, uid int64) web.Responser {
+	if err := obj.Reset(actor, uid); err != nil {
+		return ctx.Error(err, "")
+	}
+	return web.NoContent()
+}
+
+func (obj *Object[T]) zero() any {
+	var v T
+	return v
+}
+
 // 将 o 转换为 []*modelSetting
 func toModels[T any](o *T, uid int64, g string) ([]*settingPO, error) {
 	rv := reflect.ValueOf(o).Elem()
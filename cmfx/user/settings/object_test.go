@@ -1,16 +1,20 @@
-// SPDX-FileCopyrightText: 2024-2025 caixw
+// SPDX-FileCopyrightText: 2024-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
 package settings
 
 import (
+	"database/sql"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/issue9/assert/v4"
+	"github.com/issue9/web"
 
+	"github.com/issue9/cmfx/cmfx"
 	"github.com/issue9/cmfx/cmfx/initial/test"
 )
 
@@ -37,14 +41,14 @@ func TestObject(t *testing.T) {
 	// LoadObject
 
 	a.PanicString(func() {
-		_, _ = LoadObject[*options](ss, "opt", time.Minute*5)
+		_, _ = LoadObject[*options](ss, "opt", web.Phrase("opt"), time.Minute*5)
 	}, "T 的约束必须是结构体")
 
 	a.PanicString(func() {
-		_, _ = LoadObject[options](ss, "not-exists", time.Minute*5)
+		_, _ = LoadObject[options](ss, "not-exists", web.Phrase("not-exists"), time.Minute*5)
 	}, "设置对象 not-exists 未安装")
 
-	obj, err := LoadObject[options](ss, "opt", time.Minute*5)
+	obj, err := LoadObject[options](ss, "opt", web.Phrase("opt"), time.Minute*5)
 	a.NotError(err).NotNil(obj).
 		NotNil(obj.preset)
 
@@ -60,7 +64,7 @@ func TestObject(t *testing.T) {
 	// Object.Set
 
 	opt.F2 = 1
-	a.NotError(obj.Set(1, opt))
+	a.NotError(obj.Set(2, 1, opt))
 	opt, err = obj.Get(1)
 	a.NotError(err).NotNil(opt).
 		Equal(opt.F2, 1)
@@ -68,6 +72,166 @@ func TestObject(t *testing.T) {
 	a.NotError(err).Equal(size, 1) // 已入数据库
 }
 
+func TestObject_Rollback(t *testing.T) {
+	const tableName = "setting"
+
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+	mod := s.NewModule("mod")
+	Install(mod, tableName)
+
+	ss := New(mod, tableName)
+	a.NotError(InstallObject(ss, "opt", &options{F2: 2, F1: "f1"}))
+	obj, err := LoadObject[options](ss, "opt", web.Phrase("opt"), time.Minute*5)
+	a.NotError(err).NotNil(obj)
+
+	vs, err := obj.Versions(1)
+	a.NotError(err).Empty(vs)
+
+	// v1: 从默认值修改 F2
+	a.NotError(obj.Set(10, 1, &options{F1: "f1", F2: 3}))
+	// v2: 修改 F1 和 F2
+	a.NotError(obj.Set(11, 1, &options{F1: "v2", F2: 4}))
+	// 未修改任何值，不产生新的版本。
+	a.NotError(obj.Set(11, 1, &options{F1: "v2", F2: 4}))
+
+	vs, err = obj.Versions(1)
+	a.NotError(err).Length(vs, 2)
+	a.Equal(vs[0].Version, 2).
+		Equal(vs[0].Actor, 11).
+		Equal(vs[0].Values, map[string]string{"f1": `"f1"`, "F2": "3"})
+	a.Equal(vs[1].Version, 1).
+		Equal(vs[1].Actor, 10).
+		Equal(vs[1].Values, map[string]string{"F2": "2"})
+
+	// 其它用户不受影响
+	vs, err = obj.Versions(2)
+	a.NotError(err).Empty(vs)
+
+	a.Equal(obj.Rollback(12, 1, 100), cmfx.ErrNotFound())
+
+	// 回滚到 v1 之前，即默认值。
+	a.NotError(obj.Rollback(12, 1, 1))
+	opt, err := obj.Get(1)
+	a.NotError(err).Equal(opt, &options{F1: "f1", F2: 2})
+	vs, err = obj.Versions(1)
+	a.NotError(err).Length(vs, 3).
+		Equal(vs[0].Version, 3).
+		Equal(vs[0].Actor, 12).
+		Equal(vs[0].Values, map[string]string{"f1": `"v2"`, "F2": "4"})
+
+	// 回滚到 v2 之前
+	a.NotError(obj.Rollback(12, 1, 2))
+	opt, err = obj.Get(1)
+	a.NotError(err).Equal(opt, &options{F1: "f1", F2: 3})
+
+	// 回滚操作本身也可以回滚
+	a.NotError(obj.Rollback(12, 1, 3))
+	opt, err = obj.Get(1)
+	a.NotError(err).Equal(opt, &options{F1: "v2", F2: 4})
+}
+
+func TestObject_Set_history(t *testing.T) {
+	const tableName = "setting"
+
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+	mod := s.NewModule("mod")
+	Install(mod, tableName)
+
+	ss := New(mod, tableName)
+	a.NotError(InstallObject(ss, "opt", &options{F2: 2, F1: "f1"}))
+
+	// 模拟设置对象中新加的字段，默认用户没有该设置项。
+	_, err := ss.db.Where("{group}=?", "opt").And("{key}=?", "F5").Delete(&settingPO{})
+	a.NotError(err)
+	obj, err := LoadObject[options](ss, "opt", web.Phrase("opt"), time.Minute*5)
+	a.NotError(err).NotNil(obj)
+
+	a.NotError(obj.Set(10, 1, &options{F1: "f1", F2: 3, F5: "f5"}))
+	vs, err := obj.Versions(1)
+	a.NotError(err).Length(vs, 1).
+		Equal(vs[0].Values, map[string]string{"F2": "2"})
+
+	// 其它进程已经使用了相同的版本号
+	_, err = ss.db.Insert(&versionPO{Group: "opt", UID: sql.NullInt64{Valid: true, Int64: 1}, Version: 1})
+	a.Error(err)
+
+	a.NotError(obj.Rollback(11, 1, 1))
+	opt, err := obj.Get(1)
+	a.NotError(err).Equal(opt, &options{F1: "f1", F2: 2, F5: "f5"})
+
+	// 并发写入，版本号不重复。
+	wg := &sync.WaitGroup{}
+	for i := range 10 {
+		wg.Go(func() {
+			a.NotError(obj.Set(12, 2, &options{F1: "f1", F2: 100 + i}))
+		})
+	}
+	wg.Wait()
+	vs, err = obj.Versions(2)
+	a.NotError(err).Length(vs, 10)
+	for i, v := range vs {
+		a.Equal(v.Version, 10-i).Length(v.Values, 1)
+	}
+}
+
+func TestObject_Reset(t *testing.T) {
+	const tableName = "setting"
+
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+	mod := s.NewModule("mod")
+	Install(mod, tableName)
+
+	ss := New(mod, tableName)
+	a.NotError(InstallObject(ss, "opt", &options{F2: 2, F1: "f1"}))
+	obj, err := LoadObject[options](ss, "opt", web.Phrase("opt"), time.Minute*5)
+	a.NotError(err).NotNil(obj)
+
+	// 未修改过
+	a.NotError(obj.Reset(10, ss.presetUID))
+	vs, err := obj.Versions(ss.presetUID)
+	a.NotError(err).Empty(vs)
+
+	a.NotError(obj.Set(10, ss.presetUID, &options{F1: "p1", F2: 3}))
+	a.NotError(obj.Set(10, ss.presetUID, &options{F1: "p2", F2: 3}))
+	a.NotError(obj.Set(10, 1, &options{F1: "u1", F2: 4}))
+
+	// 普通用户重置为默认用户的值
+	a.NotError(obj.Reset(11, 1))
+	opt, err := obj.Get(1)
+	a.NotError(err).Equal(opt, &options{F1: "p2", F2: 3})
+	size, err := ss.db.Where("uid=?", 1).Select(true, &settingPO{})
+	a.NotError(err).Zero(size) // 删除了用户的设置项
+	vs, err = obj.Versions(1)
+	a.NotError(err).Length(vs, 2).
+		Equal(vs[0].Actor, 11).
+		Equal(vs[0].Values, map[string]string{"f1": `"u1"`, "F2": "4"})
+
+	// 已经是默认值，不产生新的版本。
+	a.NotError(obj.Reset(11, 1))
+	vs, err = obj.Versions(1)
+	a.NotError(err).Length(vs, 2)
+
+	// 默认用户的修改同步至普通用户
+	a.NotError(obj.Set(10, ss.presetUID, &options{F1: "p3", F2: 3}))
+	opt, err = obj.Get(1)
+	a.NotError(err).Equal(opt, &options{F1: "p3", F2: 3})
+
+	// 重置操作可以回滚
+	a.NotError(obj.Rollback(12, 1, vs[0].Version))
+	opt, err = obj.Get(1)
+	a.NotError(err).Equal(opt, &options{F1: "u1", F2: 4})
+
+	// 默认用户重置为安装时的值
+	a.NotError(obj.Reset(11, ss.presetUID))
+	opt, err = obj.Get(ss.presetUID)
+	a.NotError(err).Equal(opt, &options{F1: "f1", F2: 2})
+	vs, err = obj.Versions(ss.presetUID)
+	a.NotError(err).Length(vs, 4).Equal(vs[0].Actor, 11)
+}
+
 func TestGetFieldName(t *testing.T) {
 	a := assert.New(t, false)
 
@@ -0,0 +1,92 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package settings
+
+import (
+	"github.com/issue9/web"
+	"github.com/issue9/web/openapi"
+)
+
+type objectVO struct {
+	ID     string  `json:"id" yaml:"id" cbor:"id"`
+	Title  string  `json:"title" yaml:"title" cbor:"title"`
+	Schema *Schema `json:"schema" yaml:"schema" cbor:"schema"`
+}
+
+// Handle 为所有由 [LoadObject] 加载的设置对象注册接口
+//
+// 需要在所有设置对象加载完成之后调用，会注册以下接口：
+//   - GET {prefix}：所有设置对象的 ID、名称和 JSON Schema；
+//   - GET {prefix}/{id}：获取设置对象；
+//   - PUT {prefix}/{id}：修改设置对象；
+//   - DELETE {prefix}/{id}：将设置对象重置为默认值，参考 [Object.Reset]；
+//   - GET {prefix}/{id}/versions：设置对象的历史版本；
+//   - POST {prefix}/{id}/versions/{version}/rollback：回滚到指定版本修改之前的状态；
+//
+// res 用于生成 RBAC 资源，列表接口的资源 ID 为 get-settings；
+// 每个设置对象的只读接口(获取设置对象和历史版本)的资源 ID 为 get-setting-{id}，
+// 修改、重置和回滚接口的资源 ID 为 setting-{id}；
+// uid 用于获取被操作的设置对象所属的用户；actor 用于获取执行操作的用户；
+func (s *Settings) Handle(prefix *web.Prefix, api func(func(*openapi.Operation)) web.Middleware, res func(string, web.LocaleStringer) web.MiddlewareFunc, uid, actor func(*web.Context) int64) {
+	prefix.Get("", func(ctx *web.Context) web.Responser {
+		list := make([]*objectVO, 0, len(s.objects))
+		for _, o := range s.objects {
+			list = append(list, &objectVO{
+				ID:     o.ID(),
+				Title:  o.Title().LocaleString(ctx.LocalePrinter()),
+				Schema: o.Schema(ctx),
+			})
+		}
+		return web.OK(list)
+	}, res("get-settings", web.Phrase("view settings list")), api(func(o *openapi.Operation) {
+		o.Tag("settings").
+			Desc(web.Phrase("get settings list api"), nil).
+			Response200([]objectVO{})
+	}))
+
+	for _, obj := range s.objects {
+		getRes := res("get-setting-"+obj.ID(), web.Phrase("view settings %s", obj.Title()))
+		r := res("setting-"+obj.ID(), obj.Title())
+		path := "/" + obj.ID()
+
+		prefix.Get(path, func(ctx *web.Context) web.Responser {
+			return obj.HandleGet(ctx, uid(ctx))
+		}, getRes, api(func(o *openapi.Operation) {
+			o.Tag("settings").
+				Desc(web.Phrase("get settings api"), nil).
+				Response200(obj.zero())
+		})).
+			Put(path, func(ctx *web.Context) web.Responser {
+				return obj.HandlePut(ctx, actor(ctx), uid(ctx))
+			}, r, api(func(o *openapi.Operation) {
+				o.Tag("settings").
+					Desc(web.Phrase("put settings api"), nil).
+					Body(obj.zero(), false, nil, nil).
+					ResponseEmpty("204")
+			})).
+			Delete(path, func(ctx *web.Context) web.Responser {
+				return obj.HandleReset(ctx, actor(ctx), uid(ctx))
+			}, r, api(func(o *openapi.Operation) {
+				o.Tag("settings").
+					Desc(web.Phrase("reset settings api"), nil).
+					ResponseEmpty("204")
+			})).
+			Get(path+"/versions", func(ctx *web.Context) web.Responser {
+				return obj.HandleGetVersions(ctx, uid(ctx))
+			}, getRes, api(func(o *openapi.Operation) {
+				o.Tag("settings").
+					Desc(web.Phrase("get settings versions api"), nil).
+					Response200([]*Version{})
+			})).
+			Post(path+"/versions/{version:digit}/rollback", func(ctx *web.Context) web.Responser {
+				return obj.HandleRollback(ctx, actor(ctx), uid(ctx), "version")
+			}, r, api(func(o *openapi.Operation) {
+				o.Tag("settings").
+					Desc(web.Phrase("rollback settings api"), nil).
+					PathID("version:digit", web.Phrase("the setting version")).
+					ResponseEmpty("204")
+			}))
+	}
+}
@@ -0,0 +1,123 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package settings
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/mux/v9/header"
+	"github.com/issue9/web"
+	"github.com/issue9/web/server/servertest"
+
+	"github.com/issue9/cmfx/cmfx/filters"
+	"github.com/issue9/cmfx/cmfx/initial/test"
+)
+
+type filterObject struct {
+	Name  string `json:"name" comment:"name"`
+	Count int    `json:"count"`
+}
+
+func (o *filterObject) Filter(f *web.FilterContext) {
+	f.Add(filters.NotEmpty("name", &o.Name))
+}
+
+func TestSettings_Handle(t *testing.T) {
+	const tableName = "setting"
+
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+	mod := s.NewModule("mod")
+	Install(mod, tableName)
+
+	ss := New(mod, tableName)
+	a.NotError(InstallObject(ss, "opt", &options{F2: 2, F1: "f1"})).
+		NotError(InstallObject(ss, "filter", &filterObject{Name: "n1"}))
+	_, err := LoadObject[options](ss, "opt", web.Phrase("opt"), time.Minute*5)
+	a.NotError(err)
+	obj, err := LoadObject[filterObject](ss, "filter", web.Phrase("filter"), time.Minute*5)
+	a.NotError(err)
+
+	resources := make([]string, 0, 3)
+	res := func(id string, _ web.LocaleStringer) web.MiddlewareFunc {
+		resources = append(resources, id)
+		return func(next web.HandlerFunc, _, _, _ string) web.HandlerFunc { return next }
+	}
+	uid := func(*web.Context) int64 { return 1 }
+	actor := func(*web.Context) int64 { return 5 }
+	ss.Handle(s.Router().Prefix("/settings"), s.Module().API, res, uid, actor)
+	a.Equal(resources, []string{"get-settings", "get-setting-opt", "setting-opt", "get-setting-filter", "setting-filter"})
+
+	defer servertest.Run(a, s.Module().Server())()
+	defer s.Close()
+
+	s.Get("/settings").
+		Header(header.Accept, header.JSON).
+		Do(nil).
+		Status(http.StatusOK).
+		BodyFunc(func(a *assert.Assertion, body []byte) {
+			list := make([]*objectVO, 0, 2)
+			a.NotError(json.Unmarshal(body, &list)).Length(list, 2)
+			a.Equal(list[0].ID, "opt").
+				Equal(list[0].Title, "opt").
+				Equal(list[0].Schema.Properties["F2"], &Schema{Type: "integer"}).
+				Empty(list[0].Schema.Required)
+
+			a.Equal(list[1].ID, "filter").
+				Equal(list[1].Schema.Required, []string{"name"}).
+				Equal(list[1].Schema.Properties["name"].Title, "name").
+				NotEmpty(list[1].Schema.Properties["name"].Description)
+		})
+
+	s.Get("/settings/filter").
+		Header(header.Accept, header.JSON).
+		Do(nil).
+		Status(http.StatusOK).
+		StringBody(`{"name":"n1","count":0}`)
+
+	s.Put("/settings/filter", []byte(`{"name":"","count":1}`)).
+		Header(header.ContentType, header.JSON).
+		Do(nil).
+		Status(http.StatusBadRequest)
+
+	s.Put("/settings/filter", []byte(`{"name":"n2","count":1}`)).
+		Header(header.ContentType, header.JSON).
+		Do(nil).
+		Status(http.StatusNoContent)
+	o, err := obj.Get(1)
+	a.NotError(err).Equal(o, &filterObject{Name: "n2", Count: 1})
+
+	s.Get("/settings/filter/versions").
+		Header(header.Accept, header.JSON).
+		Do(nil).
+		Status(http.StatusOK).
+		BodyFunc(func(a *assert.Assertion, body []byte) {
+			vs := make([]*Version, 0, 1)
+			a.NotError(json.Unmarshal(body, &vs)).
+				Length(vs, 1).
+				Equal(vs[0].Actor, 5)
+		})
+
+	s.Post("/settings/filter/versions/2/rollback", nil).
+		Do(nil).
+		Status(http.StatusNotFound)
+
+	// 重置为默认用户的值
+	s.Delete("/settings/filter").
+		Do(nil).
+		Status(http.StatusNoContent)
+	o, err = obj.Get(1)
+	a.NotError(err).Equal(o, &filterObject{Name: "n1"})
+
+	s.Post("/settings/filter/versions/2/rollback", nil).
+		Do(nil).
+		Status(http.StatusNoContent)
+	o, err = obj.Get(1)
+	a.NotError(err).Equal(o, &filterObject{Name: "n2", Count: 1})
+}
@@ -0,0 +1,138 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package settings
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/issue9/web"
+	"github.com/issue9/web/openapi"
+	"golang.org/x/text/message"
+)
+
+var (
+	timeType          = reflect.TypeFor[time.Time]()
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// Schema 设置对象的 JSON Schema
+//
+// 仅实现了 JSON Schema 中与设置对象相关的部分字段，由 [Object.Schema] 生成。
+// 不包含取值范围、长度和枚举等约束，只能用于生成表单，不能代替服务端的验证。
+type Schema struct {
+	Type                 string             `json:"type,omitempty" yaml:"type,omitempty" cbor:"type,omitempty"`
+	Format               string             `json:"format,omitempty" yaml:"format,omitempty" cbor:"format,omitempty"`
+	Title                string             `json:"title,omitempty" yaml:"title,omitempty" cbor:"title,omitempty"`                   // 本地化的名称，由字段的 comment 标签指定。
+	Description          string             `json:"description,omitempty" yaml:"description,omitempty" cbor:"description,omitempty"` // 顶层字段的零值未通过验证时的错误信息
+	Items                *Schema            `json:"items,omitempty" yaml:"items,omitempty" cbor:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty" yaml:"properties,omitempty" cbor:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty" yaml:"additionalProperties,omitempty" cbor:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty" yaml:"required,omitempty" cbor:"required,omitempty"`
+}
+
+// 根据设置对象的类型 t 生成 [Schema]
+//
+// 只有会被保存的字段才会出现在 [Schema.Properties] 中，属性名称与 JSON 中的名称相同。
+func newSchema(p *message.Printer, t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema, t.NumField())}
+	seen := map[reflect.Type]bool{t: true}
+
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if getFieldName(f) == "" {
+			continue
+		}
+		if name := getJSONName(f); name != "" {
+			s.Properties[name] = fieldSchema(p, f, seen)
+		}
+	}
+
+	return s
+}
+
+func fieldSchema(p *message.Printer, f reflect.StructField, seen map[reflect.Type]bool) *Schema {
+	s := typeSchema(p, f.Type, seen)
+	if c := f.Tag.Get(openapi.CommentTag); c != "" {
+		s.Title = web.Phrase(c).LocaleString(p)
+	}
+	return s
+}
+
+func typeSchema(p *message.Printer, t reflect.Type, seen map[reflect.Type]bool) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	// 自定义了编码方式的类型无法确定其格式
+	if pt := reflect.PointerTo(t); pt.Implements(jsonMarshalerType) || pt.Implements(textMarshalerType) {
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 { // []byte 以 base64 编码
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: typeSchema(p, t.Elem(), seen)}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: typeSchema(p, t.Elem(), seen)}
+	case reflect.Struct:
+		s := &Schema{Type: "object"}
+		if seen[t] { // 循环引用
+			return s
+		}
+		seen[t] = true
+		defer delete(seen, t)
+
+		s.Properties = make(map[string]*Schema, t.NumField())
+		for i := range t.NumField() {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			if name := getJSONName(f); name != "" {
+				s.Properties[name] = fieldSchema(p, f, seen)
+			}
+		}
+		return s
+	default:
+		return &Schema{}
+	}
+}
+
+// 获取字段在 JSON 中的名称，返回空值表示不参与编码。
+func getJSONName(f reflect.StructField) string {
+	if !f.IsExported() {
+		return ""
+	}
+
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return f.Name
+	default:
+		return name
+	}
+}
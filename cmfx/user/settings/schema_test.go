@@ -0,0 +1,56 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package settings
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+	"golang.org/x/text/language"
+
+	"github.com/issue9/cmfx/cmfx/initial/test"
+)
+
+type schemaObject struct {
+	Str    string             `json:"str,omitempty" comment:"str"`
+	Int    int                `setting:"int"`
+	Float  float64            `json:"float"`
+	Bool   bool               `json:"bool"`
+	Bytes  []byte             `json:"bytes"`
+	Slice  []string           `json:"slice"`
+	Map    map[string]int     `json:"map"`
+	Time   time.Time          `json:"time"`
+	Ptr    *schemaObject      `json:"ptr"`
+	Nested struct{ X, y int } `json:"nested"`
+	Ignore string             `json:"-"`
+	NoSave string             `json:"noSave" setting:"-"`
+	f      int
+}
+
+func TestNewSchema(t *testing.T) {
+	a := assert.New(t, false)
+	s := test.NewSuite(a)
+	a.NotError(s.Module().Server().Locale().SetString(language.SimplifiedChinese, "str", "字符串"))
+	p := s.Module().Server().Locale().NewPrinter(language.SimplifiedChinese)
+
+	sc := newSchema(p, reflect.TypeFor[schemaObject]())
+	a.Equal(sc.Type, "object").
+		Length(sc.Properties, 10).
+		NotContains(sc.Properties, "Ignore").
+		NotContains(sc.Properties, "noSave")
+
+	a.Equal(sc.Properties["str"], &Schema{Type: "string", Title: "字符串"}).
+		Equal(sc.Properties["Int"], &Schema{Type: "integer"}).
+		Equal(sc.Properties["float"], &Schema{Type: "number"}).
+		Equal(sc.Properties["bool"], &Schema{Type: "boolean"}).
+		Equal(sc.Properties["bytes"], &Schema{Type: "string", Format: "byte"}).
+		Equal(sc.Properties["slice"], &Schema{Type: "array", Items: &Schema{Type: "string"}}).
+		Equal(sc.Properties["map"], &Schema{Type: "object", AdditionalProperties: &Schema{Type: "integer"}}).
+		Equal(sc.Properties["time"], &Schema{Type: "string", Format: "date-time"}).
+		Equal(sc.Properties["ptr"], &Schema{Type: "object"}). // 循环引用
+		Equal(sc.Properties["nested"], &Schema{Type: "object", Properties: map[string]*Schema{"X": {Type: "integer"}}})
+}
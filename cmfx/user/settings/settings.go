@@ -1,4 +1,4 @@
-// SPDX-FileCopyrightText: 2024-2025 caixw
+// SPDX-FileCopyrightText: 2024-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
@@ -16,11 +16,24 @@ import (
 
 type Settings struct {
 	db        *orm.DB
-	objects   []string
+	objects   []object // 由 LoadObject 加载的设置对象
 	presetUID int64
 	c         web.Cache
 }
 
+// 由 [Object] 实现，用于在 [Settings] 中保存不同类型的设置对象。
+type object interface {
+	ID() string
+	Title() web.LocaleStringer
+	Schema(*web.Context) *Schema
+	HandleGet(ctx *web.Context, uid int64) web.Responser
+	HandlePut(ctx *web.Context, actor, uid int64) web.Responser
+	HandleReset(ctx *web.Context, actor, uid int64) web.Responser
+	HandleGetVersions(ctx *web.Context, uid int64) web.Responser
+	HandleRollback(ctx *web.Context, actor, uid int64, versionKey string) web.Responser
+	zero() any // 设置对象的零值，用于生成文档。
+}
+
 func buildDB(mod *cmfx.Module, tableName string) *orm.DB {
 	return mod.DB().New(mod.DB().TablePrefix() + "_" + tableName)
 }
@@ -32,7 +45,7 @@ func buildDB(mod *cmfx.Module, tableName string) *orm.DB {
 func New(mod *cmfx.Module, tableName string) *Settings {
 	return &Settings{
 		db:        buildDB(mod, tableName),
-		objects:   make([]string, 0, 10),
+		objects:   make([]object, 0, 10),
 		presetUID: user.SpecialUserID,
 		c:         cache.Prefix(mod.Server().Cache(), mod.ID()),
 	}
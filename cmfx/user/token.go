@@ -1,4 +1,4 @@
-// SPDX-FileCopyrightText: 2022-2025 caixw
+// SPDX-FileCopyrightText: 2022-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
@@ -38,8 +38,14 @@ func (m *Users) SetState(tx *orm.Tx, u *User, s State) error {
 		}
 	}
 
-	_, err := m.mod.Engine(tx).Update(&User{ID: u.ID, State: s}, "state")
-	return err
+	if _, err := m.mod.Engine(tx).Update(&User{ID: u.ID, State: s}, "state"); err != nil {
+		return err
+	}
+
+	if s == StateDeleted {
+		return m.publish(tx, TopicDelete, u)
+	}
+	return nil
 }
 
 // 清空与 uid 相关的所有登录信息
@@ -153,7 +159,10 @@ func (m *Users) New(s State, username, password string, ip, ua, content string)
 		if u.ID, err = m.mod.Engine(tx).LastInsertID(u); err != nil {
 			return err
 		}
-		return m.AddSecurityLog(tx, u.ID, ip, ua, content)
+		if err = m.AddSecurityLog(tx, u.ID, ip, ua, content); err != nil {
+			return err
+		}
+		return m.publish(tx, TopicAdd, u)
 	})
 	if err != nil {
 		return nil, err
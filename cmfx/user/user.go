@@ -26,6 +26,7 @@ import (
 	"github.com/issue9/webuse/v7/middlewares/auth/token"
 
 	"github.com/issue9/cmfx/cmfx"
+	"github.com/issue9/cmfx/cmfx/outbox"
 )
 
 // SpecialUserID 特殊的用户 ID
@@ -46,11 +47,32 @@ type Users struct {
 	delEvent    *events.Event[*User]
 
 	passports []Passport
+	logins    *loginStats
+
+	// 如果未声明 [outbox.Outbox]，则为空。
+	outbox *outbox.Outbox
+}
+
+// 发布至 [outbox.Outbox] 的事件名称，可通过 [Users.Topic] 转换成主题。
+const (
+	TopicAdd    = "add"    // 添加用户
+	TopicDelete = "delete" // 删除用户
+)
+
+// Event 发布至 [outbox.Outbox] 的用户事件
+type Event struct {
+	ID int64  `json:"id"`
+	NO string `json:"no"`
+}
+
+// 令牌的缓存，以模块 ID 作为前缀。
+func newTokenStore(mod *cmfx.Module) token.Store[*User] {
+	return token.NewCacheStore[*User](cache.Prefix(mod.Server().Cache(), mod.ID()))
 }
 
 // NewUsers 声明 [Users] 对象
 func NewUsers(mod *cmfx.Module, conf *Config) *Users {
-	store := token.NewCacheStore[*User](cache.Prefix(mod.Server().Cache(), mod.ID()))
+	store := newTokenStore(mod)
 
 	m := &Users{
 		mod:       mod,
@@ -63,6 +85,9 @@ func NewUsers(mod *cmfx.Module, conf *Config) *Users {
 		delEvent:    events.New[*User](),
 
 		passports: make([]Passport, 0, 5),
+		logins:    &loginStats{counters: make(map[string]*loginCounter, 5)},
+
+		outbox: outbox.Get(mod.Server()),
 	}
 
 	mod.Router().Prefix(m.URLPrefix()).
@@ -158,6 +183,24 @@ func (m *Users) OnLogout(f func(*User)) context.CancelFunc { return m.logoutEven
 // OnAdd 添加新用户时的事件
 func (m *Users) OnAdd(f func(*User)) context.CancelFunc { return m.addEvent.Subscribe(f) }
 
+// Outbox 发布事件的 [outbox.Outbox] 对象
+//
+// 与 OnAdd 等基于内存的事件不同，通过此对象订阅的事件，即使处理失败或是进程退出，也会在之后重新投递。
+// 如果未声明 [outbox.Outbox]，则返回 nil。
+func (m *Users) Outbox() *outbox.Outbox { return m.outbox }
+
+// Topic 将事件名称 name 转换成当前模块在 [outbox.Outbox] 中的主题
+//
+// name 可以是 [TopicAdd] 或 [TopicDelete]。
+func (m *Users) Topic(name string) string { return m.Module().ID() + "_users_" + name }
+
+func (m *Users) publish(tx *orm.Tx, name string, u *User) error {
+	if m.outbox == nil {
+		return nil
+	}
+	return m.outbox.Publish(tx, m.Topic(name), &Event{ID: u.ID, NO: u.NO})
+}
+
 // OnDelete 删除用户时的事件
 func (m *Users) OnDelete(f func(*User)) context.CancelFunc { return m.addEvent.Subscribe(f) }
 
@@ -18,6 +18,7 @@ ignore (
 
 require (
 	github.com/fxamacker/cbor/v2 v2.9.0
+	github.com/go-sql-driver/mysql v1.9.3
 	github.com/go-webauthn/webauthn v0.15.0
 	github.com/goccy/go-yaml v1.19.2
 	github.com/issue9/assert/v4 v4.3.1
@@ -36,6 +37,7 @@ require (
 	github.com/issue9/web v0.104.5
 	github.com/issue9/webuse/v7 v7.0.0-20260330045341-9db2903dea90
 	github.com/kardianos/service v1.2.4
+	github.com/klauspost/compress v1.18.5
 	github.com/mattn/go-sqlite3 v1.14.37
 	github.com/shirou/gopsutil/v4 v4.26.2
 	golang.org/x/crypto v0.49.0
@@ -51,7 +53,6 @@ require (
 	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
 	github.com/ebitengine/purego v0.10.0 // indirect
 	github.com/go-ole/go-ole v1.3.0 // indirect
-	github.com/go-sql-driver/mysql v1.9.3 // indirect
 	github.com/go-viper/mapstructure/v2 v2.4.0 // indirect
 	github.com/go-webauthn/x v0.1.26 // indirect
 	github.com/golang-jwt/jwt/v5 v5.3.1 // indirect
@@ -65,7 +66,6 @@ require (
 	github.com/issue9/version v1.0.9 // indirect
 	github.com/issue9/watermark v1.2.5 // indirect
 	github.com/jellydator/ttlcache/v3 v3.4.0 // indirect
-	github.com/klauspost/compress v1.18.5 // indirect
 	github.com/lib/pq v1.12.0 // indirect
 	github.com/lufia/plan9stats v0.0.0-20250827001030-24949be3fa54 // indirect
 	github.com/power-devops/perfstat v0.0.0-20240221224432-82ca36839d55 // indirect